@@ -21,9 +21,9 @@ esac
 
 echo "Target architecture: $GOARCH"
 echo ""
-echo "Note: For module execution on Termux, use the 'clio-run-module' script"
-echo "      (Installed automatically by install.sh alongside the binary)"
-echo "      This avoids Android seccomp syscall restrictions (pidfd_open)"
+echo "Note: Modules run with 'clio run <module_id> [flow]'"
+echo "      Commands are spawned through safeexec, which avoids Android"
+echo "      seccomp syscall restrictions (clone3, faccessat2, pidfd_open)"
 echo ""
 
 # Build with Android compatibility
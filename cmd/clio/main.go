@@ -2,8 +2,11 @@ package main
 
 import (
 	"bufio"
+	"clio/internal/cli"
 	"clio/internal/config"
+	"clio/internal/examples"
 	"clio/internal/intent"
+	"clio/internal/layer1"
 	"clio/internal/repl"
 	"clio/internal/setup"
 	"fmt"
@@ -16,6 +19,14 @@ import (
 func main() {
 	applyMemoryProfile()
 
+	if len(os.Args) > 1 && cli.IsCommand(os.Args[1]) {
+		// eval scores the built-in catalogs; it loads user rules itself with --live
+		if os.Args[1] != "eval" {
+			loadUserCommands()
+		}
+		os.Exit(cli.Run(os.Args[1:]))
+	}
+	loadUserCommands()
 	if !isInteractive() {
 		runPipeMode()
 		return
@@ -33,6 +44,16 @@ func applyMemoryProfile() {
 	}
 }
 
+// loadUserCommands merges ~/.clio/commands.yaml (and ./.clio/commands.yaml
+// with project_commands set) into the static catalogs, and the examples.yaml next to them into the examples
+// database. Bad entries are reported and skipped, never fatal.
+func loadUserCommands() {
+	errs := append(layer1.LoadUserCommands(), examples.LoadUserExamples()...)
+	for _, err := range errs {
+		fmt.Fprintf(os.Stderr, "⚠️  %v\n", err)
+	}
+}
+
 func isInteractive() bool {
 	stat, err := os.Stdin.Stat()
 	if err != nil {
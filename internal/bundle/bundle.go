@@ -0,0 +1,328 @@
+// Package bundle writes and reads offline bundles: one signed, compressed
+// file holding modules, the query cache and imported reference data, so a
+// single sync can be passed between devices over Bluetooth or a USB stick.
+package bundle
+
+import (
+	"bufio"
+	"bytes"
+	"clio/internal/examples"
+	"clio/internal/layer3"
+	"clio/internal/layer4"
+	"clio/internal/modules"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// formatVersion is written in the first line of every bundle; Import refuses
+// other versions.
+const formatVersion = 1
+
+// magic starts every bundle file.
+const magic = "clio-bundle"
+
+const (
+	// maxBundleBytes bounds the file Import reads.
+	maxBundleBytes = 64 << 20
+	// maxPayloadBytes bounds the decompressed payload, against gzip bombs.
+	maxPayloadBytes = 256 << 20
+)
+
+// ErrUntrusted is returned by Import for a bundle signed by a key that is
+// neither this device's nor trusted.
+var ErrUntrusted = errors.New("bundle signer is not trusted")
+
+// payload is the signed, compressed body of a bundle.
+type payload struct {
+	Format    int                 `json:"format"`
+	CreatedAt time.Time           `json:"created_at"`
+	Modules   []bundledModule     `json:"modules"`
+	Cache     []layer4.CacheEntry `json:"cache,omitempty"`
+	TLDR      *bundledTLDR        `json:"tldr,omitempty"`
+	Examples  string              `json:"examples,omitempty"` // synced examples file
+}
+
+type bundledModule struct {
+	ID       string `json:"id"`
+	Checksum string `json:"checksum"` // SHA-256 of Content, as in the registry index
+	Content  string `json:"content"`
+}
+
+type bundledTLDR struct {
+	Source string            `json:"source"`
+	Pages  []layer3.TLDRPage `json:"pages"`
+}
+
+// ExportStats describes a written bundle.
+type ExportStats struct {
+	Path      string `json:"path"`
+	Signer    string `json:"signer"` // fingerprint of this device's key
+	Modules   int    `json:"modules"`
+	Cache     int    `json:"cache"`
+	TLDRPages int    `json:"tldr_pages"`
+	Examples  bool   `json:"examples"`
+	Bytes     int64  `json:"bytes"`
+}
+
+// ImportStats describes what Import merged.
+type ImportStats struct {
+	Signer    string    `json:"signer"`
+	CreatedAt time.Time `json:"created_at"`
+	Modules   int       `json:"modules"`           // added or updated
+	Unchanged int       `json:"unchanged"`         // local checksum already matched
+	Pinned    []string  `json:"pinned,omitempty"`  // left as they are
+	Local     []string  `json:"local,omitempty"`   // installed from a file here; left as they are
+	Invalid   []string  `json:"invalid,omitempty"` // checksum mismatch or unparsable YAML
+	Cache     int       `json:"cache"`
+	TLDRPages int       `json:"tldr_pages"`
+	Examples  int       `json:"examples"`
+}
+
+// Export writes a bundle of the given modules, or all when moduleIDs is
+// empty, with the query cache and imported reference data, signed with this
+// device's key.
+func Export(path string, moduleIDs []string) (ExportStats, error) {
+	stats := ExportStats{Path: path}
+	sources, err := layer3.ModuleSources(moduleIDs)
+	if err != nil {
+		return stats, err
+	}
+	p := payload{Format: formatVersion, CreatedAt: time.Now().UTC()}
+	for _, s := range sources {
+		sum := fmt.Sprintf("%x", sha256.Sum256([]byte(s.Content)))
+		p.Modules = append(p.Modules, bundledModule{ID: s.ModuleID, Checksum: sum, Content: s.Content})
+	}
+	if p.Cache, err = layer4.CacheEntries(); err != nil {
+		return stats, err
+	}
+	pages, err := layer3.TLDRPages()
+	if err != nil {
+		return stats, err
+	}
+	if len(pages) > 0 {
+		source := "bundle"
+		if st, err := layer3.GetTLDRStatus(); err == nil && st != nil && st.Source != "" {
+			source = st.Source
+		}
+		p.TLDR = &bundledTLDR{Source: source, Pages: pages}
+	}
+	if synced, err := examples.SyncedPath(); err == nil {
+		if data, err := os.ReadFile(synced); err == nil {
+			p.Examples = string(data)
+		}
+	}
+
+	key, err := signingKey()
+	if err != nil {
+		return stats, err
+	}
+	data, err := encode(p, key)
+	if err != nil {
+		return stats, err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return stats, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return stats, err
+	}
+
+	stats.Signer = Fingerprint(key.Public().(ed25519.PublicKey))
+	stats.Modules = len(p.Modules)
+	stats.Cache = len(p.Cache)
+	if p.TLDR != nil {
+		stats.TLDRPages = len(p.TLDR.Pages)
+	}
+	stats.Examples = p.Examples != ""
+	stats.Bytes = int64(len(data))
+	return stats, nil
+}
+
+// encode compresses p and prepends the header:
+//
+//	clio-bundle 1
+//	signer <base64 public key>
+//	signature <base64 ed25519 signature of the compressed payload>
+//	<blank line>
+func encode(p payload, key ed25519.PrivateKey) ([]byte, error) {
+	var body bytes.Buffer
+	zw := gzip.NewWriter(&body)
+	if err := json.NewEncoder(zw).Encode(p); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "%s %d\n", magic, formatVersion)
+	fmt.Fprintf(&out, "signer %s\n", base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey)))
+	fmt.Fprintf(&out, "signature %s\n\n", base64.StdEncoding.EncodeToString(ed25519.Sign(key, body.Bytes())))
+	out.Write(body.Bytes())
+	return out.Bytes(), nil
+}
+
+// decode checks a bundle's signature and returns its signer and payload.
+// Nothing is decompressed before the signature is verified.
+func decode(data []byte) (ed25519.PublicKey, payload, error) {
+	var p payload
+	r := bufio.NewReader(bytes.NewReader(data))
+	header := make(map[string]string)
+	for i := 0; ; i++ {
+		line, err := r.ReadString('\n')
+		if err != nil || i > 8 {
+			return nil, p, errors.New("not a clio bundle")
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			break
+		}
+		k, v, _ := strings.Cut(line, " ")
+		header[k] = v
+	}
+	if v, ok := header[magic]; !ok {
+		return nil, p, errors.New("not a clio bundle")
+	} else if v != fmt.Sprint(formatVersion) {
+		return nil, p, fmt.Errorf("bundle format %s is not supported; update clio", v)
+	}
+	pub, err := base64.StdEncoding.DecodeString(header["signer"])
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return nil, p, errors.New("bundle has no valid signer")
+	}
+	sig, err := base64.StdEncoding.DecodeString(header["signature"])
+	if err != nil {
+		return nil, p, errors.New("bundle has no valid signature")
+	}
+	body, _ := io.ReadAll(r)
+	if !ed25519.Verify(pub, body, sig) {
+		return nil, p, errors.New("bundle signature does not match; the file is damaged or was changed")
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, p, err
+	}
+	raw, err := io.ReadAll(io.LimitReader(zr, maxPayloadBytes+1))
+	if err != nil {
+		return nil, p, err
+	}
+	if len(raw) > maxPayloadBytes {
+		return nil, p, fmt.Errorf("bundle exceeds %d byte limit", maxPayloadBytes)
+	}
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return nil, p, fmt.Errorf("bundle payload: %w", err)
+	}
+	if p.Format != formatVersion {
+		return nil, p, fmt.Errorf("bundle format %d is not supported; update clio", p.Format)
+	}
+	return ed25519.PublicKey(pub), p, nil
+}
+
+func readBundle(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxBundleBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxBundleBytes {
+		return nil, fmt.Errorf("%s exceeds %d byte limit", filepath.Base(path), maxBundleBytes)
+	}
+	return data, nil
+}
+
+// Import verifies a bundle and merges it into this device's database.
+// Modules are checked and stored as SyncFromRegistry does: one whose YAML
+// does not match its checksum is skipped, as is one whose local checksum
+// already matches, that is pinned or that was installed from a local file.
+// Cached results are merged, newest winning; tldr pages and synced examples
+// replace the local ones. A bundle
+// from an unknown signer is refused with ErrUntrusted unless trustSigner is
+// set, which adds the signer to the trusted list first.
+func Import(path string, trustSigner bool) (ImportStats, error) {
+	var stats ImportStats
+	data, err := readBundle(path)
+	if err != nil {
+		return stats, err
+	}
+	pub, p, err := decode(data)
+	if err != nil {
+		return stats, err
+	}
+	stats.Signer = Fingerprint(pub)
+	stats.CreatedAt = p.CreatedAt
+	ok, err := trusted(pub)
+	if err != nil {
+		return stats, err
+	}
+	if !ok {
+		if !trustSigner {
+			return stats, fmt.Errorf("%w: %s", ErrUntrusted, stats.Signer)
+		}
+		if err := trust(pub); err != nil {
+			return stats, err
+		}
+	}
+
+	for _, m := range p.Modules {
+		if m.ID == "" || fmt.Sprintf("%x", sha256.Sum256([]byte(m.Content))) != m.Checksum {
+			stats.Invalid = append(stats.Invalid, m.ID)
+			continue
+		}
+		if pinned, _ := layer3.IsModulePinned(m.ID); pinned {
+			stats.Pinned = append(stats.Pinned, m.ID)
+			continue
+		}
+		if local, _ := layer3.IsModuleLocal(m.ID); local {
+			stats.Local = append(stats.Local, m.ID)
+			continue
+		}
+		if local, err := layer3.GetModuleChecksum(m.ID); err == nil && local == m.Checksum {
+			stats.Unchanged++
+			continue
+		}
+		if err := modules.StoreModule(m.ID, []byte(m.Content)); err != nil {
+			switch {
+			case errors.Is(err, layer3.ErrModulePinned):
+				stats.Pinned = append(stats.Pinned, m.ID)
+			case errors.Is(err, layer3.ErrModuleLocal):
+				stats.Local = append(stats.Local, m.ID)
+			default:
+				stats.Invalid = append(stats.Invalid, m.ID)
+			}
+			continue
+		}
+		stats.Modules++
+	}
+
+	if stats.Cache, err = layer4.MergeCache(p.Cache); err != nil {
+		return stats, err
+	}
+	if p.TLDR != nil && len(p.TLDR.Pages) > 0 {
+		if err := layer3.ReplaceTLDR(p.TLDR.Pages, p.TLDR.Source); err != nil {
+			return stats, err
+		}
+		stats.TLDRPages = len(p.TLDR.Pages)
+	}
+	if p.Examples != "" {
+		if stats.Examples, err = examples.SaveSynced([]byte(p.Examples), "bundled examples"); err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
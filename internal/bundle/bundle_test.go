@@ -0,0 +1,211 @@
+package bundle
+
+import (
+	"bytes"
+	"clio/internal/layer3"
+	"clio/internal/layer4"
+	"clio/internal/modules"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const greetV1 = `name: Greet
+description: Say hello
+version: "1.0"
+flows:
+  - name: run
+    steps:
+      - type: command
+        command: echo hello
+`
+
+const greetV2 = `name: Greet
+description: Say hello twice
+version: "2.0"
+flows:
+  - name: run
+    steps:
+      - type: command
+        command: echo hello hello
+`
+
+func checksum(s string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(s)))
+}
+
+func storedChecksum(t *testing.T, id string) string {
+	t.Helper()
+	sum, err := layer3.GetModuleChecksum(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sum
+}
+
+// writeSigned writes a bundle of p signed with a new key, as another device
+// would, and returns that key.
+func writeSigned(t *testing.T, p payload) (string, ed25519.PublicKey) {
+	t.Helper()
+	pub, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Format = formatVersion
+	data, err := encode(p, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "other.clio")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path, pub
+}
+
+func TestExportImport(t *testing.T) {
+	layer3.UseTestDB(t)
+	if err := modules.StoreModule("greet", []byte(greetV1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := layer4.PutCached("list open ports", layer4.CommandResult{Name: "ss -tlnp", Description: "Listening TCP sockets"}); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "sync.clio")
+	exp, err := Export(path, []string{"greet"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp.Modules != 1 || exp.Cache != 1 || exp.Signer == "" {
+		t.Errorf("Export = %+v, want 1 module and 1 cached result", exp)
+	}
+
+	// Importing onto the same state changes nothing
+	stats, err := Import(path, false)
+	if err != nil {
+		t.Fatalf("Import of own bundle: %v", err)
+	}
+	if stats.Modules != 0 || stats.Unchanged != 1 || stats.Signer != exp.Signer {
+		t.Errorf("Import = %+v, want 1 unchanged module signed by %s", stats, exp.Signer)
+	}
+
+	// A newer local module is replaced by the bundled one, as sync would
+	if err := modules.StoreModule("greet", []byte(greetV2)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := layer4.ClearCache(); err != nil {
+		t.Fatal(err)
+	}
+	stats, err = Import(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Modules != 1 || stats.Cache != 1 {
+		t.Errorf("Import = %+v, want 1 module and 1 cached result", stats)
+	}
+	if sum := storedChecksum(t, "greet"); sum != checksum(greetV1) {
+		t.Errorf("greet checksum = %s, want the bundled version's", sum)
+	}
+	if res, ok := layer4.GetCached("list open ports", time.Hour); !ok || res.Name != "ss -tlnp" {
+		t.Errorf("cached result after import = %+v, %v", res, ok)
+	}
+	if versions, _ := layer3.ModuleVersions("greet"); len(versions) < 2 {
+		t.Errorf("import did not record a version: %+v", versions)
+	}
+}
+
+func TestImportTampered(t *testing.T) {
+	layer3.UseTestDB(t)
+	path, _ := writeSigned(t, payload{Modules: []bundledModule{{ID: "greet", Checksum: checksum(greetV2), Content: greetV2}}})
+	data, _ := os.ReadFile(path)
+	data[len(data)-10] ^= 0xff
+	os.WriteFile(path, data, 0o644)
+
+	if _, err := Import(path, true); err == nil {
+		t.Fatal("Import of a changed bundle succeeded")
+	}
+	if err := os.WriteFile(path, []byte("PK\x03\x04 not a bundle"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Import(path, true); err == nil {
+		t.Fatal("Import of a zip file succeeded")
+	}
+}
+
+func TestImportUntrustedSigner(t *testing.T) {
+	layer3.UseTestDB(t)
+	if err := modules.StoreModule("greet", []byte(greetV1)); err != nil {
+		t.Fatal(err)
+	}
+	path, pub := writeSigned(t, payload{Modules: []bundledModule{{ID: "greet", Checksum: checksum(greetV2), Content: greetV2}}})
+
+	stats, err := Import(path, false)
+	if !errors.Is(err, ErrUntrusted) {
+		t.Fatalf("Import from an unknown signer: err = %v, want ErrUntrusted", err)
+	}
+	if stats.Signer != Fingerprint(pub) {
+		t.Errorf("Signer = %q, want %q", stats.Signer, Fingerprint(pub))
+	}
+	if sum := storedChecksum(t, "greet"); sum != checksum(greetV1) {
+		t.Error("untrusted bundle changed a module")
+	}
+
+	if _, err := Import(path, true); err != nil {
+		t.Fatalf("Import with trust: %v", err)
+	}
+	if sum := storedChecksum(t, "greet"); sum != checksum(greetV2) {
+		t.Error("trusted bundle did not update the module")
+	}
+	// Trust is remembered
+	if ok, err := trusted(pub); err != nil || !ok {
+		t.Errorf("trusted = %v, %v after --trust", ok, err)
+	}
+}
+
+func TestImportSkipsInvalidPinnedAndLocal(t *testing.T) {
+	layer3.UseTestDB(t)
+	for _, id := range []string{"greet", "pinned"} {
+		if err := modules.StoreModule(id, []byte(greetV1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := layer3.UpsertLocalModule("mine", "Greet", "Say hello", "", "1.0", greetV1, "", checksum(greetV1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := layer3.PinModule("pinned", true); err != nil {
+		t.Fatal(err)
+	}
+
+	path, _ := writeSigned(t, payload{Modules: []bundledModule{
+		{ID: "greet", Checksum: checksum(greetV1), Content: greetV2},
+		{ID: "broken", Checksum: checksum("flows: ["), Content: "flows: ["},
+		{ID: "pinned", Checksum: checksum(greetV2), Content: greetV2},
+		{ID: "mine", Checksum: checksum(greetV2), Content: greetV2},
+	}})
+	stats, err := Import(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Modules != 0 || len(stats.Invalid) != 2 || len(stats.Pinned) != 1 || len(stats.Local) != 1 {
+		t.Errorf("Import = %+v, want 2 invalid, 1 pinned and 1 local", stats)
+	}
+	for _, id := range []string{"greet", "pinned", "mine"} {
+		if sum := storedChecksum(t, id); sum != checksum(greetV1) {
+			t.Errorf("%s was changed", id)
+		}
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	pub := ed25519.PublicKey(bytes.Repeat([]byte{1}, ed25519.PublicKeySize))
+	fp := Fingerprint(pub)
+	if len(fp) != 19 || fp != Fingerprint(pub) {
+		t.Errorf("Fingerprint = %q", fp)
+	}
+}
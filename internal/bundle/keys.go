@@ -0,0 +1,139 @@
+package bundle
+
+import (
+	"bufio"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// clioDir returns ~/.clio, where the signing key and trusted signers live.
+func clioDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".clio"), nil
+}
+
+// KeyPath is this device's bundle signing key, created by the first export.
+func KeyPath() (string, error) {
+	dir, err := clioDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "bundle.key"), nil
+}
+
+// TrustedPath lists the public keys whose bundles import without --trust,
+// one base64 key per line, optionally followed by a comment.
+func TrustedPath() (string, error) {
+	dir, err := clioDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "trusted_signers"), nil
+}
+
+// Fingerprint is a short name for a public key, for comparing out loud or
+// over chat with the sender of a bundle.
+func Fingerprint(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	hex := fmt.Sprintf("%x", sum[:8])
+	return hex[:4] + "-" + hex[4:8] + "-" + hex[8:12] + "-" + hex[12:]
+}
+
+// signingKey loads this device's key, creating it on first use.
+func signingKey() (ed25519.PrivateKey, error) {
+	path, err := KeyPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err == nil {
+		seed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil || len(seed) != ed25519.SeedSize {
+			return nil, fmt.Errorf("%s: not a bundle signing key", path)
+		}
+		return ed25519.NewKeyFromSeed(seed), nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	seed := base64.StdEncoding.EncodeToString(key.Seed()) + "\n"
+	if err := os.WriteFile(path, []byte(seed), 0o600); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// trusted reports whether bundles signed by pub are accepted: it is this
+// device's own key or listed in TrustedPath.
+func trusted(pub ed25519.PublicKey) (bool, error) {
+	if path, err := KeyPath(); err == nil {
+		if _, err := os.Stat(path); err == nil {
+			key, err := signingKey()
+			if err != nil {
+				return false, err
+			}
+			if key.Public().(ed25519.PublicKey).Equal(pub) {
+				return true, nil
+			}
+		}
+	}
+
+	path, err := TrustedPath()
+	if err != nil {
+		return false, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	want := base64.StdEncoding.EncodeToString(pub)
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if fields := strings.Fields(sc.Text()); len(fields) > 0 && fields[0] == want {
+			return true, nil
+		}
+	}
+	return false, sc.Err()
+}
+
+// trust adds pub to TrustedPath.
+func trust(pub ed25519.PublicKey) error {
+	path, err := TrustedPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(f, "%s  # %s\n", base64.StdEncoding.EncodeToString(pub), Fingerprint(pub))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
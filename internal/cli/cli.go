@@ -0,0 +1,1074 @@
+// Package cli implements clio's non-interactive subcommands (clio ask, clio sync, …)
+// for use from shell scripts, editor plugins and CI.
+package cli
+
+import (
+	"bufio"
+	"clio/internal/bundle"
+	"clio/internal/config"
+	"clio/internal/eval"
+	"clio/internal/examples"
+	"clio/internal/explain"
+	"clio/internal/intent"
+	"clio/internal/layer1"
+	"clio/internal/layer2"
+	"clio/internal/layer3"
+	"clio/internal/layer4"
+	"clio/internal/modules"
+	"clio/internal/platform"
+	"clio/internal/risk"
+	"clio/internal/setup"
+	"clio/internal/tldr"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+)
+
+// Exit codes returned by Run.
+const (
+	ExitOK         = 0
+	ExitNoMatch    = 1 // ask found nothing; mirrors grep
+	ExitUsage      = 2
+	ExitError      = 3
+	ExitIncomplete = 4 // ask printed a command with slots still to fill in
+)
+
+var (
+	stdout io.Writer = os.Stdout
+	stderr io.Writer = os.Stderr
+)
+
+// usageError marks errors caused by bad arguments rather than runtime failures.
+type usageError struct{ msg string }
+
+func (e *usageError) Error() string { return e.msg }
+
+func usagef(format string, a ...interface{}) error {
+	return &usageError{msg: fmt.Sprintf(format, a...)}
+}
+
+// errNoMatch is returned when ask finds no command for the query.
+var errNoMatch = errors.New("no match")
+
+// errIncomplete is returned when ask printed a command whose slots the query
+// did not fill; the slots have already been reported.
+var errIncomplete = errors.New("incomplete command")
+
+type command struct {
+	name    string
+	usage   string
+	summary string
+	run     func(args []string) error
+}
+
+func commands() []command {
+	return []command{
+		{"ask", "ask [--json] [--top N] <query>", "Print the best command for a natural-language query", runAsk},
+		{"explain", "explain [--json] <command line>", "Describe each program, flag and operand of a command", runExplain},
+		{"examples", "examples [--json] [--limit N] <program or query>", "Show worked examples for a program or task", runExamples},
+		{"tldr", "tldr import <zip or dir> | sync | status [--json]", "Import tldr-pages for offline search", runTLDR},
+		{"index", "index [--rebuild] [--harvest] [--json]", "Build or refresh the man page search index, optionally with --help output", runIndex},
+		{"eval", "eval [--top K] [--corpus F] [--baseline F] [--write-baseline F] [--live] [--json]", "Score matching against the query corpus and its baseline", runEval},
+		{"sync", "sync [--full] [--json]", "Download changed modules from the registry", runSync},
+		{"run", "run [--plan] [--var k=v] [--resume] <module> [flow]", "Run a module flow, print its plan, or resume a failed run", runRun},
+		{"module", "module list|show|run|versions|diff|pin|unpin|rollback|remove <id> [flow|version...] | add <file or dir> [--watch] [--json]", "Inspect, run, pin, roll back or install automation modules", runModule},
+		{"setup", "setup [wizard] [--json]", "List setup wizards or show one", runSetup},
+		{"history", "history [--limit N] [clear] [--json]", "List past queries and outcomes, or wipe them", runHistory},
+		{"bundle", "bundle export <file> [module...] | import <file> [--trust] [--json]", "Share modules, cache and reference data offline as one signed file", runBundle},
+		{"cache", "cache stats|clear [--json]", "Inspect or clear the remote search cache", runCache},
+		{"config", "config get [key] | set <key> <value> [--json]", "Read or change ~/.clio/config.yaml", runConfig},
+	}
+}
+
+// IsCommand reports whether arg names a subcommand (or a help flag).
+func IsCommand(arg string) bool {
+	switch arg {
+	case "help", "-h", "--help":
+		return true
+	}
+	for _, c := range commands() {
+		if c.name == arg {
+			return true
+		}
+	}
+	return false
+}
+
+// Run executes a subcommand and returns the process exit code.
+func Run(args []string) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		printUsage(stdout)
+		return ExitOK
+	}
+	for _, c := range commands() {
+		if c.name != args[0] {
+			continue
+		}
+		err := c.run(args[1:])
+		var uerr *usageError
+		switch {
+		case err == nil:
+			return ExitOK
+		case errors.Is(err, errNoMatch):
+			fmt.Fprintf(stderr, "clio: %v\n", err)
+			return ExitNoMatch
+		case errors.Is(err, errIncomplete):
+			return ExitIncomplete
+		case errors.As(err, &uerr):
+			fmt.Fprintf(stderr, "clio %s: %v\nusage: clio %s\n", c.name, err, c.usage)
+			return ExitUsage
+		default:
+			fmt.Fprintf(stderr, "clio %s: %v\n", c.name, err)
+			return ExitError
+		}
+	}
+	fmt.Fprintf(stderr, "clio: unknown command %q\n", args[0])
+	printUsage(stderr)
+	return ExitUsage
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: clio [command]")
+	fmt.Fprintln(w, "Without a command clio starts the interactive assistant.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, c := range commands() {
+		fmt.Fprintf(w, "  %-48s %s\n", c.usage, c.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Exit codes: %d ok · %d no match · %d usage · %d error · %d slots to fill in\n",
+		ExitOK, ExitNoMatch, ExitUsage, ExitError, ExitIncomplete)
+}
+
+// parseFlags parses the flags before the first positional argument, as the
+// flag package does, so "ask what does ls -la do" keeps -la in the query.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	fs.SetOutput(io.Discard)
+	if err := fs.Parse(args); err != nil {
+		return nil, usagef("%v", err)
+	}
+	return fs.Args(), nil
+}
+
+// parseInterspersed parses flags wherever they appear, so "module list
+// --json" works. It is for commands whose arguments are subcommands, ids and
+// paths; free text such as a query must use parseFlags.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	fs.SetOutput(io.Discard)
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, usagef("%v", err)
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+func writeJSON(v interface{}) error {
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// withStdoutTo sends package-level prints (sync progress, guides) to w while fn runs,
+// keeping --json output machine-readable.
+func withStdoutTo(w *os.File, fn func() error) error {
+	saved := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = saved }()
+	return fn()
+}
+
+type askResult struct {
+	Command     string   `json:"command"`
+	Description string   `json:"description"`
+	Source      string   `json:"source"`
+	Confidence  float64  `json:"confidence"`
+	Missing     []string `json:"missing,omitempty"`
+	Reasons     []string `json:"reasons,omitempty"`
+	Risk        string   `json:"risk"`
+	// NotInstalled, Install and Alternative are set when the binary is not on PATH.
+	NotInstalled string `json:"not_installed,omitempty"`
+	Install      string `json:"install,omitempty"`
+	Alternative  string `json:"alternative,omitempty"`
+	// Stages explains each step when Command was composed from a compound query.
+	Stages []layer1.PipelineStage `json:"stages,omitempty"`
+	// Project is the project context label when Command was chosen for it.
+	Project string `json:"project,omitempty"`
+}
+
+func toAskResult(r *intent.DetectionResult) askResult {
+	out := askResult{
+		Command:     r.Command,
+		Description: r.Description,
+		Source:      r.Source,
+		Confidence:  r.Confidence,
+		Reasons:     r.Reasons,
+		Risk:        risk.Classify(r.Command).Level.String(),
+
+		NotInstalled: r.NotInstalled,
+		Install:      r.Install,
+		Alternative:  r.Alternative,
+		Project:      r.Project,
+		Stages:       r.Stages,
+	}
+	for _, s := range r.Missing {
+		out.Missing = append(out.Missing, string(s.Type))
+	}
+	return out
+}
+
+func runAsk(args []string) error {
+	fs := flag.NewFlagSet("ask", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "")
+	top := fs.Int("top", 1, "")
+	rest, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	query := strings.TrimSpace(strings.Join(rest, " "))
+	if query == "" {
+		return usagef("query required")
+	}
+
+	var results []*intent.DetectionResult
+	if *top > 1 {
+		results, err = intent.DetectAll(query, *top)
+	} else {
+		var r *intent.DetectionResult
+		r, err = intent.Detect(query)
+		results = []*intent.DetectionResult{r}
+	}
+	if err != nil {
+		return fmt.Errorf("%w for %q", errNoMatch, query)
+	}
+
+	if *asJSON {
+		out := make([]askResult, 0, len(results))
+		for _, r := range results {
+			out = append(out, toAskResult(r))
+		}
+		if *top > 1 {
+			err = writeJSON(out)
+		} else {
+			err = writeJSON(out[0])
+		}
+		if err == nil && len(results[0].Missing) > 0 {
+			err = errIncomplete
+		}
+		return err
+	}
+	for _, r := range results {
+		fmt.Fprintln(stdout, r.Command)
+	}
+	for _, s := range results[0].Missing {
+		fmt.Fprintf(stderr, "clio: fill in %s (%s)\n", s.Token, s.Hint())
+	}
+	if r := results[0]; r.NotInstalled != "" {
+		fmt.Fprintf(stderr, "clio: %s is not installed", r.NotInstalled)
+		if r.Install != "" {
+			fmt.Fprintf(stderr, "; install with: %s", r.Install)
+		}
+		fmt.Fprintln(stderr)
+	}
+	if len(results[0].Missing) > 0 {
+		return errIncomplete
+	}
+	return nil
+}
+
+func runExplain(args []string) error {
+	fs := flag.NewFlagSet("explain", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "")
+	fs.SetOutput(io.Discard)
+	// Only leading flags are ours; the command line keeps its own (-xzvf)
+	if err := fs.Parse(args); err != nil {
+		return usagef("%v", err)
+	}
+	line := strings.TrimSpace(strings.Join(fs.Args(), " "))
+	if line == "" {
+		return usagef("command line required")
+	}
+	x, err := explain.Explain(line)
+	if err != nil {
+		return usagef("%v", err)
+	}
+	if *asJSON {
+		return writeJSON(x)
+	}
+	x.Write(stdout)
+	return nil
+}
+
+func runExamples(args []string) error {
+	fs := flag.NewFlagSet("examples", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "")
+	limit := fs.Int("limit", 8, "")
+	fs.SetOutput(io.Discard)
+	// Only leading flags are ours; the query may name flags (rsync --delete)
+	if err := fs.Parse(args); err != nil {
+		return usagef("%v", err)
+	}
+	query := strings.TrimSpace(strings.Join(fs.Args(), " "))
+	if query == "" {
+		return usagef("program or query required")
+	}
+	if q, ok := examples.ParseQuery(query); ok {
+		query = q
+	}
+	exs := examples.Search(query, platform.Current())
+	if len(exs) == 0 {
+		return fmt.Errorf("%w for %q", errNoMatch, query)
+	}
+	if *limit > 0 && len(exs) > *limit {
+		exs = exs[:*limit]
+	}
+	if *asJSON {
+		return writeJSON(exs)
+	}
+	examples.Write(stdout, exs)
+	return nil
+}
+
+func runTLDR(args []string) error {
+	fs := flag.NewFlagSet("tldr", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "")
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) == 0 {
+		return usagef("expected import, sync or status")
+	}
+
+	var n int
+	switch rest[0] {
+	case "import":
+		if len(rest) != 2 {
+			return usagef("import takes one zip archive or directory")
+		}
+		n, err = tldr.Import(rest[1])
+	case "sync":
+		url := config.GetTLDRURL()
+		if url == "" {
+			return fmt.Errorf("tldr_url is off")
+		}
+		n, err = tldr.Sync(url)
+	case "status":
+		st, err := layer3.GetTLDRStatus()
+		if err != nil {
+			return err
+		}
+		if *asJSON {
+			if st == nil {
+				st = &layer3.TLDRStatus{}
+			}
+			return writeJSON(st)
+		}
+		if st == nil {
+			fmt.Fprintln(stdout, "No tldr pages imported (clio tldr sync, or clio tldr import <zip or dir>)")
+			return nil
+		}
+		fmt.Fprintf(stdout, "%d tldr page(s) from %s, imported %s\n",
+			st.Pages, st.Source, st.ImportedAt.Local().Format("2006-01-02 15:04"))
+		return nil
+	default:
+		return usagef("unknown tldr subcommand %q", rest[0])
+	}
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return writeJSON(map[string]int{"pages": n})
+	}
+	fmt.Fprintf(stdout, "Imported %d tldr page(s)\n", n)
+	return nil
+}
+
+func runIndex(args []string) error {
+	fs := flag.NewFlagSet("index", flag.ContinueOnError)
+	rebuild := fs.Bool("rebuild", false, "")
+	harvest := fs.Bool("harvest", false, "")
+	asJSON := fs.Bool("json", false, "")
+	rest, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return usagef("unexpected argument %q", rest[0])
+	}
+	stats, err := layer2.RefreshIndex(*rebuild)
+	if err != nil {
+		return err
+	}
+	var help *layer2.HarvestStats
+	if *harvest {
+		h, err := layer2.Harvest(*rebuild)
+		if err != nil {
+			return err
+		}
+		help = &h
+	}
+	if *asJSON {
+		return writeJSON(struct {
+			layer2.IndexStats
+			Help *layer2.HarvestStats `json:"help,omitempty"`
+		}{stats, help})
+	}
+	fmt.Fprintf(stdout, "%d man page(s) indexed (%d parsed, %d removed) from %s\n",
+		stats.Pages, stats.Parsed, stats.Removed, strings.Join(layer2.ManRoots(), ":"))
+	if help != nil {
+		fmt.Fprintf(stdout, "%d command(s) indexed from --help output (%d run, %d removed)\n",
+			help.Commands, help.Run, help.Removed)
+	}
+	return nil
+}
+
+func runEval(args []string) error {
+	fs := flag.NewFlagSet("eval", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "")
+	top := fs.Int("top", 3, "")
+	corpusPath := fs.String("corpus", "", "")
+	baselinePath := fs.String("baseline", "", "")
+	writeBaseline := fs.String("write-baseline", "", "")
+	live := fs.Bool("live", false, "")
+	rest, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return usagef("unexpected argument %q", rest[0])
+	}
+
+	corpus, err := eval.DefaultCorpus()
+	if *corpusPath != "" {
+		corpus, err = eval.LoadCorpus(*corpusPath)
+	}
+	if err != nil {
+		return err
+	}
+	if *live {
+		// main skips ~/.clio/commands.yaml for eval; live runs measure with it
+		for _, err := range layer1.LoadUserCommands() {
+			fmt.Fprintf(stderr, "clio eval: %v\n", err)
+		}
+	}
+	rep := eval.Run(corpus, eval.Options{TopK: *top, Live: *live})
+
+	if *writeBaseline != "" {
+		f, err := os.Create(*writeBaseline)
+		if err != nil {
+			return err
+		}
+		if err := rep.Baseline().WriteJSON(f); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	} else if *baselinePath != "" || (*corpusPath == "" && !*live) {
+		// The built-in baseline only describes the built-in corpus, run hermetically
+		base, err := eval.DefaultBaseline()
+		if *baselinePath != "" {
+			base, err = eval.LoadBaseline(*baselinePath)
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := rep.Compare(base); err != nil {
+			return err
+		}
+	}
+
+	if *asJSON {
+		if err := writeJSON(rep); err != nil {
+			return err
+		}
+	} else {
+		rep.Write(stdout)
+	}
+	if rep.Diff != nil && len(rep.Diff.Regressions) > 0 {
+		return fmt.Errorf("%d regressions against the baseline", len(rep.Diff.Regressions))
+	}
+	return nil
+}
+
+func runSync(args []string) error {
+	fs := flag.NewFlagSet("sync", flag.ContinueOnError)
+	full := fs.Bool("full", false, "")
+	asJSON := fs.Bool("json", false, "")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	sync := modules.Sync
+	if *full {
+		sync = modules.SyncFull
+	}
+	if !*asJSON {
+		return sync()
+	}
+
+	err := withStdoutTo(os.Stderr, sync)
+	status := map[string]interface{}{"ok": err == nil, "full": *full}
+	if err != nil {
+		status["error"] = err.Error()
+	}
+	if metas, lerr := layer3.ListModuleMeta(); lerr == nil {
+		status["modules"] = len(metas)
+	}
+	if werr := writeJSON(status); werr != nil {
+		return werr
+	}
+	return err
+}
+
+func runModule(args []string) error {
+	// "module run" takes run's flags, which this FlagSet does not know
+	for i, a := range args {
+		if !strings.HasPrefix(a, "-") {
+			if a == "run" {
+				return runRun(append(args[:i:i], args[i+1:]...))
+			}
+			break
+		}
+	}
+
+	fs := flag.NewFlagSet("module", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "")
+	watch := fs.Bool("watch", false, "")
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) == 0 {
+		return usagef("subcommand required")
+	}
+	if *watch && rest[0] != "add" {
+		return usagef("--watch applies to module add")
+	}
+
+	switch rest[0] {
+	case "list":
+		metas, err := modules.ListCachedModules()
+		if err != nil {
+			return err
+		}
+		if *asJSON {
+			if metas == nil {
+				metas = []layer3.ModuleMeta{}
+			}
+			return writeJSON(metas)
+		}
+		for _, m := range metas {
+			note := ""
+			if m.Origin == layer3.OriginLocal {
+				note = "  (local)"
+			}
+			fmt.Fprintf(stdout, "%-24s %-8s %s%s\n", m.ModuleID, m.Version, m.Description, note)
+		}
+		return nil
+
+	case "add":
+		if len(rest) != 2 {
+			return usagef("expected one module file or directory")
+		}
+		if *watch {
+			return watchModules(rest[1], *asJSON)
+		}
+		added, err := modules.AddLocal(rest[1])
+		if *asJSON {
+			if added == nil {
+				added = []modules.LocalModule{}
+			}
+			if werr := writeJSON(added); werr != nil {
+				return werr
+			}
+		} else {
+			for _, m := range added {
+				printLocalModule(m)
+			}
+		}
+		return err
+
+	case "remove":
+		if len(rest) != 2 {
+			return usagef("module id required")
+		}
+		if err := layer3.RemoveModule(rest[1]); err != nil {
+			return err
+		}
+		if *asJSON {
+			return writeJSON(map[string]string{"removed": rest[1]})
+		}
+		fmt.Fprintf(stdout, "Removed %s\n", rest[1])
+		return nil
+
+	case "show":
+		if len(rest) < 2 {
+			return usagef("module id required")
+		}
+		if !*asJSON {
+			return modules.ShowModuleDetail(rest[1])
+		}
+		meta, err := layer3.FindModuleMeta(rest[1])
+		if err != nil {
+			return err
+		}
+		if meta == nil {
+			return fmt.Errorf("module %q not found", rest[1])
+		}
+		return writeJSON(meta)
+
+	case "versions", "diff", "pin", "unpin", "rollback":
+		if len(rest) < 2 {
+			return usagef("module id required")
+		}
+		return runModuleVersions(rest[0], rest[1], rest[2:], *asJSON)
+	}
+	return usagef("unknown module subcommand %q", rest[0])
+}
+
+// watchLocalInterval is how often module add --watch checks its files.
+const watchLocalInterval = time.Second
+
+// watchModules installs local modules and reinstalls each file that changes
+// until interrupted. With asJSON every attempt is one JSON line.
+func watchModules(path string, asJSON bool) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if !asJSON {
+		fmt.Fprintf(stderr, "Watching %s; press Ctrl-C to stop.\n", path)
+	}
+	enc := json.NewEncoder(stdout)
+	return modules.WatchLocal(ctx, path, watchLocalInterval, func(m modules.LocalModule, err error) {
+		if asJSON {
+			line := struct {
+				modules.LocalModule
+				Error string `json:"error,omitempty"`
+			}{LocalModule: m}
+			if err != nil {
+				line.Error = err.Error()
+			}
+			enc.Encode(line)
+			return
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "❌ %v\n", err)
+			return
+		}
+		printLocalModule(m)
+	})
+}
+
+func printLocalModule(m modules.LocalModule) {
+	if !m.Changed {
+		fmt.Fprintf(stdout, "= %s %s unchanged (%s)\n", m.ID, m.Version, m.Path)
+		return
+	}
+	fmt.Fprintf(stdout, "✅ %s %s installed from %s\n", m.ID, m.Version, m.Path)
+}
+
+// runModuleVersions handles the module subcommands working on stored
+// versions. Versions are named by version string or checksum prefix.
+func runModuleVersions(sub, id string, args []string, asJSON bool) error {
+	maxArgs := map[string]int{"versions": 0, "diff": 2, "pin": 1, "unpin": 0, "rollback": 1}[sub]
+	if len(args) > maxArgs {
+		return usagef("unexpected argument %q", args[maxArgs])
+	}
+	arg := func(i int, def string) string {
+		if i < len(args) {
+			return args[i]
+		}
+		return def
+	}
+
+	switch sub {
+	case "versions":
+		versions, err := layer3.ModuleVersions(id)
+		if err != nil {
+			return err
+		}
+		if asJSON {
+			if versions == nil {
+				versions = []layer3.ModuleVersion{}
+			}
+			return writeJSON(versions)
+		}
+		for _, v := range versions {
+			var marks []string
+			if v.Current {
+				marks = append(marks, "current")
+			}
+			if v.Pinned {
+				marks = append(marks, "pinned")
+			}
+			note := ""
+			if len(marks) > 0 {
+				note = "  (" + strings.Join(marks, ", ") + ")"
+			}
+			fmt.Fprintf(stdout, "%-10s %s  %s%s\n", v.Version, v.Short(), v.SyncedAt.Local().Format("2006-01-02 15:04"), note)
+		}
+		return nil
+
+	case "diff":
+		diff, err := modules.DiffVersions(id, arg(0, "previous"), arg(1, ""))
+		if err != nil {
+			return err
+		}
+		if asJSON {
+			return writeJSON(map[string]string{"diff": diff})
+		}
+		if diff == "" {
+			fmt.Fprintln(stdout, "No differences.")
+			return nil
+		}
+		fmt.Fprint(stdout, diff)
+		return nil
+
+	case "unpin":
+		if err := layer3.PinModule(id, false); err != nil {
+			return err
+		}
+		if !asJSON {
+			fmt.Fprintf(stdout, "%s unpinned; sync will update it again.\n", id)
+			return nil
+		}
+		v, _, err := layer3.ModuleVersionContent(id, "")
+		if err != nil {
+			return err
+		}
+		return writeJSON(v)
+	}
+
+	// pin and rollback
+	ref := arg(0, "")
+	if sub == "rollback" {
+		ref = arg(0, "previous")
+	}
+	var v layer3.ModuleVersion
+	var err error
+	if ref != "" {
+		if v, err = layer3.RollbackModule(id, ref); err != nil {
+			return err
+		}
+	}
+	if sub == "pin" {
+		if err := layer3.PinModule(id, true); err != nil {
+			return err
+		}
+	}
+	if v, _, err = layer3.ModuleVersionContent(id, ""); err != nil {
+		return err
+	}
+	if asJSON {
+		return writeJSON(v)
+	}
+	if sub == "pin" {
+		fmt.Fprintf(stdout, "📌 %s pinned at %s (%s); sync will not update it.\n", id, v.Version, v.Short())
+	} else {
+		fmt.Fprintf(stdout, "%s rolled back to %s (%s).\n", id, v.Version, v.Short())
+	}
+	return nil
+}
+
+// varsFlag collects repeated --var key=value flags.
+type varsFlag map[string]string
+
+func (v varsFlag) String() string { return "" }
+
+func (v varsFlag) Set(s string) error {
+	key, value, ok := strings.Cut(s, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("expected key=value, got %q", s)
+	}
+	v[key] = value
+	return nil
+}
+
+func runRun(args []string) error {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	plan := fs.Bool("plan", false, "")
+	resume := fs.Bool("resume", false, "")
+	vars := varsFlag{}
+	fs.Var(vars, "var", "")
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) == 0 || len(rest) > 2 {
+		return usagef("expected <module> [flow]")
+	}
+	flow := "setup"
+	if len(rest) > 1 {
+		flow = rest[1]
+	}
+	if *plan {
+		_, err := modules.Plan(rest[0], flow, vars, stdout)
+		return err
+	}
+	scanner := bufio.NewScanner(os.Stdin)
+	return modules.RunModule(rest[0], flow, *resume, scanner)
+}
+
+type wizardInfo struct {
+	ID          string `json:"id"`
+	Module      string `json:"module"`
+	Flow        string `json:"flow"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Command     string `json:"command"`
+}
+
+func toWizardInfo(w setup.Wizard) wizardInfo {
+	return wizardInfo{
+		ID: w.ID, Module: w.ModuleID, Flow: w.Flow, Title: w.Title,
+		Description: w.Description, Command: setup.RunCommandFor(w),
+	}
+}
+
+func runSetup(args []string) error {
+	fs := flag.NewFlagSet("setup", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "")
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(rest) == 0 {
+		if *asJSON {
+			var out []wizardInfo
+			for _, w := range setup.AllWizards() {
+				out = append(out, toWizardInfo(w))
+			}
+			return writeJSON(out)
+		}
+		setup.ShowMenu()
+		return nil
+	}
+
+	kind, w := setup.ResolveSetup("setup " + strings.Join(rest, " "))
+	if kind != setup.MatchWizard || w == nil {
+		return usagef("unknown wizard %q", strings.Join(rest, " "))
+	}
+	if *asJSON {
+		return writeJSON(toWizardInfo(*w))
+	}
+	if err := modules.EnsureModule(w.ModuleID); err != nil {
+		fmt.Fprintf(stderr, "⚠️  Could not download module: %v\n", err)
+	}
+	setup.ShowWizardGuide(*w, false)
+	return nil
+}
+
+func runCache(args []string) error {
+	fs := flag.NewFlagSet("cache", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "")
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		return usagef("expected stats or clear")
+	}
+
+	switch rest[0] {
+	case "stats":
+		n, err := layer4.CacheStats()
+		if err != nil {
+			return err
+		}
+		if *asJSON {
+			return writeJSON(map[string]int{"entries": n})
+		}
+		fmt.Fprintf(stdout, "%d cached remote result(s)\n", n)
+		return nil
+	case "clear":
+		n, err := layer4.ClearCache()
+		if err != nil {
+			return err
+		}
+		if *asJSON {
+			return writeJSON(map[string]int{"removed": n})
+		}
+		fmt.Fprintf(stdout, "Removed %d cached remote result(s)\n", n)
+		return nil
+	}
+	return usagef("unknown cache subcommand %q", rest[0])
+}
+
+func runBundle(args []string) error {
+	fs := flag.NewFlagSet("bundle", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "")
+	trust := fs.Bool("trust", false, "")
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) == 0 {
+		return usagef("expected export or import")
+	}
+	if len(rest) < 2 {
+		return usagef("bundle %s needs a file", rest[0])
+	}
+
+	switch rest[0] {
+	case "export":
+		if *trust {
+			return usagef("--trust applies to bundle import")
+		}
+		stats, err := bundle.Export(rest[1], rest[2:])
+		if err != nil {
+			return err
+		}
+		if *asJSON {
+			return writeJSON(stats)
+		}
+		fmt.Fprintf(stdout, "Wrote %s (%d bytes): %d module(s), %d cached result(s), %d tldr page(s)\n",
+			stats.Path, stats.Bytes, stats.Modules, stats.Cache, stats.TLDRPages)
+		fmt.Fprintf(stdout, "Signed by %s\n", stats.Signer)
+		return nil
+	case "import":
+		if len(rest) > 2 {
+			return usagef("unexpected argument %q", rest[2])
+		}
+		stats, err := bundle.Import(rest[1], *trust)
+		if errors.Is(err, bundle.ErrUntrusted) {
+			fmt.Fprintf(stderr, "Check the fingerprint with the sender, then rerun with --trust to accept it.\n")
+		}
+		if err != nil {
+			return err
+		}
+		if *asJSON {
+			return writeJSON(stats)
+		}
+		fmt.Fprintf(stdout, "Bundle signed by %s on %s\n", stats.Signer, stats.CreatedAt.Local().Format("2006-01-02 15:04"))
+		fmt.Fprintf(stdout, "Updated %d module(s) (%d unchanged), merged %d cached result(s)\n",
+			stats.Modules, stats.Unchanged, stats.Cache)
+		if stats.TLDRPages > 0 {
+			fmt.Fprintf(stdout, "Imported %d tldr page(s)\n", stats.TLDRPages)
+		}
+		if stats.Examples > 0 {
+			fmt.Fprintf(stdout, "Imported %d example(s)\n", stats.Examples)
+		}
+		for _, id := range stats.Pinned {
+			fmt.Fprintf(stdout, "  📌 %s is pinned; not updated\n", id)
+		}
+		for _, id := range stats.Local {
+			fmt.Fprintf(stdout, "  🛠  %s is a local module; not updated\n", id)
+		}
+		for _, id := range stats.Invalid {
+			fmt.Fprintf(stderr, "⚠️  Skipped %s: checksum mismatch or invalid YAML\n", id)
+		}
+		return nil
+	}
+	return usagef("unknown bundle subcommand %q", rest[0])
+}
+
+func runHistory(args []string) error {
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "")
+	limit := fs.Int("limit", 20, "")
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case len(rest) == 0:
+		entries, err := layer3.ListHistory(*limit)
+		if err != nil {
+			return err
+		}
+		if *asJSON {
+			if entries == nil {
+				entries = []layer3.HistoryEntry{}
+			}
+			return writeJSON(entries)
+		}
+		for _, e := range entries {
+			cmd := e.FinalCommand
+			if cmd == "" {
+				cmd = e.Command
+			}
+			fmt.Fprintf(stdout, "%d\t%s\t%s\t%s\n", e.ID, e.Outcome, e.Query, cmd)
+		}
+		return nil
+	case len(rest) == 1 && rest[0] == "clear":
+		n, err := layer3.ClearHistory()
+		if err != nil {
+			return err
+		}
+		if *asJSON {
+			return writeJSON(map[string]int64{"removed": n})
+		}
+		fmt.Fprintf(stdout, "Removed %d history entr(ies)\n", n)
+		return nil
+	}
+	return usagef("unknown history subcommand %q", strings.Join(rest, " "))
+}
+
+func runConfig(args []string) error {
+	fs := flag.NewFlagSet("config", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "")
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) == 0 {
+		return usagef("expected get or set")
+	}
+
+	switch rest[0] {
+	case "get":
+		keys := config.Keys()
+		if len(rest) > 1 {
+			keys = rest[1:]
+		}
+		values := make(map[string]string, len(keys))
+		for _, k := range keys {
+			v, ok := config.Get(k)
+			if !ok {
+				return usagef("unknown config key %q", k)
+			}
+			values[k] = v
+		}
+		if *asJSON {
+			return writeJSON(values)
+		}
+		if len(keys) == 1 {
+			fmt.Fprintln(stdout, values[keys[0]])
+			return nil
+		}
+		for _, k := range keys {
+			fmt.Fprintf(stdout, "%s: %s\n", k, values[k])
+		}
+		return nil
+
+	case "set":
+		if len(rest) != 3 {
+			return usagef("expected set <key> <value>")
+		}
+		if err := config.Set(rest[1], rest[2]); err != nil {
+			return usagef("%v", err)
+		}
+		if *asJSON {
+			return writeJSON(map[string]string{rest[1]: rest[2]})
+		}
+		return nil
+	}
+	return usagef("unknown config subcommand %q", rest[0])
+}
@@ -0,0 +1,242 @@
+package cli
+
+import (
+	"bytes"
+	"clio/internal/config"
+	"clio/internal/layer3"
+	"clio/internal/modules"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func capture(t *testing.T) (*bytes.Buffer, *bytes.Buffer) {
+	t.Helper()
+	var out, errOut bytes.Buffer
+	oldOut, oldErr := stdout, stderr
+	stdout, stderr = &out, &errOut
+	t.Cleanup(func() { stdout, stderr = oldOut, oldErr })
+	return &out, &errOut
+}
+
+func TestRunUsageErrors(t *testing.T) {
+	capture(t)
+	cases := [][]string{
+		{"bogus"},
+		{"ask"},
+		{"config"},
+		{"config", "set", "profile"},
+		{"cache", "explode"},
+		{"history", "forget"},
+		{"explain"},
+		{"explain", "tar 'unterminated"},
+		{"eval", "extra"},
+		{"examples"},
+		{"tldr"},
+		{"tldr", "import"},
+		{"tldr", "explode"},
+		{"index", "extra"},
+		{"module", "versions"},
+		{"module", "diff", "backup", "1.0", "1.1", "1.2"},
+		{"module", "add"},
+		{"module", "add", "a.yaml", "b.yaml"},
+		{"module", "list", "--watch"},
+		{"module", "remove"},
+		{"bundle"},
+		{"bundle", "import"},
+		{"bundle", "import", "a.clio", "b.clio"},
+		{"bundle", "explode", "a.clio"},
+	}
+	for _, args := range cases {
+		if code := Run(args); code != ExitUsage {
+			t.Errorf("Run(%q) = %d, want %d", args, code, ExitUsage)
+		}
+	}
+}
+
+func TestIsCommand(t *testing.T) {
+	if !IsCommand("ask") || !IsCommand("--help") {
+		t.Error("expected ask and --help to be commands")
+	}
+	if IsCommand("list") {
+		t.Error("free-text queries must fall through to pipe/REPL mode")
+	}
+}
+
+func TestConfigSetGet(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	config.ResetCache()
+	defer config.ResetCache()
+	out, _ := capture(t)
+
+	if code := Run([]string{"config", "set", "remote_search", "off"}); code != ExitOK {
+		t.Fatalf("config set exit = %d", code)
+	}
+	if code := Run([]string{"config", "set", "profile", "turbo"}); code != ExitUsage {
+		t.Fatalf("invalid value exit = %d, want %d", code, ExitUsage)
+	}
+	out.Reset()
+	if code := Run([]string{"config", "get", "remote_search", "--json"}); code != ExitOK {
+		t.Fatalf("config get exit = %d", code)
+	}
+	var got map[string]string
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out.String(), err)
+	}
+	if got["remote_search"] != "off" {
+		t.Errorf("remote_search = %q, want off", got["remote_search"])
+	}
+}
+
+func TestAskKeepsFlagsInQuery(t *testing.T) {
+	out, errOut := capture(t)
+	if code := Run([]string{"ask", "what", "does", "ls", "-la", "do"}); code == ExitUsage {
+		t.Fatalf("ask exit = %d: %s", code, errOut)
+	}
+	if out.Len() == 0 {
+		t.Error("ask printed no command")
+	}
+}
+
+func TestAskIncomplete(t *testing.T) {
+	out, errOut := capture(t)
+	if code := Run([]string{"ask", "delete", "a", "file"}); code != ExitIncomplete {
+		t.Fatalf("ask exit = %d, want %d", code, ExitIncomplete)
+	}
+	if !strings.Contains(out.String(), "{file}") || !strings.Contains(errOut.String(), "fill in {file}") {
+		t.Errorf("ask printed %q / %q, want the template and the slot to fill in", out, errOut)
+	}
+}
+
+func TestAskJSON(t *testing.T) {
+	out, _ := capture(t)
+	if code := Run([]string{"ask", "--json", "extract", "backup.tar.gz"}); code != ExitOK {
+		t.Fatalf("ask exit = %d", code)
+	}
+	var res askResult
+	if err := json.Unmarshal(out.Bytes(), &res); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out.String(), err)
+	}
+	if !strings.Contains(res.Command, "tar -xzvf backup.tar.gz") {
+		t.Errorf("command = %q", res.Command)
+	}
+	if res.Confidence <= 0 {
+		t.Errorf("confidence = %v, want > 0", res.Confidence)
+	}
+}
+
+func TestExplainKeepsCommandFlags(t *testing.T) {
+	out, _ := capture(t)
+	if code := Run([]string{"explain", "--json", "tar", "-xzvf", "a.tgz"}); code != ExitOK {
+		t.Fatalf("explain exit = %d", code)
+	}
+	var got struct {
+		Parts []struct{ Text string } `json:"parts"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out.String(), err)
+	}
+	if len(got.Parts) != 6 || got.Parts[1].Text != "-x" {
+		t.Errorf("parts = %+v", got.Parts)
+	}
+}
+
+func TestExamplesJSON(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	out, _ := capture(t)
+	if code := Run([]string{"examples", "--json", "example of rsync with", "--delete"}); code != ExitOK {
+		t.Fatalf("examples exit = %d", code)
+	}
+	var exs []struct {
+		Command string `json:"command"`
+		Example string `json:"example"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &exs); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(exs) == 0 || !strings.Contains(exs[0].Example, "--delete") {
+		t.Errorf("examples = %+v, want rsync --delete first", exs)
+	}
+	if code := Run([]string{"examples", "xyzzy"}); code != ExitNoMatch {
+		t.Errorf("examples xyzzy exit = %d, want %d", code, ExitNoMatch)
+	}
+}
+
+func TestEvalJSON(t *testing.T) {
+	out, _ := capture(t)
+	if code := Run([]string{"eval", "--json"}); code != ExitOK {
+		t.Fatalf("eval exit = %d", code)
+	}
+	var rep struct {
+		Total    int `json:"total"`
+		Baseline *struct {
+			Regressions []interface{} `json:"regressions"`
+		} `json:"baseline"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &rep); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if rep.Total == 0 || rep.Baseline == nil {
+		t.Errorf("report = %+v, want cases compared with the baseline", rep)
+	}
+}
+
+// addTestModule installs a local module "greet" whose setup flow writes
+// {{.who}} to the returned path.
+func addTestModule(t *testing.T) string {
+	t.Helper()
+	layer3.UseTestDB(t)
+	dir := t.TempDir()
+	out := filepath.Join(dir, "greeting")
+	yaml := `name: Greet
+flows:
+  - name: setup
+    steps:
+      - type: command
+        command: echo "hello {{.who}}" >> ` + out + `
+`
+	path := filepath.Join(dir, "greet.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := modules.AddLocal(path); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestRunFlagsAfterModule(t *testing.T) {
+	out := addTestModule(t)
+	stdoutBuf, errOut := capture(t)
+
+	for _, args := range [][]string{
+		{"module", "run", "greet", "--plan", "--var", "who=ada"},
+		{"module", "run", "--plan", "greet", "--var=who=ada"},
+		{"run", "greet", "setup", "--var", "who=ada", "--plan"},
+	} {
+		stdoutBuf.Reset()
+		if code := Run(args); code != ExitOK {
+			t.Fatalf("Run(%q) = %d: %s", args, code, errOut)
+		}
+		if !strings.Contains(stdoutBuf.String(), "hello ada") {
+			t.Errorf("Run(%q) printed %q, want the plan with --var filled in", args, stdoutBuf)
+		}
+	}
+	if _, err := os.Stat(out); err == nil {
+		t.Fatal("--plan ran the module")
+	}
+
+	for _, args := range [][]string{
+		{"module", "run", "greet", "--resume"},
+		{"run", "greet", "--resume"},
+	} {
+		if code := Run(args); code != ExitOK {
+			t.Fatalf("Run(%q) = %d: %s", args, code, errOut)
+		}
+	}
+	if _, err := os.Stat(out); err != nil {
+		t.Errorf("module run --resume did not run the flow: %v", err)
+	}
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"clio/internal/platform"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -41,6 +42,12 @@ type Config struct {
 	RemoteCacheTTL string          `yaml:"remote_cache_ttl"`
 	// MemoryLimit sets the Go runtime soft memory cap (e.g. "48MiB"). Empty = default per profile.
 	MemoryLimit string `yaml:"memory_limit"`
+	// BlockDestructive refuses to run commands the risk classifier marks destructive.
+	BlockDestructive bool `yaml:"block_destructive"`
+	// ProjectCommands also loads ./.clio/commands.yaml from the working directory.
+	ProjectCommands bool `yaml:"project_commands"`
+	// TLDRURL is the tldr-pages archive sync imports; "off" skips it.
+	TLDRURL string `yaml:"tldr_url"`
 }
 
 var defaultConfig = Config{
@@ -50,6 +57,7 @@ var defaultConfig = Config{
 	SyncInterval:   "168h",
 	RemoteSearch:   RemoteAuto,
 	RemoteCacheTTL: "168h",
+	TLDRURL:        "https://github.com/tldr-pages/tldr/releases/latest/download/tldr.zip",
 }
 
 var (
@@ -110,6 +118,9 @@ func applyDefaults(cfg Config) Config {
 	if cfg.RemoteCacheTTL == "" {
 		cfg.RemoteCacheTTL = defaultConfig.RemoteCacheTTL
 	}
+	if cfg.TLDRURL == "" {
+		cfg.TLDRURL = defaultConfig.TLDRURL
+	}
 	if cfg.DBPath == "" {
 		home, err := os.UserHomeDir()
 		if err == nil {
@@ -161,6 +172,15 @@ func RemoteCacheTTL() time.Duration {
 	return d
 }
 
+// GetSyncInterval parses sync_interval from config.
+func GetSyncInterval() time.Duration {
+	d, err := time.ParseDuration(Load().SyncInterval)
+	if err != nil {
+		return 168 * time.Hour
+	}
+	return d
+}
+
 var testRegistryURL string
 
 // SetRegistryURLForTest overrides the registry URL (tests only).
@@ -176,6 +196,14 @@ func GetRegistryURL() string {
 	return Load().RegistryURL
 }
 
+// GetTLDRURL returns the tldr-pages archive to sync, or "" when disabled.
+func GetTLDRURL() string {
+	if u := Load().TLDRURL; u != "off" {
+		return u
+	}
+	return ""
+}
+
 // GetDBPath returns the SQLite database path.
 func GetDBPath() string {
 	cfg := Load()
@@ -189,6 +217,17 @@ func GetDBPath() string {
 	return filepath.Join(home, ".clio", "clio.db")
 }
 
+// BlockDestructive reports whether destructive commands must be refused outright.
+func BlockDestructive() bool {
+	return Load().BlockDestructive
+}
+
+// ProjectCommands reports whether a commands.yaml in the working directory is
+// trusted enough to load.
+func ProjectCommands() bool {
+	return Load().ProjectCommands
+}
+
 // GetMemoryLimit returns the Go runtime memory limit for the active profile.
 func GetMemoryLimit() int64 {
 	cfg := Load()
@@ -224,3 +263,131 @@ func parseMemoryLimit(s string) int64 {
 	}
 	return 0
 }
+
+// Keys lists the settings accepted by Get and Set, in config.yaml order.
+func Keys() []string {
+	return []string{
+		"profile", "registry_url", "cache_ttl", "sync_interval", "db_path",
+		"remote_search", "remote_cache_ttl", "memory_limit", "block_destructive", "tldr_url",
+		"project_commands",
+	}
+}
+
+// Path returns the location of config.yaml.
+func Path() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".clio", "config.yaml"), nil
+}
+
+// Get returns the effective value of a setting (defaults applied).
+func Get(key string) (string, bool) {
+	cfg := Load()
+	switch key {
+	case "profile":
+		return string(cfg.Profile), true
+	case "registry_url":
+		return cfg.RegistryURL, true
+	case "cache_ttl":
+		return cfg.CacheTTL, true
+	case "sync_interval":
+		return cfg.SyncInterval, true
+	case "db_path":
+		return cfg.DBPath, true
+	case "remote_search":
+		return string(cfg.RemoteSearch), true
+	case "remote_cache_ttl":
+		return cfg.RemoteCacheTTL, true
+	case "memory_limit":
+		return cfg.MemoryLimit, true
+	case "block_destructive":
+		return strconv.FormatBool(cfg.BlockDestructive), true
+	case "tldr_url":
+		return cfg.TLDRURL, true
+	case "project_commands":
+		return strconv.FormatBool(cfg.ProjectCommands), true
+	}
+	return "", false
+}
+
+// Set validates a setting and writes it to config.yaml, keeping other keys intact.
+func Set(key, value string) error {
+	if err := validate(key, value); err != nil {
+		return err
+	}
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+
+	doc := make(map[string]interface{})
+	if data, err := os.ReadFile(path); err == nil {
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("parse %s: %w", path, err)
+		}
+	}
+	doc[key] = value
+	if b, err := strconv.ParseBool(value); err == nil && (key == "block_destructive" || key == "project_commands") {
+		doc[key] = b // keep it a YAML bool, not the string "true"
+	}
+
+	out, err := yaml.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, out, 0644); err != nil {
+		return err
+	}
+	ResetCache()
+	return nil
+}
+
+func validate(key, value string) error {
+	switch key {
+	case "profile":
+		switch Profile(value) {
+		case ProfileAuto, ProfileLite, ProfileFull:
+			return nil
+		}
+		return fmt.Errorf("profile must be auto, lite or full")
+	case "remote_search":
+		switch RemoteSearchMode(value) {
+		case RemoteAuto, RemoteOn, RemoteOff:
+			return nil
+		}
+		return fmt.Errorf("remote_search must be auto, on or off")
+	case "cache_ttl", "sync_interval", "remote_cache_ttl":
+		if _, err := time.ParseDuration(value); err != nil {
+			return fmt.Errorf("%s must be a duration like 24h: %w", key, err)
+		}
+		return nil
+	case "memory_limit":
+		if value != "" && parseMemoryLimit(value) <= 0 {
+			return fmt.Errorf("memory_limit must look like 48MiB")
+		}
+		return nil
+	case "registry_url":
+		if !strings.HasPrefix(value, "http://") && !strings.HasPrefix(value, "https://") {
+			return fmt.Errorf("registry_url must start with http:// or https://")
+		}
+		return nil
+	case "tldr_url":
+		if value != "off" && !strings.HasPrefix(value, "http://") && !strings.HasPrefix(value, "https://") {
+			return fmt.Errorf("tldr_url must start with http:// or https://, or be off")
+		}
+		return nil
+	case "block_destructive", "project_commands":
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("%s must be true or false", key)
+		}
+		return nil
+	case "db_path":
+		return nil
+	}
+	return fmt.Errorf("unknown config key %q (known: %s)", key, strings.Join(Keys(), ", "))
+}
@@ -47,3 +47,28 @@ func TestGetMemoryLimitLiteDefault(t *testing.T) {
 		t.Fatalf("GetMemoryLimit() = %d, want %d", got, 48<<20)
 	}
 }
+
+func TestSetPersistsAndValidates(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	ResetCache()
+	defer ResetCache()
+
+	if err := Set("profile", "lite"); err != nil {
+		t.Fatalf("Set profile: %v", err)
+	}
+	if err := Set("remote_search", "off"); err != nil {
+		t.Fatalf("Set remote_search: %v", err)
+	}
+	if v, _ := Get("profile"); v != "lite" {
+		t.Errorf("profile = %q, want lite", v)
+	}
+	if v, _ := Get("remote_search"); v != "off" {
+		t.Errorf("remote_search = %q, want off", v)
+	}
+	if err := Set("profile", "turbo"); err == nil {
+		t.Error("expected error for invalid profile")
+	}
+	if err := Set("nope", "x"); err == nil {
+		t.Error("expected error for unknown key")
+	}
+}
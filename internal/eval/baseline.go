@@ -0,0 +1,139 @@
+package eval
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+//go:embed baseline.json
+var defaultBaseline []byte
+
+// Baseline records a previous run's answer to every case.
+type Baseline struct {
+	CorpusVersion int                     `json:"corpus_version"`
+	Accuracy      float64                 `json:"accuracy"`
+	Cases         map[string]BaselineCase `json:"cases"`
+}
+
+// BaselineCase is one case's recorded answer.
+type BaselineCase struct {
+	Got     string `json:"got"`
+	Correct bool   `json:"correct"`
+}
+
+// Change is a case whose answer differs from the baseline.
+type Change struct {
+	Key    string `json:"key"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// Diff compares a run with a baseline.
+type Diff struct {
+	Regressions []Change `json:"regressions"` // correct before, wrong now
+	Fixed       []Change `json:"fixed"`       // wrong before, correct now
+	Changed     []Change `json:"changed"`     // different answer, same verdict
+	Added       []string `json:"added"`       // cases the baseline does not know
+	Removed     []string `json:"removed"`     // baseline cases no longer in the corpus
+}
+
+// DefaultBaseline returns the baseline built into clio.
+func DefaultBaseline() (*Baseline, error) {
+	return parseBaseline(defaultBaseline, "built-in baseline")
+}
+
+// LoadBaseline reads a baseline file.
+func LoadBaseline(path string) (*Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseBaseline(data, path)
+}
+
+func parseBaseline(data []byte, name string) (*Baseline, error) {
+	var b Baseline
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return &b, nil
+}
+
+// Baseline turns the report into a baseline for later runs.
+func (r *Report) Baseline() *Baseline {
+	b := &Baseline{
+		CorpusVersion: r.CorpusVersion,
+		Accuracy:      r.Accuracy,
+		Cases:         make(map[string]BaselineCase, len(r.Results)),
+	}
+	for _, res := range r.Results {
+		b.Cases[res.Key] = BaselineCase{Got: res.Got, Correct: res.Correct}
+	}
+	return b
+}
+
+// WriteJSON writes the baseline with one case per line, so diffs of the
+// checked-in file show exactly which answers moved.
+func (b *Baseline) WriteJSON(w io.Writer) error {
+	keys := make([]string, 0, len(b.Cases))
+	for k := range b.Cases {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	fmt.Fprintf(w, "{\n  \"corpus_version\": %d,\n  \"accuracy\": %.4f,\n  \"cases\": {\n", b.CorpusVersion, b.Accuracy)
+	for i, k := range keys {
+		key, _ := json.Marshal(k)
+		val, err := json.Marshal(b.Cases[k])
+		if err != nil {
+			return err
+		}
+		sep := ","
+		if i == len(keys)-1 {
+			sep = ""
+		}
+		fmt.Fprintf(w, "    %s: %s%s\n", key, val, sep)
+	}
+	_, err := fmt.Fprintln(w, "  }\n}")
+	return err
+}
+
+// Compare diffs the report against b and stores the result in r.Diff.
+// Baselines from another corpus version cannot be compared.
+func (r *Report) Compare(b *Baseline) (*Diff, error) {
+	if b.CorpusVersion != r.CorpusVersion {
+		return nil, fmt.Errorf("baseline is for corpus version %d, corpus is version %d; regenerate it with --write-baseline",
+			b.CorpusVersion, r.CorpusVersion)
+	}
+	d := &Diff{}
+	seen := make(map[string]bool, len(r.Results))
+	for _, res := range r.Results {
+		seen[res.Key] = true
+		old, ok := b.Cases[res.Key]
+		if !ok {
+			d.Added = append(d.Added, res.Key)
+			continue
+		}
+		c := Change{Key: res.Key, Before: old.Got, After: res.Got}
+		switch {
+		case old.Correct && !res.Correct:
+			d.Regressions = append(d.Regressions, c)
+		case !old.Correct && res.Correct:
+			d.Fixed = append(d.Fixed, c)
+		case normalize(old.Got) != normalize(res.Got):
+			d.Changed = append(d.Changed, c)
+		}
+	}
+	for k := range b.Cases {
+		if !seen[k] {
+			d.Removed = append(d.Removed, k)
+		}
+	}
+	sort.Strings(d.Removed)
+	r.Diff = d
+	return d, nil
+}
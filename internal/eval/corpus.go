@@ -0,0 +1,109 @@
+// Package eval measures matching quality: it runs a versioned corpus of
+// query → expected-command pairs through the intent pipeline and compares
+// the results with a stored baseline.
+package eval
+
+import (
+	"bytes"
+	"clio/internal/platform"
+	_ "embed"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed corpus.yaml
+var defaultCorpus []byte
+
+// Corpus is a versioned set of evaluation cases.
+type Corpus struct {
+	Version  int    `yaml:"version"`
+	Platform string `yaml:"platform"` // default for cases that name none
+	Cases    []Case `yaml:"cases"`
+}
+
+// Case is one query and the commands that count as a correct answer.
+type Case struct {
+	Query    string   `yaml:"query"`
+	Want     []string `yaml:"want"`
+	None     bool     `yaml:"none"`     // the query should get no suggestion
+	Layer    string   `yaml:"layer"`    // source expected to answer ("static", "compose"…)
+	Platform string   `yaml:"platform"` // see platforms
+	Project  []string `yaml:"project"`  // project kinds the query runs in (go, node…)
+}
+
+// platforms are the machines a case can be evaluated on.
+var platforms = map[string]platform.Info{
+	"termux": {OS: "linux", Distro: "termux", PackageManager: "pkg", Termux: true},
+	"debian": {OS: "linux", Distro: "debian", PackageManager: "apt"},
+	"fedora": {OS: "linux", Distro: "fedora", PackageManager: "dnf"},
+	"arch":   {OS: "linux", Distro: "arch", PackageManager: "pacman"},
+	"alpine": {OS: "linux", Distro: "alpine", PackageManager: "apk", Root: true},
+	"macos":  {OS: "darwin", Distro: "macos", PackageManager: "brew"},
+}
+
+// DefaultCorpus returns the corpus built into clio.
+func DefaultCorpus() (*Corpus, error) {
+	return parseCorpus(defaultCorpus, "built-in corpus")
+}
+
+// LoadCorpus reads a corpus file.
+func LoadCorpus(path string) (*Corpus, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseCorpus(data, path)
+}
+
+func parseCorpus(data []byte, name string) (*Corpus, error) {
+	var c Corpus
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&c); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if c.Platform == "" {
+		c.Platform = "termux"
+	}
+	seen := make(map[string]bool)
+	for i, tc := range c.Cases {
+		switch {
+		case tc.Query == "":
+			return nil, fmt.Errorf("%s: case %d has no query", name, i+1)
+		case len(tc.Want) == 0 && !tc.None:
+			return nil, fmt.Errorf("%s: %q needs want or none", name, tc.Query)
+		case len(tc.Want) > 0 && tc.None:
+			return nil, fmt.Errorf("%s: %q has both want and none", name, tc.Query)
+		}
+		if _, ok := platforms[c.platformFor(tc)]; !ok {
+			return nil, fmt.Errorf("%s: %q: unknown platform %q", name, tc.Query, c.platformFor(tc))
+		}
+		if seen[tc.Key()] {
+			return nil, fmt.Errorf("%s: duplicate case %q", name, tc.Key())
+		}
+		seen[tc.Key()] = true
+	}
+	return &c, nil
+}
+
+func (c *Corpus) platformFor(tc Case) string {
+	if tc.Platform != "" {
+		return tc.Platform
+	}
+	return c.Platform
+}
+
+// Key identifies a case in baselines: the query plus any platform or project
+// it runs under, since "install git" is a different case on debian.
+func (tc Case) Key() string {
+	key := tc.Query
+	if tc.Platform != "" {
+		key += " @" + tc.Platform
+	}
+	for _, p := range tc.Project {
+		key += " +" + p
+	}
+	return key
+}
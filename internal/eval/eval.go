@@ -0,0 +1,244 @@
+package eval
+
+import (
+	"clio/internal/intent"
+	"clio/internal/platform"
+	"clio/internal/project"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Options controls a run.
+type Options struct {
+	TopK int // ranked candidates checked for top-k accuracy (default 3)
+	// Live keeps query history, man pages, cached modules and remote search
+	// in play. Results then depend on the machine and cannot be compared
+	// with a baseline made elsewhere.
+	Live bool
+}
+
+// Result is the outcome of one case.
+type Result struct {
+	Key        string   `json:"key"`
+	Query      string   `json:"query"`
+	Want       []string `json:"want,omitempty"`
+	Got        string   `json:"got,omitempty"` // "" when nothing was suggested
+	Source     string   `json:"source,omitempty"`
+	Layer      string   `json:"layer,omitempty"` // expected source, if the case names one
+	Confidence float64  `json:"confidence,omitempty"`
+	Correct    bool     `json:"correct"`
+	// Rank is the 1-based position of the first correct command among the
+	// top-k candidates, 0 if none was correct.
+	Rank int `json:"rank"`
+}
+
+// Answered reports whether the pipeline suggested anything.
+func (r Result) Answered() bool {
+	return r.Got != ""
+}
+
+// LayerStats counts how one source performed.
+type LayerStats struct {
+	Answered int `json:"answered"` // top suggestions that came from this source
+	Correct  int `json:"correct"`
+	Expected int `json:"expected"` // cases naming this source as their layer
+	Routed   int `json:"routed"`   // … that this source actually answered
+}
+
+// Bucket is one confidence band of the calibration table.
+type Bucket struct {
+	Low        float64 `json:"low"`
+	High       float64 `json:"high"`
+	Count      int     `json:"count"`
+	Confidence float64 `json:"mean_confidence"`
+	Accuracy   float64 `json:"accuracy"`
+}
+
+// Report summarizes a run.
+type Report struct {
+	CorpusVersion int                    `json:"corpus_version"`
+	Total         int                    `json:"total"`
+	Answered      int                    `json:"answered"`
+	Correct       int                    `json:"correct"`
+	Accuracy      float64                `json:"accuracy"`  // correct / total
+	Precision     float64                `json:"precision"` // correct answers / answers
+	TopK          int                    `json:"top_k"`
+	TopKCorrect   int                    `json:"top_k_correct"`
+	TopKAccuracy  float64                `json:"top_k_accuracy"`
+	Layers        map[string]*LayerStats `json:"layers"`
+	Calibration   []Bucket               `json:"calibration"`
+	// ECE is the expected calibration error: the gap between confidence and
+	// accuracy, averaged over buckets weighted by size. 0 is perfect.
+	ECE     float64  `json:"ece"`
+	Results []Result `json:"results"`
+	Diff    *Diff    `json:"baseline,omitempty"`
+}
+
+// Run evaluates every case in c.
+func Run(c *Corpus, opts Options) *Report {
+	if opts.TopK <= 0 {
+		opts.TopK = 3
+	}
+	if !opts.Live {
+		intent.SetHermetic(true)
+		defer intent.SetHermetic(false)
+	}
+	defer platform.Override(nil)
+	defer project.Override(nil)
+
+	results := make([]Result, 0, len(c.Cases))
+	for _, tc := range c.Cases {
+		info := platforms[c.platformFor(tc)]
+		platform.Override(&info)
+		ctx := project.Context{Kinds: tc.Project}
+		project.Override(&ctx)
+		results = append(results, runCase(tc, opts.TopK))
+	}
+	return summarize(c.Version, opts.TopK, results)
+}
+
+func runCase(tc Case, k int) Result {
+	r := Result{Key: tc.Key(), Query: tc.Query, Want: tc.Want, Layer: tc.Layer}
+	if res, err := intent.Detect(tc.Query); err == nil {
+		r.Got, r.Source, r.Confidence = res.Command, res.Source, res.Confidence
+	}
+	if tc.None {
+		r.Correct = !r.Answered()
+		return r
+	}
+	if r.Answered() && matches(tc.Want, r.Got) {
+		r.Correct, r.Rank = true, 1
+		return r
+	}
+	cands, err := intent.DetectAll(tc.Query, k)
+	if err != nil {
+		return r
+	}
+	for i, cand := range cands {
+		if matches(tc.Want, cand.Command) {
+			r.Rank = i + 1
+			break
+		}
+	}
+	return r
+}
+
+func matches(want []string, got string) bool {
+	got = normalize(got)
+	for _, w := range want {
+		if normalize(w) == got {
+			return true
+		}
+	}
+	return false
+}
+
+func normalize(cmd string) string {
+	return strings.Join(strings.Fields(cmd), " ")
+}
+
+func summarize(version, k int, results []Result) *Report {
+	rep := &Report{
+		CorpusVersion: version,
+		Total:         len(results),
+		TopK:          k,
+		Layers:        make(map[string]*LayerStats),
+		Results:       results,
+	}
+	layer := func(name string) *LayerStats {
+		if rep.Layers[name] == nil {
+			rep.Layers[name] = &LayerStats{}
+		}
+		return rep.Layers[name]
+	}
+
+	for _, r := range results {
+		if r.Correct {
+			rep.Correct++
+		}
+		if r.Rank > 0 || (r.Correct && len(r.Want) == 0) {
+			rep.TopKCorrect++
+		}
+		if r.Layer != "" {
+			l := layer(r.Layer)
+			l.Expected++
+			if r.Source == r.Layer {
+				l.Routed++
+			}
+		}
+		if !r.Answered() {
+			continue
+		}
+		rep.Answered++
+		l := layer(r.Source)
+		l.Answered++
+		if r.Correct {
+			l.Correct++
+		}
+	}
+	rep.Accuracy = ratio(rep.Correct, rep.Total)
+	rep.TopKAccuracy = ratio(rep.TopKCorrect, rep.Total)
+	correctAnswers := 0
+	for _, l := range rep.Layers {
+		correctAnswers += l.Correct
+	}
+	rep.Precision = ratio(correctAnswers, rep.Answered)
+	rep.Calibration, rep.ECE = calibrate(results)
+	return rep
+}
+
+// calibrationBands are the confidence bands of the calibration table.
+var calibrationBands = []float64{0, 0.5, 0.7, 0.8, 0.9, 0.95, 1.0001}
+
+// calibrate groups answered results by confidence and compares each band's
+// mean confidence with how often it was right.
+func calibrate(results []Result) ([]Bucket, float64) {
+	var buckets []Bucket
+	answered := 0
+	for i := 0; i+1 < len(calibrationBands); i++ {
+		b := Bucket{Low: calibrationBands[i], High: math.Min(calibrationBands[i+1], 1)}
+		correct := 0
+		for _, r := range results {
+			if !r.Answered() || r.Confidence < calibrationBands[i] || r.Confidence >= calibrationBands[i+1] {
+				continue
+			}
+			b.Count++
+			b.Confidence += r.Confidence
+			if r.Correct {
+				correct++
+			}
+		}
+		if b.Count == 0 {
+			continue
+		}
+		answered += b.Count
+		b.Confidence /= float64(b.Count)
+		b.Accuracy = ratio(correct, b.Count)
+		buckets = append(buckets, b)
+	}
+	ece := 0.0
+	for _, b := range buckets {
+		ece += float64(b.Count) / float64(answered) * math.Abs(b.Confidence-b.Accuracy)
+	}
+	return buckets, ece
+}
+
+// Failures returns the incorrect results, ordered by key.
+func (r *Report) Failures() []Result {
+	var out []Result
+	for _, res := range r.Results {
+		if !res.Correct {
+			out = append(out, res)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+func ratio(n, d int) float64 {
+	if d == 0 {
+		return 0
+	}
+	return float64(n) / float64(d)
+}
@@ -0,0 +1,161 @@
+package eval
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+const refresh = "go run ./cmd/clio eval --write-baseline internal/eval/baseline.json"
+
+// TestCorpusAgainstBaseline fails when a change to stemming, aliases or
+// rules breaks a query that used to work, when the checked-in baseline no
+// longer matches the corpus, and when it records a case as failing: a wrong
+// answer is a bug to fix, not one to accept.
+func TestCorpusAgainstBaseline(t *testing.T) {
+	corpus, err := DefaultCorpus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, err := DefaultBaseline()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, c := range base.Cases {
+		if !c.Correct {
+			t.Errorf("baseline accepts a failing case: %q gets %q", key, orNone(c.Got))
+		}
+	}
+	rep := Run(corpus, Options{})
+	d, err := rep.Compare(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range d.Regressions {
+		t.Errorf("regressed: %q was %q, now %q", c.Key, c.Before, orNone(c.After))
+	}
+	for _, c := range d.Fixed {
+		t.Errorf("fixed: %q now %q; refresh the baseline: %s", c.Key, c.After, refresh)
+	}
+	for _, k := range append(d.Added, d.Removed...) {
+		t.Errorf("baseline out of date for %q; refresh it: %s", k, refresh)
+	}
+	for _, c := range d.Changed {
+		t.Logf("changed (still wrong): %q %q → %q", c.Key, c.Before, c.After)
+	}
+}
+
+func TestParseCorpusRejects(t *testing.T) {
+	cases := map[string]string{
+		"no want":          "version: 1\ncases:\n  - {query: list files}\n",
+		"want and none":    "version: 1\ncases:\n  - {query: list files, want: [ls], none: true}\n",
+		"unknown field":    "version: 1\ncases:\n  - {query: list files, want: [ls], expect: [ls]}\n",
+		"unknown platform": "version: 1\ncases:\n  - {query: list files, want: [ls], platform: plan9}\n",
+		"duplicate":        "version: 1\ncases:\n  - {query: a, want: [ls]}\n  - {query: a, want: [ls -la]}\n",
+	}
+	for name, data := range cases {
+		if _, err := parseCorpus([]byte(data), name); err == nil {
+			t.Errorf("%s: parsed without error", name)
+		}
+	}
+	// The same query on another platform is a different case
+	ok := "version: 1\ncases:\n  - {query: a, want: [ls]}\n  - {query: a, want: [ls], platform: macos}\n"
+	if _, err := parseCorpus([]byte(ok), "ok"); err != nil {
+		t.Errorf("platform variants: %v", err)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	results := []Result{
+		{Key: "a", Want: []string{"ls"}, Got: "ls", Source: "static", Layer: "static", Confidence: 1, Correct: true, Rank: 1},
+		{Key: "b", Want: []string{"df -h"}, Got: "du", Source: "static", Layer: "static", Confidence: 0.96, Rank: 2},
+		{Key: "c", Want: []string{"free -h"}, Got: "free -h", Source: "fuzzy", Layer: "static", Confidence: 0.88, Correct: true, Rank: 1},
+		{Key: "d", Want: []string{"top"}},
+		{Key: "e", Correct: true}, // nothing expected, nothing suggested
+	}
+	rep := summarize(1, 3, results)
+
+	if rep.Total != 5 || rep.Answered != 3 || rep.Correct != 3 || rep.TopKCorrect != 4 {
+		t.Errorf("counts = total %d answered %d correct %d top-k %d, want 5 3 3 4",
+			rep.Total, rep.Answered, rep.Correct, rep.TopKCorrect)
+	}
+	if math.Abs(rep.Precision-2.0/3) > 1e-9 {
+		t.Errorf("Precision = %v, want 2/3", rep.Precision)
+	}
+	static := rep.Layers["static"]
+	if static.Answered != 2 || static.Correct != 1 || static.Expected != 3 || static.Routed != 2 {
+		t.Errorf("static = %+v", *static)
+	}
+	if fuzzy := rep.Layers["fuzzy"]; fuzzy.Answered != 1 || fuzzy.Correct != 1 {
+		t.Errorf("fuzzy = %+v", *fuzzy)
+	}
+
+	if len(rep.Calibration) != 2 {
+		t.Fatalf("Calibration = %+v, want two bands", rep.Calibration)
+	}
+	high := rep.Calibration[1] // 0.95–1.00: confidences 1 and 0.96, one right
+	if high.Count != 2 || math.Abs(high.Confidence-0.98) > 1e-9 || high.Accuracy != 0.5 {
+		t.Errorf("high band = %+v", high)
+	}
+	// (1·|0.88−1| + 2·|0.98−0.5|) / 3
+	if want := (0.12 + 2*0.48) / 3; math.Abs(rep.ECE-want) > 1e-9 {
+		t.Errorf("ECE = %v, want %v", rep.ECE, want)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	base := &Baseline{CorpusVersion: 2, Cases: map[string]BaselineCase{
+		"ok":      {Got: "ls", Correct: true},
+		"broken":  {Got: "du", Correct: false},
+		"wrong":   {Got: "du", Correct: false},
+		"same":    {Got: "df -h", Correct: true},
+		"dropped": {Got: "pwd", Correct: true},
+	}}
+	rep := &Report{CorpusVersion: 2, Results: []Result{
+		{Key: "ok", Got: "ls -la"},
+		{Key: "broken", Got: "df -h", Correct: true},
+		{Key: "wrong", Got: "du -sh"},
+		{Key: "same", Got: "df  -h", Correct: true},
+		{Key: "new", Got: "top", Correct: true},
+	}}
+	d, err := rep.Compare(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := func(cs []Change) string {
+		var out []string
+		for _, c := range cs {
+			out = append(out, c.Key)
+		}
+		return strings.Join(out, ",")
+	}
+	if keys(d.Regressions) != "ok" || keys(d.Fixed) != "broken" || keys(d.Changed) != "wrong" {
+		t.Errorf("regressions %q fixed %q changed %q", keys(d.Regressions), keys(d.Fixed), keys(d.Changed))
+	}
+	if strings.Join(d.Added, ",") != "new" || strings.Join(d.Removed, ",") != "dropped" {
+		t.Errorf("added %v removed %v", d.Added, d.Removed)
+	}
+
+	if _, err := rep.Compare(&Baseline{CorpusVersion: 1}); err == nil {
+		t.Error("Compare accepted a baseline for another corpus version")
+	}
+}
+
+func TestBaselineRoundTrip(t *testing.T) {
+	rep := &Report{CorpusVersion: 3, Accuracy: 0.5, Results: []Result{
+		{Key: `say "hi"`, Got: `echo "hi"`, Correct: true},
+		{Key: "nothing"},
+	}}
+	var buf bytes.Buffer
+	if err := rep.Baseline().WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	b, err := parseBaseline(buf.Bytes(), "round trip")
+	if err != nil {
+		t.Fatalf("%v\n%s", err, buf.String())
+	}
+	if b.CorpusVersion != 3 || len(b.Cases) != 2 || b.Cases[`say "hi"`].Got != `echo "hi"` || !b.Cases[`say "hi"`].Correct {
+		t.Errorf("round trip = %+v", b)
+	}
+}
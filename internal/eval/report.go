@@ -0,0 +1,88 @@
+package eval
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Write prints the report for people: headline numbers, per-layer rates,
+// the calibration table, baseline changes and every failing case.
+func (r *Report) Write(w io.Writer) {
+	fmt.Fprintf(w, "Corpus v%d: %d cases\n", r.CorpusVersion, r.Total)
+	fmt.Fprintf(w, "Top-1 accuracy : %d/%d (%s)\n", r.Correct, r.Total, percent(r.Accuracy))
+	fmt.Fprintf(w, "Top-%d accuracy : %d/%d (%s)\n", r.TopK, r.TopKCorrect, r.Total, percent(r.TopKAccuracy))
+	fmt.Fprintf(w, "Precision      : %s of %d answered\n", percent(r.Precision), r.Answered)
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Layer          answered  correct  expected  routed")
+	names := make([]string, 0, len(r.Layers))
+	for name := range r.Layers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		l := r.Layers[name]
+		fmt.Fprintf(w, "  %-12s %8d  %7s  %8d  %6s\n", name, l.Answered, rate(l.Correct, l.Answered), l.Expected, rate(l.Routed, l.Expected))
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Calibration (ECE %.3f)\n", r.ECE)
+	for _, b := range r.Calibration {
+		fmt.Fprintf(w, "  %.2f–%.2f  %3d answered  mean confidence %s  accuracy %s\n",
+			b.Low, b.High, b.Count, percent(b.Confidence), percent(b.Accuracy))
+	}
+
+	if d := r.Diff; d != nil {
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "Baseline: %d regressed, %d fixed, %d changed, %d new, %d removed\n",
+			len(d.Regressions), len(d.Fixed), len(d.Changed), len(d.Added), len(d.Removed))
+		writeChanges(w, "REGRESSED", d.Regressions)
+		writeChanges(w, "FIXED", d.Fixed)
+		writeChanges(w, "CHANGED", d.Changed)
+	}
+
+	if failures := r.Failures(); len(failures) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "Failures (%d)\n", len(failures))
+		for _, f := range failures {
+			want := "no suggestion"
+			if len(f.Want) > 0 {
+				want = strings.Join(f.Want, " | ")
+			}
+			got := "no suggestion"
+			if f.Answered() {
+				got = fmt.Sprintf("%s [%s %.0f%%]", f.Got, f.Source, f.Confidence*100)
+			}
+			fmt.Fprintf(w, "  %q\n      want %s\n      got  %s\n", f.Key, want, got)
+			if f.Rank > 0 {
+				fmt.Fprintf(w, "      (correct command ranked %d)\n", f.Rank)
+			}
+		}
+	}
+}
+
+func writeChanges(w io.Writer, label string, changes []Change) {
+	for _, c := range changes {
+		fmt.Fprintf(w, "  %-9s %q: %s → %s\n", label, c.Key, orNone(c.Before), orNone(c.After))
+	}
+}
+
+func orNone(cmd string) string {
+	if cmd == "" {
+		return "(none)"
+	}
+	return cmd
+}
+
+func percent(f float64) string {
+	return fmt.Sprintf("%.1f%%", f*100)
+}
+
+func rate(n, d int) string {
+	if d == 0 {
+		return "-"
+	}
+	return percent(float64(n) / float64(d))
+}
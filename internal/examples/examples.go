@@ -0,0 +1,224 @@
+// Package examples is clio's database of worked examples: short command lines
+// with a one-line explanation, keyed by the program they use. The database is
+// embedded in the binary, updated by sync and extended by user files.
+package examples
+
+import (
+	"bytes"
+	"clio/internal/platform"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed examples.yaml
+var builtin []byte
+
+// Example is one worked example.
+type Example struct {
+	Command     string   `yaml:"command" json:"command"` // program, see Program
+	Example     string   `yaml:"example" json:"example"`
+	Explanation string   `yaml:"explanation" json:"explanation"`
+	Tags        []string `yaml:"tags" json:"tags,omitempty"`
+	Platforms   []string `yaml:"platforms" json:"platforms,omitempty"` // platform keys; empty is everywhere
+	Origin      string   `yaml:"-" json:"origin"`                      // "built-in", "synced" or a file path
+}
+
+// File is the schema of examples.yaml and of the user's examples files.
+type File struct {
+	Version  int       `yaml:"version"`
+	Examples []Example `yaml:"examples"`
+}
+
+var (
+	mu       sync.Mutex
+	loadOnce sync.Once
+	all      []Example
+)
+
+// load reads the built-in database and the last synced copy. User files are
+// merged on top by LoadUserExamples.
+func load() {
+	loadOnce.Do(func() {
+		f, err := parse(builtin, "built-in examples")
+		if err != nil {
+			panic(err) // the embedded file is checked by the tests
+		}
+		merge(f, "built-in")
+		if path, err := SyncedPath(); err == nil {
+			if f, err := parseFile(path); err == nil {
+				merge(f, "synced")
+			}
+		}
+	})
+}
+
+// UserExamplePaths lists the user's example files in load order:
+// ~/.clio/examples.yaml, then the project's ./.clio/examples.yaml.
+func UserExamplePaths() []string {
+	var paths []string
+	if home, err := os.UserHomeDir(); err == nil {
+		paths = append(paths, filepath.Join(home, ".clio", "examples.yaml"))
+	}
+	if wd, err := os.Getwd(); err == nil {
+		local := filepath.Join(wd, ".clio", "examples.yaml")
+		if len(paths) == 0 || local != paths[0] {
+			paths = append(paths, local)
+		}
+	}
+	return paths
+}
+
+// LoadUserExamples merges every file from UserExamplePaths into the database.
+// Missing files are skipped; the returned errors name the file and entry that
+// failed validation. Valid entries are loaded even when others fail.
+func LoadUserExamples() []error {
+	var errs []error
+	for _, path := range UserExamplePaths() {
+		if err := LoadFile(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// LoadFile parses one examples file and merges its valid entries.
+func LoadFile(path string) error {
+	f, err := parseFile(path)
+	if err != nil {
+		return err
+	}
+	load()
+	var errs []error
+	for _, e := range merge(f, path) {
+		errs = append(errs, fmt.Errorf("%s: %w", path, e))
+	}
+	return errors.Join(errs...)
+}
+
+func parseFile(path string) (File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return File{}, err
+	}
+	return parse(data, path)
+}
+
+func parse(data []byte, name string) (File, error) {
+	var f File
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true) // catch typos such as "exmaple:"
+	if err := dec.Decode(&f); err != nil && !errors.Is(err, io.EOF) {
+		return File{}, fmt.Errorf("%s: %w", name, err)
+	}
+	return f, nil
+}
+
+// merge validates f and adds its valid examples in front of the ones already
+// loaded, so later sources are listed first. An example with the same command
+// line as an existing one replaces it.
+func merge(f File, origin string) []error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	var errs []error
+	var added []Example
+	replaced := make(map[string]bool)
+	for i, e := range f.Examples {
+		e.Command = strings.TrimSpace(e.Command)
+		e.Example = strings.TrimSpace(e.Example)
+		e.Explanation = strings.TrimSpace(e.Explanation)
+		switch {
+		case e.Command == "" || e.Example == "":
+			errs = append(errs, fmt.Errorf("examples[%d]: command and example are required", i))
+			continue
+		case e.Explanation == "":
+			errs = append(errs, fmt.Errorf("examples[%d] (%s): explanation is required", i, e.Example))
+			continue
+		}
+		e.Origin = origin
+		added = append(added, e)
+		replaced[normalize(e.Example)] = true
+	}
+	kept := added
+	for _, e := range all {
+		if !replaced[normalize(e.Example)] {
+			kept = append(kept, e)
+		}
+	}
+	all = kept
+	return errs
+}
+
+// All returns every example that applies to p.
+func All(p platform.Info) []Example {
+	load()
+	mu.Lock()
+	defer mu.Unlock()
+	var out []Example
+	for _, e := range all {
+		if e.appliesTo(p) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// Programs lists the programs with at least one example on any platform.
+func Programs() map[string]bool {
+	load()
+	mu.Lock()
+	defer mu.Unlock()
+	out := make(map[string]bool)
+	for _, e := range all {
+		out[e.Command] = true
+	}
+	return out
+}
+
+func (e Example) appliesTo(p platform.Info) bool {
+	if len(e.Platforms) == 0 {
+		return true
+	}
+	for _, key := range p.Keys() {
+		for _, want := range e.Platforms {
+			if key == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Program returns the program a command line runs: its first word, skipping
+// sudo and VAR=value assignments, without any directory. "./script.sh" is
+// kept as is, since "a script in this directory" is what its examples cover.
+func Program(cmdline string) string {
+	fields := strings.Fields(cmdline)
+	for len(fields) > 1 && (fields[0] == "sudo" || isAssignment(fields[0])) {
+		fields = fields[1:]
+	}
+	if len(fields) == 0 {
+		return ""
+	}
+	if strings.HasPrefix(fields[0], "./") {
+		return fields[0]
+	}
+	return filepath.Base(fields[0])
+}
+
+func isAssignment(word string) bool {
+	i := strings.IndexByte(word, '=')
+	return i > 0 && !strings.ContainsAny(word[:i], "-/.'\"")
+}
+
+func normalize(cmd string) string {
+	return strings.Join(strings.Fields(cmd), " ")
+}
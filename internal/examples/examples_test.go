@@ -0,0 +1,264 @@
+package examples
+
+import (
+	"clio/internal/layer1"
+	"clio/internal/platform"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var (
+	termux = platform.Info{OS: "linux", Distro: "termux", PackageManager: "pkg", Termux: true}
+	debian = platform.Info{OS: "linux", Distro: "debian", PackageManager: "apt"}
+	macos  = platform.Info{OS: "darwin", Distro: "macos", PackageManager: "brew"}
+)
+
+// reset reloads the database from the built-in file only, with HOME pointing
+// at an empty directory.
+func reset(t *testing.T) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	mu.Lock()
+	all, loadOnce = nil, sync.Once{}
+	mu.Unlock()
+	t.Cleanup(func() {
+		mu.Lock()
+		all, loadOnce = nil, sync.Once{}
+		mu.Unlock()
+	})
+}
+
+// TestBuiltinCoversCatalog checks that every command clio can suggest, on
+// every platform it rewrites commands for, has at least one example.
+func TestBuiltinCoversCatalog(t *testing.T) {
+	reset(t)
+	platforms := []platform.Info{
+		termux, debian, macos,
+		{OS: "linux", Distro: "fedora", PackageManager: "dnf"},
+		{OS: "linux", Distro: "centos", PackageManager: "yum"},
+		{OS: "linux", Distro: "arch", PackageManager: "pacman"},
+		{OS: "linux", Distro: "alpine", PackageManager: "apk", Root: true},
+		{OS: "linux", Distro: "opensuse", PackageManager: "zypper"},
+	}
+	entries := append(layer1.CatalogCommands(), layer1.ProjectCommands()...)
+	for _, p := range platforms {
+		for _, e := range entries {
+			cmd := e.For(p).Cmd
+			if len(For(cmd, p)) == 0 {
+				t.Errorf("%s: no example for %q (program %q)", p.Distro, cmd, Program(cmd))
+			}
+		}
+	}
+}
+
+func TestBuiltinIsValid(t *testing.T) {
+	f, err := parse(builtin, "built-in")
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]bool)
+	for _, e := range f.Examples {
+		if e.Command == "" || e.Example == "" || e.Explanation == "" {
+			t.Errorf("incomplete example %+v", e)
+		}
+		if seen[normalize(e.Example)] {
+			t.Errorf("duplicate example %q", e.Example)
+		}
+		seen[normalize(e.Example)] = true
+	}
+}
+
+func TestProgram(t *testing.T) {
+	tests := []struct{ cmd, want string }{
+		{"rsync -avz src/ dest/", "rsync"},
+		{"sudo apt install git", "apt"},
+		{"LC_ALL=C sort file", "sort"},
+		{"/usr/bin/find . -name x", "find"},
+		{"vendor/bin/phpunit", "phpunit"},
+		{"./script.sh arg", "./script.sh"},
+		{"sudo", "sudo"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Program(tt.cmd); got != tt.want {
+			t.Errorf("Program(%q) = %q, want %q", tt.cmd, got, tt.want)
+		}
+	}
+}
+
+func TestFor(t *testing.T) {
+	reset(t)
+	tests := []struct {
+		cmd   string
+		p     platform.Info
+		first string
+		not   string // must not be listed
+	}{
+		{"tar -xzvf {file}", termux, "tar -xzvf file.tar.gz", ""},
+		{"tar -czvf archive.tar.gz {dir}", termux, "tar -czvf archive.tar.gz dir/", ""},
+		{"sudo apt install git", debian, "sudo apt install git", ""},
+		{"sed 's/a/b/'", macos, "sed 's/old/new/g' file", "sed -i 's/old/new/g' file"},
+		{"sed 's/a/b/'", debian, "sed 's/old/new/g' file", "sed -i '' 's/old/new/g' file"},
+	}
+	for _, tt := range tests {
+		got := For(tt.cmd, tt.p)
+		if len(got) == 0 || got[0].Example != tt.first {
+			t.Errorf("For(%q)[0] = %v, want %q", tt.cmd, got, tt.first)
+			continue
+		}
+		for _, e := range got {
+			if e.Example == tt.not {
+				t.Errorf("For(%q) on %s lists %q", tt.cmd, tt.p.Distro, e.Example)
+			}
+		}
+	}
+	if got := For("no-such-program", termux); len(got) != 0 {
+		t.Errorf("For(unknown) = %v", got)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	reset(t)
+	tests := []struct {
+		query string
+		first string
+	}{
+		{"rsync with delete", "rsync -av --delete src/ dest/"},
+		{"tar list contents", "tar -tzvf file.tar.gz"},
+		{"git undo changes", "git restore file"},
+		{"resume download", "wget -c URL"},
+		{"follow a log", "tail -f logfile"},
+		{"compress a folder", "zip -r archive.zip dir/"},
+	}
+	for _, tt := range tests {
+		got := Search(tt.query, termux)
+		if len(got) == 0 || got[0].Example != tt.first {
+			first := ""
+			if len(got) > 0 {
+				first = got[0].Example
+			}
+			t.Errorf("Search(%q)[0] = %q, want %q", tt.query, first, tt.first)
+		}
+	}
+	// Naming a program keeps its examples even when no other word matches
+	if got := Search("ssh whatever", termux); len(got) == 0 || got[0].Command != "ssh" {
+		t.Errorf("Search(ssh whatever) = %v", got)
+	}
+	if got := Search("xyzzy plugh", termux); len(got) != 0 {
+		t.Errorf("Search(nonsense) = %v", got)
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		ok    bool
+	}{
+		{"example of rsync with delete", "rsync with delete", true},
+		{"Examples for tar?", "tar", true},
+		{"show me an example of find", "find", true},
+		{"give me some examples using grep", "grep", true},
+		{"git examples", "git", true},
+		{"example chmod", "chmod", true},
+		{"examples", "", false},
+		{"show examples", "", false},
+		{"list files", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseQuery(tt.input)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParseQuery(%q) = %q, %v; want %q, %v", tt.input, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestLoadFileMergesAndReplaces(t *testing.T) {
+	reset(t)
+	path := filepath.Join(t.TempDir(), "examples.yaml")
+	data := `examples:
+  - command: rsync
+    example: rsync -avz src/ dest/
+    explanation: Team wording
+  - command: deploy
+    example: deploy --prod
+    explanation: Ship it
+    tags: [release]
+  - command: broken
+    example: broken
+  - example: no command
+    explanation: skipped
+`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	err := LoadFile(path)
+	if err == nil || !strings.Contains(err.Error(), "examples[2]") || !strings.Contains(err.Error(), "examples[3]") {
+		t.Fatalf("LoadFile error = %v, want examples[2] and examples[3] rejected", err)
+	}
+
+	rsync := For("rsync", termux)
+	count := 0
+	for _, e := range rsync {
+		if e.Example == "rsync -avz src/ dest/" {
+			count++
+			if e.Explanation != "Team wording" || e.Origin != path {
+				t.Errorf("rsync -avz = %+v, want the user's entry", e)
+			}
+		}
+	}
+	if count != 1 {
+		t.Errorf("rsync -avz listed %d times", count)
+	}
+	if got := Search("release", termux); len(got) == 0 || got[0].Example != "deploy --prod" {
+		t.Errorf("Search(release) = %v", got)
+	}
+}
+
+func TestLoadFileRejectsUnknownFields(t *testing.T) {
+	reset(t)
+	path := filepath.Join(t.TempDir(), "examples.yaml")
+	if err := os.WriteFile(path, []byte("examples:\n  - comand: ls\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadFile(path); err == nil || !strings.Contains(err.Error(), "comand") {
+		t.Errorf("LoadFile error = %v, want unknown field", err)
+	}
+}
+
+func TestSync(t *testing.T) {
+	reset(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/examples" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("version: 1\nexamples:\n  - command: jq\n    example: jq . file.json\n    explanation: Pretty-print JSON\n"))
+	}))
+	defer srv.Close()
+
+	n, err := Sync(srv.URL)
+	if err != nil || n != 1 {
+		t.Fatalf("Sync = %d, %v", n, err)
+	}
+	if got := For("jq", termux); len(got) != 1 || got[0].Origin != "synced" {
+		t.Fatalf("For(jq) after sync = %v", got)
+	}
+
+	// A later run picks the saved copy up again
+	mu.Lock()
+	all, loadOnce = nil, sync.Once{}
+	mu.Unlock()
+	if got := For("jq", termux); len(got) != 1 {
+		t.Errorf("For(jq) after reload = %v", got)
+	}
+
+	if _, err := Sync(srv.URL + "/missing"); err == nil {
+		t.Error("Sync from a missing endpoint succeeded")
+	}
+}
@@ -0,0 +1,203 @@
+package examples
+
+import (
+	"clio/internal/layer1"
+	"clio/internal/platform"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// For returns the examples for the program cmdline runs, those sharing the
+// most flags with cmdline first: "tar -xzvf" lists extraction before creation.
+func For(cmdline string, p platform.Info) []Example {
+	prog := Program(cmdline)
+	if prog == "" {
+		return nil
+	}
+	args := make(map[string]bool)
+	for _, f := range strings.Fields(cmdline) {
+		if f != prog && !strings.HasPrefix(f, "{") {
+			args[f] = true
+		}
+	}
+
+	type scored struct {
+		ex    Example
+		score int
+	}
+	var out []scored
+	for _, e := range All(p) {
+		if e.Command != prog {
+			continue
+		}
+		score := 0
+		for _, f := range strings.Fields(e.Example) {
+			if args[f] {
+				score++
+			}
+		}
+		out = append(out, scored{e, score})
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].score > out[j].score })
+	exs := make([]Example, len(out))
+	for i, s := range out {
+		exs[i] = s.ex
+	}
+	return exs
+}
+
+// searchStopWords carry no meaning in "give me an example of rsync with delete".
+var searchStopWords = map[string]bool{
+	"a": true, "an": true, "the": true, "of": true, "for": true, "with": true, "to": true,
+	"in": true, "on": true, "and": true, "use": true, "using": true, "how": true, "i": true,
+	"do": true, "me": true, "some": true, "show": true, "give": true, "example": true, "examples": true,
+}
+
+// Search finds examples for a free-text query. Words naming a program limit
+// the results to that program; the other words rank examples by how many of
+// them appear in the command line or tags (two points) or the explanation
+// (one point). A word also matches through the catalog's verb and noun
+// aliases, so "folder" finds "directory". Without a program, only examples
+// matching some word are returned.
+func Search(query string, p platform.Info) []Example {
+	programs := Programs()
+	var progs []string
+	var terms [][]string // each word's stem and alias targets
+	for _, w := range words(query) {
+		switch {
+		case programs[w]:
+			progs = append(progs, w)
+		case !searchStopWords[w]:
+			alts := []string{layer1.Stem(w)}
+			for _, alias := range []string{layer1.VerbAliases[w], layer1.NounAliases[w]} {
+				if alias != "" {
+					alts = append(alts, layer1.Stem(alias))
+				}
+			}
+			terms = append(terms, alts)
+		}
+	}
+	if len(progs) == 0 && len(terms) == 0 {
+		return nil
+	}
+
+	type scored struct {
+		ex    Example
+		score int
+	}
+	var out []scored
+	for _, e := range All(p) {
+		if len(progs) > 0 && !contains(progs, e.Command) {
+			continue
+		}
+		strong := stems(e.Example + " " + strings.Join(e.Tags, " "))
+		weak := stems(e.Explanation)
+		score := 0
+		for _, alts := range terms {
+			if anyIn(strong, alts) {
+				score += 2
+			} else if anyIn(weak, alts) {
+				score++
+			}
+		}
+		if score == 0 && len(progs) == 0 {
+			continue
+		}
+		out = append(out, scored{e, score})
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].score > out[j].score })
+	exs := make([]Example, len(out))
+	for i, s := range out {
+		exs[i] = s.ex
+	}
+	return exs
+}
+
+// words splits s into lowercase words, breaking at anything but letters,
+// digits, "-" and "_" inside a word: "--delete" gives "delete".
+func words(s string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_'
+	})
+	out := fields[:0]
+	for _, f := range fields {
+		if f = strings.Trim(f, "-_"); f != "" {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+func stems(s string) map[string]bool {
+	out := make(map[string]bool)
+	for _, w := range words(s) {
+		out[layer1.Stem(w)] = true
+	}
+	return out
+}
+
+func anyIn(set map[string]bool, words []string) bool {
+	for _, w := range words {
+		if set[w] {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	examplePrefixRe = regexp.MustCompile(`^(?:(?:show|give|list)(?: me)? )?(?:an? |some |more )?examples? (?:(?:of|for|using|with) )?(.+)$`)
+	exampleSuffixRe = regexp.MustCompile(`^(?:(?:show|give|list)(?: me)? )?(.+?) examples?$`)
+)
+
+// ParseQuery recognizes a request for examples: "example of rsync with
+// delete", "examples for tar" or "git examples". It returns what to search for.
+func ParseQuery(input string) (string, bool) {
+	q := strings.TrimRight(strings.ToLower(strings.TrimSpace(input)), "?!. ")
+	for _, re := range []*regexp.Regexp{examplePrefixRe, exampleSuffixRe} {
+		if m := re.FindStringSubmatch(q); m != nil && hasContent(m[1]) {
+			return strings.TrimSpace(m[1]), true
+		}
+	}
+	return "", false
+}
+
+// hasContent reports whether s has a word besides "show", "me" and the like.
+func hasContent(s string) bool {
+	for _, w := range words(s) {
+		if !searchStopWords[w] {
+			return true
+		}
+	}
+	return false
+}
+
+// maxExampleWidth caps the example column so long command lines do not push
+// every explanation off screen.
+const maxExampleWidth = 36
+
+// Write prints examples as an aligned "example  - explanation" list.
+func Write(w io.Writer, exs []Example) {
+	width := 0
+	for _, e := range exs {
+		if n := len(e.Example); n > width && n <= maxExampleWidth {
+			width = n
+		}
+	}
+	for _, e := range exs {
+		fmt.Fprintf(w, "  %-*s  - %s\n", width, e.Example, e.Explanation)
+	}
+}
@@ -0,0 +1,72 @@
+package examples
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const maxSyncBytes = 2 << 20 // same cap as a module download
+
+var syncHTTP = &http.Client{Timeout: 30 * time.Second}
+
+// SyncedPath is where sync keeps the registry's examples between runs.
+func SyncedPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".clio", "examples.synced.yaml"), nil
+}
+
+// Sync downloads the registry's examples and saves them with SaveSynced.
+func Sync(registryURL string) (int, error) {
+	resp, err := syncHTTP.Get(registryURL + "/api/v1/examples")
+	if err != nil {
+		return 0, fmt.Errorf("failed to connect to registry: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("registry returned status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSyncBytes))
+	if err != nil {
+		return 0, err
+	}
+	if len(body) == maxSyncBytes {
+		return 0, fmt.Errorf("examples exceed %d byte limit", maxSyncBytes)
+	}
+	return SaveSynced(body, "registry examples")
+}
+
+// SaveSynced replaces the synced examples with body, a file in the registry's
+// format named name in errors, and merges them into the database. It returns
+// how many valid examples body holds. Nothing is saved when body fails to parse.
+func SaveSynced(body []byte, name string) (int, error) {
+	f, err := parse(body, name)
+	if err != nil {
+		return 0, err
+	}
+
+	path, err := SyncedPath()
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return 0, err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, body, 0o644); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return 0, err
+	}
+
+	load()
+	invalid := merge(f, "synced")
+	return len(f.Examples) - len(invalid), nil
+}
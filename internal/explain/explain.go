@@ -0,0 +1,357 @@
+// Package explain describes an existing command line program by program and
+// flag by flag: the reverse of intent.Detect.
+package explain
+
+import (
+	"clio/internal/layer2"
+	"clio/internal/risk"
+	"clio/internal/shell"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// Kind is what a part of the command line is.
+type Kind string
+
+const (
+	KindProgram  Kind = "program"
+	KindFlag     Kind = "flag"
+	KindOperand  Kind = "operand"
+	KindOperator Kind = "operator"
+	KindRedirect Kind = "redirect"
+)
+
+// Part is one word or operator of the command line and what it does.
+type Part struct {
+	Text   string `json:"text"`
+	Kind   Kind   `json:"kind"`
+	Desc   string `json:"desc"`
+	Danger string `json:"danger,omitempty"`
+}
+
+// Explanation describes a whole command line.
+type Explanation struct {
+	Command  string   `json:"command"`
+	Parts    []Part   `json:"parts"`
+	Risk     string   `json:"risk"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// manOptions is swapped out in tests; the real one runs man.
+var manOptions = layer2.Options
+
+// manCache keeps parsed man pages for the life of the process.
+var manCache = map[string]map[string]string{}
+
+// explainer walks the tokens of one command line.
+type explainer struct {
+	out         *Explanation
+	name        string // current program
+	prog        Program
+	expectProg  bool // next word starts a command
+	pending     *Flag
+	pendingName string
+	args        int  // operands and flags seen for the current program
+	noMoreFlags bool // after --
+	// outer is the program whose -exec the current command belongs to, until
+	// its ; or + terminator.
+	outer *outerProgram
+}
+
+// outerProgram is the explainer state to return to after find -exec.
+type outerProgram struct {
+	name string
+	prog Program
+	args int
+	flag string
+}
+
+// Explain tokenizes line and describes each program, flag, operand and
+// operator. Flags missing from the offline database are looked up in the
+// local man page.
+func Explain(line string) (*Explanation, error) {
+	toks, err := shell.Tokenize(line)
+	if err != nil {
+		return nil, err
+	}
+	if len(toks) == 0 {
+		return nil, fmt.Errorf("empty command")
+	}
+
+	e := &explainer{out: &Explanation{Command: line}, expectProg: true}
+	for i := 0; i < len(toks); i++ {
+		t := toks[i]
+		switch {
+		case t.IsRedirect():
+			target := ""
+			if i+1 < len(toks) && !toks[i+1].Op {
+				i++
+				target = toks[i].Text
+			}
+			e.redirect(t, target)
+		case t.Op:
+			e.add(Part{Text: t.Text, Kind: KindOperator, Desc: operatorDesc(t.Text)})
+			e.expectProg = true
+		default:
+			e.word(t)
+		}
+	}
+
+	a := risk.Classify(line)
+	e.out.Risk = a.Level.String()
+	if a.Level >= risk.Privileged {
+		e.out.Warnings = append(e.out.Warnings, a.Summary())
+	}
+	return e.out, nil
+}
+
+func (e *explainer) add(p Part) {
+	e.out.Parts = append(e.out.Parts, p)
+	if p.Danger != "" {
+		e.out.Warnings = append(e.out.Warnings, e.name+" "+p.Text+": "+p.Danger)
+	}
+}
+
+func (e *explainer) redirect(t shell.Token, target string) {
+	desc := operatorDesc(t.Text)
+	if desc == "" {
+		desc = operatorDesc(t.Redirect())
+	}
+	text := t.Text
+	if target != "" {
+		if strings.HasSuffix(t.Text, "&") {
+			text += target // 2>&1
+		} else {
+			text += " " + target
+		}
+	}
+	e.add(Part{Text: text, Kind: KindRedirect, Desc: desc})
+}
+
+func (e *explainer) word(t shell.Token) {
+	switch {
+	case e.outer != nil && !e.expectProg && (t.Text == ";" || t.Text == "+"):
+		e.endCommand(t.Text)
+		return
+
+	case e.outer != nil && !e.expectProg && t.Text == "{}":
+		e.add(Part{Text: t.Text, Kind: KindOperand, Desc: "replaced by the path " + e.outer.name + " matched"})
+
+	case e.expectProg && strings.Contains(t.Text, "=") && !strings.HasPrefix(t.Text, "-"):
+		name, _, _ := strings.Cut(t.Text, "=")
+		e.add(Part{Text: t.Text, Kind: KindOperand, Desc: "set environment variable " + name + " for this command"})
+
+	case e.expectProg:
+		e.program(t.Text)
+
+	case e.pending != nil:
+		e.add(Part{Text: t.Text, Kind: KindOperand, Desc: e.pending.Arg + " for " + e.pendingName})
+		e.pending = nil
+
+	case !e.noMoreFlags && t.Text == "--":
+		e.add(Part{Text: t.Text, Kind: KindFlag, Desc: "end of options: everything after is an operand"})
+		e.noMoreFlags = true
+
+	case !e.noMoreFlags && len(t.Text) > 1 && strings.HasPrefix(t.Text, "-"):
+		e.flag(t.Text)
+
+	case e.prog.Bundled && e.args == 0 && e.knownBundle(t.Text):
+		e.bundle(t.Text, "")
+
+	case e.prog.Wrapper:
+		e.program(t.Text)
+
+	default:
+		desc := e.prog.Operand
+		if desc == "" {
+			desc = "argument"
+		}
+		if t.Expand {
+			desc += " (the shell expands this first)"
+		}
+		e.add(Part{Text: t.Text, Kind: KindOperand, Desc: desc})
+	}
+	e.args++
+}
+
+func (e *explainer) program(word string) {
+	e.name = filepath.Base(word)
+	e.prog = programs[e.name]
+	e.expectProg, e.noMoreFlags, e.pending, e.args = false, false, nil, -1
+	desc := e.prog.Summary
+	if desc == "" {
+		desc = "program (not in the offline database; see man " + e.name + ")"
+	}
+	e.add(Part{Text: word, Kind: KindProgram, Desc: desc})
+}
+
+// endCommand explains the terminator of a -exec command and returns to the
+// program that ran it.
+func (e *explainer) endCommand(term string) {
+	desc := "ends " + e.outer.flag + ": run the command once per match"
+	if term == "+" {
+		desc = "ends " + e.outer.flag + ": run the command with many matches at once"
+	}
+	e.name, e.prog, e.args = e.outer.name, e.outer.prog, e.outer.args
+	e.pending, e.noMoreFlags, e.outer = nil, false, nil
+	e.add(Part{Text: term, Kind: KindOperator, Desc: desc})
+	e.args++
+}
+
+func (e *explainer) flag(text string) {
+	// Exact entries first: long options, find's -name, kill -9
+	name, value, hasValue := strings.Cut(text, "=")
+	if f, ok := e.lookup(name); ok || strings.HasPrefix(text, "--") || len(name) == 2 {
+		if !ok {
+			f = Flag{Desc: "unknown option"}
+		}
+		if hasValue {
+			e.add(Part{Text: text, Kind: KindFlag, Desc: f.Desc + ": " + value, Danger: f.Danger})
+			return
+		}
+		e.add(Part{Text: text, Kind: KindFlag, Desc: f.Desc, Danger: f.Danger})
+		if f.Command {
+			e.outer = &outerProgram{name: e.name, prog: e.prog, args: e.args, flag: text}
+			e.expectProg = true
+		} else if f.Arg != "" {
+			e.pending, e.pendingName = &f, text
+		}
+		return
+	}
+	if e.knownBundle(text[1:]) {
+		e.bundle(text[1:], "-")
+		return
+	}
+	e.add(Part{Text: text, Kind: KindFlag, Desc: "unknown option"})
+}
+
+// bundle explains combined short flags such as -xzvf or ps's aux. A flag that
+// takes a value consumes the rest of the bundle, or else the next word.
+func (e *explainer) bundle(letters, prefix string) {
+	for j := 0; j < len(letters); j++ {
+		flag := "-" + letters[j:j+1]
+		f, ok := e.lookup(flag)
+		if !ok {
+			f = Flag{Desc: "unknown option"}
+		}
+		text := prefix + letters[j:j+1]
+		if f.Arg != "" {
+			if rest := letters[j+1:]; rest != "" {
+				e.add(Part{Text: text, Kind: KindFlag, Desc: f.Desc + ": " + rest, Danger: f.Danger})
+				return
+			}
+			e.add(Part{Text: text, Kind: KindFlag, Desc: f.Desc, Danger: f.Danger})
+			e.pending, e.pendingName = &f, flag
+			return
+		}
+		e.add(Part{Text: text, Kind: KindFlag, Desc: f.Desc, Danger: f.Danger})
+	}
+}
+
+// knownBundle reports whether word is a run of known short flags, where a
+// flag taking a value may end it with the value itself (-n20).
+func (e *explainer) knownBundle(word string) bool {
+	for _, r := range word {
+		f, ok := e.lookup("-" + string(r))
+		if !ok {
+			return false
+		}
+		if f.Arg != "" {
+			return true
+		}
+	}
+	return word != ""
+}
+
+// lookup finds a flag in the offline database, then in the man page.
+func (e *explainer) lookup(flag string) (Flag, bool) {
+	if f, ok := e.prog.Flags[flag]; ok {
+		return f, true
+	}
+	opts, ok := manCache[e.name]
+	if !ok {
+		opts = manOptions(e.name)
+		manCache[e.name] = opts
+	}
+	if desc, ok := opts[flag]; ok {
+		return Flag{Desc: desc + " (man page)"}, true
+	}
+	return Flag{}, false
+}
+
+func operatorDesc(op string) string {
+	if d, ok := operators[op]; ok {
+		return d
+	}
+	if strings.HasPrefix(op, "2") {
+		if d, ok := operators["2"+strings.TrimLeft(op, "0123456789")]; ok {
+			return d
+		}
+	}
+	return ""
+}
+
+// Write prints the explanation as an aligned table with warnings last.
+func (x *Explanation) Write(w io.Writer) {
+	width := 0
+	for _, p := range x.Parts {
+		if n := len(p.Text); n > width && n <= 24 {
+			width = n
+		}
+	}
+	fmt.Fprintln(w, x.Command)
+	for _, p := range x.Parts {
+		marker := " "
+		if p.Danger != "" {
+			marker = "!"
+		}
+		fmt.Fprintf(w, " %s %-*s  %s\n", marker, width, p.Text, p.Desc)
+	}
+	fmt.Fprintf(w, "Risk: %s\n", x.Risk)
+	for _, warn := range x.Warnings {
+		fmt.Fprintf(w, "⚠️  %s\n", warn)
+	}
+}
+
+// ParseQuery recognizes a request to explain a command: "explain <cmd>",
+// "what does <cmd> do?" or a `backquoted` command. It returns the command line.
+func ParseQuery(input string) (string, bool) {
+	input = strings.TrimSpace(input)
+	lower := strings.ToLower(input)
+	if strings.HasPrefix(lower, "explain ") {
+		return unquote(input[len("explain "):]), true
+	}
+	if start := strings.IndexByte(input, '`'); start >= 0 {
+		if end := strings.IndexByte(input[start+1:], '`'); end > 0 {
+			return input[start+1 : start+1+end], true
+		}
+	}
+	for _, prefix := range []string{"what does ", "what do ", "what is "} {
+		if !strings.HasPrefix(lower, prefix) {
+			continue
+		}
+		rest := strings.TrimRight(input[len(prefix):], "?! ")
+		for _, suffix := range []string{" do", " mean", " doing"} {
+			if strings.HasSuffix(strings.ToLower(rest), suffix) {
+				cmd := unquote(rest[:len(rest)-len(suffix)])
+				// Only when it starts with a program we know; "what does my phone do" is a question
+				if fields := strings.Fields(cmd); len(fields) > 0 {
+					if _, ok := programs[filepath.Base(fields[0])]; ok {
+						return cmd, true
+					}
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+func unquote(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && (s[0] == '"' && s[len(s)-1] == '"' || s[0] == '\'' && s[len(s)-1] == '\'') {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,155 @@
+package explain
+
+import (
+	"strings"
+	"testing"
+)
+
+func init() {
+	// Never shell out to man from tests
+	manOptions = func(name string) map[string]string {
+		if name == "tail" {
+			return map[string]string{"--retry": "keep trying to open a file"}
+		}
+		return nil
+	}
+}
+
+func parts(x *Explanation) []string {
+	var out []string
+	for _, p := range x.Parts {
+		out = append(out, p.Text)
+	}
+	return out
+}
+
+func TestExplainBundledFlags(t *testing.T) {
+	x, err := Explain("tar -xzvf file.tar.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"tar", "-x", "-z", "-v", "-f", "file.tar.gz"}
+	if got := parts(x); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf("parts = %q, want %q", got, want)
+	}
+	if last := x.Parts[5]; last.Kind != KindOperand || !strings.Contains(last.Desc, "-f") {
+		t.Errorf("file.tar.gz = %+v, want the value of -f", last)
+	}
+}
+
+func TestExplainPipesAndRedirects(t *testing.T) {
+	x, err := Explain("ps aux | grep -in ssh > out.txt 2>&1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kinds := map[string]Kind{}
+	for _, p := range x.Parts {
+		kinds[p.Text] = p.Kind
+	}
+	cases := map[string]Kind{
+		"ps": KindProgram, "a": KindFlag, "x": KindFlag, "|": KindOperator,
+		"grep": KindProgram, "-i": KindFlag, "-n": KindFlag, "ssh": KindOperand,
+		"> out.txt": KindRedirect, "2>&1": KindRedirect,
+	}
+	for text, kind := range cases {
+		if kinds[text] != kind {
+			t.Errorf("%q kind = %q, want %q (parts %q)", text, kinds[text], kind, parts(x))
+		}
+	}
+}
+
+func TestExplainFindExec(t *testing.T) {
+	cases := []struct {
+		cmd, program, term string
+	}{
+		{"find . -name '*.log' -exec rm {} +", "rm", "+"},
+		{`find . -type f -exec chmod 644 {} \; -print`, "chmod", ";"},
+	}
+	for _, c := range cases {
+		x, err := Explain(c.cmd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		byText := map[string]Part{}
+		for _, p := range x.Parts {
+			if strings.Contains(p.Desc, "directory to search") && p.Text != "." {
+				t.Errorf("%s: %q described as find's directory", c.cmd, p.Text)
+			}
+			byText[p.Text] = p
+		}
+		if p := byText[c.program]; p.Kind != KindProgram {
+			t.Errorf("%s: %s = %+v, want a program", c.cmd, c.program, p)
+		}
+		if p := byText["{}"]; !strings.Contains(p.Desc, "path find matched") {
+			t.Errorf("%s: {} = %+v, want the matched path", c.cmd, p)
+		}
+		if p := byText[c.term]; !strings.HasPrefix(p.Desc, "ends -exec") {
+			t.Errorf("%s: %s = %+v, want the end of -exec", c.cmd, c.term, p)
+		}
+	}
+	x, _ := Explain(`find . -type f -exec chmod 644 {} \; -print`)
+	if p := x.Parts[6]; p.Text != "644" || p.Kind != KindOperand || !strings.Contains(p.Desc, "mode") {
+		t.Errorf("644 = %+v, want chmod's mode", p)
+	}
+	if p := x.Parts[len(x.Parts)-1]; p.Text != "-print" || p.Kind != KindFlag {
+		t.Errorf("-print after ; = %+v, want find's flag again", p)
+	}
+}
+
+func TestExplainDangerousFlags(t *testing.T) {
+	x, err := Explain("sudo rm -rf build")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x.Risk != "destructive" {
+		t.Errorf("risk = %q, want destructive", x.Risk)
+	}
+	var flagged []string
+	for _, p := range x.Parts {
+		if p.Danger != "" {
+			flagged = append(flagged, p.Text)
+		}
+	}
+	if strings.Join(flagged, " ") != "-r -f" {
+		t.Errorf("dangerous parts = %q, want -r -f", flagged)
+	}
+	if x.Parts[1].Kind != KindProgram {
+		t.Errorf("rm after sudo should be a program, got %+v", x.Parts[1])
+	}
+}
+
+func TestExplainValuesAndManFallback(t *testing.T) {
+	x, err := Explain("tail -n20 --retry app.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := x.Parts[1]; got.Text != "-n" || !strings.Contains(got.Desc, "20") {
+		t.Errorf("-n20 = %+v", got)
+	}
+	if got := x.Parts[2]; !strings.Contains(got.Desc, "(man page)") {
+		t.Errorf("--retry = %+v, want man page description", got)
+	}
+	x, _ = Explain("find . -newer ref")
+	if got := x.Parts[2]; got.Text != "-newer" || got.Desc != "unknown option" {
+		t.Errorf("-newer = %+v, want one unknown option", got)
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	cases := []struct {
+		input, want string
+		ok          bool
+	}{
+		{"explain tar -xzvf a.tgz", "tar -xzvf a.tgz", true},
+		{"what does `rm -rf ~` do", "rm -rf ~", true},
+		{"what does chmod -R 755 . do?", "chmod -R 755 .", true},
+		{"what does my phone do", "", false},
+		{"list files", "", false},
+	}
+	for _, c := range cases {
+		got, ok := ParseQuery(c.input)
+		if got != c.want || ok != c.ok {
+			t.Errorf("ParseQuery(%q) = %q, %v; want %q, %v", c.input, got, ok, c.want, c.ok)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package explain
+
+// Program is what the offline database knows about one command.
+type Program struct {
+	Summary string
+	Flags   map[string]Flag
+	// Operand describes arguments that are not flags, e.g. "file to delete".
+	Operand string
+	// Bundled is set for tar and ps, whose first argument may be flag letters
+	// without a dash ("tar xzvf", "ps aux").
+	Bundled bool
+	// Wrapper programs run their first operand as another command (sudo, xargs).
+	Wrapper bool
+}
+
+// Flag describes one option. Arg names its value when the flag takes one
+// (the next word, or the rest of a bundle such as -ffile.tar).
+type Flag struct {
+	Desc   string
+	Arg    string
+	Danger string // why the flag is risky; empty for safe flags
+	// Command is set for find's -exec: the words up to ; or + are a command
+	// of their own, with {} standing for the matched paths.
+	Command bool
+}
+
+// programs is the offline flag database, seeded from the examples the REPL
+// shows for each command.
+var programs = map[string]Program{
+	"ls": {Summary: "list directory contents", Operand: "file or directory to list", Flags: map[string]Flag{
+		"-l": {Desc: "long format: permissions, owner, size, date"},
+		"-a": {Desc: "include hidden files (names starting with .)"},
+		"-A": {Desc: "include hidden files except . and .."},
+		"-h": {Desc: "human-readable sizes (K, M, G) with -l"},
+		"-t": {Desc: "sort by modification time, newest first"},
+		"-r": {Desc: "reverse the sort order"},
+		"-R": {Desc: "list subdirectories recursively"},
+		"-S": {Desc: "sort by size, largest first"},
+		"-d": {Desc: "list directories themselves, not their contents"},
+		"-F": {Desc: "mark types: / for directories, * for executables"},
+		"-1": {Desc: "one entry per line"},
+	}},
+	"find": {Summary: "search for files in a directory tree", Operand: "directory to search (default .)", Flags: map[string]Flag{
+		"-name":     {Desc: "match file names against a pattern", Arg: "pattern"},
+		"-iname":    {Desc: "like -name, ignoring case", Arg: "pattern"},
+		"-type":     {Desc: "only this type: f file, d directory, l symlink", Arg: "type"},
+		"-size":     {Desc: "size test: +100M larger than, -1k smaller than", Arg: "size"},
+		"-mtime":    {Desc: "modified days ago: -7 within a week, +30 older than", Arg: "days"},
+		"-maxdepth": {Desc: "descend at most this many levels", Arg: "levels"},
+		"-exec":     {Desc: "run a command for each match ({} is the file, ends at ; or +)", Command: true},
+		"-execdir":  {Desc: "like -exec, run from the directory of each match", Command: true},
+		"-ok":       {Desc: "like -exec, asking before each run", Command: true},
+		"-delete":   {Desc: "delete every match", Danger: "deletes matching files without asking"},
+		"-print":    {Desc: "print each match (the default)"},
+		"-empty":    {Desc: "only empty files and directories"},
+	}},
+	"grep": {Summary: "search text for lines matching a pattern", Operand: "pattern, then files to search", Flags: map[string]Flag{
+		"-r": {Desc: "search directories recursively"},
+		"-R": {Desc: "search recursively, following symlinks"},
+		"-i": {Desc: "ignore case"},
+		"-n": {Desc: "show line numbers"},
+		"-v": {Desc: "invert: show lines that do not match"},
+		"-l": {Desc: "only print names of matching files"},
+		"-c": {Desc: "count matching lines"},
+		"-w": {Desc: "match whole words only"},
+		"-E": {Desc: "extended regular expressions (|, +, ?)"},
+		"-o": {Desc: "print only the matched part"},
+	}},
+	"tar": {Summary: "create, list or extract tar archives", Operand: "files to add or extract", Bundled: true, Flags: map[string]Flag{
+		"-x": {Desc: "extract files from an archive"},
+		"-c": {Desc: "create a new archive"},
+		"-t": {Desc: "list the archive's contents"},
+		"-z": {Desc: "filter through gzip (.tar.gz / .tgz)"},
+		"-j": {Desc: "filter through bzip2 (.tar.bz2)"},
+		"-J": {Desc: "filter through xz (.tar.xz)"},
+		"-v": {Desc: "verbose: list files as they are processed"},
+		"-f": {Desc: "use this archive file", Arg: "archive"},
+		"-C": {Desc: "change to this directory first", Arg: "directory"},
+	}},
+	"chmod": {Summary: "change file permissions", Operand: "mode (755, +x, u+w), then files", Flags: map[string]Flag{
+		"-R": {Desc: "apply to directories recursively", Danger: "changes every file below the directory"},
+		"-v": {Desc: "report each file processed"},
+	}},
+	"chown": {Summary: "change file owner and group", Operand: "user[:group], then files", Flags: map[string]Flag{
+		"-R": {Desc: "apply to directories recursively", Danger: "changes every file below the directory"},
+	}},
+	"cp": {Summary: "copy files and directories", Operand: "source(s), then destination", Flags: map[string]Flag{
+		"-r": {Desc: "copy directories recursively"},
+		"-R": {Desc: "copy directories recursively"},
+		"-i": {Desc: "ask before overwriting"},
+		"-n": {Desc: "never overwrite existing files"},
+		"-f": {Desc: "force: overwrite without asking", Danger: "overwrites existing files silently"},
+		"-v": {Desc: "show each file copied"},
+		"-p": {Desc: "keep permissions and timestamps"},
+		"-a": {Desc: "archive: recursive, keeping links and attributes"},
+	}},
+	"mv": {Summary: "move or rename files", Operand: "source(s), then destination", Flags: map[string]Flag{
+		"-i": {Desc: "ask before overwriting"},
+		"-n": {Desc: "never overwrite existing files"},
+		"-f": {Desc: "force: overwrite without asking", Danger: "overwrites existing files silently"},
+		"-v": {Desc: "show each file moved"},
+	}},
+	"rm": {Summary: "delete files (there is no trash)", Operand: "file to delete", Flags: map[string]Flag{
+		"-r": {Desc: "delete directories and everything in them", Danger: "recursive delete"},
+		"-R": {Desc: "delete directories and everything in them", Danger: "recursive delete"},
+		"-f": {Desc: "force: never ask, ignore missing files", Danger: "no confirmation before deleting"},
+		"-i": {Desc: "ask before every delete"},
+		"-v": {Desc: "show each file deleted"},
+	}},
+	"df": {Summary: "show free disk space per filesystem", Operand: "file or mount point to report on", Flags: map[string]Flag{
+		"-h": {Desc: "human-readable sizes (K, M, G)"},
+		"-T": {Desc: "show filesystem type"},
+		"-i": {Desc: "show inode usage instead of blocks"},
+	}},
+	"du": {Summary: "show disk usage of files and directories", Operand: "file or directory to measure", Flags: map[string]Flag{
+		"-h": {Desc: "human-readable sizes"},
+		"-s": {Desc: "summary: one total per argument"},
+		"-a": {Desc: "include files, not just directories"},
+		"-c": {Desc: "print a grand total"},
+	}},
+	"free": {Summary: "show memory usage", Flags: map[string]Flag{
+		"-h": {Desc: "human-readable sizes"},
+		"-m": {Desc: "sizes in MiB"},
+		"-s": {Desc: "repeat every N seconds", Arg: "seconds"},
+	}},
+	"ps": {Summary: "list running processes", Bundled: true, Flags: map[string]Flag{
+		"-a": {Desc: "processes of all users (with u and x: everything)"},
+		"-u": {Desc: "user-oriented format: owner, CPU and memory"},
+		"-x": {Desc: "include processes without a terminal"},
+		"-e": {Desc: "every process"},
+		"-f": {Desc: "full format listing"},
+	}},
+	"tail": {Summary: "print the last lines of a file", Operand: "file to read", Flags: map[string]Flag{
+		"-n": {Desc: "number of lines to show", Arg: "lines"},
+		"-f": {Desc: "follow: keep printing as the file grows (Ctrl+C to stop)"},
+	}},
+	"head": {Summary: "print the first lines of a file", Operand: "file to read", Flags: map[string]Flag{
+		"-n": {Desc: "number of lines to show", Arg: "lines"},
+		"-c": {Desc: "number of bytes to show", Arg: "bytes"},
+	}},
+	"cat":   {Summary: "print files to the terminal", Operand: "file to print", Flags: map[string]Flag{"-n": {Desc: "number every line"}}},
+	"wc":    {Summary: "count lines, words and bytes", Operand: "file to count", Flags: map[string]Flag{"-l": {Desc: "count lines"}, "-w": {Desc: "count words"}, "-c": {Desc: "count bytes"}}},
+	"mkdir": {Summary: "create directories", Operand: "directory to create", Flags: map[string]Flag{"-p": {Desc: "create parent directories as needed, no error if it exists"}, "-v": {Desc: "report each directory created"}}},
+	"wget": {Summary: "download files from the web", Operand: "URL to download", Flags: map[string]Flag{
+		"-c": {Desc: "continue a partial download"},
+		"-O": {Desc: "save to this file name", Arg: "file"},
+		"-q": {Desc: "quiet: no progress output"},
+		"-r": {Desc: "download recursively (whole site section)"},
+	}},
+	"curl": {Summary: "transfer data from or to a URL", Operand: "URL", Flags: map[string]Flag{
+		"-O": {Desc: "save with the remote file name"},
+		"-o": {Desc: "save to this file", Arg: "file"},
+		"-L": {Desc: "follow redirects"},
+		"-s": {Desc: "silent: no progress bar"},
+		"-S": {Desc: "show errors even with -s"},
+		"-f": {Desc: "fail on HTTP errors instead of printing the error page"},
+		"-I": {Desc: "fetch headers only"},
+		"-X": {Desc: "HTTP method to use", Arg: "method"},
+		"-d": {Desc: "send this data in the request body", Arg: "data"},
+		"-H": {Desc: "add a request header", Arg: "header"},
+		"-k": {Desc: "skip TLS certificate checks", Danger: "accepts forged certificates"},
+	}},
+	"sed": {Summary: "stream editor: transform text", Operand: "script (e.g. s/old/new/g), then files", Flags: map[string]Flag{
+		"-i": {Desc: "edit files in place", Danger: "rewrites the files; keep a backup"},
+		"-n": {Desc: "print only lines the script prints explicitly"},
+		"-e": {Desc: "add this script", Arg: "script"},
+		"-E": {Desc: "extended regular expressions"},
+	}},
+	"awk": {Summary: "pattern scanning and text processing", Operand: "program (e.g. '{print $1}'), then files", Flags: map[string]Flag{
+		"-F": {Desc: "field separator", Arg: "separator"},
+		"-v": {Desc: "set a variable before running", Arg: "var=value"},
+	}},
+	"kill": {Summary: "send a signal to a process", Operand: "process ID", Flags: map[string]Flag{
+		"-9":    {Desc: "SIGKILL: stop immediately", Danger: "the process cannot save work or clean up"},
+		"-KILL": {Desc: "SIGKILL: stop immediately", Danger: "the process cannot save work or clean up"},
+		"-15":   {Desc: "SIGTERM: ask the process to exit (the default)"},
+		"-l":    {Desc: "list signal names"},
+	}},
+	"unzip": {Summary: "extract .zip archives", Operand: "archive to extract", Flags: map[string]Flag{
+		"-d": {Desc: "extract into this directory", Arg: "directory"},
+		"-l": {Desc: "list contents without extracting"},
+		"-o": {Desc: "overwrite files without asking", Danger: "overwrites existing files silently"},
+	}},
+	"zip": {Summary: "create .zip archives", Operand: "archive name, then files", Flags: map[string]Flag{
+		"-r": {Desc: "include directories recursively"},
+	}},
+	"ping": {Summary: "check whether a host is reachable", Operand: "host name or IP", Flags: map[string]Flag{
+		"-c": {Desc: "stop after this many packets", Arg: "count"},
+	}},
+	"git": {Summary: "version control", Operand: "subcommand and its arguments", Flags: map[string]Flag{
+		"--force":            {Desc: "force the operation", Danger: "can overwrite remote history or local changes"},
+		"-f":                 {Desc: "force the operation", Danger: "can overwrite remote history or local changes"},
+		"--hard":             {Desc: "reset the working tree too", Danger: "discards uncommitted changes"},
+		"--global":           {Desc: "apply to all repositories of this user"},
+		"-m":                 {Desc: "commit or tag message", Arg: "message"},
+		"-b":                 {Desc: "create a new branch", Arg: "branch"},
+		"--force-with-lease": {Desc: "force push only if the remote has not moved"},
+	}},
+	"dd": {Summary: "copy and convert raw data (disks, images)", Operand: "if=input of=output bs=size", Flags: map[string]Flag{}},
+	"ssh-keygen": {Summary: "create SSH keys", Flags: map[string]Flag{
+		"-t": {Desc: "key type, e.g. ed25519", Arg: "type"},
+		"-C": {Desc: "comment, usually your email", Arg: "comment"},
+		"-f": {Desc: "key file to write", Arg: "file"},
+	}},
+	"scp": {Summary: "copy files over SSH", Operand: "source, then destination (host:path)", Flags: map[string]Flag{
+		"-r": {Desc: "copy directories recursively"},
+		"-P": {Desc: "SSH port", Arg: "port"},
+	}},
+	"sudo":  {Summary: "run the rest of the line as root", Wrapper: true, Flags: map[string]Flag{"-u": {Desc: "run as this user instead of root", Arg: "user"}}},
+	"top":   {Summary: "live view of processes (q quits)"},
+	"htop":  {Summary: "interactive process viewer (q quits)"},
+	"pwd":   {Summary: "print the current directory"},
+	"echo":  {Summary: "print its arguments", Operand: "text to print", Flags: map[string]Flag{"-n": {Desc: "no trailing newline"}, "-e": {Desc: "interpret backslash escapes"}}},
+	"sort":  {Summary: "sort lines", Operand: "file to sort", Flags: map[string]Flag{"-n": {Desc: "numeric sort"}, "-r": {Desc: "reverse order"}, "-h": {Desc: "human-readable numbers (2K, 1G)"}, "-u": {Desc: "drop duplicate lines"}}},
+	"touch": {Summary: "create empty files or update timestamps", Operand: "file to touch"},
+	"nohup": {Summary: "keep running after the terminal closes", Wrapper: true},
+	"time":  {Summary: "report how long the command takes", Wrapper: true},
+	"xargs": {Summary: "run a command with arguments read from input", Wrapper: true, Flags: map[string]Flag{"-0": {Desc: "input is NUL-separated (pairs with find -print0)"}, "-n": {Desc: "at most this many arguments per run", Arg: "count"}}},
+}
+
+// operators explains shell control and redirection operators.
+var operators = map[string]string{
+	"|":  "pipe: send the output of the left command into the right one",
+	"&&": "run the next command only if the previous one succeeded",
+	"||": "run the next command only if the previous one failed",
+	";":  "then run the next command",
+	"&":  "run the previous command in the background",
+	">":  "write output to a file, replacing its contents",
+	">>": "append output to a file",
+	"<":  "read input from a file",
+	"2>": "write error messages to a file",
+	"&>": "write output and errors to a file",
+	">&": "duplicate an output stream (e.g. 2>&1 sends errors with normal output)",
+}
@@ -0,0 +1,258 @@
+package intent
+
+import (
+	"clio/internal/layer1"
+	"clio/internal/layer2"
+	"clio/internal/layer3"
+	"clio/internal/platform"
+	"clio/internal/project"
+	"clio/internal/tldr"
+	"fmt"
+	"sort"
+)
+
+// AmbiguityMargin is how close the top two confidences must be before the
+// REPL offers a "did you mean" choice instead of picking one.
+const AmbiguityMargin = 0.05
+
+// userConfidence is given to rules from commands.yaml: the user wrote them to
+// override what clio would otherwise suggest, project and composed commands
+// included. The rule score breaks the tie with a composed command.
+const userConfidence = 1.0
+
+// fastPathConfidence is how sure the built-in rules must be before tldr pages,
+// man pages and modules are left out; none of those can outrank such a match.
+const fastPathConfidence = 0.9
+
+// DetectAll merges candidates from every layer into one list ranked by
+// confidence (0–1). Each result carries Reasons explaining its score.
+// limit <= 0 returns every candidate. The built-in rules are tried first;
+// tldr pages, man pages and modules are searched too unless a rule matched
+// with at least fastPathConfidence and history holds no real preference.
+// Remote search runs only when nothing local matches.
+func DetectAll(input string, limit int) ([]*DetectionResult, error) {
+	return detectAll(input, limit, learnedPreferences(input))
+}
+
+// detectAll is DetectAll with the learned preferences already looked up.
+func detectAll(input string, limit int, prefs preferences) ([]*DetectionResult, error) {
+	// Setup wizards are exact intents; there is nothing to rank against them.
+	if res, ok := detectSetup(input); ok {
+		res.Reasons = []string{"setup wizard alias or phrase"}
+		return []*DetectionResult{res}, nil
+	}
+
+	var all []*DetectionResult
+	if res, ok := detectCompose(input); ok {
+		res.Reasons = []string{fmt.Sprintf("composed %d stages from the query's clauses", len(res.Stages))}
+		all = append(all, res)
+	}
+	all = append(all, staticCandidates(input)...)
+
+	keywords := IsolateKeywords(input)
+	if len(keywords) > 0 && !hermetic && (best(all) < fastPathConfidence || prefs.decisive()) {
+		all = append(all, tldrCandidates(input)...)
+		all = append(all, manCandidates(keywords)...)
+		all = append(all, moduleCandidates(keywords)...)
+	}
+
+	if len(all) == 0 {
+		res, err := tryRemote(input)
+		if err != nil {
+			return nil, err
+		}
+		res.Reasons = []string{"remote search"}
+		return []*DetectionResult{res}, nil
+	}
+
+	ranked := applyLearning(input, mergeCandidates(all), prefs)
+	if limit > 0 && len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	for _, res := range ranked {
+		checkInstalled(res)
+	}
+	return ranked, nil
+}
+
+// best returns the highest confidence among cands, or 0.
+func best(cands []*DetectionResult) float64 {
+	top := 0.0
+	for _, c := range cands {
+		if c.Confidence > top {
+			top = c.Confidence
+		}
+	}
+	return top
+}
+
+// IsAmbiguous reports whether the top two candidates are too close to call.
+func IsAmbiguous(cands []*DetectionResult) bool {
+	return len(cands) >= 2 && cands[0].Confidence-cands[1].Confidence <= AmbiguityMargin
+}
+
+func staticCandidates(input string) []*DetectionResult {
+	var out []*DetectionResult
+
+	chores := layer1.RankProject(input, project.Current())
+	for i, s := range chores {
+		out = append(out, scoredResult(input, s, i, "project", 0.99*relative(s.Score, chores[0].Score)))
+	}
+
+	phrases := layer1.RankPhrases(input)
+	for i, s := range phrases {
+		out = append(out, scoredResult(input, s, i, "static", userOr(s, 0.98*relative(s.Score, phrases[0].Score))))
+	}
+
+	for i, s := range layer1.RankCatalog(input) {
+		out = append(out, scoredResult(input, s, i, "static", userOr(s, 0.95*clamp(float64(s.Score)/20))))
+	}
+
+	// The bare verb-noun pair ranks below the rules above, which match more
+	// of the query ("large files" rather than just "find files")
+	verb, noun := layer1.ParseIntent(input)
+	if entry, ok := layer1.LookupVerbNoun(verb, noun); ok {
+		res := staticResult(input, entry, "static", 0.94)
+		res.Reasons = []string{fmt.Sprintf("exact verb %q + noun %q", verb, noun)}
+		out = append(out, res)
+	}
+
+	if len(out) == 0 {
+		fuzzy := layer1.RankFuzzy(input)
+		for i, s := range fuzzy {
+			out = append(out, scoredResult(input, s, i, "fuzzy", 0.9*relative(s.Score, fuzzy[0].Score)))
+		}
+	}
+	return out
+}
+
+func tldrCandidates(input string) []*DetectionResult {
+	results, err := tldr.Search(input, platform.Current())
+	if err != nil {
+		return nil
+	}
+	var out []*DetectionResult
+	for _, r := range results {
+		if !tldrConfident(r) {
+			continue
+		}
+		res := tldrResult(input, r, 0.82*relative(r.Score, results[0].Score))
+		res.Reasons = []string{fmt.Sprintf("tldr %s: %d of %d words matched (score %d)", r.Name, r.Hits, r.Terms, r.Score)}
+		out = append(out, res)
+	}
+	return out
+}
+
+func manCandidates(keywords []string) []*DetectionResult {
+	results := layer2.Search(keywords)
+	out := make([]*DetectionResult, 0, len(results))
+	for _, r := range results {
+		if r.Score <= 10 {
+			continue
+		}
+		from := "man"
+		if r.Section == layer3.HelpSection {
+			from = "--help"
+		}
+		out = append(out, &DetectionResult{
+			Command:     r.Name,
+			Description: r.Description,
+			Source:      "man",
+			Confidence:  0.8 * relative(r.Score, results[0].Score),
+			Reasons:     []string{fmt.Sprintf("%s: %d of %d keywords in name or summary (score %d)", from, r.Hits, len(keywords), r.Score)},
+		})
+	}
+	return out
+}
+
+func moduleCandidates(keywords []string) []*DetectionResult {
+	mods, err := layer3.SearchModules(keywords)
+	if err != nil {
+		return nil
+	}
+	out := make([]*DetectionResult, 0, len(mods))
+	for _, m := range mods {
+		if m.Score < minModuleScore {
+			continue
+		}
+		out = append(out, &DetectionResult{
+			Command:     m.Command,
+			Description: m.Description,
+			Source:      "module",
+			Confidence:  moduleConfidence(m.Score),
+			Reasons:     []string{fmt.Sprintf("module: relevance %.2f (tags: %s)", m.Score, m.Keywords)},
+		})
+	}
+	return out
+}
+
+// userOr returns userConfidence for a commands.yaml rule and confidence for
+// a built-in one.
+func userOr(s layer1.Scored, confidence float64) float64 {
+	if s.User {
+		return userConfidence
+	}
+	return confidence
+}
+
+// scoredResult turns the rank-th match of a layer-1 ranking into a candidate.
+func scoredResult(input string, s layer1.Scored, rank int, source string, confidence float64) *DetectionResult {
+	res := staticResult(input, s.Entry, source, confidence)
+	res.Reasons = []string{s.Reason}
+	res.ruleScore, res.ruleRank = s.Score, rank
+	return res
+}
+
+// mergeCandidates collapses duplicate commands, keeping the highest confidence
+// and the union of reasons, then sorts best first. Equal confidences go to the
+// higher rule score, then to the rule its layer ranked first (catalog order
+// among equal scores), then to the command in alphabetical order, so the order
+// never depends on which layer answered first.
+func mergeCandidates(all []*DetectionResult) []*DetectionResult {
+	byCmd := make(map[string]*DetectionResult, len(all))
+	var order []*DetectionResult
+	for _, c := range all {
+		existing, ok := byCmd[c.Command]
+		if !ok {
+			byCmd[c.Command] = c
+			order = append(order, c)
+			continue
+		}
+		reasons := append(append([]string{}, existing.Reasons...), c.Reasons...)
+		if c.Confidence > existing.Confidence {
+			*existing = *c
+		}
+		existing.Reasons = reasons
+	}
+	sort.Slice(order, func(i, j int) bool {
+		a, b := order[i], order[j]
+		if a.Confidence != b.Confidence {
+			return a.Confidence > b.Confidence
+		}
+		if a.ruleScore != b.ruleScore {
+			return a.ruleScore > b.ruleScore
+		}
+		if a.ruleRank != b.ruleRank {
+			return a.ruleRank < b.ruleRank
+		}
+		return a.Command < b.Command
+	})
+	return order
+}
+
+func relative(score, top int) float64 {
+	if top <= 0 {
+		return 0
+	}
+	return clamp(float64(score) / float64(top))
+}
+
+func clamp(f float64) float64 {
+	if f < 0 {
+		return 0
+	}
+	if f > 1 {
+		return 1
+	}
+	return f
+}
@@ -0,0 +1,83 @@
+package intent
+
+import "testing"
+
+func TestDetectAllRanksAndExplains(t *testing.T) {
+	cands, err := DetectAll("show me the files here", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cands) < 2 {
+		t.Fatalf("expected several candidates, got %d", len(cands))
+	}
+	seen := make(map[string]bool)
+	for i, c := range cands {
+		if c.Confidence < 0 || c.Confidence > 1 {
+			t.Errorf("candidate %q confidence %v out of range", c.Command, c.Confidence)
+		}
+		if i > 0 && c.Confidence > cands[i-1].Confidence {
+			t.Errorf("candidates not sorted: %v after %v", c.Confidence, cands[i-1].Confidence)
+		}
+		if len(c.Reasons) == 0 {
+			t.Errorf("candidate %q has no reasons", c.Command)
+		}
+		if seen[c.Command] {
+			t.Errorf("duplicate candidate %q", c.Command)
+		}
+		seen[c.Command] = true
+	}
+	if !containsStr(cands[0].Command, "ls") {
+		t.Errorf("top candidate = %q, want ls", cands[0].Command)
+	}
+}
+
+func TestDetectAllSetupIsSingle(t *testing.T) {
+	cands, err := DetectAll("setup vim", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cands) != 1 || cands[0].Source != "setup" {
+		t.Fatalf("DetectAll(setup vim) = %+v", cands)
+	}
+}
+
+func TestMergeCandidatesKeepsBestAndReasons(t *testing.T) {
+	merged := mergeCandidates([]*DetectionResult{
+		{Command: "ls -la", Confidence: 0.6, Reasons: []string{"a"}},
+		{Command: "df -h", Confidence: 0.7, Reasons: []string{"b"}},
+		{Command: "ls -la", Confidence: 0.9, Reasons: []string{"c"}},
+	})
+	if len(merged) != 2 || merged[0].Command != "ls -la" || merged[0].Confidence != 0.9 {
+		t.Fatalf("merge = %+v", merged)
+	}
+	if len(merged[0].Reasons) != 2 {
+		t.Fatalf("reasons = %v, want both", merged[0].Reasons)
+	}
+	if !IsAmbiguous([]*DetectionResult{{Confidence: 0.9}, {Confidence: 0.88}}) {
+		t.Fatal("0.90 vs 0.88 should be ambiguous")
+	}
+}
+
+func TestMergeCandidatesBreaksTies(t *testing.T) {
+	cands := []*DetectionResult{
+		{Command: "wget {url}", Confidence: 0.98, ruleScore: 30, ruleRank: 1},
+		{Command: "man wget", Confidence: 0.98},
+		{Command: "cp {file} {dir}", Confidence: 0.98, ruleScore: 30},
+		{Command: "curl -O {url}", Confidence: 0.98},
+		{Command: "scp {file} {host}:~/", Confidence: 0.98, ruleScore: 40, ruleRank: 2},
+	}
+	want := []string{"scp {file} {host}:~/", "cp {file} {dir}", "wget {url}", "curl -O {url}", "man wget"}
+	for _, order := range [][]int{{0, 1, 2, 3, 4}, {4, 3, 2, 1, 0}, {1, 3, 0, 4, 2}} {
+		in := make([]*DetectionResult, len(order))
+		for i, j := range order {
+			c := *cands[j]
+			in[i] = &c
+		}
+		merged := mergeCandidates(in)
+		for i, c := range merged {
+			if c.Command != want[i] {
+				t.Errorf("order %v: merged[%d] = %q, want %q", order, i, c.Command, want[i])
+			}
+		}
+	}
+}
@@ -3,10 +3,10 @@ package intent
 import (
 	"clio/internal/config"
 	"clio/internal/layer1"
-	"clio/internal/layer2"
-	"clio/internal/layer3"
 	"clio/internal/layer4"
+	"clio/internal/project"
 	"clio/internal/setup"
+	"clio/internal/tldr"
 	"fmt"
 	"strings"
 )
@@ -14,94 +14,128 @@ import (
 type DetectionResult struct {
 	Command     string
 	Description string
-	Source      string // "setup", "static", "fuzzy", "man", "module", "remote", "remote-cached"
+	Source      string // "setup", "compose", "project", "static", "fuzzy", "tldr", "man", "module", "remote", "remote-cached"
 	Confidence  float64
+	// Missing lists command placeholders the query did not supply (see layer1.Slot).
+	Missing []layer1.Slot
+	// Reasons explains how the candidate scored (filled by DetectAll).
+	Reasons []string
+	// Template is the catalog command before its placeholders were filled.
+	Template string
+	// NotInstalled is the binary Command needs that is not on PATH; Install
+	// installs it with the detected package manager and Alternative is an
+	// installed command that does the same job. All empty when nothing is missing.
+	NotInstalled string
+	Install      string
+	Alternative  string
+	// Stages explains each step of a command composed from a compound query.
+	Stages []layer1.PipelineStage
+	// Project labels the project context (see project.Context.Label) when
+	// Command was chosen for it, e.g. "go test ./..." for "run tests".
+	Project string
+
+	// ruleScore and ruleRank are the layer-1 rule score and the rule's place
+	// in its layer's ranking; they break confidence ties.
+	ruleScore, ruleRank int
 }
 
-// Detect determines the best command for natural-language input.
-func Detect(input string) (*DetectionResult, error) {
-	// Layer 0: setup wizards (Termux, Vim, Git, dev tools, databases)
-	if kind, wizard := setup.ResolveSetup(input); kind != setup.MatchNone {
-		if kind == setup.MatchWizard && wizard != nil {
-			return &DetectionResult{
-				Command:     setup.RunCommandFor(*wizard),
-				Description: wizard.Description,
-				Source:      "setup",
-				Confidence:  1.0,
-			}, nil
-		}
-		return &DetectionResult{
-			Command:     "setup",
-			Description: setup.ShortDescription(),
-			Source:      "setup-menu",
-			Confidence:  1.0,
-		}, nil
+// Key identifies the suggestion independent of the arguments filled into it,
+// so history about "tar -xzvf a.tar.gz" also applies to "tar -xzvf b.tar.gz".
+func (r *DetectionResult) Key() string {
+	if r.Template != "" {
+		return r.Template
 	}
+	return r.Command
+}
 
-	if result, ok := detectStatic(input); ok {
-		return result, nil
-	}
+// hermetic limits detection to what is built into clio (see SetHermetic).
+var hermetic bool
 
-	keywords := IsolateKeywords(input)
-	if len(keywords) == 0 {
-		return tryRemote(input)
-	}
+// SetHermetic makes Detect and DetectAll ignore query history, man pages,
+// cached modules and remote search, so a query gets the same answer on any
+// machine. clio eval runs this way.
+func SetHermetic(on bool) {
+	hermetic = on
+}
 
-	// Layer 2: man pages — skipped on lite profile (subprocess heavy)
-	if !config.IsLiteProfile() {
-		manResults := layer2.Search(keywords)
-		if len(manResults) > 0 && manResults[0].Score > 10 {
-			top := manResults[0]
-			return &DetectionResult{
-				Command:     top.Name,
-				Description: top.Description,
-				Source:      "man",
-				Confidence:  0.8,
-			}, nil
-		}
+// Detect determines the best command for natural-language input: the top
+// candidate of DetectAll, so both always agree.
+func Detect(input string) (*DetectionResult, error) {
+	cands, err := DetectAll(input, 1)
+	if err != nil {
+		return nil, err
 	}
+	return cands[0], nil
+}
 
-	// Layer 3: local automation modules
-	modules, err := layer3.SearchModules(keywords)
-	if err == nil && len(modules) > 0 {
-		top := modules[0]
+func detectSetup(input string) (*DetectionResult, bool) {
+	kind, wizard := setup.ResolveSetup(input)
+	switch {
+	case kind == setup.MatchNone:
+		return nil, false
+	case kind == setup.MatchWizard && wizard != nil:
 		return &DetectionResult{
-			Command:     top.Command,
-			Description: top.Description,
-			Source:      "module",
-			Confidence:  0.85,
-		}, nil
+			Command:     setup.RunCommandFor(*wizard),
+			Description: wizard.Description,
+			Source:      "setup",
+			Confidence:  1.0,
+		}, true
 	}
-
-	return tryRemote(input)
+	return &DetectionResult{
+		Command:     "setup",
+		Description: setup.ShortDescription(),
+		Source:      "setup-menu",
+		Confidence:  1.0,
+	}, true
 }
 
-func detectStatic(input string) (*DetectionResult, bool) {
-	if entry, ok := layer1.MatchPhrase(input); ok {
-		return staticResult(entry, "static", 0.98), true
-	}
-	if entry, ok := layer1.MatchCatalog(input); ok {
-		return staticResult(entry, "static", 0.95), true
-	}
-	verb, noun := layer1.ParseIntent(input)
-	if entry, ok := layer1.LookupVerbNoun(verb, noun); ok {
-		return staticResult(entry, "static", 1.0), true
+// detectCompose joins the commands for each clause of a compound query.
+func detectCompose(input string) (*DetectionResult, bool) {
+	p, ok := layer1.Compose(input)
+	if !ok {
+		return nil, false
 	}
+	return &DetectionResult{
+		Command:     p.Command,
+		Description: p.Describe(),
+		Source:      "compose",
+		Confidence:  1.0, // covers every clause, where other matches cover one
+		Missing:     p.Missing,
+		Stages:      p.Stages,
+	}, true
+}
 
-	// Fuzzy + slang expansion — conservative retry before network
-	if entry, kind, ok := layer1.MatchWithFuzzy(input); ok {
-		conf := 0.88
-		if kind == "phrase" {
-			conf = 0.92
-		}
-		return staticResult(entry, "fuzzy", conf), true
-	}
+// minModuleScore is the share of keywords a module must match, tags
+// counting extra, to be offered at all.
+const minModuleScore = 0.5
 
-	return nil, false
+// moduleConfidence maps a SearchModules score to a confidence; a module that
+// matches every keyword ranks just above a man page.
+func moduleConfidence(score float64) float64 {
+	return 0.85 * score
+}
+
+// tldrConfident accepts an example that names the page or matches two query
+// words (every word of a shorter query), not one that only shares a word with
+// a page description.
+func tldrConfident(r tldr.Result) bool {
+	return r.Score >= 5 && r.Hits >= min(2, r.Terms)
+}
+
+func tldrResult(input string, r tldr.Result, confidence float64) *DetectionResult {
+	cmd, missing := layer1.FillSlots(r.Command, layer1.ExtractArgs(input))
+	return &DetectionResult{
+		Command:     cmd,
+		Description: r.Description,
+		Source:      "tldr",
+		Confidence:  confidence,
+		Missing:     missing,
+		Template:    r.Command,
+	}
 }
 
 func tryRemote(input string) (*DetectionResult, error) {
-	if !config.ShouldUseRemote() {
+	if hermetic || !config.ShouldUseRemote() {
 		return nil, fmt.Errorf("no match found")
 	}
 
@@ -142,11 +176,22 @@ func isWeakRemoteResult(r layer4.CommandResult) bool {
 	return name == ""
 }
 
-func staticResult(entry layer1.CommandEntry, source string, confidence float64) *DetectionResult {
-	return &DetectionResult{
-		Command:     entry.Cmd,
+func staticResult(input string, entry layer1.CommandEntry, source string, confidence float64) *DetectionResult {
+	ctx := project.Current()
+	forProject := entry.ForProject(ctx)
+	changed := forProject != entry
+	entry = forProject.ForCurrent()
+	cmd, missing := layer1.FillSlots(entry.Cmd, layer1.ExtractArgs(input))
+	res := &DetectionResult{
+		Command:     cmd,
 		Description: entry.Desc,
 		Source:      source,
 		Confidence:  confidence,
+		Missing:     missing,
+		Template:    entry.Cmd,
+	}
+	if source == "project" || changed {
+		res.Project = ctx.Label()
 	}
+	return res
 }
@@ -1,6 +1,17 @@
 package intent
 
-import "testing"
+import (
+	"clio/internal/layer1"
+	"clio/internal/platform"
+	"clio/internal/project"
+	"clio/internal/tldr"
+	"testing"
+)
+
+func init() {
+	// The tests run inside clio's own Go module; keep its go.mod from steering results
+	project.Override(&project.Context{})
+}
 
 func TestDetectConversationalQueries(t *testing.T) {
 	cases := []struct {
@@ -54,6 +65,10 @@ func findSub(s, sub string) bool {
 }
 
 func TestDetectNigerianStudentQueries(t *testing.T) {
+	// These users are on Termux, where package commands stay pkg
+	platform.Override(&platform.Info{OS: "linux", Distro: "termux", PackageManager: "pkg", Termux: true})
+	defer platform.Override(nil)
+
 	cases := []struct {
 		query    string
 		contains string
@@ -153,3 +168,189 @@ func TestSearchTextInFiles(t *testing.T) {
 		t.Fatalf("got %q, want grep", result.Command)
 	}
 }
+
+func TestDetectFillsArguments(t *testing.T) {
+	cases := []struct {
+		query   string
+		want    string
+		missing int
+	}{
+		{"extract backup.tar.gz", "tar -xzvf backup.tar.gz", 0},
+		{"find notes.pdf in Documents", "find Documents -name 'notes.pdf'", 0},
+		{"find large files", "find . -type f -size +100M", 0},
+		{"kill process 4312", "kill 4312", 0},
+		{"how do I delete a directory", "rm -rf {dir}", 1},
+		{"download https://example.com/a.zip", "wget https://example.com/a.zip", 0},
+		{"find files bigger than 500mb", "find . -type f -size +500M", 0},
+		{"ssh into user@10.0.0.5", "ssh user@10.0.0.5", 0},
+		{"copy my notes.txt to Downloads", "cp notes.txt Downloads", 0},
+	}
+	for _, c := range cases {
+		result, err := Detect(c.query)
+		if err != nil {
+			t.Errorf("Detect(%q): %v", c.query, err)
+			continue
+		}
+		if result.Command != c.want || len(result.Missing) != c.missing {
+			t.Errorf("Detect(%q) = %q (%d missing), want %q (%d missing)",
+				c.query, result.Command, len(result.Missing), c.want, c.missing)
+		}
+	}
+}
+
+func TestDetectUsesPlatformVariant(t *testing.T) {
+	cases := []struct {
+		info platform.Info
+		want string
+	}{
+		{platform.Info{OS: "linux", Distro: "termux", PackageManager: "pkg", Termux: true}, "pkg install git"},
+		{platform.Info{OS: "linux", Distro: "debian", PackageManager: "apt"}, "sudo apt install git"},
+		{platform.Info{OS: "linux", Distro: "alpine", PackageManager: "apk", Root: true}, "apk add git"},
+		{platform.Info{OS: "darwin", Distro: "macos", PackageManager: "brew"}, "brew install git"},
+	}
+	defer platform.Override(nil)
+	for _, c := range cases {
+		info := c.info
+		platform.Override(&info)
+		result, err := Detect("install git")
+		if err != nil {
+			t.Fatalf("Detect(install git) on %s: %v", c.info.Distro, err)
+		}
+		if result.Command != c.want {
+			t.Errorf("Detect(install git) on %s = %q, want %q", c.info.Distro, result.Command, c.want)
+		}
+	}
+}
+
+func TestDetectUsesProjectContext(t *testing.T) {
+	goProject := project.Context{Root: "/src/app", Kinds: []string{"go", "make"}, Markers: []string{"go.mod", "Makefile"}, Git: true}
+	yarnProject := project.Context{Root: "/src/web", Kinds: []string{"node"}, Tools: []string{"yarn"}, Markers: []string{"package.json"}}
+	pyProject := project.Context{Root: "/src/tool", Kinds: []string{"python"}, Markers: []string{"requirements.txt"}}
+	cases := []struct {
+		ctx   project.Context
+		query string
+		want  string
+	}{
+		{goProject, "run the tests", "go test ./..."},
+		{yarnProject, "run the tests", "yarn test"},
+		{pyProject, "run the tests", "python -m pytest"},
+		{goProject, "install dependencies", "go mod download"},
+		{pyProject, "install dependencies", "pip install -r requirements.txt"},
+		{goProject, "list modules", "go list -m all"},
+		{yarnProject, "list modules", "yarn list"},
+		{project.Context{}, "list modules", "npm list"},
+		{goProject, "check disk space", "df -h"},
+	}
+	defer project.Override(&project.Context{})
+	for _, c := range cases {
+		ctx := c.ctx
+		project.Override(&ctx)
+		result, err := Detect(c.query)
+		if err != nil {
+			t.Errorf("Detect(%q) in %v: %v", c.query, c.ctx.Kinds, err)
+			continue
+		}
+		if result.Command != c.want {
+			t.Errorf("Detect(%q) in %v = %q, want %q", c.query, c.ctx.Kinds, result.Command, c.want)
+		}
+		if wantLabel := c.want != "df -h" && len(c.ctx.Kinds) > 0; wantLabel != (result.Project != "") {
+			t.Errorf("Detect(%q) in %v: Project = %q", c.query, c.ctx.Kinds, result.Project)
+		}
+	}
+}
+
+func TestDetectComposesCompoundQueries(t *testing.T) {
+	cases := []struct {
+		query  string
+		want   string
+		stages int
+	}{
+		{"find large files and delete them", "find . -type f -size +100M -exec rm {} +", 2},
+		{"show processes using the most memory", "ps aux | sort -rnk 4 | head -n 10", 3},
+	}
+	for _, c := range cases {
+		result, err := Detect(c.query)
+		if err != nil {
+			t.Fatalf("Detect(%q): %v", c.query, err)
+		}
+		if result.Source != "compose" || result.Command != c.want || len(result.Stages) != c.stages {
+			t.Errorf("Detect(%q) = %s %q with %d stages, want compose %q with %d",
+				c.query, result.Source, result.Command, len(result.Stages), c.want, c.stages)
+		}
+		cands, err := DetectAll(c.query, 3)
+		if err != nil || cands[0].Command != c.want {
+			t.Errorf("DetectAll(%q) did not rank the pipeline first", c.query)
+		}
+	}
+}
+
+func TestTLDRConfident(t *testing.T) {
+	cases := []struct {
+		r    tldr.Result
+		want bool
+	}{
+		{tldr.Result{Name: "htop", Score: 5, Hits: 1, Terms: 1}, true},
+		{tldr.Result{Name: "tar", Score: 8, Hits: 2, Terms: 3}, true},
+		{tldr.Result{Name: "free", Score: 6, Hits: 2, Terms: 2}, true},
+		{tldr.Result{Name: "free", Score: 3, Hits: 1, Terms: 1}, false}, // one description word
+		{tldr.Result{Name: "tar", Score: 5, Hits: 1, Terms: 3}, false},
+		{tldr.Result{Name: "tar", Score: 4, Hits: 2, Terms: 2}, false},
+	}
+	for _, c := range cases {
+		if got := tldrConfident(c.r); got != c.want {
+			t.Errorf("tldrConfident(%+v) = %v, want %v", c.r, got, c.want)
+		}
+	}
+}
+
+// Detect answers with the top candidate DetectAll ranks.
+func TestDetectAgreesWithDetectAll(t *testing.T) {
+	for _, q := range []string{
+		"how do I delete a directory",
+		"find large files",
+		"unzip file",
+		"search command history",
+		"show me the end of the log file",
+		"download something from the web",
+	} {
+		res, err := Detect(q)
+		if err != nil {
+			t.Errorf("Detect(%q): %v", q, err)
+			continue
+		}
+		cands, err := DetectAll(q, 0)
+		if err != nil || len(cands) == 0 {
+			t.Errorf("DetectAll(%q) = %v, %v", q, cands, err)
+			continue
+		}
+		if cands[0].Command != res.Command {
+			t.Errorf("Detect(%q) = %q, DetectAll ranks %q first", q, res.Command, cands[0].Command)
+		}
+	}
+}
+
+func TestDetectPrefersUserRules(t *testing.T) {
+	errs := layer1.UseTestCommands(t, layer1.UserCommands{Phrases: []layer1.UserPhrase{
+		{Terms: []string{"run", "tests"}, Command: "make test-all"},
+		{Terms: []string{"disk", "memory"}, Command: "./scripts/usage.sh"},
+	}})
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+	defer project.Override(&project.Context{})
+	project.Override(&project.Context{Root: "/src/app", Kinds: []string{"go"}, Markers: []string{"go.mod"}})
+
+	for query, want := range map[string]string{
+		"run the tests":                     "make test-all",      // over the project's go test ./...
+		"check disk space and memory usage": "./scripts/usage.sh", // over the composed df -h && free -h
+	} {
+		result, err := Detect(query)
+		if err != nil {
+			t.Errorf("Detect(%q): %v", query, err)
+			continue
+		}
+		if result.Command != want {
+			t.Errorf("Detect(%q) = %q, want the user's rule %q", query, result.Command, want)
+		}
+	}
+}
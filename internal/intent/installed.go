@@ -0,0 +1,90 @@
+package intent
+
+import (
+	"clio/internal/layer1"
+	"clio/internal/layer2"
+	"clio/internal/platform"
+	"clio/internal/shell"
+	"strings"
+)
+
+// isInstalled is swapped out in tests.
+var isInstalled = layer2.IsInstalled
+
+// checkInstalled flags a shell command whose binary is not on PATH and fills
+// in how to install it or what to use instead.
+func checkInstalled(res *DetectionResult) {
+	switch res.Source {
+	case "module", "setup", "setup-menu":
+		return // run in-process by clio itself
+	}
+	res.NotInstalled = MissingBinary(res.Command)
+	if res.NotInstalled == "" {
+		res.Install, res.Alternative = "", ""
+		return
+	}
+	res.Install = InstallCommand(res.NotInstalled)
+	res.Alternative = Alternative(res.Command, res.NotInstalled)
+}
+
+// MissingBinary returns the first binary cmd runs that is not on PATH, or "".
+// Wrappers such as sudo -u, env and sh -c are looked through (see
+// shell.Unwrap); builtins, paths such as ./script.sh and placeholders are
+// not checked.
+func MissingBinary(cmd string) string {
+	segs, err := shell.Split(cmd)
+	if err != nil {
+		return ""
+	}
+	for _, seg := range segs {
+		u := shell.Unwrap(segmentWords(seg))
+		if u.Script != "" {
+			if bin := MissingBinary(u.Script); bin != "" {
+				return bin
+			}
+			continue
+		}
+		if len(u.Args) == 0 {
+			continue
+		}
+		bin := u.Args[0]
+		if shell.IsBuiltin(bin) || strings.ContainsAny(bin, "/{}$") {
+			continue
+		}
+		if !isInstalled(bin) {
+			return bin
+		}
+	}
+	return ""
+}
+
+// segmentWords returns the words of a simple command up to its first
+// redirection.
+func segmentWords(seg []shell.Token) []string {
+	var words []string
+	for _, t := range seg {
+		if t.Op {
+			break
+		}
+		words = append(words, t.Text)
+	}
+	return words
+}
+
+// InstallCommand returns how to install binary with the detected package
+// manager, or "" when its package is unknown.
+func InstallCommand(binary string) string {
+	cmd, _ := layer1.InstallCommand(binary, platform.Current())
+	return cmd
+}
+
+// Alternative returns the first installed command that can stand in for cmd,
+// whose binary is missing. Placeholders left in cmd carry over.
+func Alternative(cmd, binary string) string {
+	for _, alt := range layer1.Alternatives(cmd, binary) {
+		if MissingBinary(alt) == "" {
+			return alt
+		}
+	}
+	return ""
+}
@@ -0,0 +1,76 @@
+package intent
+
+import (
+	"clio/internal/platform"
+	"testing"
+)
+
+// withInstalled makes only bins look installed.
+func withInstalled(t *testing.T, bins ...string) {
+	t.Helper()
+	orig := isInstalled
+	isInstalled = func(name string) bool {
+		for _, b := range bins {
+			if b == name {
+				return true
+			}
+		}
+		return false
+	}
+	t.Cleanup(func() { isInstalled = orig })
+}
+
+func TestMissingBinary(t *testing.T) {
+	withInstalled(t, "ps", "grep", "sudo")
+	cases := []struct {
+		cmd, want string
+	}{
+		{"ps aux | grep ssh", ""},
+		{"ps aux | htop", "htop"},
+		{"sudo lsusb -v", "lsusb"},
+		{"cd /tmp && tree", "tree"}, // cd is a builtin
+		{"./script.sh", ""},
+		{"LANG=C ps", ""},
+		{"LANG=C tree", "tree"},
+		{"sudo -u postgres psql", "psql"},
+		{"env -u HOME nice -n 5 htop", "htop"},
+		{"timeout 5s ncdu", "ncdu"},
+		{"sh -c 'ps aux | tree'", "tree"},
+		{"sudo ps > out.txt", ""},
+	}
+	for _, c := range cases {
+		if got := MissingBinary(c.cmd); got != c.want {
+			t.Errorf("MissingBinary(%q) = %q, want %q", c.cmd, got, c.want)
+		}
+	}
+}
+
+func TestCheckInstalledSuggestsInstallAndAlternative(t *testing.T) {
+	platform.Override(&platform.Info{OS: "linux", Distro: "debian", PackageManager: "apt"})
+	defer platform.Override(nil)
+	withInstalled(t, "top", "curl")
+
+	res := &DetectionResult{Command: "htop", Source: "static"}
+	checkInstalled(res)
+	if res.NotInstalled != "htop" || res.Install != "sudo apt install htop" || res.Alternative != "top" {
+		t.Errorf("htop: missing=%q install=%q alt=%q", res.NotInstalled, res.Install, res.Alternative)
+	}
+
+	res = &DetectionResult{Command: "wget https://example.com/a.zip", Source: "static"}
+	checkInstalled(res)
+	if res.Alternative != "curl -O https://example.com/a.zip" {
+		t.Errorf("wget alternative = %q", res.Alternative)
+	}
+
+	res = &DetectionResult{Command: "top", Source: "static"}
+	checkInstalled(res)
+	if res.NotInstalled != "" || res.Install != "" {
+		t.Errorf("installed top flagged: %+v", res)
+	}
+
+	res = &DetectionResult{Command: "clio run backup", Source: "module"}
+	checkInstalled(res)
+	if res.NotInstalled != "" {
+		t.Errorf("module flagged as missing %q", res.NotInstalled)
+	}
+}
@@ -0,0 +1,200 @@
+package intent
+
+import (
+	"clio/internal/layer1"
+	"clio/internal/layer3"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// learnWindow is how many recent history entries inform re-ranking.
+	learnWindow = 500
+	// minSimilarity is the keyword overlap (Jaccard) for queries to count as similar.
+	minSimilarity = 0.5
+	// learnStep converts a preference score into a confidence adjustment.
+	learnStep = 0.05
+	maxBoost  = 0.15
+	maxDemote = 0.25
+	// historyOnlyScore is the preference needed to suggest a command no layer found.
+	historyOnlyScore = 2.0
+	// minPreferenceScore is the preference that lets history re-rank
+	// candidates: about one run for the same wording.
+	minPreferenceScore = 1.0
+)
+
+// outcomeWeight is how strongly each outcome counts for (or against) a command.
+var outcomeWeight = map[string]float64{
+	layer3.OutcomeRan:       1,
+	layer3.OutcomeEdited:    0.5,
+	layer3.OutcomeCancelled: -1,
+}
+
+// recentHistory is swapped out in tests.
+var recentHistory = func() ([]layer3.HistoryEntry, error) {
+	return layer3.ListHistory(learnWindow)
+}
+
+type preference struct {
+	score       float64
+	picked      int
+	rejected    int
+	description string
+}
+
+// preferences maps a command to what similar past queries did with it.
+type preferences map[string]*preference
+
+// decisive reports whether some command was picked often enough to overrule
+// the built-in rules. Until one has, history only demotes what the user
+// rejected: a single weak pick does not promote anything.
+func (prefs preferences) decisive() bool {
+	for _, p := range prefs {
+		if p.score >= minPreferenceScore {
+			return true
+		}
+	}
+	return false
+}
+
+// HistoryKeywords normalizes a query to the sorted keyword set used to
+// match similar wording.
+func HistoryKeywords(input string) string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, k := range IsolateKeywords(input) {
+		if !seen[k] {
+			seen[k] = true
+			out = append(out, k)
+		}
+	}
+	sort.Strings(out)
+	return strings.Join(out, " ")
+}
+
+// Record stores what the user did with a suggestion so later queries learn from it.
+func Record(input string, res *DetectionResult, outcome, finalCmd string) error {
+	return layer3.RecordHistory(layer3.HistoryEntry{
+		Query:        input,
+		Keywords:     HistoryKeywords(input),
+		Command:      res.Key(),
+		FinalCommand: finalCmd,
+		Description:  res.Description,
+		Source:       res.Source,
+		Outcome:      outcome,
+	})
+}
+
+// learnedPreferences scores each command by past outcomes for similar
+// queries, weighted by how similar the wording was.
+func learnedPreferences(input string) preferences {
+	if hermetic {
+		return nil
+	}
+	keywords := strings.Fields(HistoryKeywords(input))
+	if len(keywords) == 0 {
+		return nil
+	}
+	entries, err := recentHistory()
+	if err != nil || len(entries) == 0 {
+		return nil
+	}
+
+	prefs := make(preferences)
+	for _, e := range entries {
+		sim := jaccard(keywords, strings.Fields(e.Keywords))
+		if sim < minSimilarity {
+			continue
+		}
+		p, ok := prefs[e.Command]
+		if !ok {
+			p = &preference{description: e.Description}
+			prefs[e.Command] = p
+		}
+		w := outcomeWeight[e.Outcome]
+		p.score += w * sim
+		if w > 0 {
+			p.picked++
+		} else if w < 0 {
+			p.rejected++
+		}
+	}
+	return prefs
+}
+
+// applyLearning nudges candidate confidence by learned preferences, adds
+// strongly preferred commands no layer found, and re-sorts. Rejected commands
+// are always demoted; picked ones are promoted only once prefs are decisive.
+func applyLearning(input string, cands []*DetectionResult, prefs preferences) []*DetectionResult {
+	if len(prefs) == 0 {
+		return cands
+	}
+	decisive := prefs.decisive()
+
+	seen := make(map[string]bool, len(cands))
+	for _, c := range cands {
+		seen[c.Key()] = true
+		if p, ok := prefs[c.Key()]; ok && (p.score < 0 || decisive) {
+			c.Confidence = clamp(c.Confidence + learnAdjustment(p.score))
+			c.Reasons = append(c.Reasons, p.reason())
+		}
+	}
+	if !decisive {
+		return sortByConfidence(cands)
+	}
+	for cmd, p := range prefs {
+		if seen[cmd] || p.score < historyOnlyScore {
+			continue
+		}
+		res := staticResult(input, layer1.CommandEntry{Cmd: cmd, Desc: p.description}, "history", 0.6+learnAdjustment(p.score))
+		res.Reasons = []string{p.reason()}
+		cands = append(cands, res)
+	}
+	return sortByConfidence(cands)
+}
+
+// sortByConfidence re-sorts cands after learning, keeping the order
+// mergeCandidates gave equal confidences.
+func sortByConfidence(cands []*DetectionResult) []*DetectionResult {
+	sort.SliceStable(cands, func(i, j int) bool {
+		return cands[i].Confidence > cands[j].Confidence
+	})
+	return cands
+}
+
+func learnAdjustment(score float64) float64 {
+	adj := score * learnStep
+	if adj > maxBoost {
+		return maxBoost
+	}
+	if adj < -maxDemote {
+		return -maxDemote
+	}
+	return adj
+}
+
+func (p *preference) reason() string {
+	return fmt.Sprintf("learned: picked %d×, rejected %d× for similar queries", p.picked, p.rejected)
+}
+
+func jaccard(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	set := make(map[string]bool, len(a))
+	for _, w := range a {
+		set[w] = true
+	}
+	inter := 0
+	union := len(set)
+	for _, w := range b {
+		if set[w] {
+			inter++
+			set[w] = false // count duplicates once
+		} else if _, ok := set[w]; !ok {
+			union++
+		}
+	}
+	return float64(inter) / float64(union)
+}
@@ -0,0 +1,156 @@
+package intent
+
+import (
+	"clio/internal/layer3"
+	"testing"
+)
+
+// withHistory makes learning see entries instead of the local database.
+func withHistory(t *testing.T, entries []layer3.HistoryEntry) {
+	t.Helper()
+	orig := recentHistory
+	recentHistory = func() ([]layer3.HistoryEntry, error) { return entries, nil }
+	t.Cleanup(func() { recentHistory = orig })
+}
+
+func entry(query, command, outcome string) layer3.HistoryEntry {
+	return layer3.HistoryEntry{Query: query, Keywords: HistoryKeywords(query), Command: command, Outcome: outcome}
+}
+
+func TestHistoryKeywords(t *testing.T) {
+	a := HistoryKeywords("find the large files")
+	b := HistoryKeywords("large files find")
+	if a == "" || a != b {
+		t.Errorf("HistoryKeywords not order-independent: %q vs %q", a, b)
+	}
+}
+
+func TestLearningReordersCandidates(t *testing.T) {
+	const query = "find large files"
+	withHistory(t, nil)
+	before, err := DetectAll(query, 0)
+	if err != nil || len(before) < 2 {
+		t.Fatalf("DetectAll(%q) = %v, %v", query, before, err)
+	}
+	top, second := before[0].Key(), before[1].Key()
+
+	withHistory(t, []layer3.HistoryEntry{
+		entry(query, second, layer3.OutcomeRan),
+		entry("find big large files", second, layer3.OutcomeEdited),
+		entry(query, top, layer3.OutcomeCancelled),
+		entry("install git", top, layer3.OutcomeRan), // unrelated wording, ignored
+	})
+	after, err := DetectAll(query, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after[0].Key() != second {
+		t.Errorf("top after learning = %q, want %q", after[0].Key(), second)
+	}
+	res, err := Detect(query)
+	if err != nil || res.Key() != second {
+		t.Errorf("Detect(%q) = %v, %v; want %q", query, res, err, second)
+	}
+}
+
+// A weakly similar pick does not promote anything, and history is read once
+// per query.
+func TestWeakPreferenceKeepsDetect(t *testing.T) {
+	const query = "find large files"
+	withHistory(t, nil)
+	want, err := Detect(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cands, err := DetectAll(query, 0)
+	if err != nil || len(cands) < 2 {
+		t.Fatalf("DetectAll(%q) = %v, %v", query, cands, err)
+	}
+	other := cands[0].Key()
+	if other == want.Key() {
+		other = cands[1].Key()
+	}
+
+	reads := 0
+	for _, entries := range [][]layer3.HistoryEntry{
+		{entry("find large files quickly please", other, layer3.OutcomeEdited)},
+		{entry("find large files quickly please", other, layer3.OutcomeRan)},
+	} {
+		withHistory(t, entries)
+		orig := recentHistory
+		recentHistory = func() ([]layer3.HistoryEntry, error) { reads++; return orig() }
+		reads = 0
+		res, err := Detect(query)
+		if err != nil || res.Key() != want.Key() {
+			t.Errorf("Detect(%q) with %+v = %v, %v; want %q", query, entries, res, err, want.Key())
+		}
+		if reads != 1 {
+			t.Errorf("Detect read history %d times, want 1", reads)
+		}
+	}
+}
+
+// Rejections demote a command even when nothing was picked often enough to
+// promote another.
+func TestRejectionsDemoteCandidate(t *testing.T) {
+	const query = "find large files"
+	withHistory(t, nil)
+	before, err := DetectAll(query, 0)
+	if err != nil || len(before) < 2 {
+		t.Fatalf("DetectAll(%q) = %v, %v", query, before, err)
+	}
+	top := before[0]
+
+	withHistory(t, []layer3.HistoryEntry{
+		entry(query, top.Key(), layer3.OutcomeCancelled),
+		entry(query, top.Key(), layer3.OutcomeCancelled),
+		entry("find the large files", top.Key(), layer3.OutcomeCancelled),
+	})
+	after, err := DetectAll(query, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after[0].Key() == top.Key() {
+		t.Errorf("%q still ranks first after three rejections", top.Key())
+	}
+	for _, c := range after {
+		if c.Key() == top.Key() && c.Confidence >= top.Confidence {
+			t.Errorf("%q confidence %v, want below %v", c.Key(), c.Confidence, top.Confidence)
+		}
+	}
+	res, err := Detect(query)
+	if err != nil || res.Key() == top.Key() {
+		t.Errorf("Detect(%q) = %v, %v; want something other than the rejected %q", query, res, err, top.Key())
+	}
+}
+
+func TestLearningAddsHistoryCandidate(t *testing.T) {
+	const query = "show disk space"
+	withHistory(t, []layer3.HistoryEntry{
+		entry(query, "ncdu", layer3.OutcomeRan),
+		entry(query, "ncdu", layer3.OutcomeRan),
+		entry(query, "ncdu", layer3.OutcomeRan),
+	})
+	cands, err := DetectAll(query, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range cands {
+		if c.Command == "ncdu" {
+			if c.Source != "history" {
+				t.Errorf("ncdu source = %q, want history", c.Source)
+			}
+			return
+		}
+	}
+	t.Errorf("learned command ncdu not offered: %v", cands)
+}
+
+func TestLearningClampsAdjustment(t *testing.T) {
+	if got := learnAdjustment(100); got != maxBoost {
+		t.Errorf("learnAdjustment(100) = %v, want %v", got, maxBoost)
+	}
+	if got := learnAdjustment(-100); got != -maxDemote {
+		t.Errorf("learnAdjustment(-100) = %v, want %v", got, -maxDemote)
+	}
+}
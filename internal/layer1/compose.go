@@ -0,0 +1,390 @@
+package layer1
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Compound queries ("find large files and delete them", "count lines in all
+// go files") need more than one catalog entry. Compose splits a query into
+// clauses, resolves each clause to one or more typed stages and joins them
+// by what flows between them: text is piped, paths from find feed -exec,
+// other paths go through xargs, and stages that read nothing run after &&.
+
+// Data is what a pipeline stage reads or writes.
+type Data string
+
+const (
+	DataNone  Data = ""      // reads nothing / prints nothing worth passing on
+	DataText  Data = "text"  // lines of text
+	DataPaths Data = "paths" // one file path per line
+	DataProcs Data = "procs" // ps aux table: %CPU is column 3, %MEM column 4
+
+	dataSame Data = "same" // writes the same kind of data it reads (sort, head)
+)
+
+// PipelineStage is one step of a composed command.
+type PipelineStage struct {
+	Join string `json:"join,omitempty"` // "", "|", "&&", "-exec" or "xargs"
+	Cmd  string `json:"cmd"`
+	Desc string `json:"desc"`
+}
+
+// Pipeline is a command composed from several stages.
+type Pipeline struct {
+	Command string
+	Stages  []PipelineStage
+	Missing []Slot // placeholders no clause supplied
+}
+
+// composeStage is a building block a clause can resolve to.
+type composeStage struct {
+	cmd  string // reads stdin, or runs on its own when in is DataNone
+	args string // takes paths as arguments; {} marks where they go, else they are appended
+	desc string
+	in   Data
+	out  Data
+}
+
+// composeRule matches a clause when every term appears in it. needs names a
+// slot the clause must supply (the generic "files" producer needs a pattern).
+type composeRule struct {
+	terms  []string
+	needs  SlotType
+	stages []composeStage
+}
+
+var headStage = composeStage{cmd: "head -n {n:10}", desc: "keep the first {n:10} lines", in: DataText, out: dataSame}
+
+var composeRules = []composeRule{
+	// producers
+	{[]string{"large", "file"}, "", []composeStage{{cmd: "find {dir:.} -type f -size {size:+100M}", desc: "find files over the size limit", out: DataPaths}}},
+	{[]string{"big", "file"}, "", []composeStage{{cmd: "find {dir:.} -type f -size {size:+100M}", desc: "find files over the size limit", out: DataPaths}}},
+	{[]string{"empty", "file"}, "", []composeStage{{cmd: "find {dir:.} -type f -empty", desc: "find empty files", out: DataPaths}}},
+	{[]string{"old", "file"}, "", []composeStage{{cmd: "find {dir:.} -type f -mtime +30", desc: "find files not changed in 30 days", out: DataPaths}}},
+	{[]string{"file"}, SlotPattern, []composeStage{{cmd: "find {dir:.} -type f -name {pattern}", desc: "find files matching the pattern", out: DataPaths}}},
+	{[]string{"process"}, "", []composeStage{{cmd: "ps aux", desc: "list running processes", out: DataProcs}}},
+
+	// filters
+	{[]string{"count", "lines"}, "", []composeStage{{cmd: "wc -l", args: "wc -l", desc: "count lines", in: DataText, out: DataText}}},
+	{[]string{"count", "word"}, "", []composeStage{{cmd: "wc -w", args: "wc -w", desc: "count words", in: DataText, out: DataText}}},
+	{[]string{"count"}, "", []composeStage{{cmd: "wc -l", desc: "count the results", in: DataText, out: DataText}}},
+	{[]string{"contain"}, SlotPattern, []composeStage{{cmd: "grep {pattern}", args: "grep -l {pattern}", desc: "keep those containing the pattern", in: DataText, out: dataSame}}},
+	{[]string{"match"}, SlotPattern, []composeStage{{cmd: "grep {pattern}", args: "grep -l {pattern}", desc: "keep those matching the pattern", in: DataText, out: dataSame}}},
+	{[]string{"most", "memory"}, "", []composeStage{{cmd: "sort -rnk 4", desc: "sort by memory use, highest first", in: DataProcs, out: DataProcs}, headStage}},
+	{[]string{"most", "cpu"}, "", []composeStage{{cmd: "sort -rnk 3", desc: "sort by CPU use, highest first", in: DataProcs, out: DataProcs}, headStage}},
+	{[]string{"unique"}, "", []composeStage{{cmd: "sort -u", desc: "sort and drop duplicates", in: DataText, out: dataSame}}},
+	{[]string{"sort"}, "", []composeStage{{cmd: "sort", desc: "sort the lines", in: DataText, out: dataSame}}},
+	{[]string{"top"}, "", []composeStage{headStage}},
+	{[]string{"first"}, "", []composeStage{headStage}},
+	{[]string{"last"}, "", []composeStage{{cmd: "tail -n {n:10}", desc: "keep the last {n:10} lines", in: DataText, out: dataSame}}},
+
+	// consumers
+	{[]string{"remove"}, "", []composeStage{{args: "rm", desc: "delete each file", in: DataPaths}}},
+	{[]string{"copy"}, "", []composeStage{{args: "cp {} {dir}", desc: "copy each file to the folder", in: DataPaths}}},
+	{[]string{"move"}, "", []composeStage{{args: "mv {} {dir}", desc: "move each file to the folder", in: DataPaths}}},
+	{[]string{"archive"}, "", []composeStage{{cmd: "tar -czvf archive.tar.gz -T -", desc: "pack the listed files into archive.tar.gz", in: DataPaths}}},
+	{[]string{"save"}, SlotFile, []composeStage{{cmd: "tee {file}", desc: "save the output to a file", in: DataText, out: dataSame}}},
+}
+
+// clauseSplitRe separates clauses on conjunctions and ordering words.
+var clauseSplitRe = regexp.MustCompile(`(?i)\s*(?:,\s*)?\b(?:and then|and|then|after that|afterwards|followed by|before)\b\s*|\s*[;|]\s*`)
+
+// afterRe finds "X after Y", which runs Y first.
+var afterRe = regexp.MustCompile(`(?i)^(.+?)\s+after\s+(.+)$`)
+
+// SplitClauses breaks a compound query into clauses in the order they should run.
+func SplitClauses(input string) []string {
+	var out []string
+	for _, part := range clauseSplitRe.Split(input, -1) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if m := afterRe.FindStringSubmatch(part); m != nil {
+			out = append(out, strings.TrimSpace(m[2]), strings.TrimSpace(m[1]))
+			continue
+		}
+		out = append(out, part)
+	}
+	return out
+}
+
+// Compose builds a pipeline for input. It succeeds only when the query
+// resolves to at least two stages whose inputs and outputs fit together.
+func Compose(input string) (Pipeline, bool) {
+	var p Pipeline
+	var parts []string // command text of each stage, joined at the end
+	out := DataNone
+	bare := false // the command so far is a single find, so paths can go to -exec
+
+	for _, clause := range SplitClauses(input) {
+		stages, ok := resolveClause(clause)
+		if !ok {
+			return Pipeline{}, false
+		}
+		args := ExtractArgs(clause)
+		globs, texts := splitPatterns(args[SlotPattern])
+		n := strconv.Itoa(firstNumber(clause, 10))
+		for _, st := range stages {
+			st.cmd = strings.ReplaceAll(st.cmd, "{n:10}", n)
+			st.desc = strings.ReplaceAll(st.desc, "{n:10}", n)
+
+			join, cmd, next, ok := joinStage(len(p.Stages) == 0, out, bare, st)
+			if !ok {
+				return Pipeline{}, false
+			}
+			// find -name wants the glob ("*.log"), grep the quoted text
+			// ("error"). A glob is never a grep pattern: without text the
+			// slot stays missing.
+			if strings.HasPrefix(cmd, "find ") {
+				args[SlotPattern] = append(append([]string{}, globs...), texts...)
+			} else {
+				args[SlotPattern] = texts
+			}
+			cmd, missing := FillSlots(cmd, args)
+			p.Missing = append(p.Missing, missing...)
+			stage := PipelineStage{Join: join, Cmd: cmd, Desc: st.desc}
+			p.Stages = append(p.Stages, stage)
+			parts = append(parts, stage.String())
+			// Paths can go straight to -exec while the command ends in a plain find
+			bare = (join == "" || join == "&&") && strings.HasPrefix(cmd, "find ")
+			out = next
+		}
+	}
+	if len(p.Stages) < 2 {
+		return Pipeline{}, false
+	}
+	p.Command = strings.Join(parts, " ")
+	return p, true
+}
+
+// joinStage decides how st attaches to a command producing out (first: no
+// command yet). It returns the join, the stage's command text and what the
+// combined command produces.
+func joinStage(first bool, out Data, bare bool, st composeStage) (string, string, Data, bool) {
+	next := st.out
+	if next == dataSame {
+		next = out
+	}
+	switch {
+	case first && st.in == DataNone:
+		return "", st.cmd, next, true
+	case first:
+		return "", "", DataNone, false // "delete them" with nothing before it
+	case st.in == DataNone:
+		return "&&", st.cmd, next, true
+	case out == DataNone:
+		return "", "", DataNone, false // nothing to read from
+	case st.args != "" && out == DataPaths:
+		if bare {
+			if strings.Contains(st.args, "{}") {
+				return "-exec", st.args + ` \;`, next, true
+			}
+			return "-exec", st.args + " {} +", next, true
+		}
+		if strings.Contains(st.args, "{}") {
+			return "xargs", "-I{} " + st.args, next, true
+		}
+		return "xargs", st.args, next, true
+	case st.cmd == "":
+		return "", "", DataNone, false // needs paths as arguments, got something else
+	case st.in == DataPaths && out != DataPaths, st.in == DataProcs && out != DataProcs:
+		return "", "", DataNone, false
+	}
+	return "|", st.cmd, next, true
+}
+
+// resolveClause turns one clause into stages: compose rules first, then a
+// single catalog entry that runs on its own.
+func resolveClause(clause string) ([]composeStage, bool) {
+	if stages := matchComposeRules(clause); len(stages) > 0 {
+		return stages, true
+	}
+	entry, ok := MatchPhrase(clause)
+	if !ok {
+		entry, ok = LookupVerbNoun(ParseIntent(clause))
+	}
+	if !ok {
+		return nil, false
+	}
+	entry = entry.ForCurrent()
+	return []composeStage{{cmd: entry.Cmd, desc: entry.Desc, out: outputOf(entry.Cmd)}}, true
+}
+
+// matchComposeRules picks the most specific rules matching clause, skipping
+// rules whose terms are all covered by a longer match ("count" inside
+// "count lines"), and orders the stages by stageOrder, then by where their
+// words appear.
+func matchComposeRules(clause string) []composeStage {
+	set := phraseTokenSet(fileTypeRe.ReplaceAllStringFunc(clause, dropFileType))
+	args := ExtractArgs(clause)
+	positions := termPositions(clause)
+
+	rules := make([]composeRule, len(composeRules))
+	copy(rules, composeRules)
+	sort.SliceStable(rules, func(i, j int) bool { return len(rules[i].terms) > len(rules[j].terms) })
+
+	type match struct {
+		rule     composeRule
+		position int
+	}
+	var matches []match
+	covered := make(map[string]bool)
+	for _, r := range rules {
+		if !phraseTermsMatch(set, r.terms) || (r.needs != "" && len(args[r.needs]) == 0) {
+			continue
+		}
+		fresh := false
+		pos := -1
+		for _, term := range r.terms {
+			if !covered[term] {
+				fresh = true
+			}
+			if p, ok := positions[term]; ok && (pos < 0 || p < pos) {
+				pos = p
+			}
+		}
+		if !fresh {
+			continue
+		}
+		for _, term := range r.terms {
+			covered[term] = true
+		}
+		matches = append(matches, match{r, pos})
+	}
+
+	type placed struct {
+		stage    composeStage
+		position int
+	}
+	var stages []placed
+	seen := make(map[string]bool)
+	for _, m := range matches {
+		for _, st := range m.rule.stages {
+			key := st.cmd + "\x00" + st.args
+			if seen[key] {
+				continue // "top 5 … most cpu" needs one head, not two
+			}
+			seen[key] = true
+			stages = append(stages, placed{st, m.position})
+		}
+	}
+	sort.SliceStable(stages, func(i, j int) bool {
+		oi, oj := stageOrder(stages[i].stage), stageOrder(stages[j].stage)
+		if oi != oj {
+			return oi < oj
+		}
+		return stages[i].position < stages[j].position
+	})
+	out := make([]composeStage, len(stages))
+	for i, p := range stages {
+		out[i] = p.stage
+	}
+	return out
+}
+
+// fileTypeRe finds "zip files"; the type word already became the *.zip
+// pattern and must not also match a rule ("zip" → archive).
+var fileTypeRe = regexp.MustCompile(`(?i)\b(\w+)\s+(files?)\b`)
+
+func dropFileType(m string) string {
+	sub := fileTypeRe.FindStringSubmatch(m)
+	if fileExtensions[strings.ToLower(sub[1])] {
+		return sub[2]
+	}
+	return m
+}
+
+// stageOrder places a clause's stages: producers, then filters, then
+// head/tail limits, then stages that only act on what they receive.
+func stageOrder(st composeStage) int {
+	switch {
+	case st.in == DataNone:
+		return 0
+	case st.out == DataNone:
+		return 3
+	case strings.HasPrefix(st.cmd, "head ") || strings.HasPrefix(st.cmd, "tail "):
+		return 2
+	}
+	return 1
+}
+
+// splitPatterns separates globs ("*.pdf") from literal search text.
+func splitPatterns(patterns []string) (globs, texts []string) {
+	for _, p := range patterns {
+		if strings.ContainsAny(p, "*?[") {
+			globs = append(globs, p)
+		} else {
+			texts = append(texts, p)
+		}
+	}
+	return globs, texts
+}
+
+// termPositions maps each normalized form of each word to its first index.
+func termPositions(clause string) map[string]int {
+	pos := make(map[string]int)
+	for i, t := range stringsFieldsLower(clause) {
+		t = trimPunct(t)
+		forms := []string{t, Stem(t)}
+		if v, ok := VerbAliases[Stem(t)]; ok {
+			forms = append(forms, v)
+		}
+		for _, f := range forms {
+			if _, ok := pos[f]; !ok {
+				pos[f] = i
+			}
+		}
+	}
+	return pos
+}
+
+// outputOf guesses what a catalog command prints, so it can feed later stages.
+func outputOf(cmd string) Data {
+	switch {
+	case strings.HasPrefix(cmd, "find "):
+		return DataPaths
+	case cmd == "ps aux":
+		return DataProcs
+	}
+	return DataText
+}
+
+// firstNumber returns the first whole number in clause ("top 5"), or def.
+func firstNumber(clause string, def int) int {
+	for _, f := range strings.Fields(clause) {
+		if n, err := strconv.Atoi(trimPunct(f)); err == nil && n > 0 {
+			return n
+		}
+	}
+	return def
+}
+
+// Describe summarizes the pipeline's stages in one line.
+func (p Pipeline) Describe() string {
+	descs := make([]string, len(p.Stages))
+	for i, s := range p.Stages {
+		descs[i] = s.Desc
+	}
+	d := strings.Join(descs, ", then ")
+	if d == "" {
+		return d
+	}
+	return strings.ToUpper(d[:1]) + d[1:]
+}
+
+// String renders the stage for explanations: "| sort -rnk 4".
+func (s PipelineStage) String() string {
+	switch s.Join {
+	case "":
+		return s.Cmd
+	case "-exec":
+		return "-exec " + s.Cmd
+	case "xargs":
+		return "| xargs " + s.Cmd
+	}
+	return s.Join + " " + s.Cmd
+}
@@ -0,0 +1,122 @@
+package layer1
+
+import (
+	"clio/internal/platform"
+	"clio/internal/risk"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitClauses(t *testing.T) {
+	cases := []struct {
+		input string
+		want  []string
+	}{
+		{"find large files and delete them", []string{"find large files", "delete them"}},
+		{"check disk space, then memory usage", []string{"check disk space", "memory usage"}},
+		{"update packages; upgrade them", []string{"update packages", "upgrade them"}},
+		{"delete them after finding large files", []string{"finding large files", "delete them"}},
+		{"count lines in all go files", []string{"count lines in all go files"}},
+	}
+	for _, c := range cases {
+		if got := SplitClauses(c.input); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("SplitClauses(%q) = %q, want %q", c.input, got, c.want)
+		}
+	}
+}
+
+func TestCompose(t *testing.T) {
+	platform.Override(&debian)
+	defer platform.Override(nil)
+
+	cases := []struct {
+		input string
+		want  string // "" means the query should not be composed
+		joins []string
+	}{
+		{"find large files and delete them", "find . -type f -size +100M -exec rm {} +", []string{"", "-exec"}},
+		{"count lines in all go files", "find . -type f -name '*.go' -exec wc -l {} +", []string{"", "-exec"}},
+		{"show processes using the most memory", "ps aux | sort -rnk 4 | head -n 10", []string{"", "|", "|"}},
+		{"show top 5 processes using the most cpu", "ps aux | sort -rnk 3 | head -n 5", []string{"", "|", "|"}},
+		{`find log files containing "error" and count them`, "find . -type f -name '*.log' -exec grep -l 'error' {} + | wc -l", []string{"", "-exec", "|"}},
+		{"find large files in Downloads then move them to backup/", `find Downloads -type f -size +100M -exec mv {} backup/ \;`, []string{"", "-exec"}},
+		{"compress pdf files", "find . -type f -name '*.pdf' | tar -czvf archive.tar.gz -T -", []string{"", "|"}},
+		{"check disk space and memory usage", "df -h && free -h", []string{"", "&&"}},
+		{"disk usage after memory usage", "free -h && df -h", []string{"", "&&"}},
+
+		// single commands and mismatched stages are left to the other layers
+		{"find large files", "", nil},
+		{"how do I unzip a zip file", "", nil},
+		{"delete them", "", nil},
+		{"show processes and delete them", "", nil}, // processes are not paths
+		{"copy file and folder", "", nil},
+	}
+	for _, c := range cases {
+		p, ok := Compose(c.input)
+		if c.want == "" {
+			if ok {
+				t.Errorf("Compose(%q) = %q, want no pipeline", c.input, p.Command)
+			}
+			continue
+		}
+		if !ok {
+			t.Errorf("Compose(%q): no pipeline, want %q", c.input, c.want)
+			continue
+		}
+		if p.Command != c.want {
+			t.Errorf("Compose(%q) = %q, want %q", c.input, p.Command, c.want)
+		}
+		var joins []string
+		for _, s := range p.Stages {
+			joins = append(joins, s.Join)
+			if s.Desc == "" {
+				t.Errorf("Compose(%q): stage %q has no description", c.input, s.Cmd)
+			}
+		}
+		if !reflect.DeepEqual(joins, c.joins) {
+			t.Errorf("Compose(%q) joins = %q, want %q", c.input, joins, c.joins)
+		}
+	}
+}
+
+func TestComposeReportsMissingSlots(t *testing.T) {
+	p, ok := Compose("find large files and copy them")
+	if !ok {
+		t.Fatal("Compose: no pipeline")
+	}
+	if len(p.Missing) != 1 || p.Missing[0].Type != SlotDir {
+		t.Errorf("Missing = %+v, want one dir slot", p.Missing)
+	}
+}
+
+func TestComposeGrepNeedsText(t *testing.T) {
+	p, ok := Compose("find log files containing and delete them")
+	if !ok {
+		t.Fatal("Compose: no pipeline")
+	}
+	if strings.Contains(p.Command, "grep -l '*.log'") {
+		t.Errorf("Compose = %q, the file glob became the grep pattern", p.Command)
+	}
+	if len(p.Missing) != 1 || p.Missing[0].Type != SlotPattern {
+		t.Errorf("Missing = %+v, want one pattern slot", p.Missing)
+	}
+}
+
+func TestComposedDeletesAreDestructive(t *testing.T) {
+	for _, input := range []string{
+		"find large files and delete them",
+		`find log files containing "error" and delete them`,
+		"find empty files then remove them",
+		"find old files and delete them",
+	} {
+		p, ok := Compose(input)
+		if !ok {
+			t.Errorf("Compose(%q): no pipeline", input)
+			continue
+		}
+		if a := risk.Classify(p.Command); a.Level != risk.Destructive {
+			t.Errorf("Classify(%q) = %s (%v), want destructive", p.Command, a.Level, a.Reasons)
+		}
+	}
+}
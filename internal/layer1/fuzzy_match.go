@@ -3,12 +3,19 @@ package layer1
 // FuzzyExpandTokens adds catalog keys that are within edit distance 1 of query tokens.
 // Only runs on words >= 5 chars to avoid false positives (e.g. "ls" -> "as").
 func FuzzyExpandTokens(set map[string]bool) {
+	for w := range fuzzyCorrections(set) {
+		set[w] = true
+	}
+}
+
+// fuzzyCorrections maps each catalog word within edit distance 1 of a query token to that token.
+func fuzzyCorrections(set map[string]bool) map[string]string {
 	if len(set) == 0 {
-		return
+		return nil
 	}
 
 	candidates := collectCatalogWords()
-	expanded := make(map[string]bool)
+	expanded := make(map[string]string)
 
 	for token := range set {
 		if len(token) < 5 {
@@ -18,15 +25,12 @@ func FuzzyExpandTokens(set map[string]bool) {
 			if abs(len(token)-len(word)) > 1 {
 				continue
 			}
-			if levenshtein(token, word) == 1 {
-				expanded[word] = true
+			if levenshtein(token, word) == 1 && !set[word] {
+				expanded[word] = token
 			}
 		}
 	}
-
-	for w := range expanded {
-		set[w] = true
-	}
+	return expanded
 }
 
 func collectCatalogWords() []string {
@@ -79,53 +83,17 @@ func MatchWithFuzzy(input string) (CommandEntry, string, bool) {
 }
 
 func matchPhraseFromSet(set map[string]bool) (CommandEntry, bool) {
-	bestScore := 0
-	var best CommandEntry
-	for _, rule := range PhraseCatalog {
-		if !phraseTermsMatch(set, rule.terms) {
-			continue
-		}
-		score := len(rule.terms) * 10
-		for _, term := range rule.terms {
-			if set[term] {
-				score += 5
-			}
-		}
-		if score > bestScore {
-			bestScore = score
-			best = rule.entry
-		}
+	ranked := rankPhraseSet(set, false)
+	if len(ranked) == 0 {
+		return CommandEntry{}, false
 	}
-	return best, bestScore > 0
+	return ranked[0].Entry, true
 }
 
 func matchCatalogFromSet(set map[string]bool) (CommandEntry, bool) {
-	bestScore := 0
-	var best CommandEntry
-
-	for verb, nouns := range VerbNounCatalog {
-		verbScore := scoreVerb(verb, set)
-		if verbScore == 0 {
-			continue
-		}
-		for noun, entry := range nouns {
-			nounScore := scoreNoun(noun, set)
-			if nounScore == 0 {
-				continue
-			}
-			score := verbScore + nounScore
-			if noun == "file" && hasSpecificNounInSet(set) {
-				score -= 8
-			}
-			if score > bestScore {
-				bestScore = score
-				best = entry
-			}
-		}
-	}
-
-	if bestScore >= 12 {
-		return best, true
+	ranked := rankCatalogSet(set, false)
+	if len(ranked) == 0 {
+		return CommandEntry{}, false
 	}
-	return CommandEntry{}, false
+	return ranked[0].Entry, true
 }
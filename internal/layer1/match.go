@@ -3,49 +3,11 @@ package layer1
 // MatchCatalog scores the full sentence against the verb-noun catalog.
 // It prefers specific nouns (process, log, disk) over the generic "file".
 func MatchCatalog(input string) (CommandEntry, bool) {
-	set := TokenSet(input)
-	if len(set) == 0 {
+	ranked := RankCatalog(input)
+	if len(ranked) == 0 {
 		return CommandEntry{}, false
 	}
-
-	bestScore := 0
-	var best CommandEntry
-
-	for verb, nouns := range VerbNounCatalog {
-		verbScore := scoreVerb(verb, set)
-		if verbScore == 0 {
-			continue
-		}
-		for noun, entry := range nouns {
-			nounScore := scoreNoun(noun, set)
-			if nounScore == 0 {
-				continue
-			}
-			score := verbScore + nounScore
-			if noun == "file" && hasSpecificNounInSet(set) {
-				score -= 8
-			}
-			if set["text"] && noun == "text" {
-				score += 6
-			}
-			if set["text"] && noun == "file" {
-				score -= 6
-			}
-			if score > bestScore {
-				bestScore = score
-				best = entry
-			}
-		}
-	}
-
-	// Noun-only queries: "memory usage", "disk space" (no explicit verb)
-	if bestScore < 12 {
-		if entry, ok := matchNounOnly(set); ok {
-			return entry, true
-		}
-	}
-
-	return best, bestScore >= 12
+	return ranked[0].Entry, true
 }
 
 func scoreVerb(verb string, set map[string]bool) int {
@@ -0,0 +1,115 @@
+package layer1
+
+import (
+	"clio/internal/platform"
+	"strings"
+)
+
+// binaryPackages maps a binary to the Termux package that provides it when
+// the names differ. Other managers' names come from packageNames.
+var binaryPackages = map[string]string{
+	"lsusb": "usbutils", "lspci": "pciutils", "lscpu": "util-linux", "lsblk": "util-linux",
+	"ifconfig": "net-tools", "netstat": "net-tools", "ss": "iproute2", "ip": "iproute2",
+	"dig": "dnsutils", "nslookup": "dnsutils", "host": "dnsutils",
+	"ping": "inetutils", "traceroute": "traceroute",
+	"ssh": "openssh", "scp": "openssh", "sftp": "openssh", "ssh-keygen": "openssh",
+	"python3": "python", "pip": "python-pip", "pip3": "python-pip",
+	"node": "nodejs", "npm": "nodejs",
+	"ps": "procps", "free": "procps", "top": "procps", "pgrep": "procps", "pkill": "procps",
+	"killall": "psmisc", "pstree": "psmisc",
+	"rg": "ripgrep", "fd": "fd", "7z": "p7zip", "sqlite3": "sqlite", "xxd": "xxd",
+	"termux-clipboard-set": "termux-api", "termux-clipboard-get": "termux-api",
+	"termux-battery-status": "termux-api",
+	"man":                   "man", "vim": "vim", "nano": "nano", "htop": "htop", "tree": "tree",
+	"xclip": "xclip", "lshw": "lshw", "wget": "wget", "curl": "curl", "git": "git",
+	"zip": "zip", "unzip": "unzip", "rsync": "rsync", "jq": "jq", "ncdu": "ncdu",
+	"tmux": "tmux", "gh": "gh", "nmap": "nmap", "file": "file", "less": "less",
+}
+
+// PackageFor returns the package that provides binary under p's package
+// manager, or "" when it is unknown or not packaged there.
+func PackageFor(binary string, p platform.Info) string {
+	pkg, ok := binaryPackages[binary]
+	if !ok {
+		return ""
+	}
+	if renamed, ok := packageNames[pkg][p.PackageManager]; ok {
+		return renamed
+	}
+	if strings.HasPrefix(pkg, "termux-") && !p.Termux {
+		return ""
+	}
+	return pkg
+}
+
+// InstallCommand returns the command that installs binary on p, e.g.
+// "sudo apt install usbutils" for lsusb on Debian.
+func InstallCommand(binary string, p platform.Info) (string, bool) {
+	pkg := PackageFor(binary, p)
+	if pkg == "" {
+		return "", false
+	}
+	if p.PackageManager == "pkg" {
+		return "pkg install " + pkg, true
+	}
+	prefix, ok := packageOps[p.PackageManager]["install"]
+	if !ok {
+		return "", false
+	}
+	if p.Root {
+		prefix = strings.TrimPrefix(prefix, "sudo ")
+	}
+	return prefix + " " + pkg, true
+}
+
+// commandAlternatives lists commands doing the same job as a catalog command,
+// best first. Used when the suggested binary is not installed.
+var commandAlternatives = map[string][]string{
+	"htop":                  {"top"},
+	"tree":                  {"find . -print", "ls -R"},
+	"lshw":                  {"lscpu", "cat /proc/cpuinfo"},
+	"lscpu":                 {"cat /proc/cpuinfo"},
+	"lsusb":                 {"ls /sys/bus/usb/devices"},
+	"xclip -sel clip":       {"termux-clipboard-set", "pbcopy", "wl-copy", "xsel -b"},
+	"ifconfig":              {"ip -brief addr", "ip a"},
+	"ip a":                  {"ifconfig", "ip -brief addr"},
+	"ip -brief addr":        {"ifconfig"},
+	"ip route":              {"netstat -rn", "route -n"},
+	"ss -tuln":              {"netstat -tuln"},
+	"ss -tan":               {"netstat -an"},
+	"netstat -tuln":         {"ss -tuln"},
+	"netstat -an":           {"ss -tan"},
+	"free -h":               {"cat /proc/meminfo", "vm_stat"},
+	"termux-battery-status": {"cat /sys/class/power_supply/BAT0/capacity"},
+}
+
+// binaryAlternatives replace just the binary (and its leading flags) when the
+// arguments carry over, e.g. "wget URL" → "curl -O URL".
+var binaryAlternatives = map[string][]string{
+	"wget": {"curl -O"},
+	"curl": {"wget"},
+	"vim":  {"nano", "vi"},
+	"nano": {"vim", "vi"},
+	"htop": {"top"},
+	"rg":   {"grep -r"},
+	"fd":   {"find . -name"},
+	"gzip": {"xz"},
+}
+
+// Alternatives returns replacement commands for cmd whose first word is
+// binary, best first. Callers check which ones are installed.
+func Alternatives(cmd, binary string) []string {
+	if alts, ok := commandAlternatives[cmd]; ok {
+		return alts
+	}
+	var out []string
+	rest := strings.TrimSpace(strings.TrimPrefix(cmd, binary))
+	for _, alt := range binaryAlternatives[binary] {
+		if rest == "" {
+			out = append(out, alt)
+		} else {
+			out = append(out, alt+" "+rest)
+		}
+	}
+	return out
+}
@@ -32,42 +32,45 @@ var PhraseCatalog = []phraseRule{
 	{[]string{"public", "ip"}, CommandEntry{"curl ifconfig.me", "Check your public IP address"}},
 	{[]string{"my", "ip"}, CommandEntry{"curl ifconfig.me", "Check your public IP address"}},
 	{[]string{"what", "ip"}, CommandEntry{"curl ifconfig.me", "Check your public IP address"}},
-	{[]string{"internet", "working"}, CommandEntry{"ping -c 4 google.com", "Test internet connection"}},
-	{[]string{"wifi", "working"}, CommandEntry{"ping -c 4 google.com", "Test network connection"}},
+	{[]string{"internet", "working"}, CommandEntry{"ping -c 4 {host:google.com}", "Test internet connection"}},
+	{[]string{"wifi", "working"}, CommandEntry{"ping -c 4 {host:google.com}", "Test network connection"}},
 
 	// processes
 	{[]string{"running", "process"}, CommandEntry{"ps aux", "List running processes"}},
 	{[]string{"process", "running"}, CommandEntry{"ps aux", "List running processes"}},
 	{[]string{"what", "running"}, CommandEntry{"ps aux", "See what is running"}},
 	{[]string{"apps", "running"}, CommandEntry{"ps aux", "List running apps"}},
-	{[]string{"kill", "process"}, CommandEntry{"kill", "Stop a running process"}},
-	{[]string{"stop", "program"}, CommandEntry{"kill", "Stop a running program"}},
-	{[]string{"stop", "process"}, CommandEntry{"kill", "Stop a running process"}},
-	{[]string{"program", "stuck"}, CommandEntry{"kill", "Force-stop a stuck program"}},
+	{[]string{"kill", "process"}, CommandEntry{"kill {pid}", "Stop a running process"}},
+	{[]string{"stop", "program"}, CommandEntry{"kill {pid}", "Stop a running program"}},
+	{[]string{"stop", "process"}, CommandEntry{"kill {pid}", "Stop a running process"}},
+	{[]string{"program", "stuck"}, CommandEntry{"kill {pid}", "Force-stop a stuck program"}},
 
 	// files / logs
-	{[]string{"end", "log"}, CommandEntry{"tail -f", "Watch the end of a log file"}},
-	{[]string{"last", "lines"}, CommandEntry{"tail -n 20", "Show the last lines of a file"}},
-	{[]string{"beginning", "file"}, CommandEntry{"head", "Show the start of a file"}},
-	{[]string{"large", "file"}, CommandEntry{"find . -type f -size +100M", "Find large files"}},
-	{[]string{"find", "pdf"}, CommandEntry{`find . -name "*.pdf"`, "Find PDF files"}},
-	{[]string{"search", "text"}, CommandEntry{"grep -r", "Search for text inside files"}},
-	{[]string{"text", "files"}, CommandEntry{"grep -r", "Search for text inside files"}},
+	{[]string{"end", "log"}, CommandEntry{"tail -f {file}", "Watch the end of a log file"}},
+	{[]string{"last", "lines"}, CommandEntry{"tail -n 20 {file}", "Show the last lines of a file"}},
+	{[]string{"beginning", "file"}, CommandEntry{"head {file}", "Show the start of a file"}},
+	{[]string{"large", "file"}, CommandEntry{"find {dir:.} -type f -size {size:+100M}", "Find large files"}},
+	{[]string{"bigger", "file"}, CommandEntry{"find {dir:.} -type f -size {size:+100M}", "Find files over a size"}},
+	{[]string{"larger", "file"}, CommandEntry{"find {dir:.} -type f -size {size:+100M}", "Find files over a size"}},
+	{[]string{"smaller", "file"}, CommandEntry{"find {dir:.} -type f -size {size:-1M}", "Find files under a size"}},
+	{[]string{"find", "pdf"}, CommandEntry{`find {dir:.} -name {pattern:"*.pdf"}`, "Find PDF files"}},
+	{[]string{"search", "text"}, CommandEntry{"grep -r {pattern} {dir:.}", "Search for text inside files"}},
+	{[]string{"text", "files"}, CommandEntry{"grep -r {pattern} {dir:.}", "Search for text inside files"}},
 	{[]string{"hidden", "file"}, CommandEntry{"ls -la", "List hidden files"}},
 
 	// archives
-	{[]string{"unzip"}, CommandEntry{"unzip", "Extract a zip file"}},
-	{[]string{"extract", "zip"}, CommandEntry{"unzip", "Extract a zip archive"}},
-	{[]string{"zip", "file"}, CommandEntry{"unzip", "Extract a zip file"}},
-	{[]string{"extract", "tar"}, CommandEntry{"tar -xzvf", "Extract a tar archive"}},
-	{[]string{"tar", "file"}, CommandEntry{"tar -xzvf", "Extract a tar.gz file"}},
-	{[]string{"compress", "zip"}, CommandEntry{"zip -r archive.zip", "Compress files into a zip"}},
+	{[]string{"unzip"}, CommandEntry{"unzip {file}", "Extract a zip file"}},
+	{[]string{"extract", "zip"}, CommandEntry{"unzip {file}", "Extract a zip archive"}},
+	{[]string{"zip", "file"}, CommandEntry{"unzip {file}", "Extract a zip file"}},
+	{[]string{"extract", "tar"}, CommandEntry{"tar -xzvf {file}", "Extract a tar archive"}},
+	{[]string{"tar", "file"}, CommandEntry{"tar -xzvf {file}", "Extract a tar.gz file"}},
+	{[]string{"compress", "zip"}, CommandEntry{"zip -r archive.zip {dir}", "Compress files into a zip"}},
 
 	// permissions
 	{[]string{"change", "permission"}, CommandEntry{"chmod", "Change file permissions"}},
 	{[]string{"file", "permission"}, CommandEntry{"chmod", "Change file permissions"}},
-	{[]string{"make", "executable"}, CommandEntry{"chmod +x", "Make a file executable"}},
-	{[]string{"chmod", "executable"}, CommandEntry{"chmod +x", "Make a file executable"}},
+	{[]string{"make", "executable"}, CommandEntry{"chmod +x {file}", "Make a file executable"}},
+	{[]string{"chmod", "executable"}, CommandEntry{"chmod +x {file}", "Make a file executable"}},
 
 	// typos / casual phrasing
 	{[]string{"chek", "disk"}, CommandEntry{"df -h", "Check disk space"}},
@@ -85,66 +88,71 @@ var PhraseCatalog = []phraseRule{
 	{[]string{"phone", "full"}, CommandEntry{"df -h", "Check if phone storage is full"}},
 	{[]string{"space", "finish"}, CommandEntry{"df -h", "Check space — storage may be finished"}},
 	{[]string{"no", "space"}, CommandEntry{"df -h", "Check available disk space"}},
-	{[]string{"data", "no", "work"}, CommandEntry{"ping -c 4 google.com", "Test if mobile data is working"}},
-	{[]string{"internet", "no", "work"}, CommandEntry{"ping -c 4 google.com", "Test internet connection"}},
-	{[]string{"network", "no", "work"}, CommandEntry{"ping -c 4 google.com", "Test network connection"}},
-	{[]string{"wifi", "no", "work"}, CommandEntry{"ping -c 4 google.com", "Test WiFi connection"}},
-	{[]string{"data", "not", "working"}, CommandEntry{"ping -c 4 google.com", "Test if data is working"}},
-	{[]string{"internet", "not", "working"}, CommandEntry{"ping -c 4 google.com", "Test internet"}},
-	{[]string{"check", "data"}, CommandEntry{"ping -c 4 google.com", "Check if data/internet works"}},
-	{[]string{"app", "dey", "jam"}, CommandEntry{"kill", "Stop a jammed app (wetin i go do?)"}},
-	{[]string{"phone", "dey", "jam"}, CommandEntry{"kill", "Stop phone/app that has jammed"}},
-	{[]string{"dey", "hang"}, CommandEntry{"kill", "Stop a hung/frozen app"}},
-	{[]string{"dey", "jam"}, CommandEntry{"kill", "Stop a jammed app"}},
-	{[]string{"app", "jam"}, CommandEntry{"kill", "Force-stop a jammed app"}},
+	{[]string{"data", "no", "work"}, CommandEntry{"ping -c 4 {host:google.com}", "Test if mobile data is working"}},
+	{[]string{"internet", "no", "work"}, CommandEntry{"ping -c 4 {host:google.com}", "Test internet connection"}},
+	{[]string{"network", "no", "work"}, CommandEntry{"ping -c 4 {host:google.com}", "Test network connection"}},
+	{[]string{"wifi", "no", "work"}, CommandEntry{"ping -c 4 {host:google.com}", "Test WiFi connection"}},
+	{[]string{"data", "not", "working"}, CommandEntry{"ping -c 4 {host:google.com}", "Test if data is working"}},
+	{[]string{"internet", "not", "working"}, CommandEntry{"ping -c 4 {host:google.com}", "Test internet"}},
+	{[]string{"check", "data"}, CommandEntry{"ping -c 4 {host:google.com}", "Check if data/internet works"}},
+	{[]string{"app", "dey", "jam"}, CommandEntry{"kill {pid}", "Stop a jammed app (wetin i go do?)"}},
+	{[]string{"phone", "dey", "jam"}, CommandEntry{"kill {pid}", "Stop phone/app that has jammed"}},
+	{[]string{"dey", "hang"}, CommandEntry{"kill {pid}", "Stop a hung/frozen app"}},
+	{[]string{"dey", "jam"}, CommandEntry{"kill {pid}", "Stop a jammed app"}},
+	{[]string{"app", "jam"}, CommandEntry{"kill {pid}", "Force-stop a jammed app"}},
 	{[]string{"phone", "slow"}, CommandEntry{"free -h", "Check memory — phone is running slow"}},
-	{[]string{"no", "gree"}, CommandEntry{"kill", "Force-stop app that won't respond"}},
-	{[]string{"e", "no", "gree"}, CommandEntry{"kill", "Stop program that is not responding"}},
-	{[]string{"comot", "file"}, CommandEntry{"rm", "Remove/delete a file (comot)"}},
-	{[]string{"comot", "folder"}, CommandEntry{"rm -rf", "Remove a folder"}},
-	{[]string{"clear", "file"}, CommandEntry{"rm", "Delete a file"}},
-	{[]string{"clear", "folder"}, CommandEntry{"rm -rf", "Delete a folder"}},
-	{[]string{"send", "file"}, CommandEntry{"scp", "Send file to another device"}},
-	{[]string{"download", "file"}, CommandEntry{"wget", "Download a file"}},
-	{[]string{"download", "something"}, CommandEntry{"wget", "Download a file from the web"}},
-	{[]string{"copy", "file"}, CommandEntry{"cp", "Copy a file"}},
-	{[]string{"move", "file"}, CommandEntry{"mv", "Move a file"}},
-	{[]string{"open", "file"}, CommandEntry{"cat", "Open and view a file"}},
-	{[]string{"see", "file"}, CommandEntry{"cat", "View file contents"}},
-	{[]string{"make", "folder"}, CommandEntry{"mkdir -p", "Create a new folder"}},
-	{[]string{"create", "folder"}, CommandEntry{"mkdir -p", "Create a folder"}},
-	{[]string{"new", "folder"}, CommandEntry{"mkdir -p", "Create a new folder"}},
+	{[]string{"no", "gree"}, CommandEntry{"kill {pid}", "Force-stop app that won't respond"}},
+	{[]string{"e", "no", "gree"}, CommandEntry{"kill {pid}", "Stop program that is not responding"}},
+	{[]string{"comot", "file"}, CommandEntry{"rm {file}", "Remove/delete a file (comot)"}},
+	{[]string{"comot", "folder"}, CommandEntry{"rm -rf {dir}", "Remove a folder"}},
+	{[]string{"clear", "file"}, CommandEntry{"rm {file}", "Delete a file"}},
+	{[]string{"clear", "folder"}, CommandEntry{"rm -rf {dir}", "Delete a folder"}},
+	{[]string{"send", "file"}, CommandEntry{"scp {file} {host}:~/", "Send file to another device"}},
+	{[]string{"ssh", "host"}, CommandEntry{"ssh {host}", "Log in to a remote machine"}},
+	{[]string{"connect", "host"}, CommandEntry{"ssh {host}", "Log in to a remote machine"}},
+	{[]string{"download", "file"}, CommandEntry{"wget {url}", "Download a file"}},
+	{[]string{"download", "url"}, CommandEntry{"wget {url}", "Download a file from a URL"}},
+	{[]string{"download", "something"}, CommandEntry{"wget {url}", "Download a file from the web"}},
+	{[]string{"copy", "file"}, CommandEntry{"cp {file} {dir}", "Copy a file"}},
+	{[]string{"move", "file"}, CommandEntry{"mv {file} {dir}", "Move a file"}},
+	{[]string{"open", "file"}, CommandEntry{"cat {file}", "Open and view a file"}},
+	{[]string{"see", "file"}, CommandEntry{"cat {file}", "View file contents"}},
+	{[]string{"make", "folder"}, CommandEntry{"mkdir -p {dir}", "Create a new folder"}},
+	{[]string{"create", "folder"}, CommandEntry{"mkdir -p {dir}", "Create a folder"}},
+	{[]string{"new", "folder"}, CommandEntry{"mkdir -p {dir}", "Create a new folder"}},
 
 	// coding / schoolwork — Nigerian CS students on Termux
 	{[]string{"install", "python"}, CommandEntry{"pkg install python", "Install Python on Termux"}},
 	{[]string{"install", "git"}, CommandEntry{"pkg install git", "Install Git"}},
 	{[]string{"install", "node"}, CommandEntry{"pkg install nodejs", "Install Node.js"}},
-	{[]string{"clone", "project"}, CommandEntry{"git clone", "Clone a project from GitHub"}},
-	{[]string{"clone", "repo"}, CommandEntry{"git clone", "Clone a repository"}},
+	{[]string{"clone", "project"}, CommandEntry{"git clone {url}", "Clone a project from GitHub"}},
+	{[]string{"clone", "repo"}, CommandEntry{"git clone {url}", "Clone a repository"}},
 	{[]string{"push", "code"}, CommandEntry{"git push", "Push code to GitHub"}},
 	{[]string{"commit", "code"}, CommandEntry{`git commit -m "update"`, "Save/commit your code changes"}},
 	{[]string{"run", "code"}, CommandEntry{"python script.py", "Run your Python code"}},
 	{[]string{"run", "script"}, CommandEntry{"./script.sh", "Run a script"}},
-	{[]string{"lecture", "note"}, CommandEntry{`find . -name "*.pdf"`, "Find lecture notes (PDF)"}},
+	{[]string{"lecture", "note"}, CommandEntry{`find {dir:.} -name {pattern:"*.pdf"}`, "Find lecture notes (PDF)"}},
 	{[]string{"find", "assignment"}, CommandEntry{`find . -name "*assignment*"`, "Find assignment files"}},
 	{[]string{"find", "project"}, CommandEntry{"find . -type d -name '*project*'", "Find project folders"}},
 	{[]string{"update", "packages"}, CommandEntry{"pkg upgrade", "Update installed packages"}},
 	{[]string{"update", "termux"}, CommandEntry{"pkg upgrade", "Update Termux packages"}},
-	{[]string{"make", "executable"}, CommandEntry{"chmod +x", "Make a script runnable"}},
+	{[]string{"make", "executable"}, CommandEntry{"chmod +x {file}", "Make a script runnable"}},
 
 	// more casual English as spoken on Nigerian campuses
 	{[]string{"abeg", "show"}, CommandEntry{"ls -la", "Please show files (abeg show)"}},
-	{[]string{"how", "copy"}, CommandEntry{"cp", "How to copy files"}},
-	{[]string{"how", "delete"}, CommandEntry{"rm", "How to delete a file"}},
-	{[]string{"how", "move"}, CommandEntry{"mv", "How to move files"}},
+	{[]string{"how", "copy"}, CommandEntry{"cp {file} {dir}", "How to copy files"}},
+	{[]string{"how", "delete"}, CommandEntry{"rm {file}", "How to delete a file"}},
+	{[]string{"how", "delete", "directory"}, CommandEntry{"rm -rf {dir}", "How to delete a directory"}},
+	{[]string{"how", "delete", "folder"}, CommandEntry{"rm -rf {dir}", "How to delete a folder"}},
+	{[]string{"how", "move"}, CommandEntry{"mv {file} {dir}", "How to move files"}},
 	{[]string{"how", "install"}, CommandEntry{"pkg install", "How to install a package"}},
 	{[]string{"my", "files"}, CommandEntry{"ls -la", "List your files"}},
 	{[]string{"see", "hidden"}, CommandEntry{"ls -la", "See hidden files"}},
 	{[]string{"battery", "level"}, CommandEntry{"termux-battery-status", "Check battery level on phone"}},
 
 	// slang / indirect phrasing (synonym-expanded)
-	{[]string{"phone", "acting"}, CommandEntry{"kill", "Stop misbehaving app"}},
-	{[]string{"app", "acting"}, CommandEntry{"kill", "Stop misbehaving app"}},
+	{[]string{"phone", "acting"}, CommandEntry{"kill {pid}", "Stop misbehaving app"}},
+	{[]string{"app", "acting"}, CommandEntry{"kill {pid}", "Stop misbehaving app"}},
 	{[]string{"acting", "somehow"}, CommandEntry{"ps aux", "See what is running"}},
 	{[]string{"phone", "lagging"}, CommandEntry{"free -h", "Check memory — phone is lagging"}},
 	{[]string{"storage", "finish"}, CommandEntry{"df -h", "Check storage — may be full"}},
@@ -152,40 +160,23 @@ var PhraseCatalog = []phraseRule{
 
 // MatchPhrase finds the best phrase-rule match for conversational input.
 func MatchPhrase(input string) (CommandEntry, bool) {
-	set := phraseTokenSet(input)
-	bestScore := 0
-	var best CommandEntry
-	for _, rule := range PhraseCatalog {
-		if !phraseTermsMatch(set, rule.terms) {
-			continue
-		}
-		score := len(rule.terms) * 10
-		for _, term := range rule.terms {
-			if set[term] {
-				score += 5
-			}
-		}
-		// Deprioritize ultra-common words that appear in many sentences
-		for _, term := range rule.terms {
-			if term == "in" || term == "the" {
-				score -= 4
-			}
-		}
-		if score > bestScore {
-			bestScore = score
-			best = rule.entry
-		}
+	ranked := RankPhrases(input)
+	if len(ranked) == 0 {
+		return CommandEntry{}, false
 	}
-	return best, bestScore > 0
+	return ranked[0].Entry, true
 }
 
 // phraseTokenSet keeps question words that conversationalStopwords would drop.
 func phraseTokenSet(input string) map[string]bool {
 	raw := stringsFieldsLower(input)
 	set := make(map[string]bool, len(raw)*2)
+	prev := ""
 	for _, t := range raw {
 		t = trimPunct(t)
-		if t == "" {
+		folder := isFolderArg(prev, t)
+		prev = t
+		if t == "" || folder {
 			continue
 		}
 		stemmed := Stem(t)
@@ -194,6 +185,9 @@ func phraseTokenSet(input string) map[string]bool {
 		if v, ok := VerbAliases[stemmed]; ok {
 			set[v] = true
 		}
+		for _, n := range argNouns(t) {
+			set[n] = true
+		}
 	}
 	applyPidginAliases(set)
 	return set
@@ -216,3 +210,17 @@ func stringsFieldsLower(s string) []string {
 func trimPunct(s string) string {
 	return strings.Trim(s, "?!.,;:\"'()")
 }
+
+// CatalogCommands returns every built-in entry: verb-noun pairs, then phrase rules.
+func CatalogCommands() []CommandEntry {
+	var out []CommandEntry
+	for _, nouns := range VerbNounCatalog {
+		for _, e := range nouns {
+			out = append(out, e)
+		}
+	}
+	for _, rule := range PhraseCatalog {
+		out = append(out, rule.entry)
+	}
+	return out
+}
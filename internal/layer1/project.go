@@ -0,0 +1,186 @@
+package layer1
+
+import (
+	"clio/internal/project"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Project chores ("run tests", "install dependencies") mean a different
+// command in every ecosystem. projectTasks holds each chore's command keyed
+// like project.Context.Keys: lock-file tool, project kind, then make.
+
+// projectTask is one chore and its command per project key.
+type projectTask struct {
+	desc string // %s is the project kind or tool, e.g. "Go"
+	cmds map[string]string
+}
+
+var projectTasks = map[string]projectTask{
+	"test": {"Run the %s tests", map[string]string{
+		"go": "go test ./...", "node": "npm test", "yarn": "yarn test", "pnpm": "pnpm test", "bun": "bun test",
+		"python": "python -m pytest", "poetry": "poetry run pytest", "uv": "uv run pytest",
+		"php": "vendor/bin/phpunit", "rust": "cargo test", "make": "make test",
+	}},
+	"build": {"Build the %s project", map[string]string{
+		"go": "go build ./...", "node": "npm run build", "yarn": "yarn build", "pnpm": "pnpm build", "bun": "bun run build",
+		"python": "python -m build", "poetry": "poetry build", "uv": "uv build",
+		"rust": "cargo build", "make": "make",
+	}},
+	"run": {"Run the %s project", map[string]string{
+		"go": "go run .", "node": "npm start", "yarn": "yarn start", "pnpm": "pnpm start", "bun": "bun run start",
+		"rust": "cargo run", "make": "make run",
+	}},
+	"deps": {"Install the %s dependencies", map[string]string{
+		"go": "go mod download", "node": "npm install", "yarn": "yarn install", "pnpm": "pnpm install", "bun": "bun install",
+		"python": "pip install -r requirements.txt", "poetry": "poetry install", "uv": "uv sync",
+		"php": "composer install", "rust": "cargo fetch",
+	}},
+	"list-deps": {"List the %s dependencies", map[string]string{
+		"go": "go list -m all", "node": "npm list", "yarn": "yarn list", "pnpm": "pnpm list", "bun": "bun pm ls",
+		"python": "pip list", "poetry": "poetry show", "uv": "uv pip list",
+		"php": "composer show", "rust": "cargo tree --depth 1",
+	}},
+	"update-deps": {"Update the %s dependencies", map[string]string{
+		"go": "go get -u ./...", "node": "npm update", "yarn": "yarn upgrade", "pnpm": "pnpm update", "bun": "bun update",
+		"python": "pip install -U -r requirements.txt", "poetry": "poetry update", "uv": "uv lock --upgrade",
+		"php": "composer update", "rust": "cargo update",
+	}},
+	"lint": {"Lint the %s code", map[string]string{
+		"go": "go vet ./...", "node": "npm run lint", "yarn": "yarn lint", "pnpm": "pnpm lint", "bun": "bun run lint",
+		"python": "ruff check .", "rust": "cargo clippy", "make": "make lint",
+	}},
+	"format": {"Format the %s code", map[string]string{
+		"go": "gofmt -w .", "node": "npx prettier --write .", "python": "black .", "rust": "cargo fmt",
+	}},
+	"clean": {"Remove %s build output", map[string]string{
+		"go": "go clean", "rust": "cargo clean", "make": "make clean",
+	}},
+}
+
+// ProjectCommands returns every project chore's command for every project key.
+func ProjectCommands() []CommandEntry {
+	var out []CommandEntry
+	for _, t := range projectTasks {
+		for key, cmd := range t.cmds {
+			out = append(out, CommandEntry{cmd, fmt.Sprintf(t.desc, project.Name(key))})
+		}
+	}
+	return out
+}
+
+// projectPhrases map wording to a chore. Matching works like PhraseCatalog.
+var projectPhrases = []struct {
+	terms []string
+	task  string
+}{
+	{[]string{"run", "test"}, "test"},
+	{[]string{"unit", "test"}, "test"},
+	{[]string{"test", "suite"}, "test"},
+	{[]string{"test", "project"}, "test"},
+	{[]string{"test", "code"}, "test"},
+	{[]string{"build", "project"}, "build"},
+	{[]string{"build", "code"}, "build"},
+	{[]string{"build", "app"}, "build"},
+	{[]string{"compile", "project"}, "build"},
+	{[]string{"compile", "code"}, "build"},
+	{[]string{"run", "project"}, "run"},
+	{[]string{"run", "app"}, "run"},
+	{[]string{"run", "code"}, "run"},
+	{[]string{"run", "server"}, "run"},
+	{[]string{"install", "dependencies"}, "deps"},
+	{[]string{"install", "dependency"}, "deps"},
+	{[]string{"install", "deps"}, "deps"},
+	{[]string{"install", "requirements"}, "deps"},
+	{[]string{"download", "dependencies"}, "deps"},
+	{[]string{"list", "dependencies"}, "list-deps"},
+	{[]string{"list", "dependency"}, "list-deps"},
+	{[]string{"list", "deps"}, "list-deps"},
+	{[]string{"list", "module"}, "list-deps"},
+	{[]string{"update", "dependencies"}, "update-deps"},
+	{[]string{"update", "deps"}, "update-deps"},
+	{[]string{"upgrade", "dependencies"}, "update-deps"},
+	{[]string{"upgrade", "deps"}, "update-deps"},
+	{[]string{"lint"}, "lint"},
+	{[]string{"linter"}, "lint"},
+	{[]string{"format", "code"}, "format"},
+	{[]string{"format", "project"}, "format"},
+	{[]string{"clean", "build"}, "clean"},
+	{[]string{"clean", "project"}, "clean"},
+}
+
+// projectVariants are catalog commands that only fit one ecosystem; inside a
+// project they become that project's command for the same chore.
+var projectVariants = map[string]string{
+	"npm list":         "list-deps",
+	"python script.py": "run",
+}
+
+// projectBonus lifts project rules above generic phrase rules with the same wording.
+const projectBonus = 10
+
+// ProjectTask returns the command for a chore in ctx.
+func ProjectTask(task string, ctx project.Context) (CommandEntry, bool) {
+	t, ok := projectTasks[task]
+	if !ok {
+		return CommandEntry{}, false
+	}
+	for _, key := range ctx.Keys() {
+		if cmd, ok := t.cmds[key]; ok {
+			return CommandEntry{cmd, fmt.Sprintf(t.desc, project.Name(key))}, true
+		}
+	}
+	return CommandEntry{}, false
+}
+
+// RankProject returns project rules matching input that ctx has a command
+// for, best first. Outside a project it returns nothing.
+func RankProject(input string, ctx project.Context) []Scored {
+	if len(ctx.Kinds) == 0 {
+		return nil
+	}
+	set := phraseTokenSet(input)
+	var out []Scored
+	seen := make(map[string]bool)
+	for _, rule := range projectPhrases {
+		if seen[rule.task] || !phraseTermsMatch(set, rule.terms) {
+			continue
+		}
+		entry, ok := ProjectTask(rule.task, ctx)
+		if !ok {
+			continue
+		}
+		seen[rule.task] = true
+		score := len(rule.terms)*15 + projectBonus
+		out = append(out, Scored{
+			Entry:  entry,
+			Score:  score,
+			Reason: fmt.Sprintf("project rule [%s] for %s score %d", strings.Join(rule.terms, " "), strings.Join(ctx.Markers, ", "), score),
+		})
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Score > out[j].Score })
+	return out
+}
+
+// MatchProject finds the best project rule for input in ctx.
+func MatchProject(input string, ctx project.Context) (CommandEntry, bool) {
+	ranked := RankProject(input, ctx)
+	if len(ranked) == 0 {
+		return CommandEntry{}, false
+	}
+	return ranked[0].Entry, true
+}
+
+// ForProject swaps an ecosystem-specific catalog command ("npm list") for
+// ctx's command for the same chore. Other entries are returned unchanged.
+func (e CommandEntry) ForProject(ctx project.Context) CommandEntry {
+	task, ok := projectVariants[e.Cmd]
+	if !ok {
+		return e
+	}
+	if entry, ok := ProjectTask(task, ctx); ok {
+		return entry
+	}
+	return e
+}
@@ -0,0 +1,70 @@
+package layer1
+
+import (
+	"clio/internal/project"
+	"testing"
+)
+
+var (
+	goProject   = project.Context{Kinds: []string{"go", "make"}, Markers: []string{"go.mod", "Makefile"}}
+	makeProject = project.Context{Kinds: []string{"make"}, Markers: []string{"Makefile"}}
+	pnpmProject = project.Context{Kinds: []string{"node"}, Tools: []string{"pnpm"}, Markers: []string{"package.json"}}
+	phpProject  = project.Context{Kinds: []string{"php"}, Markers: []string{"composer.json"}}
+	rustProject = project.Context{Kinds: []string{"rust"}, Markers: []string{"Cargo.toml"}}
+)
+
+func TestMatchProject(t *testing.T) {
+	cases := []struct {
+		query string
+		ctx   project.Context
+		want  string // "" means no project rule applies
+	}{
+		{"run the tests", goProject, "go test ./..."},
+		{"run the tests", makeProject, "make test"},
+		{"run unit tests", rustProject, "cargo test"},
+		{"install dependencies", pnpmProject, "pnpm install"},
+		{"install the deps", phpProject, "composer install"},
+		{"show dependencies", rustProject, "cargo tree --depth 1"},
+		{"update dependencies", phpProject, "composer update"},
+		{"build the project", goProject, "go build ./..."},
+		{"build the project", makeProject, "make"},
+		{"start the app", pnpmProject, "pnpm start"},
+		{"clean build", goProject, "go clean"},
+		{"format code", phpProject, ""},          // no formatter for PHP
+		{"run the tests", project.Context{}, ""}, // not in a project
+		{"check disk space", goProject, ""},
+	}
+	for _, c := range cases {
+		entry, ok := MatchProject(c.query, c.ctx)
+		if c.want == "" {
+			if ok {
+				t.Errorf("MatchProject(%q, %v) = %q, want no match", c.query, c.ctx.Kinds, entry.Cmd)
+			}
+			continue
+		}
+		if !ok || entry.Cmd != c.want {
+			t.Errorf("MatchProject(%q, %v) = %q, %v; want %q", c.query, c.ctx.Kinds, entry.Cmd, ok, c.want)
+		}
+	}
+}
+
+func TestCommandEntryForProject(t *testing.T) {
+	cases := []struct {
+		cmd  string
+		ctx  project.Context
+		want string
+	}{
+		{"npm list", goProject, "go list -m all"},
+		{"npm list", pnpmProject, "pnpm list"},
+		{"npm list", makeProject, "npm list"}, // make has no dependency list
+		{"npm list", project.Context{}, "npm list"},
+		{"python script.py", rustProject, "cargo run"},
+		{"ls -F", goProject, "ls -F"},
+	}
+	for _, c := range cases {
+		got := CommandEntry{Cmd: c.cmd}.ForProject(c.ctx)
+		if got.Cmd != c.want {
+			t.Errorf("ForProject(%q, %v) = %q, want %q", c.cmd, c.ctx.Kinds, got.Cmd, c.want)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package layer1
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Scored is a catalog or phrase entry together with the score that matched it.
+type Scored struct {
+	Entry  CommandEntry
+	Score  int
+	Reason string // human-readable explanation of why it matched
+	User   bool   // the rule came from a commands.yaml file
+}
+
+// RankPhrases returns every phrase rule matching input, best first.
+func RankPhrases(input string) []Scored {
+	return rankPhraseSet(phraseTokenSet(input), true)
+}
+
+// RankCatalog returns every verb-noun entry scoring at least the match threshold, best first.
+// Noun-only hints ("memory usage") are included when no verb-noun pair reaches it.
+func RankCatalog(input string) []Scored {
+	set := TokenSet(input)
+	if len(set) == 0 {
+		return nil
+	}
+	ranked := rankCatalogSet(set, true)
+	if len(ranked) == 0 {
+		if entry, ok := matchNounOnly(set); ok {
+			ranked = append(ranked, Scored{Entry: entry, Score: 12, Reason: "noun-only hint"})
+		}
+	}
+	return ranked
+}
+
+// RankFuzzy retries phrase and catalog ranking after slang and typo expansion.
+// Reasons name each corrected token and its edit distance.
+func RankFuzzy(input string) []Scored {
+	set := TokenSet(input)
+	applySlangExpansions(set)
+	applyPidginAliases(set)
+	corrections := fuzzyCorrections(set)
+	for w := range corrections {
+		set[w] = true
+	}
+
+	note := ""
+	if len(corrections) > 0 {
+		fixes := make([]string, 0, len(corrections))
+		for word, token := range corrections {
+			fixes = append(fixes, fmt.Sprintf("%s→%s (distance 1)", token, word))
+		}
+		sort.Strings(fixes)
+		note = "; fuzzy " + strings.Join(fixes, ", ")
+	}
+
+	ranked := rankPhraseSet(set, false)
+	for i := range ranked {
+		ranked[i].Reason += note
+	}
+	for _, s := range rankCatalogSet(set, false) {
+		s.Reason += note
+		ranked = append(ranked, s)
+	}
+	return ranked
+}
+
+func rankPhraseSet(set map[string]bool, penalizeCommon bool) []Scored {
+	var out []Scored
+	for i, rule := range PhraseCatalog {
+		if !phraseTermsMatch(set, rule.terms) {
+			continue
+		}
+		score := len(rule.terms) * 10
+		for _, term := range rule.terms {
+			if set[term] {
+				score += 5
+			}
+		}
+		// Deprioritize ultra-common words that appear in many sentences
+		if penalizeCommon {
+			for _, term := range rule.terms {
+				if term == "in" || term == "the" {
+					score -= 4
+				}
+			}
+		}
+		if score <= 0 {
+			continue
+		}
+		kind := "phrase rule"
+		user := i < userPhraseCount
+		if user {
+			score += userRuleBonus
+			kind = "user phrase rule"
+		}
+		out = append(out, Scored{
+			Entry:  rule.entry,
+			Score:  score,
+			Reason: fmt.Sprintf("%s [%s] score %d", kind, strings.Join(rule.terms, " "), score),
+			User:   user,
+		})
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Score > out[j].Score })
+	return out
+}
+
+func rankCatalogSet(set map[string]bool, textBonus bool) []Scored {
+	var out []Scored
+	for verb, nouns := range VerbNounCatalog {
+		verbScore := scoreVerb(verb, set)
+		if verbScore == 0 {
+			continue
+		}
+		for noun, entry := range nouns {
+			nounScore := scoreNoun(noun, set)
+			if nounScore == 0 {
+				continue
+			}
+			score := verbScore + nounScore
+			if noun == "file" && hasSpecificNounInSet(set) {
+				score -= 8
+			}
+			if textBonus {
+				if set["text"] && noun == "text" {
+					score += 6
+				}
+				if set["text"] && noun == "file" {
+					score -= 6
+				}
+			}
+			if score < 12 {
+				continue
+			}
+			reason := fmt.Sprintf("verb %q (%d) + noun %q (%d) = %d", verb, verbScore, noun, nounScore, score)
+			if userPairs[verb+" "+noun] {
+				score += userRuleBonus
+				reason = fmt.Sprintf("user verb %q + noun %q = %d", verb, noun, score)
+			}
+			out = append(out, Scored{
+				Entry:  entry,
+				Score:  score,
+				Reason: reason,
+				User:   userPairs[verb+" "+noun],
+			})
+		}
+	}
+	// Map iteration is random; break ties by command so rankings are stable.
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Score != out[j].Score {
+			return out[i].Score > out[j].Score
+		}
+		return out[i].Entry.Cmd < out[j].Entry.Cmd
+	})
+	return out
+}
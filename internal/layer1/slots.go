@@ -0,0 +1,442 @@
+package layer1
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SlotType names the kind of operand a command placeholder expects.
+type SlotType string
+
+const (
+	SlotFile    SlotType = "file"
+	SlotDir     SlotType = "dir"
+	SlotPattern SlotType = "pattern"
+	SlotHost    SlotType = "host"
+	SlotPID     SlotType = "pid"
+	SlotSize    SlotType = "size"
+	SlotURL     SlotType = "url"
+	// SlotValue is an untyped operand, such as tldr's {{username}}.
+	SlotValue SlotType = "value"
+
+	// slotName collects bare names ("folder called projects") usable as file or dir.
+	slotName SlotType = "name"
+)
+
+// Slot is a typed placeholder inside CommandEntry.Cmd, written {file} or {dir:.}
+// where the part after the colon is used when the query supplies no value.
+// tldr-pages placeholders such as {{path/to/file}} are parsed too; their type
+// is inferred from the text, which is kept as Label.
+type Slot struct {
+	Type    SlotType
+	Default string
+	Token   string // placeholder text as it appears in the command
+	Label   string // tldr placeholder text, e.g. "path/to/file"
+}
+
+var (
+	slotRe = regexp.MustCompile(`\{(file|dir|pattern|host|pid|size|url)(?::([^{}]*))?\}`)
+	// anySlotRe also matches tldr's {{...}}, tried first so {{file}} is one slot.
+	anySlotRe = regexp.MustCompile(`\{\{(.+?)\}\}|\{(file|dir|pattern|host|pid|size|url)(?::([^{}]*))?\}`)
+)
+
+// Slots returns the typed placeholders in the entry's command, in order.
+func (e CommandEntry) Slots() []Slot {
+	return ParseSlots(e.Cmd)
+}
+
+// ParseSlots returns the typed placeholders in a command string, in order.
+func ParseSlots(cmd string) []Slot {
+	matches := anySlotRe.FindAllStringSubmatch(cmd, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+	out := make([]Slot, 0, len(matches))
+	for _, m := range matches {
+		if m[1] != "" {
+			out = append(out, tldrSlot(m[0], m[1]))
+			continue
+		}
+		out = append(out, Slot{Type: SlotType(m[2]), Default: m[3], Token: m[0]})
+	}
+	return out
+}
+
+var (
+	tldrOptionRe = regexp.MustCompile(`^\[(-[^|\]]*)(?:\|[^\]]*)?\]$`)
+	tldrNumberRe = regexp.MustCompile(`^\d+(\.\d+)?$`)
+)
+
+// tldrSlot infers a slot type from a tldr placeholder: {{path/to/directory}}
+// is a dir, {{https://example.com}} a url, {{[-r|--recursive]}} an option
+// that defaults to its short form and {{10}} a number that defaults to itself.
+func tldrSlot(token, label string) Slot {
+	s := Slot{Type: SlotValue, Token: token, Label: label}
+	l := strings.ToLower(label)
+	switch m := tldrOptionRe.FindStringSubmatch(label); {
+	case m != nil:
+		s.Default = m[1]
+	case tldrNumberRe.MatchString(label):
+		s.Default = label
+	case strings.Contains(l, "url") || strings.Contains(l, "://"):
+		s.Type = SlotURL
+	case strings.Contains(l, "dir") || strings.Contains(l, "folder"):
+		s.Type = SlotDir
+	case strings.Contains(l, "path/to") || strings.Contains(l, "file"):
+		s.Type = SlotFile
+	case strings.Contains(l, "pattern") || strings.Contains(l, "regex") || strings.Contains(l, "glob") ||
+		strings.Contains(l, "search"):
+		s.Type = SlotPattern
+	case strings.Contains(l, "host") || strings.Contains(l, "remote") || strings.Contains(l, "server") ||
+		strings.Contains(l, "ip_address"):
+		s.Type = SlotHost
+	case strings.Contains(l, "pid"):
+		s.Type = SlotPID
+	case strings.Contains(l, "size"):
+		s.Type = SlotSize
+	}
+	return s
+}
+
+// Hint is a short example shown when the user is asked to fill the slot.
+func (s Slot) Hint() string {
+	if s.Label != "" {
+		return "e.g. " + s.Label
+	}
+	switch s.Type {
+	case SlotFile:
+		return "e.g. notes.txt"
+	case SlotDir:
+		return "e.g. Documents/"
+	case SlotPattern:
+		return `e.g. "*.pdf"`
+	case SlotHost:
+		return "e.g. user@192.168.1.5"
+	case SlotPID:
+		return "find it with: ps aux"
+	case SlotSize:
+		return "e.g. +100M"
+	case SlotURL:
+		return "e.g. https://example.com/file.zip"
+	}
+	return ""
+}
+
+// Args holds operands pulled out of a natural-language query, grouped by slot type.
+type Args map[SlotType][]string
+
+// slotFallbacks lists which argument pools may fill a slot, most specific first.
+var slotFallbacks = map[SlotType][]SlotType{
+	SlotFile:    {SlotFile, slotName},
+	SlotDir:     {SlotDir, slotName},
+	SlotPattern: {SlotPattern, SlotFile, slotName},
+	SlotHost:    {SlotHost},
+	SlotPID:     {SlotPID},
+	SlotSize:    {SlotSize},
+	SlotURL:     {SlotURL},
+}
+
+// FillSlots substitutes extracted arguments (or slot defaults) into cmd.
+// It returns the resulting command and any slots that are still unfilled.
+func FillSlots(cmd string, args Args) (string, []Slot) {
+	slots := ParseSlots(cmd)
+	if len(slots) == 0 {
+		return cmd, nil
+	}
+
+	used := make(map[string]bool)
+	var missing []Slot
+	for _, slot := range slots {
+		value := ""
+		for _, pool := range slotFallbacks[slot.Type] {
+			for _, v := range args[pool] {
+				if !used[v] {
+					value = v
+					break
+				}
+			}
+			if value != "" {
+				break
+			}
+		}
+		switch {
+		case value != "":
+			used[value] = true
+			cmd = FillSlot(cmd, slot, value)
+		case slot.Default != "":
+			cmd = strings.Replace(cmd, slot.Token, slot.Default, 1)
+		default:
+			missing = append(missing, slot)
+		}
+	}
+	return cmd, missing
+}
+
+// FillSlot replaces the first occurrence of slot with a shell-quoted value.
+func FillSlot(cmd string, slot Slot, value string) string {
+	return strings.Replace(cmd, slot.Token, quoteSlotValue(slot.Type, value), 1)
+}
+
+// quoteSlotValue single-quotes v unless it is a plain word, so nothing in a
+// value is ever expanded by the shell. Quotes the user typed around the value
+// are taken as part of asking for a literal and dropped first; patterns are
+// always quoted so their globs reach the command.
+func quoteSlotValue(t SlotType, v string) string {
+	if len(v) >= 2 && (v[0] == '"' || v[0] == '\'') && v[len(v)-1] == v[0] {
+		v = v[1 : len(v)-1]
+	}
+	quote := t == SlotPattern || v == ""
+	for _, r := range v {
+		if !isShellSafe(r) {
+			quote = true
+			break
+		}
+	}
+	if !quote {
+		return v
+	}
+	return "'" + strings.ReplaceAll(v, "'", `'\''`) + "'"
+}
+
+func isShellSafe(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return true
+	}
+	return strings.ContainsRune("_./~:@%+=,-", r)
+}
+
+var (
+	quotedRe = regexp.MustCompile("\"([^\"]+)\"|'([^']+)'|`([^`]+)`")
+	ipv4Re   = regexp.MustCompile(`^\d{1,3}(\.\d{1,3}){3}$`)
+	sizeRe   = regexp.MustCompile(`^(?i)(\d+(?:\.\d+)?)(b|k|kb|kib|m|mb|mib|g|gb|gib)$`)
+	unitRe   = regexp.MustCompile(`^(?i)(b|bytes?|k|kb|kib|m|mb|mib|megs?|g|gb|gib|gigs?)$`)
+	fileRe   = regexp.MustCompile(`^[\w~][\w.~+-]*\.[A-Za-z0-9]{1,5}$`)
+)
+
+// hostTLDs separate domains (google.com) from file names (notes.txt).
+var hostTLDs = map[string]bool{
+	"com": true, "org": true, "net": true, "io": true, "dev": true, "ng": true,
+	"co": true, "uk": true, "edu": true, "gov": true, "app": true, "me": true,
+	"info": true, "local": true, "lan": true,
+}
+
+// fileExtensions are recognised in "pdf files" style patterns.
+var fileExtensions = map[string]bool{
+	"pdf": true, "txt": true, "go": true, "py": true, "js": true, "ts": true,
+	"jpg": true, "jpeg": true, "png": true, "gif": true, "mp3": true, "mp4": true,
+	"zip": true, "log": true, "md": true, "csv": true, "json": true, "yaml": true,
+	"yml": true, "sh": true, "html": true, "css": true, "php": true, "java": true,
+	"c": true, "doc": true, "docx": true, "xls": true, "xlsx": true, "apk": true,
+}
+
+// knownFolders are directory names recognised without a trailing slash.
+var knownFolders = map[string]bool{
+	"documents": true, "downloads": true, "download": true, "desktop": true,
+	"music": true, "pictures": true, "videos": true, "movies": true, "dcim": true,
+	"storage": true, "home": true, "tmp": true,
+}
+
+var (
+	dirPrepositions = map[string]bool{"in": true, "into": true, "inside": true, "to": true, "under": true, "from": true}
+	nameMarkers     = map[string]bool{"called": true, "named": true}
+	pidHints        = map[string]bool{"pid": true, "process": true, "id": true, "kill": true, "stop": true}
+	smallerHints    = map[string]bool{"smaller": true, "less": true, "under": true, "below": true, "tiny": true}
+)
+
+// ExtractArgs pulls file names, paths, globs, sizes, hosts, URLs and PIDs from a query.
+func ExtractArgs(input string) Args {
+	args := make(Args)
+	add := func(t SlotType, v string) {
+		for _, existing := range args[t] {
+			if existing == v {
+				return
+			}
+		}
+		args[t] = append(args[t], v)
+	}
+
+	// Quoted text is taken literally as a search pattern.
+	for _, m := range quotedRe.FindAllStringSubmatch(input, -1) {
+		for _, g := range m[1:] {
+			if g != "" {
+				add(SlotPattern, g)
+			}
+		}
+	}
+	input = quotedRe.ReplaceAllString(input, " ")
+
+	raw := strings.Fields(input)
+	tokens := make([]string, len(raw))
+	lower := make([]string, len(raw))
+	for i, t := range raw {
+		tokens[i] = strings.TrimRight(strings.Trim(t, "!,;:()"), ".?")
+		lower[i] = strings.ToLower(tokens[i])
+	}
+
+	hasPIDHint := false
+	for _, l := range lower {
+		if pidHints[l] || pidHints[Stem(l)] {
+			hasPIDHint = true
+			break
+		}
+	}
+
+	for i, tok := range tokens {
+		if tok == "" {
+			continue
+		}
+		low := lower[i]
+		prev, next := "", ""
+		if i > 0 {
+			prev = lower[i-1]
+		}
+		if i+1 < len(lower) {
+			next = lower[i+1]
+		}
+
+		switch {
+		case strings.Contains(low, "://"):
+			add(SlotURL, tok)
+		case nameMarkers[prev]:
+			add(slotName, tok)
+		case strings.Contains(tok, "@") && !strings.HasPrefix(tok, "@"):
+			add(SlotHost, tok)
+		case ipv4Re.MatchString(tok) || low == "localhost":
+			add(SlotHost, tok)
+		case sizeRe.MatchString(tok):
+			add(SlotSize, findSize(tok, sizeSign(lower[:i])))
+		case isNumber(tok) && unitRe.MatchString(next):
+			add(SlotSize, findSize(tok+next, sizeSign(lower[:i])))
+		case isNumber(tok):
+			if hasPIDHint {
+				add(SlotPID, tok)
+			}
+		case strings.ContainsAny(tok, "*?["):
+			add(SlotPattern, tok)
+		case strings.HasPrefix(tok, ".") && fileExtensions[strings.TrimPrefix(low, ".")]:
+			add(SlotPattern, "*"+low)
+		case tok == "." || tok == ".." || strings.HasPrefix(tok, "~") || strings.Contains(tok, "/"):
+			if !strings.HasSuffix(tok, "/") && fileRe.MatchString(lastPathElem(tok)) {
+				add(SlotFile, tok)
+			} else {
+				add(SlotDir, tok)
+			}
+		case fileRe.MatchString(tok):
+			ext := low[strings.LastIndex(low, ".")+1:]
+			if hostTLDs[ext] && !fileExtensions[ext] {
+				add(SlotHost, tok)
+			} else {
+				add(SlotFile, tok)
+			}
+		case fileExtensions[low] && (next == "file" || next == "files"):
+			add(SlotPattern, "*."+low)
+		case dirPrepositions[prev] && (knownFolders[low] || isCapitalized(tok)):
+			add(SlotDir, tok)
+		}
+	}
+	return args
+}
+
+func lastPathElem(p string) string {
+	if i := strings.LastIndex(p, "/"); i >= 0 {
+		return p[i+1:]
+	}
+	return p
+}
+
+func isNumber(s string) bool {
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
+
+func isCapitalized(s string) bool {
+	return s != "" && s[0] >= 'A' && s[0] <= 'Z'
+}
+
+// sizeSign returns "-" when the words before a size ask for smaller files.
+func sizeSign(before []string) string {
+	for _, w := range before {
+		if smallerHints[w] {
+			return "-"
+		}
+	}
+	return "+"
+}
+
+// findSize converts "100mb" or "1.5GB" into find(1) -size syntax such as +100M.
+func findSize(s, sign string) string {
+	m := sizeRe.FindStringSubmatch(normalizeUnit(s))
+	if m == nil {
+		return sign + s
+	}
+	n, _ := strconv.ParseFloat(m[1], 64)
+	unit := strings.ToUpper(m[2][:1])
+	units := []string{"B", "K", "M", "G"}
+	idx := 0
+	for i, u := range units {
+		if u == unit {
+			idx = i
+		}
+	}
+	// find only accepts whole numbers; step down a unit for fractions.
+	for n != float64(int64(n)) && idx > 0 {
+		n *= 1024
+		idx--
+	}
+	suffix := map[string]string{"B": "c", "K": "k", "M": "M", "G": "G"}[units[idx]]
+	return sign + strconv.FormatInt(int64(n), 10) + suffix
+}
+
+func normalizeUnit(s string) string {
+	low := strings.ToLower(s)
+	for _, pair := range [][2]string{{"bytes", "b"}, {"byte", "b"}, {"megs", "mb"}, {"meg", "mb"}, {"gigs", "gb"}, {"gig", "gb"}} {
+		if strings.HasSuffix(low, pair[0]) {
+			return strings.TrimSuffix(low, pair[0]) + pair[1]
+		}
+	}
+	return low
+}
+
+// fileNouns derives catalog nouns from a file-name token so "extract backup.tar.gz"
+// scores like "extract tar file".
+func fileNouns(tok string) []string {
+	if !fileRe.MatchString(tok) {
+		return nil
+	}
+	ext := tok[strings.LastIndex(tok, ".")+1:]
+	if hostTLDs[ext] && !fileExtensions[ext] {
+		return nil
+	}
+	nouns := []string{"file"}
+	switch {
+	case strings.HasSuffix(tok, ".tar.gz"), ext == "tgz", ext == "tar":
+		nouns = append(nouns, "tar", "archive")
+	case ext == "zip":
+		nouns = append(nouns, "zip", "archive")
+	case ext == "pdf", ext == "log":
+		nouns = append(nouns, ext)
+	}
+	return nouns
+}
+
+// argNouns derives catalog nouns from an argument token: file names as in
+// fileNouns, "url" for a URL and "host" for user@host or an IPv4 address, so
+// "download https://…" and "ssh into user@10.0.0.5" reach their rules.
+func argNouns(tok string) []string {
+	switch {
+	case strings.Contains(tok, "://"):
+		return []string{"url"}
+	case strings.Contains(tok, "@") && !strings.HasPrefix(tok, "@"), ipv4Re.MatchString(tok):
+		return []string{"host"}
+	}
+	return fileNouns(tok)
+}
+
+// isFolderArg reports whether tok, preceded by prev, names a folder operand
+// ("to Downloads") rather than a word of the request, so it is not matched as
+// a verb or noun.
+func isFolderArg(prev, tok string) bool {
+	return dirPrepositions[prev] && knownFolders[tok]
+}
@@ -0,0 +1,141 @@
+package layer1
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSlots(t *testing.T) {
+	slots := ParseSlots("find {dir:.} -name {pattern}")
+	want := []Slot{
+		{Type: SlotDir, Default: ".", Token: "{dir:.}"},
+		{Type: SlotPattern, Token: "{pattern}"},
+	}
+	if !reflect.DeepEqual(slots, want) {
+		t.Fatalf("ParseSlots = %+v, want %+v", slots, want)
+	}
+	if got := ParseSlots("find . -exec rm {} +"); got != nil {
+		t.Fatalf("find -exec braces parsed as slots: %+v", got)
+	}
+}
+
+func TestExtractArgs(t *testing.T) {
+	cases := []struct {
+		input string
+		kind  SlotType
+		want  []string
+	}{
+		{"extract backup.tar.gz", SlotFile, []string{"backup.tar.gz"}},
+		{"find notes.pdf in Documents", SlotDir, []string{"Documents"}},
+		{"find notes.pdf in Documents", SlotFile, []string{"notes.pdf"}},
+		{"copy report.txt to ~/backup/", SlotDir, []string{"~/backup/"}},
+		{"find *.go files", SlotPattern, []string{"*.go"}},
+		{"list all pdf files", SlotPattern, []string{"*.pdf"}},
+		{"search for 'TODO' in code", SlotPattern, []string{"TODO"}},
+		{"find files bigger than 500mb", SlotSize, []string{"+500M"}},
+		{"find files smaller than 10 kb", SlotSize, []string{"-10k"}},
+		{"find files over 1.5GB", SlotSize, []string{"+1536M"}},
+		{"ping google.com", SlotHost, []string{"google.com"}},
+		{"ssh to admin@192.168.1.5", SlotHost, []string{"admin@192.168.1.5"}},
+		{"kill process 4312", SlotPID, []string{"4312"}},
+		{"download https://example.com/a.zip", SlotURL, []string{"https://example.com/a.zip"}},
+	}
+	for _, c := range cases {
+		got := ExtractArgs(c.input)[c.kind]
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("ExtractArgs(%q)[%s] = %v, want %v", c.input, c.kind, got, c.want)
+		}
+	}
+}
+
+func TestFillSlots(t *testing.T) {
+	cases := []struct {
+		cmd     string
+		input   string
+		want    string
+		missing int
+	}{
+		{"tar -xzvf {file}", "extract backup.tar.gz", "tar -xzvf backup.tar.gz", 0},
+		{"find {dir:.} -name {pattern}", "find notes.pdf in Documents", "find Documents -name 'notes.pdf'", 0},
+		{"find {dir:.} -type f -size {size:+100M}", "find large files", "find . -type f -size +100M", 0},
+		{"cp {file} {dir}", "copy my notes.txt to Downloads", "cp notes.txt Downloads", 0},
+		{"mkdir -p {dir}", "create a folder called projects", "mkdir -p projects", 0},
+		{"rm {file}", "delete a file", "rm {file}", 1},
+		{"mv {file} {file}", "rename a.txt to b.txt", "mv a.txt b.txt", 0},
+	}
+	for _, c := range cases {
+		got, missing := FillSlots(c.cmd, ExtractArgs(c.input))
+		if got != c.want || len(missing) != c.missing {
+			t.Errorf("FillSlots(%q, %q) = %q (%d missing), want %q (%d missing)",
+				c.cmd, c.input, got, len(missing), c.want, c.missing)
+		}
+	}
+}
+
+func TestFillSlotQuotesUnsafeValues(t *testing.T) {
+	slot := Slot{Type: SlotFile, Token: "{file}"}
+	cases := []struct{ value, want string }{
+		{"my notes.txt", "cat 'my notes.txt'"},
+		{"$(rm -rf ~)", `cat '$(rm -rf ~)'`},
+		{"`id`.txt", "cat '`id`.txt'"},
+		{`"$(id)"`, `cat '$(id)'`},
+		{"'a'$(id)'b'", `cat 'a'\''$(id)'\''b'`},
+		{"it's.txt", `cat 'it'\''s.txt'`},
+	}
+	for _, c := range cases {
+		if got := FillSlot("cat {file}", slot, c.value); got != c.want {
+			t.Errorf("FillSlot(%q) = %q, want %q", c.value, got, c.want)
+		}
+	}
+	pattern := Slot{Type: SlotPattern, Token: "{pattern}"}
+	if got := FillSlot("grep {pattern}", pattern, `"$HOME"`); got != `grep '$HOME'` {
+		t.Errorf("FillSlot(pattern) = %q", got)
+	}
+}
+
+func TestParseTLDRSlots(t *testing.T) {
+	cases := []struct {
+		label string
+		want  Slot
+	}{
+		{"path/to/file", Slot{Type: SlotFile}},
+		{"path/to/directory", Slot{Type: SlotDir}},
+		{"https://example.com/file.zip", Slot{Type: SlotURL}},
+		{"search_pattern", Slot{Type: SlotPattern}},
+		{"remote_host", Slot{Type: SlotHost}},
+		{"pid", Slot{Type: SlotPID}},
+		{"username", Slot{Type: SlotValue}},
+		{"[-r|--recursive]", Slot{Type: SlotValue, Default: "-r"}},
+		{"10", Slot{Type: SlotValue, Default: "10"}},
+	}
+	for _, c := range cases {
+		token := "{{" + c.label + "}}"
+		c.want.Token, c.want.Label = token, c.label
+		got := ParseSlots("cmd " + token)
+		if len(got) != 1 || got[0] != c.want {
+			t.Errorf("ParseSlots(%q) = %+v, want %+v", token, got, c.want)
+		}
+	}
+}
+
+func TestFillTLDRSlots(t *testing.T) {
+	cases := []struct {
+		cmd     string
+		input   string
+		want    string
+		missing int
+	}{
+		{"tar xf {{path/to/source.tar[.gz|.bz2|.xz]}}", "extract backup.tar.gz", "tar xf backup.tar.gz", 0},
+		{"grep {{[-r|--recursive]}} {{search_pattern}} {{path/to/directory}}", "search for 'TODO' in src/",
+			"grep -r 'TODO' src/", 0},
+		{"head {{[-n|--lines]}} {{10}} {{path/to/file}}", "first lines of notes.txt", "head -n 10 notes.txt", 0},
+		{"chown {{user}} {{path/to/file}}", "change owner of a.txt", "chown {{user}} a.txt", 1},
+	}
+	for _, c := range cases {
+		got, missing := FillSlots(c.cmd, ExtractArgs(c.input))
+		if got != c.want || len(missing) != c.missing {
+			t.Errorf("FillSlots(%q, %q) = %q (%d missing), want %q (%d missing)",
+				c.cmd, c.input, got, len(missing), c.want, c.missing)
+		}
+	}
+}
@@ -35,7 +35,7 @@ var VerbNounCatalog = map[string]map[string]CommandEntry{
         "route":     {"ip route", "List network routes"},
         "user":      {"cat /etc/passwd", "List system users"},
         "group":     {"cat /etc/group", "List system groups"},
-        "history":   {"history", "List command history"},
+        "history":   {"cat ~/.bash_history", "List command history"},
         "module":    {"npm list", "List npm modules (if applicable)"}, // Context aware?
         "package":   {"pkg list-installed", "List installed packages (Termux)"},
         "port":      {"netstat -tuln", "List open ports"},
@@ -45,9 +45,9 @@ var VerbNounCatalog = map[string]map[string]CommandEntry{
         "hardware":  {"lshw", "List hardware configuration"},
     },
     "create": {
-        "directory": {"mkdir -p", "Create a new directory"},
-        "folder":    {"mkdir -p", "Create a new directory"},
-        "file":      {"touch", "Create an empty file"},
+        "directory": {"mkdir -p {dir}", "Create a new directory"},
+        "folder":    {"mkdir -p {dir}", "Create a new directory"},
+        "file":      {"touch {file}", "Create an empty file"},
         "link":      {"ln -s", "Create a symbolic link"},
         "symlink":   {"ln -s", "Create a symbolic link"},
         "alias":     {"alias name='cmd'", "Create an alias"},
@@ -55,15 +55,15 @@ var VerbNounCatalog = map[string]map[string]CommandEntry{
         "group":     {"groupadd", "Create a new group"},
         "password":  {"passwd", "Create/Change password"},
         "key":       {"ssh-keygen", "Create SSH key pair"},
-        "archive":   {"tar -czvf archive.tar.gz", "Create a compressed archive"},
-        "zip":       {"zip -r archive.zip", "Create a zip archive"},
+        "archive":   {"tar -czvf archive.tar.gz {dir}", "Create a compressed archive"},
+        "zip":       {"zip -r archive.zip {dir}", "Create a zip archive"},
     },
     "remove": {
-        "file":      {"rm", "Remove a file"},
-        "directory": {"rm -rf", "Remove a directory recursively"},
-        "folder":    {"rm -rf", "Remove a directory recursively"},
+        "file":      {"rm {file}", "Remove a file"},
+        "directory": {"rm -rf {dir}", "Remove a directory recursively"},
+        "folder":    {"rm -rf {dir}", "Remove a directory recursively"},
         "all":       {"rm -rf *", "Remove everything in current directory"},
-        "process":   {"kill", "Kill a process"},
+        "process":   {"kill {pid}", "Kill a process"},
         "package":   {"pkg uninstall", "Uninstall a package"},
         "user":      {"userdel", "Remove a user"},
         "group":     {"groupdel", "Remove a group"},
@@ -72,29 +72,29 @@ var VerbNounCatalog = map[string]map[string]CommandEntry{
         "job":       {"kill %1", "Kill a background job"},
     },
     "copy": {
-        "file":      {"cp", "Copy a file"},
-        "directory": {"cp -r", "Copy a directory recursively"},
-        "folder":    {"cp -r", "Copy a directory recursively"},
+        "file":      {"cp {file} {dir}", "Copy a file"},
+        "directory": {"cp -r {dir} {dir}", "Copy a directory recursively"},
+        "folder":    {"cp -r {dir} {dir}", "Copy a directory recursively"},
         "content":   {"cp -r", "Copy contents"},
-        "remote":    {"scp", "Copy files to/from remote host"},
+        "remote":    {"scp {file} {host}:~/", "Copy files to/from remote host"},
         "text":      {"xclip -sel clip", "Copy text to clipboard (if installed)"},
     },
     "move": {
-        "file":      {"mv", "Move or rename a file"},
-        "directory": {"mv", "Move or rename a directory"},
-        "folder":    {"mv", "Move or rename a directory"},
+        "file":      {"mv {file} {dir}", "Move or rename a file"},
+        "directory": {"mv {dir} {dir}", "Move or rename a directory"},
+        "folder":    {"mv {dir} {dir}", "Move or rename a directory"},
     },
     "rename": {
-        "file":      {"mv", "Rename a file"},
-        "directory": {"mv", "Rename a directory"},
-        "folder":    {"mv", "Rename a directory"},
+        "file":      {"mv {file} {file}", "Rename a file"},
+        "directory": {"mv {dir} {dir}", "Rename a directory"},
+        "folder":    {"mv {dir} {dir}", "Rename a directory"},
     },
     "view": {
-        "file":      {"cat", "View file content"},
-        "content":   {"cat", "View file content"},
-        "log":       {"tail -f", "View log file in real-time"},
-        "end":       {"tail", "View end of file"},
-        "start":     {"head", "View start of file"},
+        "file":      {"cat {file}", "View file content"},
+        "content":   {"cat {file}", "View file content"},
+        "log":       {"tail -f {file}", "View log file in real-time"},
+        "end":       {"tail {file}", "View end of file"},
+        "start":     {"head {file}", "View start of file"},
         "process":   {"top", "View running processes"},
         "tree":      {"tree", "View directory tree"},
         "calendar":  {"cal", "View calendar"},
@@ -104,21 +104,21 @@ var VerbNounCatalog = map[string]map[string]CommandEntry{
         "folder":    {"pwd", "View current folder path"},
     },
     "edit": {
-        "file":      {"nano", "Edit file with nano"},
-        "code":      {"vim", "Edit file with vim"},
+        "file":      {"nano {file}", "Edit file with nano"},
+        "code":      {"vim {file}", "Edit file with vim"},
         "permission": {"chmod", "Change file permissions"},
         "owner":     {"chown", "Change file owner"},
         "group":     {"chgrp", "Change file group"},
         "password":  {"passwd", "Change user password"},
     },
     "search": {
-        "file":      {"find . -name", "Search for files by name"},
-        "text":      {"grep -r", "Search for text in files"},
-        "string":    {"grep -r", "Search for text in files"},
+        "file":      {"find {dir:.} -name {pattern}", "Search for files by name"},
+        "text":      {"grep -r {pattern} {dir:.}", "Search for text in files"},
+        "string":    {"grep -r {pattern} {dir:.}", "Search for text in files"},
         "command":   {"which", "Locate a command"},
         "package":   {"pkg search", "Search for packages"},
-        "history":   {"history | grep", "Search command history"},
-        "process":   {"pgrep", "Search for process ID"},
+        "history":   {"grep {pattern} ~/.bash_history", "Search command history"},
+        "process":   {"pgrep {pattern}", "Search for process ID"},
     },
     "check": {
         "disk":      {"df -h", "Check disk space"},
@@ -126,8 +126,8 @@ var VerbNounCatalog = map[string]map[string]CommandEntry{
         "memory":    {"free -h", "Check memory usage"},
         "ram":       {"free -h", "Check memory usage"},
         "cpu":       {"lscpu", "Check CPU info"},
-        "network":   {"ping google.com", "Check network connectivity"},
-        "internet":  {"ping google.com", "Check internet connectivity"},
+        "network":   {"ping -c 4 {host:google.com}", "Check network connectivity"},
+        "internet":  {"ping -c 4 {host:google.com}", "Check internet connectivity"},
         "port":      {"netstat -tuln", "Check open ports"},
         "version":   {"uname -a", "Check kernel version"},
         "os":        {"cat /etc/os-release", "Check OS details"},
@@ -149,17 +149,17 @@ var VerbNounCatalog = map[string]map[string]CommandEntry{
         "admin":     {"sudo", "Run as root/admin"},
     },
     "archive": {
-        "file":      {"tar -czvf", "Compress files into tar.gz"},
-        "directory": {"tar -czvf", "Compress directory into tar.gz"},
+        "file":      {"tar -czvf archive.tar.gz {file}", "Compress files into tar.gz"},
+        "directory": {"tar -czvf archive.tar.gz {dir}", "Compress directory into tar.gz"},
     },
     "extract": {
-        "file":      {"tar -xzvf", "Extract tar.gz archive"},
-        "archive":   {"tar -xzvf", "Extract tar.gz archive"},
-        "zip":       {"unzip", "Extract zip archive"},
+        "file":      {"tar -xzvf {file}", "Extract tar.gz archive"},
+        "archive":   {"tar -xzvf {file}", "Extract tar.gz archive"},
+        "zip":       {"unzip {file}", "Extract zip archive"},
     },
     "download": {
-        "file":      {"wget", "Download file from URL"},
-        "web":       {"curl -O", "Download file from URL"},
+        "file":      {"wget {url}", "Download file from URL"},
+        "web":       {"curl -O {url}", "Download file from URL"},
     },
 }
 
@@ -51,9 +51,12 @@ func Tokenize(input string) []string {
 		out = append(out, tok)
 	}
 
+	prev := ""
 	for _, t := range raw {
 		t = strings.Trim(t, "?!.,;:\"'()")
-		if t == "" || conversationalStopwords[t] || nigerianStopwords[t] {
+		folder := isFolderArg(prev, t)
+		prev = t
+		if t == "" || folder || conversationalStopwords[t] || nigerianStopwords[t] {
 			continue
 		}
 		stemmed := Stem(t)
@@ -64,6 +67,9 @@ func Tokenize(input string) []string {
 		if n, ok := NounAliases[stemmed]; ok {
 			add(n)
 		}
+		for _, n := range argNouns(t) {
+			add(n)
+		}
 	}
 	return out
 }
@@ -0,0 +1,267 @@
+package layer1
+
+import (
+	"bytes"
+	"clio/internal/config"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// userRuleBonus lifts rules from commands.yaml above any built-in match for
+// the same words, so a team can override what clio suggests.
+const userRuleBonus = 20
+
+// userPhraseCount is how many rules at the front of PhraseCatalog came from
+// commands.yaml files.
+var userPhraseCount int
+
+// userPairs records verb-noun entries that came from a commands.yaml file.
+var userPairs = map[string]bool{}
+
+// UserCommands is the schema of a commands.yaml file.
+type UserCommands struct {
+	Phrases     []UserPhrase        `yaml:"phrases"`
+	Commands    []UserEntry         `yaml:"commands"`
+	VerbAliases map[string]string   `yaml:"verb_aliases"`
+	NounAliases map[string]string   `yaml:"noun_aliases"`
+	Slang       map[string][]string `yaml:"slang"`
+}
+
+// UserPhrase matches when every term appears in the query, like PhraseCatalog.
+type UserPhrase struct {
+	Terms       []string `yaml:"terms"`
+	Command     string   `yaml:"command"`
+	Description string   `yaml:"description"`
+}
+
+// UserEntry adds (or replaces) one VerbNounCatalog entry.
+type UserEntry struct {
+	Verb        string `yaml:"verb"`
+	Noun        string `yaml:"noun"`
+	Command     string `yaml:"command"`
+	Description string `yaml:"description"`
+}
+
+// UserCommandPaths lists the commands.yaml files in load order: the user's
+// ~/.clio/commands.yaml, then, only with project_commands set, the project's
+// ./.clio/commands.yaml. Later files win where both define the same words. A
+// checked-out repository is not trusted by default, since its rules outrank
+// the built-ins.
+func UserCommandPaths() []string {
+	var paths []string
+	if home, err := os.UserHomeDir(); err == nil {
+		paths = append(paths, filepath.Join(home, ".clio", "commands.yaml"))
+	}
+	if !config.ProjectCommands() {
+		return paths
+	}
+	if wd, err := os.Getwd(); err == nil {
+		local := filepath.Join(wd, ".clio", "commands.yaml")
+		if len(paths) == 0 || local != paths[0] {
+			paths = append(paths, local)
+		}
+	}
+	return paths
+}
+
+// LoadUserCommands merges every commands.yaml from UserCommandPaths into the
+// catalogs. Missing files are skipped; the returned errors name the file and
+// entry that failed validation. Valid entries are loaded even when others fail.
+func LoadUserCommands() []error {
+	var errs []error
+	for _, path := range UserCommandPaths() {
+		if err := LoadUserCommandsFile(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// LoadUserCommandsFile parses one commands.yaml and merges its valid entries.
+func LoadUserCommandsFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var uc UserCommands
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true) // catch typos such as "comand:"
+	if err := dec.Decode(&uc); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	var errs []error
+	for _, e := range MergeUserCommands(uc) {
+		errs = append(errs, fmt.Errorf("%s: %w", path, e))
+	}
+	return errors.Join(errs...)
+}
+
+// MergeUserCommands validates uc and adds its valid entries to the catalogs,
+// replacing built-ins with the same key.
+func MergeUserCommands(uc UserCommands) []error {
+	var errs []error
+
+	var phrases []phraseRule
+	for i, p := range uc.Phrases {
+		terms := normalizeWords(p.Terms)
+		if err := validateUserCommand(p.Command); err != nil {
+			errs = append(errs, fmt.Errorf("phrases[%d]: %w", i, err))
+			continue
+		}
+		if len(terms) == 0 {
+			errs = append(errs, fmt.Errorf("phrases[%d]: terms must not be empty", i))
+			continue
+		}
+		phrases = append(phrases, phraseRule{
+			terms: terms,
+			entry: CommandEntry{p.Command, userDesc(p.Description, p.Command)},
+		})
+	}
+	// Newer files go first so they win ties against earlier ones
+	PhraseCatalog = append(phrases, PhraseCatalog...)
+	userPhraseCount += len(phrases)
+
+	for i, c := range uc.Commands {
+		verb, noun := normalizeWord(c.Verb), normalizeWord(c.Noun)
+		if verb == "" || noun == "" {
+			errs = append(errs, fmt.Errorf("commands[%d]: verb and noun are required", i))
+			continue
+		}
+		if err := validateUserCommand(c.Command); err != nil {
+			errs = append(errs, fmt.Errorf("commands[%d] (%s %s): %w", i, verb, noun, err))
+			continue
+		}
+		if VerbNounCatalog[verb] == nil {
+			VerbNounCatalog[verb] = make(map[string]CommandEntry)
+		}
+		VerbNounCatalog[verb][noun] = CommandEntry{c.Command, userDesc(c.Description, c.Command)}
+		userPairs[verb+" "+noun] = true
+	}
+
+	for alias, verb := range uc.VerbAliases {
+		a, v := normalizeWord(alias), normalizeWord(verb)
+		if a == "" || v == "" {
+			errs = append(errs, fmt.Errorf("verb_aliases[%q]: alias and verb are required", alias))
+			continue
+		}
+		if VerbNounCatalog[v] == nil {
+			errs = append(errs, fmt.Errorf("verb_aliases[%q]: unknown verb %q", alias, verb))
+			continue
+		}
+		VerbAliases[a] = v
+	}
+
+	for alias, noun := range uc.NounAliases {
+		a, n := normalizeWord(alias), normalizeWord(noun)
+		if a == "" || n == "" {
+			errs = append(errs, fmt.Errorf("noun_aliases[%q]: alias and noun are required", alias))
+			continue
+		}
+		NounAliases[a] = n
+	}
+
+	for word, targets := range uc.Slang {
+		w := strings.ToLower(strings.TrimSpace(word))
+		if w == "" {
+			errs = append(errs, fmt.Errorf("slang: empty word"))
+			continue
+		}
+		slangExpansions[w] = normalizeWords(targets)
+	}
+	return errs
+}
+
+// testingT is the part of testing.TB UseTestCommands needs.
+type testingT interface {
+	Helper()
+	Cleanup(func())
+}
+
+// UseTestCommands merges uc into the catalogs until the test t ends, then
+// restores them, so tests in any package can try commands.yaml rules.
+func UseTestCommands(t testingT, uc UserCommands) []error {
+	t.Helper()
+	phrases, count := PhraseCatalog, userPhraseCount
+	verbs := make(map[string]map[string]CommandEntry, len(VerbNounCatalog))
+	for v, nouns := range VerbNounCatalog {
+		copied := make(map[string]CommandEntry, len(nouns))
+		for n, e := range nouns {
+			copied[n] = e
+		}
+		verbs[v] = copied
+	}
+	verbAliases, nounAliases := copyMap(VerbAliases), copyMap(NounAliases)
+	slang := make(map[string][]string, len(slangExpansions))
+	for k, v := range slangExpansions {
+		slang[k] = v
+	}
+	pairs := make(map[string]bool, len(userPairs))
+	for k, v := range userPairs {
+		pairs[k] = v
+	}
+	t.Cleanup(func() {
+		PhraseCatalog, userPhraseCount = phrases, count
+		VerbNounCatalog, VerbAliases, NounAliases, slangExpansions = verbs, verbAliases, nounAliases, slang
+		userPairs = pairs
+	})
+	return MergeUserCommands(uc)
+}
+
+func copyMap(m map[string]string) map[string]string {
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+// placeholderRe finds {name} and {name:default}; ${VAR} and find's {} are shell syntax.
+var placeholderRe = regexp.MustCompile(`\$?\{[a-z_]+(?::[^{}]*)?\}`)
+
+// validateUserCommand rejects empty commands and placeholders FillSlots
+// would leave in place.
+func validateUserCommand(cmd string) error {
+	if strings.TrimSpace(cmd) == "" {
+		return errors.New("command is required")
+	}
+	for _, ph := range placeholderRe.FindAllString(cmd, -1) {
+		if !strings.HasPrefix(ph, "$") && !slotRe.MatchString(ph) {
+			return fmt.Errorf("unknown placeholder %s (use file, dir, pattern, host, pid, size or url)", ph)
+		}
+	}
+	return nil
+}
+
+// normalizeWord lowercases and stems w the way query tokens are.
+func normalizeWord(w string) string {
+	w = strings.ToLower(strings.TrimSpace(w))
+	if w == "" {
+		return ""
+	}
+	return Stem(w)
+}
+
+func normalizeWords(words []string) []string {
+	out := make([]string, 0, len(words))
+	for _, w := range words {
+		if w = strings.ToLower(strings.TrimSpace(w)); w != "" {
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+func userDesc(desc, cmd string) string {
+	if desc != "" {
+		return desc
+	}
+	return "Custom command: " + cmd
+}
@@ -0,0 +1,114 @@
+package layer1
+
+import (
+	"clio/internal/config"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadUserCommandsFile(t *testing.T) {
+	UseTestCommands(t, UserCommands{})
+	path := filepath.Join(t.TempDir(), "commands.yaml")
+	yaml := `
+phrases:
+  - terms: [deploy, staging]
+    command: make deploy ENV=staging
+    description: Deploy to staging
+  - terms: [disk, space]
+    command: duf
+  - terms: [broken]
+    command: ls {folder}
+commands:
+  - verb: tail
+    noun: api
+    command: kubectl logs -f deploy/api
+  - verb: list
+    noun: process
+    command: procs
+  - verb: find
+    noun: exec
+    command: find {dir:.} -exec ls {} +
+verb_aliases:
+  ship: run
+  yeet: nowhere
+slang:
+  borked: [stuck]
+`
+	if err := os.WriteFile(path, []byte(yaml), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := LoadUserCommandsFile(path)
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	for _, want := range []string{"phrases[2]", "{folder}", "yeet"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %s", err, want)
+		}
+	}
+	if strings.Contains(err.Error(), "commands[2]") {
+		t.Errorf("find's {} was rejected: %v", err)
+	}
+
+	cases := []struct {
+		query string
+		want  string
+	}{
+		{"deploy to staging", "make deploy ENV=staging"},
+		{"how much disk space is left", "duf"}, // user rule beats the built-in
+		{"tail the api logs", "kubectl logs -f deploy/api"},
+		{"list processes", "procs"},
+	}
+	for _, c := range cases {
+		var got string
+		if ranked := RankPhrases(c.query); len(ranked) > 0 {
+			got = ranked[0].Entry.Cmd
+		}
+		if got != c.want {
+			if ranked := RankCatalog(c.query); len(ranked) > 0 {
+				got = ranked[0].Entry.Cmd
+			}
+		}
+		if got != c.want {
+			t.Errorf("%q → %q, want %q", c.query, got, c.want)
+		}
+	}
+	if VerbAliases["ship"] != "run" {
+		t.Errorf("verb alias ship not loaded")
+	}
+	if got := slangExpansions["borked"]; len(got) != 1 || got[0] != "stuck" {
+		t.Errorf("slang borked = %v", got)
+	}
+}
+
+func TestLoadUserCommandsFileRejectsUnknownFields(t *testing.T) {
+	UseTestCommands(t, UserCommands{})
+	path := filepath.Join(t.TempDir(), "commands.yaml")
+	if err := os.WriteFile(path, []byte("phrases:\n  - terms: [x]\n    comand: ls\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadUserCommandsFile(path); err == nil || !strings.Contains(err.Error(), "comand") {
+		t.Errorf("expected unknown-field error, got %v", err)
+	}
+}
+
+func TestUserCommandPathsSkipsProjectByDefault(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Chdir(t.TempDir())
+	config.ResetCache()
+	defer config.ResetCache()
+
+	if paths := UserCommandPaths(); len(paths) != 1 || !strings.HasPrefix(paths[0], home) {
+		t.Fatalf("UserCommandPaths() = %v, want only the home file", paths)
+	}
+	if err := config.Set("project_commands", "true"); err != nil {
+		t.Fatal(err)
+	}
+	if paths := UserCommandPaths(); len(paths) != 2 {
+		t.Errorf("UserCommandPaths() with project_commands = %v, want the project file too", paths)
+	}
+}
@@ -0,0 +1,149 @@
+package layer1
+
+import (
+	"clio/internal/platform"
+	"strings"
+)
+
+// The catalogs are written for Termux (pkg, GNU coreutils, net-tools). For
+// builds the platform changes, CommandEntry.For rewrites an entry: package
+// commands are translated per package manager, and other commands are looked
+// up in commandVariants by platform key (see platform.Info.Keys).
+
+// commandVariants maps a catalog command to replacements keyed by package
+// manager, distro, OS or userland. Keys not listed keep the catalog command.
+var commandVariants = map[string]map[string]string{
+	"free -h": {"bsd": "vm_stat"},
+	"ip a":    {"termux": "ifconfig", "bsd": "ifconfig"},
+	"ip route": {
+		"termux": "ip route",
+		"bsd":    "netstat -rn",
+	},
+	"ifconfig": {"termux": "ifconfig", "linux": "ip -brief addr", "bsd": "ifconfig"},
+	"netstat -tuln": {
+		"termux": "netstat -tuln",
+		"linux":  "ss -tuln",
+		"bsd":    "lsof -iTCP -sTCP:LISTEN -P -n",
+	},
+	"netstat -an":           {"termux": "netstat -an", "linux": "ss -tan"},
+	"lsusb":                 {"bsd": "system_profiler SPUSBDataType"},
+	"lscpu":                 {"darwin": "sysctl -n machdep.cpu.brand_string", "freebsd": "sysctl hw.model hw.ncpu"},
+	"lshw":                  {"darwin": "system_profiler SPHardwareDataType"},
+	"cat /etc/os-release":   {"darwin": "sw_vers"},
+	"cat /etc/passwd":       {"darwin": "dscl . list /Users"},
+	"cat /etc/group":        {"darwin": "dscl . list /Groups"},
+	"xclip -sel clip":       {"termux": "termux-clipboard-set", "darwin": "pbcopy"},
+	"termux-battery-status": {"termux": "termux-battery-status", "linux": "cat /sys/class/power_supply/BAT0/capacity", "darwin": "pmset -g batt"},
+	"ls -F":                 {"bsd": "ls -FG"},
+}
+
+// packageOps translates "pkg <op>" into each package manager's syntax.
+// A leading "sudo " is dropped when already root.
+var packageOps = map[string]map[string]string{
+	"apt": {
+		"install": "sudo apt install", "uninstall": "sudo apt remove", "search": "apt search",
+		"update": "sudo apt update", "upgrade": "sudo apt upgrade", "list-installed": "apt list --installed",
+	},
+	"dnf": {
+		"install": "sudo dnf install", "uninstall": "sudo dnf remove", "search": "dnf search",
+		"update": "sudo dnf check-update", "upgrade": "sudo dnf upgrade", "list-installed": "dnf list --installed",
+	},
+	"yum": {
+		"install": "sudo yum install", "uninstall": "sudo yum remove", "search": "yum search",
+		"update": "sudo yum check-update", "upgrade": "sudo yum update", "list-installed": "yum list installed",
+	},
+	"pacman": {
+		"install": "sudo pacman -S", "uninstall": "sudo pacman -R", "search": "pacman -Ss",
+		"update": "sudo pacman -Sy", "upgrade": "sudo pacman -Syu", "list-installed": "pacman -Q",
+	},
+	"apk": {
+		"install": "sudo apk add", "uninstall": "sudo apk del", "search": "apk search",
+		"update": "sudo apk update", "upgrade": "sudo apk upgrade", "list-installed": "apk info",
+	},
+	"zypper": {
+		"install": "sudo zypper install", "uninstall": "sudo zypper remove", "search": "zypper search",
+		"update": "sudo zypper refresh", "upgrade": "sudo zypper update", "list-installed": "zypper search --installed-only",
+	},
+	"brew": {
+		"install": "brew install", "uninstall": "brew uninstall", "search": "brew search",
+		"update": "brew update", "upgrade": "brew upgrade", "list-installed": "brew list",
+	},
+}
+
+// packageNames maps Termux package names to other managers' names where they
+// differ. An empty name means the manager does not package it (e.g. procps on macOS).
+var packageNames = map[string]map[string]string{
+	"python":     {"apt": "python3", "dnf": "python3", "yum": "python3", "apk": "python3", "zypper": "python3"},
+	"nodejs":     {"brew": "node"},
+	"openssh":    {"apt": "openssh-client", "dnf": "openssh-clients", "yum": "openssh-clients", "apk": "openssh-client"},
+	"dnsutils":   {"dnf": "bind-utils", "yum": "bind-utils", "pacman": "bind", "apk": "bind-tools", "brew": "bind", "zypper": "bind-utils"},
+	"inetutils":  {"apt": "iputils-ping", "dnf": "iputils", "yum": "iputils", "apk": "iputils", "pacman": "iputils", "zypper": "iputils"},
+	"iproute2":   {"dnf": "iproute", "yum": "iproute"},
+	"python-pip": {"apt": "python3-pip", "dnf": "python3-pip", "yum": "python3-pip", "apk": "py3-pip", "pacman": "python-pip", "zypper": "python3-pip", "brew": "python"},
+	"sqlite":     {"apt": "sqlite3"},
+	"fd":         {"apt": "fd-find", "dnf": "fd-find"},
+	"man":        {"apt": "man-db", "dnf": "man-db", "yum": "man-db", "pacman": "man-db", "apk": "man-db"},
+	"xxd":        {"dnf": "vim-common", "yum": "vim-common", "pacman": "vim", "apk": "xxd"},
+	"procps":     {"pacman": "procps-ng", "dnf": "procps-ng", "yum": "procps-ng", "brew": ""},
+}
+
+// For returns the entry adjusted for p. Entries without a variant for p are
+// returned unchanged.
+func (e CommandEntry) For(p platform.Info) CommandEntry {
+	if cmd, ok := packageVariant(e.Cmd, p); ok {
+		return CommandEntry{cmd, platformDesc(e.Desc, p)}
+	}
+	variants, ok := commandVariants[e.Cmd]
+	if !ok {
+		return e
+	}
+	for _, key := range p.Keys() {
+		if cmd, ok := variants[key]; ok {
+			return CommandEntry{cmd, platformDesc(e.Desc, p)}
+		}
+	}
+	return e
+}
+
+// ForCurrent is For on the detected platform.
+func (e CommandEntry) ForCurrent() CommandEntry {
+	return e.For(platform.Current())
+}
+
+// packageVariant rewrites "pkg <op> [packages]" for p's package manager.
+func packageVariant(cmd string, p platform.Info) (string, bool) {
+	fields := strings.Fields(cmd)
+	if len(fields) < 2 || fields[0] != "pkg" || p.PackageManager == "pkg" {
+		return "", false
+	}
+	ops, ok := packageOps[p.PackageManager]
+	if !ok {
+		return "", false // unknown manager: the pkg form is the best hint we have
+	}
+	prefix, ok := ops[fields[1]]
+	if !ok {
+		return "", false
+	}
+	if p.Root {
+		prefix = strings.TrimPrefix(prefix, "sudo ")
+	}
+	out := []string{prefix}
+	for _, name := range fields[2:] {
+		if renamed, ok := packageNames[name][p.PackageManager]; ok {
+			name = renamed
+		}
+		if name != "" {
+			out = append(out, name)
+		}
+	}
+	return strings.Join(out, " "), true
+}
+
+// platformDesc drops Termux-only wording from descriptions of rewritten commands.
+func platformDesc(desc string, p platform.Info) string {
+	if p.Termux {
+		return desc
+	}
+	desc = strings.Replace(desc, " (Termux)", "", 1)
+	return strings.Replace(desc, " on Termux", "", 1)
+}
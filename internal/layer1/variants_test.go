@@ -0,0 +1,93 @@
+package layer1
+
+import (
+	"clio/internal/platform"
+	"testing"
+)
+
+var (
+	termux = platform.Info{OS: "linux", Distro: "termux", PackageManager: "pkg", Termux: true}
+	debian = platform.Info{OS: "linux", Distro: "debian", PackageManager: "apt"}
+	ubuntu = platform.Info{OS: "linux", Distro: "ubuntu", Like: []string{"debian"}, PackageManager: "apt"}
+	fedora = platform.Info{OS: "linux", Distro: "fedora", PackageManager: "dnf"}
+	arch   = platform.Info{OS: "linux", Distro: "arch", PackageManager: "pacman"}
+	alpine = platform.Info{OS: "linux", Distro: "alpine", PackageManager: "apk", Root: true}
+	macos  = platform.Info{OS: "darwin", Distro: "macos", PackageManager: "brew"}
+)
+
+func TestCommandEntryFor(t *testing.T) {
+	cases := []struct {
+		cmd  string
+		p    platform.Info
+		want string
+	}{
+		{"pkg install", termux, "pkg install"},
+		{"pkg install", debian, "sudo apt install"},
+		{"pkg install python", ubuntu, "sudo apt install python3"},
+		{"pkg install python", fedora, "sudo dnf install python3"},
+		{"pkg install python", arch, "sudo pacman -S python"},
+		{"pkg install python", alpine, "apk add python3"}, // root: no sudo
+		{"pkg install nodejs", macos, "brew install node"},
+		{"pkg upgrade", arch, "sudo pacman -Syu"},
+		{"pkg list-installed", debian, "apt list --installed"},
+		{"pkg search", macos, "brew search"},
+		{"pkg uninstall", alpine, "apk del"},
+		{"netstat -tuln", termux, "netstat -tuln"},
+		{"netstat -tuln", debian, "ss -tuln"},
+		{"netstat -tuln", macos, "lsof -iTCP -sTCP:LISTEN -P -n"},
+		{"ip a", termux, "ifconfig"},
+		{"ip a", fedora, "ip a"},
+		{"ip a", macos, "ifconfig"},
+		{"free -h", arch, "free -h"},
+		{"free -h", macos, "vm_stat"},
+		{"lsusb", macos, "system_profiler SPUSBDataType"},
+		{"ls -F", macos, "ls -FG"},
+		{"ls -F", debian, "ls -F"},
+		{"xclip -sel clip", termux, "termux-clipboard-set"},
+		{"termux-battery-status", termux, "termux-battery-status"},
+		{"termux-battery-status", debian, "cat /sys/class/power_supply/BAT0/capacity"},
+		{"df -h", macos, "df -h"},
+	}
+	for _, c := range cases {
+		got := CommandEntry{c.cmd, "desc"}.For(c.p)
+		if got.Cmd != c.want {
+			t.Errorf("%q on %s = %q, want %q", c.cmd, c.p.Distro, got.Cmd, c.want)
+		}
+	}
+}
+
+func TestCommandEntryForDropsTermuxWording(t *testing.T) {
+	e := CommandEntry{"pkg list-installed", "List installed packages (Termux)"}
+	if got := e.For(debian).Desc; got != "List installed packages" {
+		t.Errorf("desc on debian = %q", got)
+	}
+	if got := e.For(termux).Desc; got != e.Desc {
+		t.Errorf("desc on termux = %q", got)
+	}
+}
+
+func TestInstallCommand(t *testing.T) {
+	cases := []struct {
+		bin  string
+		p    platform.Info
+		want string
+	}{
+		{"lsusb", termux, "pkg install usbutils"},
+		{"lsusb", debian, "sudo apt install usbutils"},
+		{"dig", fedora, "sudo dnf install bind-utils"},
+		{"dig", alpine, "apk add bind-tools"},
+		{"ssh", ubuntu, "sudo apt install openssh-client"},
+		{"ping", arch, "sudo pacman -S iputils"},
+		{"tree", macos, "brew install tree"},
+		{"termux-clipboard-set", termux, "pkg install termux-api"},
+		{"termux-clipboard-set", debian, ""},
+		{"free", macos, ""},
+		{"no-such-tool", debian, ""},
+	}
+	for _, c := range cases {
+		got, ok := InstallCommand(c.bin, c.p)
+		if got != c.want || ok != (c.want != "") {
+			t.Errorf("InstallCommand(%q) on %s = %q, %v; want %q", c.bin, c.p.Distro, got, ok, c.want)
+		}
+	}
+}
@@ -0,0 +1,336 @@
+package layer2
+
+import (
+	"bytes"
+	"clio/internal/config"
+	"clio/internal/layer3"
+	"clio/internal/safeexec"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// helpTimeout bounds each --help run; a program still running after it
+// was probably waiting for input or doing real work.
+var helpTimeout = 2 * time.Second
+
+// maxHelpBytes caps the output read from one --help run.
+const maxHelpBytes = 64 << 10
+
+// helpDenylist names commands never run for --help: they act before parsing
+// flags, take over the terminal or ask for credentials on some systems.
+var helpDenylist = map[string]bool{
+	"reboot": true, "shutdown": true, "halt": true, "poweroff": true, "init": true, "telinit": true,
+	"kill": true, "killall": true, "pkill": true, "rm": true, "rmdir": true, "dd": true,
+	"fdisk": true, "parted": true, "wipefs": true, "shred": true, "sudo": true, "su": true,
+	"doas": true, "login": true, "passwd": true, "chsh": true, "xdg-open": true, "open": true,
+	"startx": true, "X": true, "Xorg": true, "vi": true, "vim": true, "nano": true, "emacs": true,
+	"less": true, "more": true, "top": true, "htop": true, "watch": true,
+	// Android tools that talk to system services
+	"am": true, "pm": true, "cmd": true, "input": true, "svc": true, "setprop": true,
+}
+
+// helpDenyPrefixes are denied name prefixes: filesystem tools, and Termux
+// API commands that open dialogs or touch the device.
+var helpDenyPrefixes = []string{"mkfs", "fsck", "termux-"}
+
+// HarvestStats reports what a --help harvest did.
+type HarvestStats struct {
+	Commands int `json:"commands"` // searchable --help pages after the harvest
+	Run      int `json:"run"`
+	Removed  int `json:"removed"`
+}
+
+// PathExecutables returns the executables on PATH by name; the first
+// directory listing a name wins, as in a shell.
+func PathExecutables() map[string]string {
+	out := make(map[string]string)
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if dir == "" {
+			continue // "." is never harvested
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if _, ok := out[e.Name()]; ok || strings.HasPrefix(e.Name(), ".") {
+				continue
+			}
+			path := filepath.Join(dir, e.Name())
+			if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() && info.Mode()&0o111 != 0 {
+				out[e.Name()] = path
+			}
+		}
+	}
+	return out
+}
+
+func helpDenied(name string) bool {
+	if helpDenylist[name] {
+		return true
+	}
+	for _, p := range helpDenyPrefixes {
+		if strings.HasPrefix(name, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Harvest indexes the --help output of PATH commands that have no man page,
+// so they can be searched like one. Commands whose executable is unchanged
+// since the last harvest are not run again unless rebuild is set.
+func Harvest(rebuild bool) (HarvestStats, error) {
+	var stats HarvestStats
+	if err := EnsureIndex(); err != nil {
+		return stats, err
+	}
+	documented, err := layer3.IndexedNames()
+	if err != nil {
+		return stats, err
+	}
+	stamps, err := layer3.HelpIndexStamps()
+	if err != nil {
+		return stats, err
+	}
+
+	sources := make(map[string]layer3.ManStamp)
+	var todo []string
+	for name, path := range PathExecutables() {
+		if documented[name] || helpDenied(name) {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		stamp := layer3.ManStamp{MTime: info.ModTime().Unix(), Size: info.Size()}
+		sources[path] = stamp
+		if old, ok := stamps[path]; !ok || old != stamp || rebuild {
+			todo = append(todo, path)
+		}
+	}
+
+	dir, err := os.MkdirTemp("", "clio-help")
+	if err != nil {
+		return stats, err
+	}
+	defer os.RemoveAll(dir)
+
+	workers := 4
+	if config.IsLiteProfile() {
+		workers = 2
+	}
+	changed := make([]layer3.ManPage, len(todo))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				changed[i] = helpPage(todo[i], sources[todo[i]], dir)
+			}
+		}()
+	}
+	for i := range todo {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	var removed []string
+	for path := range stamps {
+		if _, ok := sources[path]; !ok {
+			removed = append(removed, path)
+		}
+	}
+	if err := layer3.UpdateHelpIndex(changed, removed); err != nil {
+		return stats, err
+	}
+	stats.Run, stats.Removed = len(changed), len(removed)
+	stats.Commands, err = layer3.HelpIndexSize()
+	return stats, err
+}
+
+// helpPage runs path with --help, then -h, and parses what it printed. A
+// command without usable help is returned without a name, so it is
+// remembered but not searched.
+func helpPage(path string, stamp layer3.ManStamp, dir string) layer3.ManPage {
+	page := layer3.ManPage{Path: path, Section: layer3.HelpSection, MTime: stamp.MTime, Size: stamp.Size}
+	for _, flag := range []string{"--help", "-h"} {
+		parsed, ok := ParseHelp(runHelp(path, flag, dir))
+		if !ok {
+			continue
+		}
+		page.Name = filepath.Base(path)
+		page.Description = parsed.Description
+		page.Body = parsed.Body
+		page.Options = parsed.Options
+		break
+	}
+	return page
+}
+
+// errHelpTooLong stops copying output once maxHelpBytes were read.
+var errHelpTooLong = errors.New("help output too long")
+
+// cappedBuffer keeps the first maxHelpBytes written to it.
+type cappedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (c *cappedBuffer) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	room := maxHelpBytes - c.buf.Len()
+	if len(p) > room {
+		c.buf.Write(p[:room])
+		return room, errHelpTooLong
+	}
+	return c.buf.Write(p)
+}
+
+// runHelp returns what path prints for flag on stdout and stderr, which is
+// where many programs send usage. Stdin is empty and the working directory
+// is dir, so a program that ignores the flag has nothing to act on.
+func runHelp(path, flag, dir string) string {
+	cmd := safeexec.Command(path, flag)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "PAGER=cat", "MANPAGER=cat", "TERM=dumb", "NO_COLOR=1")
+	var out cappedBuffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	cmd.WaitDelay = helpTimeout / 4 // children left holding the output
+	if err := cmd.Start(); err != nil {
+		return ""
+	}
+	timer := time.AfterFunc(helpTimeout, func() { cmd.Process.Kill() })
+	cmd.Wait()
+	timer.Stop()
+
+	out.mu.Lock()
+	defer out.mu.Unlock()
+	return out.buf.String()
+}
+
+var (
+	usageRe = regexp.MustCompile(`(?i)^\s*usage(?: of \S+)?:\s*(.*)$`)
+	// helpOptionRe matches a flag table row, "  -a, --all    do not ignore",
+	// or a flag alone on its line, "  -count int", described below it.
+	helpOptionRe = regexp.MustCompile(`^\s+(-{1,2}[A-Za-z0-9?][\w-]*(?:[ =,]\s*\S+)*?)(?:\s{2,}|\t+)(\S.*)$|^\s+(-{1,2}[A-Za-z0-9?][\w-]*)(?:[ =]\S+)?$`)
+	// commandRowRe matches a subcommand row: "  build       Compile packages".
+	commandRowRe = regexp.MustCompile(`^\s{2,}([a-z][\w:-]*)(?:,\s*[a-z][\w-]*)*\s{2,}(\S.*)$`)
+	// helpHeadingRe matches "Options:", "Available Commands:" and the like.
+	helpHeadingRe = regexp.MustCompile(`^[A-Za-z][\w ]*:$`)
+	// overviewRe matches the label LLVM tools put before their description.
+	overviewRe = regexp.MustCompile(`^(?i:overview|description):\s*`)
+	// versionRe matches the version in a banner such as "GNU grep 3.11".
+	versionRe = regexp.MustCompile(`^[vV]?\d+(\.\d+)+`)
+)
+
+// ParseHelp extracts a page from a program's --help output: the first line
+// of prose as its description, usage and subcommand rows as body and the
+// flag table as options. It reports false when text has neither a usage
+// line nor flags, as when a program ignored the flag.
+func ParseHelp(text string) (Page, bool) {
+	var page Page
+	var body, options strings.Builder
+	usage, inUsage, pendingOpt := false, false, false
+
+	for _, line := range strings.Split(stripOverstrike(text), "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			inUsage, pendingOpt = false, false
+			continue
+		}
+		indented := line[0] == ' ' || line[0] == '\t'
+		if m := usageRe.FindStringSubmatch(line); m != nil {
+			usage, inUsage, pendingOpt = true, true, false
+			if m[1] != "" {
+				appendText(&body, m[1], maxBodyBytes)
+			}
+			continue
+		}
+		if helpHeadingRe.MatchString(trimmed) {
+			inUsage, pendingOpt = false, false
+			continue
+		}
+		if m := helpOptionRe.FindStringSubmatch(line); m != nil {
+			inUsage, pendingOpt = false, m[3] != ""
+			appendText(&options, strings.TrimSpace(m[1]+m[3]+" "+m[2]), maxOptionsBytes)
+			continue
+		}
+		if pendingOpt && indented {
+			// Go's flag package puts the description on the next line
+			appendText(&options, trimmed, maxOptionsBytes)
+			pendingOpt = false
+			continue
+		}
+		pendingOpt = false
+		if inUsage && (indented || strings.HasPrefix(trimmed, "or:")) {
+			appendText(&body, trimmed, maxBodyBytes)
+			continue
+		}
+		inUsage = false
+		if m := commandRowRe.FindStringSubmatch(line); m != nil {
+			appendText(&body, m[1]+" "+m[2], maxBodyBytes)
+			continue
+		}
+		if page.Description == "" && indent(line) < 4 && isProse(trimmed) {
+			page.Description = overviewRe.ReplaceAllString(trimmed, "")
+			continue
+		}
+		appendText(&body, trimmed, maxBodyBytes)
+	}
+	if !usage && options.Len() == 0 {
+		return Page{}, false
+	}
+	page.Body = body.String()
+	page.Options = options.String()
+	return page, true
+}
+
+// isProse reports whether line reads like a sentence rather than a banner
+// such as "GNU grep 3.11", a copyright notice, a flag or a lone URL.
+func isProse(line string) bool {
+	words := strings.Fields(line)
+	if len(words) < 3 || strings.Contains(line, "://") || strings.HasPrefix(line, "Copyright") {
+		return false
+	}
+	if r, _ := utf8.DecodeRuneInString(line); !unicode.IsLetter(r) {
+		return false
+	}
+	for _, w := range words {
+		if versionRe.MatchString(w) {
+			return false
+		}
+	}
+	return true
+}
+
+// indent returns the width of line's leading whitespace, a tab counting as
+// eight columns.
+func indent(line string) int {
+	n := 0
+	for _, c := range line {
+		switch c {
+		case ' ':
+			n++
+		case '\t':
+			n += 8
+		default:
+			return n
+		}
+	}
+	return n
+}
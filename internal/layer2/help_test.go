@@ -0,0 +1,148 @@
+package layer2
+
+import (
+	"clio/internal/layer3"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const gnuHelp = `Usage: grep [OPTION]... PATTERNS [FILE]...
+Search for PATTERNS in each FILE.
+Example: grep -i 'hello world' menu.h main.c
+
+Pattern selection and interpretation:
+  -E, --extended-regexp     PATTERNS are extended regular expressions
+  -i, --ignore-case         ignore case distinctions in patterns and data
+`
+
+const goFlagHelp = `Usage of hey:
+  -c int
+    	Number of workers to run concurrently. (default 50)
+  -z duration
+    	Duration of application to send requests.
+`
+
+const cobraHelp = `Kubectl controls the Kubernetes cluster manager.
+
+Usage:
+  kubectl [flags] [options]
+
+Available Commands:
+  apply         Apply a configuration to a resource by file name or stdin
+  logs          Print the logs for a container in a pod
+
+Flags:
+  -h, --help   help for kubectl
+`
+
+const commanderHelp = `Usage: http-server [options] [path]
+
+Serve a directory over HTTP from the command line
+
+Options:
+  -p, --port <port>  Port to use (default: 8080)
+  -V, --version      output the version number
+`
+
+func TestParseHelp(t *testing.T) {
+	tests := []struct {
+		name        string
+		text        string
+		ok          bool
+		description string
+		body        string // substring
+		options     string // substring
+	}{
+		{"gnu", gnuHelp, true, "Search for PATTERNS in each FILE.", "[OPTION]... PATTERNS", "-i, --ignore-case ignore case distinctions"},
+		{"go flag", goFlagHelp, true, "", "", "-c Number of workers to run concurrently."},
+		{"cobra", cobraHelp, true, "Kubectl controls the Kubernetes cluster manager.", "logs Print the logs for a container", "-h, --help help for kubectl"},
+		{"commander", commanderHelp, true, "Serve a directory over HTTP from the command line", "http-server [options] [path]", "-p, --port <port> Port to use"},
+		{"banner", "GNU hello 2.12\nUsage: hello [OPTION]...\nPrint a friendly greeting.\n", true, "Print a friendly greeting.", "hello [OPTION]...", ""},
+		{"overview", "OVERVIEW: llvm object file dumper\n\nUSAGE: llvm-objdump [options] <input files>\n", true, "llvm object file dumper", "llvm-objdump [options]", ""},
+		{"no prose", "Copyright (C) 2016 g10 Code GmbH\nUsage: pinentry [options]\n  -C, --demangle[=STYLE] Decode symbol names\n              Add <incr> to the start address\n", true, "", "pinentry [options]", ""},
+		{"ignored flag", "hello world\n", false, "", "", ""},
+		{"empty", "", false, "", "", ""},
+	}
+	for _, tt := range tests {
+		page, ok := ParseHelp(tt.text)
+		if ok != tt.ok {
+			t.Errorf("%s: ok = %v, want %v", tt.name, ok, tt.ok)
+			continue
+		}
+		if page.Description != tt.description {
+			t.Errorf("%s: description = %q, want %q", tt.name, page.Description, tt.description)
+		}
+		if !strings.Contains(page.Body, tt.body) {
+			t.Errorf("%s: body = %q, want it to contain %q", tt.name, page.Body, tt.body)
+		}
+		if !strings.Contains(page.Options, tt.options) {
+			t.Errorf("%s: options = %q, want it to contain %q", tt.name, page.Options, tt.options)
+		}
+	}
+}
+
+func writeScript(t *testing.T, path, body string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body), 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHarvest(t *testing.T) {
+	layer3.UseTestDB(t)
+	sleep, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Skip("no sleep on PATH")
+	}
+	defer func(d time.Duration) { helpTimeout = d }(helpTimeout)
+	helpTimeout = 300 * time.Millisecond
+
+	bin := t.TempDir()
+	t.Setenv("PATH", bin)
+	t.Setenv("MANPATH", t.TempDir())
+	// PATH holds only the fixtures, so scripts use builtins and full paths
+	writeScript(t, filepath.Join(bin, "gopher"), "printf '%s' '"+commanderHelp+"'\n")
+	writeScript(t, filepath.Join(bin, "shorty"), `[ "$1" = -h ] && echo "usage: shorty [-v] file" >&2; exit 1`+"\n")
+	writeScript(t, filepath.Join(bin, "sleepy"), "exec "+sleep+" 5\n")
+	writeScript(t, filepath.Join(bin, "quiet"), "exit 0\n")
+	writeScript(t, filepath.Join(bin, "shred"), ": > "+filepath.Join(bin, "ran")+"\n")
+	writeScript(t, filepath.Join(bin, "termux-toast"), ": > "+filepath.Join(bin, "ran")+"\n")
+	os.WriteFile(filepath.Join(bin, "notes.txt"), []byte("not executable"), 0o644)
+
+	start := time.Now()
+	stats, err := Harvest(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Commands != 2 || stats.Run != 4 {
+		t.Fatalf("Harvest = %+v, want 2 commands from 4 run", stats)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("Harvest took %v; sleepy was not cut off", elapsed)
+	}
+	if _, err := os.Stat(filepath.Join(bin, "ran")); err == nil {
+		t.Error("a denylisted command was run")
+	}
+
+	matches, err := layer3.SearchManIndex([]string{"serve", "directory", "http"}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) == 0 || matches[0].Name != "gopher" || matches[0].Section != layer3.HelpSection {
+		t.Errorf("search = %+v, want gopher from --help", matches)
+	}
+
+	// Unchanged commands are not run again; removed ones are dropped
+	os.Remove(filepath.Join(bin, "gopher"))
+	stats, err = Harvest(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Commands != 1 || stats.Run != 0 || stats.Removed != 1 {
+		t.Errorf("second harvest = %+v, want 1 command, nothing run, 1 removed", stats)
+	}
+}
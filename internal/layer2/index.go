@@ -0,0 +1,178 @@
+package layer2
+
+import (
+	"bufio"
+	"clio/internal/layer3"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// indexedSections are the man sections searched, as with man -k -s 1,8.
+var indexedSections = []string{"1", "8"}
+
+// indexMaxAge is how old the index may get before Search refreshes it.
+// A refresh only parses files whose size or modification time changed.
+const indexMaxAge = 24 * time.Hour
+
+// IndexStats reports what a refresh did.
+type IndexStats struct {
+	Pages   int `json:"pages"` // searchable pages after the refresh
+	Parsed  int `json:"parsed"`
+	Removed int `json:"removed"`
+}
+
+// ManRoots lists the directories holding man1/, man8/ and so on: MANPATH,
+// where an empty entry stands for the defaults, or the defaults when unset.
+func ManRoots() []string {
+	defaults := []string{"/usr/share/man", "/usr/local/share/man", "/usr/local/man", "/opt/homebrew/share/man"}
+	if prefix := os.Getenv("PREFIX"); prefix != "" {
+		defaults = append([]string{filepath.Join(prefix, "share", "man")}, defaults...) // Termux
+	}
+
+	var roots []string
+	seen := make(map[string]bool)
+	add := func(dir string) {
+		if dir != "" && !seen[dir] {
+			seen[dir] = true
+			roots = append(roots, dir)
+		}
+	}
+	manpath := os.Getenv("MANPATH")
+	if manpath == "" {
+		manpath = ":" // just the defaults
+	}
+	for _, dir := range filepath.SplitList(manpath) {
+		if dir != "" {
+			add(dir)
+			continue
+		}
+		for _, d := range defaults {
+			add(d)
+		}
+	}
+	return roots
+}
+
+// manSources returns the man page source files under roots with their stamps.
+// Symlinked pages are left out: the page they point to lists their names.
+func manSources(roots []string) map[string]layer3.ManStamp {
+	out := make(map[string]layer3.ManStamp)
+	for _, root := range roots {
+		for _, sec := range indexedSections {
+			dir := filepath.Join(root, "man"+sec)
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				continue
+			}
+			for _, e := range entries {
+				if !e.Type().IsRegular() {
+					continue
+				}
+				if _, s, ok := pageName(e.Name()); !ok || !strings.HasPrefix(s, sec) {
+					continue
+				}
+				info, err := e.Info()
+				if err != nil {
+					continue
+				}
+				out[filepath.Join(dir, e.Name())] = layer3.ManStamp{MTime: info.ModTime().Unix(), Size: info.Size()}
+			}
+		}
+	}
+	return out
+}
+
+// pageName splits a source file name such as "ls.1.gz" or "tar.1" into the
+// page name and section. Other compressions are not read.
+func pageName(file string) (name, section string, ok bool) {
+	file = strings.TrimSuffix(file, ".gz")
+	ext := filepath.Ext(file)
+	if len(ext) < 2 || ext[1] < '1' || ext[1] > '9' {
+		return "", "", false
+	}
+	return strings.TrimSuffix(file, ext), ext[1:], true
+}
+
+// RefreshIndex brings the man page index up to date with the sources under
+// ManRoots. Unchanged files are skipped unless rebuild is set.
+func RefreshIndex(rebuild bool) (IndexStats, error) {
+	var stats IndexStats
+	stamps, err := layer3.ManIndexStamps()
+	if err != nil {
+		return stats, err
+	}
+	sources := manSources(ManRoots())
+
+	var changed []layer3.ManPage
+	for path, stamp := range sources {
+		if old, ok := stamps[path]; ok && old == stamp && !rebuild {
+			continue
+		}
+		changed = append(changed, readPage(path, stamp))
+	}
+	var removed []string
+	for path := range stamps {
+		if _, ok := sources[path]; !ok {
+			removed = append(removed, path)
+		}
+	}
+	if err := layer3.UpdateManIndex(changed, removed); err != nil {
+		return stats, err
+	}
+	stats.Parsed, stats.Removed = len(changed), len(removed)
+	stats.Pages, err = layer3.ManIndexSize()
+	return stats, err
+}
+
+// readPage parses one source file. A page that cannot be read or parsed is
+// returned without a name, so it is remembered but not searched.
+func readPage(path string, stamp layer3.ManStamp) layer3.ManPage {
+	name, section, _ := pageName(filepath.Base(path))
+	page := layer3.ManPage{Path: path, Section: section, MTime: stamp.MTime, Size: stamp.Size}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return page
+	}
+	defer f.Close()
+	var r io.Reader = bufio.NewReader(f)
+	if strings.HasSuffix(path, ".gz") {
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return page
+		}
+		defer zr.Close()
+		r = zr
+	}
+	parsed, err := ParseRoff(r)
+	if err != nil {
+		return page
+	}
+	page.Name = name
+	page.Description = parsed.Description
+	page.Body = parsed.Body
+	page.Options = parsed.Options
+	for _, n := range parsed.Names {
+		if n != name {
+			page.Aliases = append(page.Aliases, n)
+		}
+	}
+	return page
+}
+
+// EnsureIndex refreshes the index when it is older than a day.
+func EnsureIndex() error {
+	refreshed, err := layer3.ManIndexRefreshedAt()
+	if err != nil {
+		return err
+	}
+	if time.Since(refreshed) < indexMaxAge {
+		return nil
+	}
+	_, err = RefreshIndex(false)
+	return err
+}
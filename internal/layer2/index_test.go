@@ -0,0 +1,102 @@
+package layer2
+
+import (
+	"clio/internal/layer3"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writePage(t *testing.T, path, src string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if filepath.Ext(path) != ".gz" {
+		f.WriteString(src)
+		return
+	}
+	zw := gzip.NewWriter(f)
+	zw.Write([]byte(src))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestManRoots(t *testing.T) {
+	t.Setenv("PREFIX", "")
+	t.Setenv("MANPATH", "/opt/man::/opt/man")
+	got := ManRoots()
+	if len(got) < 2 || got[0] != "/opt/man" || got[1] != "/usr/share/man" {
+		t.Errorf("ManRoots() = %v, want /opt/man then the defaults", got)
+	}
+	for i, dir := range got {
+		if i > 0 && dir == "/opt/man" {
+			t.Errorf("ManRoots() repeats /opt/man: %v", got)
+		}
+	}
+}
+
+func TestIndexRefreshAndSearch(t *testing.T) {
+	layer3.UseTestDB(t)
+	root := t.TempDir()
+	t.Setenv("MANPATH", root)
+	writePage(t, filepath.Join(root, "man1", "ls.1.gz"), lsRoff)
+	writePage(t, filepath.Join(root, "man1", "gzip.1"), gzipMdoc)
+	writePage(t, filepath.Join(root, "man1", "gunzip.1.gz"), ".so man1/gzip.1\n")
+	writePage(t, filepath.Join(root, "man5", "ls.conf.5.gz"), lsRoff) // not a section clio searches
+	os.Symlink(filepath.Join(root, "man1", "ls.1.gz"), filepath.Join(root, "man1", "dir.1.gz"))
+
+	stats, err := RefreshIndex(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Pages != 2 || stats.Parsed != 3 {
+		t.Fatalf("first refresh = %+v, want 2 pages from 3 parsed files", stats)
+	}
+
+	tests := []struct {
+		keywords []string
+		first    string
+		hits     int
+	}{
+		{[]string{"list", "directory"}, "ls", 2},
+		{[]string{"compress"}, "gzip", 1},         // prefix of "compression"
+		{[]string{"gunzip"}, "gzip", 1},           // other names on the NAME line
+		{[]string{"stdout", "suffix"}, "gzip", 0}, // options only
+	}
+	for _, tt := range tests {
+		got := Search(tt.keywords)
+		if len(got) == 0 || got[0].Name != tt.first || got[0].Hits != tt.hits {
+			t.Errorf("Search(%v) = %+v, want %s first with %d hits", tt.keywords, got, tt.first, tt.hits)
+		}
+	}
+	if got := Search([]string{"xyzzy"}); len(got) != 0 {
+		t.Errorf("Search(xyzzy) = %+v", got)
+	}
+
+	// Unchanged files are not parsed again; edited and deleted ones are noticed
+	if stats, _ := RefreshIndex(false); stats.Parsed != 0 {
+		t.Errorf("second refresh parsed %d files", stats.Parsed)
+	}
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(filepath.Join(root, "man1", "gzip.1"), later, later)
+	os.Remove(filepath.Join(root, "man1", "ls.1.gz"))
+	stats, err = RefreshIndex(false)
+	if err != nil || stats.Parsed != 1 || stats.Removed != 1 || stats.Pages != 1 {
+		t.Errorf("refresh after changes = %+v, %v", stats, err)
+	}
+	if got := Search([]string{"list", "directory"}); len(got) != 0 {
+		t.Errorf("removed page still found: %+v", got)
+	}
+	if stats, _ := RefreshIndex(true); stats.Parsed != 2 {
+		t.Errorf("rebuild parsed %d files, want 2", stats.Parsed)
+	}
+}
@@ -3,24 +3,87 @@ package layer2
 import (
 	"bufio"
 	"clio/internal/config"
+	"clio/internal/layer3"
 	"clio/internal/safeexec"
+	"os"
+	"regexp"
 	"sort"
 	"strings"
+	"time"
 )
 
 type Result struct {
 	Name        string
 	Description string
 	Score       int
+	Hits        int    // keywords found in the page's name or NAME line
+	Section     string // "1", "8", layer3.HelpSection for --help output; "" from man -k
 }
 
+// maxResults is how many pages Search returns.
+const maxResults = 5
+
+// aproposTimeout bounds each man -k run of the fallback search.
+const aproposTimeout = 3 * time.Second
+
 // Search queries the system manual pages for the given keywords.
 // It returns a list of matching commands sorted by relevance.
+// Pages are searched in clio's own index (see RefreshIndex); man -k is only
+// used when the index cannot be opened.
 func Search(keywords []string) []Result {
 	if len(keywords) == 0 {
 		return nil
 	}
+	if results, err := searchIndex(keywords); err == nil {
+		return results
+	}
+	return searchApropos(keywords)
+}
+
+// searchIndex ranks indexed pages with BM25 and scores them like
+// searchApropos, so callers can keep one threshold for both.
+func searchIndex(keywords []string) ([]Result, error) {
+	if err := EnsureIndex(); err != nil {
+		return nil, err
+	}
+	matches, err := layer3.SearchManIndex(keywords, 4*maxResults)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var results []Result
+	for _, m := range matches {
+		if seen[m.Name] {
+			continue // same page in another section or MANPATH entry
+		}
+		seen[m.Name] = true
+		results = append(results, scoreResult(Result{Name: m.Name, Description: m.Description, Score: 10 * m.Hits, Hits: m.Hits, Section: m.Section}, keywords))
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if len(results) > maxResults {
+		results = results[:maxResults]
+	}
+	return results, nil
+}
 
+// scoreResult adds the bonus for a page named like a keyword or whose NAME
+// line contains one.
+func scoreResult(res Result, keywords []string) Result {
+	for _, kw := range keywords {
+		if res.Name == kw {
+			res.Score += 50
+		}
+		if strings.Contains(res.Description, kw) {
+			res.Score += 5
+		}
+	}
+	return res
+}
+
+// searchApropos runs man -k once per keyword.
+func searchApropos(keywords []string) []Result {
 	// On lite profile only search the top keyword to avoid multiple subprocess spawns
 	maxKeywords := len(keywords)
 	if config.IsLiteProfile() && maxKeywords > 1 {
@@ -38,6 +101,7 @@ func Search(keywords []string) []Result {
 		if err := cmd.Start(); err != nil {
 			continue
 		}
+		timer := time.AfterFunc(aproposTimeout, func() { cmd.Process.Kill() })
 
 		scanner := bufio.NewScanner(output)
 		for scanner.Scan() {
@@ -67,29 +131,23 @@ func Search(keywords []string) []Result {
 				}
 			}
 			matches[rawName].Score += 10
+			matches[rawName].Hits++
 		}
 		cmd.Wait()
+		timer.Stop()
 	}
 
 	results := make([]Result, 0, len(matches))
 	for _, res := range matches {
-		for _, kw := range keywords {
-			if res.Name == kw {
-				res.Score += 50
-			}
-			if strings.Contains(res.Description, kw) {
-				res.Score += 5
-			}
-		}
-		results = append(results, *res)
+		results = append(results, scoreResult(*res, keywords))
 	}
 
 	sort.Slice(results, func(i, j int) bool {
 		return results[i].Score > results[j].Score
 	})
 
-	if len(results) > 5 {
-		return results[:5]
+	if len(results) > maxResults {
+		return results[:maxResults]
 	}
 
 	return results
@@ -100,3 +158,87 @@ func IsInstalled(cmdName string) bool {
 	_, err := safeexec.LookPath(cmdName)
 	return err == nil
 }
+
+// Options returns flag descriptions parsed from the local man page of name,
+// e.g. "-z" → "filter the archive through gzip". Empty when there is no page.
+func Options(name string) map[string]string {
+	cmd := safeexec.Command("man", name)
+	cmd.Env = append(os.Environ(), "MANPAGER=cat", "PAGER=cat", "MANWIDTH=120")
+	out, err := cmd.Output()
+	if err != nil {
+		return nil
+	}
+	return ParseOptions(string(out))
+}
+
+// sectionHeading matches man page headings such as "OPTIONS" or "DESCRIPTION".
+var sectionHeading = regexp.MustCompile(`^[A-Z][A-Z /-]+$`)
+
+// optionLine matches an indented flag list like "-z, --gzip, --gunzip".
+var optionLine = regexp.MustCompile(`^\s+(-{1,2}[A-Za-z0-9?][\w-]*)((?:,\s*-{1,2}[\w-]+)*)(?:[ =]\S+)?(?:\s{2,}(.*))?$`)
+
+// ParseOptions reads flag descriptions from rendered man page text. It looks
+// in OPTIONS and, for GNU tools that list flags there, DESCRIPTION. A flag's
+// description is the rest of its line or the first indented line below it.
+func ParseOptions(text string) map[string]string {
+	opts := make(map[string]string)
+	inOptions := false
+	var pending []string // flags waiting for a description on the next line
+	for _, line := range strings.Split(stripOverstrike(text), "\n") {
+		if sectionHeading.MatchString(line) {
+			inOptions = strings.Contains(line, "OPTIONS") || line == "DESCRIPTION"
+			pending = nil
+			continue
+		}
+		if !inOptions {
+			continue
+		}
+		if m := optionLine.FindStringSubmatch(line); m != nil {
+			flags := []string{m[1]}
+			for _, f := range strings.Split(m[2], ",") {
+				if f = strings.TrimSpace(f); f != "" {
+					flags = append(flags, f)
+				}
+			}
+			if desc := strings.TrimSpace(m[3]); desc != "" {
+				setOptions(opts, flags, desc)
+				pending = nil
+			} else {
+				pending = flags
+			}
+			continue
+		}
+		if desc := strings.TrimSpace(line); desc != "" && len(pending) > 0 {
+			setOptions(opts, pending, desc)
+			pending = nil
+		}
+	}
+	return opts
+}
+
+func setOptions(opts map[string]string, flags []string, desc string) {
+	for _, f := range flags {
+		if _, ok := opts[f]; !ok {
+			opts[f] = desc
+		}
+	}
+}
+
+// stripOverstrike removes the backspace bold/underline sequences and ANSI
+// escapes man emits when formatting is kept.
+func stripOverstrike(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case i+1 < len(s) && s[i+1] == '\b':
+			i++ // drop the overstruck char; the one after \b is kept
+		case s[i] == '\x1b':
+			for i < len(s) && s[i] != 'm' {
+				i++
+			}
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,50 @@
+package layer2
+
+import "testing"
+
+const tarManPage = `TAR(1)                      GNU TAR Manual                      TAR(1)
+
+NAME
+       tar - an archiving utility
+
+OPTIONS
+   Operation modifiers
+       -z, --gzip, --gunzip, --ungzip
+              Filter the archive through gzip(1).
+
+       -f, --file=ARCHIVE
+              Use archive file or device ARCHIVE.
+
+       -v, --verbose
+              Verbosely list files processed.
+
+       --exclude=PATTERN   Exclude files matching PATTERN.
+
+SEE ALSO
+       -q     not an option section
+`
+
+func TestParseOptions(t *testing.T) {
+	opts := ParseOptions(tarManPage)
+	cases := map[string]string{
+		"-z":        "Filter the archive through gzip(1).",
+		"--gunzip":  "Filter the archive through gzip(1).",
+		"-f":        "Use archive file or device ARCHIVE.",
+		"--file":    "Use archive file or device ARCHIVE.",
+		"--exclude": "Exclude files matching PATTERN.",
+	}
+	for flag, want := range cases {
+		if got := opts[flag]; got != want {
+			t.Errorf("%s = %q, want %q", flag, got, want)
+		}
+	}
+	if _, ok := opts["-q"]; ok {
+		t.Error("parsed a flag outside OPTIONS")
+	}
+}
+
+func TestStripOverstrike(t *testing.T) {
+	if got := stripOverstrike("-\b-z\bz, \x1b[1m--gzip\x1b[0m"); got != "-z, --gzip" {
+		t.Errorf("stripOverstrike = %q", got)
+	}
+}
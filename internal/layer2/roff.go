@@ -0,0 +1,371 @@
+package layer2
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Page is the searchable text of a man page source.
+type Page struct {
+	Names       []string // from the NAME line: "gzip, gunzip, zcat"
+	Description string   // NAME line summary
+	Body        string   // DESCRIPTION text other than flag paragraphs
+	Options     string   // flag paragraphs from OPTIONS or DESCRIPTION
+}
+
+// ErrRedirect is returned for a page that is only ".so" to another page.
+var ErrRedirect = errors.New("man page redirects with .so")
+
+// Limits on indexed text per page; the start of DESCRIPTION says what a
+// command is for, and long option lists add little beyond their first flags.
+const (
+	maxBodyBytes    = 2000
+	maxOptionsBytes = 6000
+)
+
+// ParseRoff extracts the NAME, DESCRIPTION and OPTIONS text of a man page
+// written in man(7) or mdoc(7) roff. Formatting is dropped; it is meant for
+// search, not display.
+func ParseRoff(r io.Reader) (Page, error) {
+	var page Page
+	var body, options, name, para strings.Builder
+	section := ""
+	skipUntilDots := false
+
+	flush := func() {
+		text := strings.Join(strings.Fields(para.String()), " ")
+		para.Reset()
+		if text == "" {
+			return
+		}
+		switch {
+		case section == "NAME":
+			appendText(&name, text, 1<<10)
+		case strings.HasPrefix(text, "-") && (section == "DESCRIPTION" || strings.Contains(section, "OPTION")):
+			appendText(&options, text, maxOptionsBytes)
+		case section == "DESCRIPTION":
+			appendText(&body, text, maxBodyBytes)
+		case strings.Contains(section, "OPTION"):
+			appendText(&options, text, maxOptionsBytes)
+		}
+	}
+	add := func(s string) {
+		if s = strings.TrimSpace(s); s != "" {
+			para.WriteString(s)
+			para.WriteByte(' ')
+		}
+	}
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64<<10), 1<<20)
+	var line string
+	for sc.Scan() {
+		// A trailing backslash continues the line
+		l := sc.Text()
+		if strings.HasSuffix(l, `\`) && !strings.HasSuffix(l, `\\`) {
+			line += strings.TrimSuffix(l, `\`)
+			continue
+		}
+		l, line = line+l, ""
+
+		if skipUntilDots {
+			skipUntilDots = strings.TrimSpace(l) != ".."
+			continue
+		}
+		if l == "" || strings.HasPrefix(l, `.\"`) || strings.HasPrefix(l, `'\"`) || strings.HasPrefix(l, `\"`) {
+			continue
+		}
+		if l[0] != '.' && l[0] != '\'' {
+			add(unescape(l))
+			continue
+		}
+
+		fields := splitArgs(strings.TrimSpace(l[1:]))
+		if len(fields) == 0 {
+			continue
+		}
+		macro, args := fields[0], fields[1:]
+		switch macro {
+		case "so":
+			return page, ErrRedirect
+		case "de", "de1", "am", "ig":
+			skipUntilDots = true
+		case "SH", "Sh":
+			flush()
+			section = strings.ToUpper(unescape(strings.Join(args, " ")))
+		case "SS", "Ss", "PP", "P", "LP", "HP", "TP", "TQ", "Pp", "sp", "br", "Bl", "El", "Bd", "Ed", "RS", "RE":
+			flush()
+		case "IP":
+			flush()
+			if len(args) > 0 && strings.HasPrefix(unescape(args[0]), "-") {
+				add(unescape(args[0]))
+			}
+		case "It":
+			flush()
+			add(mdocText(args))
+		case "B", "I", "SM", "SB":
+			add(unescapeAll(args, " "))
+		case "BR", "IR", "RB", "RI", "BI", "IB":
+			add(unescapeAll(args, ""))
+		case "Nm":
+			if section == "NAME" {
+				for _, a := range args {
+					if n := strings.Trim(unescape(a), " ,"); n != "" {
+						page.Names = append(page.Names, n)
+					}
+				}
+			} else if len(page.Names) > 0 && len(args) == 0 {
+				add(page.Names[0])
+			} else {
+				add(mdocText(args))
+			}
+		case "Nd":
+			page.Description = mdocText(args)
+		default:
+			if mdocInline[macro] {
+				add(mdocText(fields))
+			}
+			// Other requests (.TH, .ft, .if, .Dd, ...) carry no searchable text
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return page, err
+	}
+	flush()
+
+	// man(7): "ls \- list directory contents"
+	if len(page.Names) == 0 {
+		names, desc, _ := strings.Cut(name.String(), " - ")
+		for _, n := range strings.Split(names, ",") {
+			if n = strings.TrimSpace(n); n != "" {
+				page.Names = append(page.Names, n)
+			}
+		}
+		page.Description = strings.TrimSpace(desc)
+	}
+	if len(page.Names) == 0 {
+		return page, errors.New("man page has no NAME section")
+	}
+	page.Body = body.String()
+	page.Options = options.String()
+	return page, nil
+}
+
+// appendText adds a paragraph to b, cutting it at a rune boundary once b
+// reaches limit bytes.
+func appendText(b *strings.Builder, text string, limit int) {
+	room := limit - b.Len()
+	if room <= 1 {
+		return
+	}
+	if b.Len() > 0 {
+		b.WriteByte(' ')
+		room--
+	}
+	if len(text) > room {
+		for room > 0 && !utf8.RuneStart(text[room]) {
+			room--
+		}
+		text = text[:room]
+	}
+	b.WriteString(text)
+}
+
+// splitArgs splits a request line into words, honoring "double quotes"
+// ("" is a literal quote) and keeping escapes such as "\ " inside a word.
+func splitArgs(s string) []string {
+	var out []string
+	var cur strings.Builder
+	inQuote, inWord := false, false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s):
+			if s[i+1] == '"' && !inQuote {
+				i = len(s) // \" starts a comment
+				continue
+			}
+			cur.WriteByte(c)
+			cur.WriteByte(s[i+1])
+			i++
+			inWord = true
+		case c == '"' && inQuote && i+1 < len(s) && s[i+1] == '"':
+			cur.WriteByte('"')
+			i++
+		case c == '"' && (inQuote || !inWord):
+			inQuote = !inQuote
+			inWord = true
+		case (c == ' ' || c == '\t') && !inQuote:
+			if inWord {
+				out = append(out, cur.String())
+				cur.Reset()
+				inWord = false
+			}
+		default:
+			cur.WriteByte(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		out = append(out, cur.String())
+	}
+	return out
+}
+
+func unescapeAll(args []string, sep string) string {
+	parts := make([]string, len(args))
+	for i, a := range args {
+		parts[i] = unescape(a)
+	}
+	return strings.Join(parts, sep)
+}
+
+// glyphs maps the roff special characters worth keeping as text.
+var glyphs = map[string]string{
+	"em": "-", "en": "-", "hy": "-", "mi": "-", "bu": "*", "aq": "'", "dq": `"`,
+	"lq": `"`, "rq": `"`, "oq": "'", "cq": "'", "co": "(c)", "rg": "(R)", "tm": "(TM)",
+	"R": "(R)", "Tm": "(TM)", "ti": "~", "ha": "^", "ga": "`", "rs": `\`, "ba": "|",
+}
+
+// unescape removes roff escapes from s: fonts, sizes, registers and strings
+// disappear, special characters become their plain-text look-alike.
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch c := s[i]; c {
+		case '-', '.', '\'':
+			b.WriteByte(c)
+		case 'e', '\\':
+			b.WriteByte('\\')
+		case ' ', '~', '0':
+			b.WriteByte(' ')
+		case '"':
+			return b.String() // comment to end of line
+		case '(':
+			if i+2 < len(s) {
+				b.WriteString(glyphs[s[i+1:i+3]])
+				i += 2
+			} else {
+				i = len(s)
+			}
+		case '[':
+			name, n := bracketed(s[i:])
+			b.WriteString(glyphs[name])
+			i += n - 1
+		case 'f', '*', 'n', 'F', 'g', 'k', 'm', 'M', 'V', 'Y':
+			// One-letter, (xx or [name] argument
+			if i+1 < len(s) {
+				switch s[i+1] {
+				case '(':
+					if c == '*' && i+3 < len(s) {
+						b.WriteString(glyphs[s[i+2:i+4]])
+					}
+					i += 3
+				case '[':
+					name, n := bracketed(s[i+1:])
+					if c == '*' {
+						b.WriteString(glyphs[name])
+					}
+					i += n
+				default:
+					if c == '*' {
+						b.WriteString(glyphs[s[i+1:i+2]])
+					}
+					i++
+				}
+			}
+		case 's':
+			// \s+2, \s-1, \s0, \s(12, \s[12]
+			if i+1 < len(s) && (s[i+1] == '+' || s[i+1] == '-') {
+				i++
+			}
+			switch {
+			case i+1 < len(s) && s[i+1] == '(':
+				i += 3
+			case i+1 < len(s) && s[i+1] == '[':
+				_, n := bracketed(s[i+1:])
+				i += n
+			default:
+				for j := 0; j < 2 && i+1 < len(s) && s[i+1] >= '0' && s[i+1] <= '9'; j++ {
+					i++
+				}
+			}
+		case 'h', 'v', 'w', 'l', 'L', 'D', 'X', 'Z', 'b', 'o', 'x', 'N', 'B', 'A', 'C', 'R', 'S', 'H':
+			// Delimited argument: \h'1n', \w'text'
+			if i+1 < len(s) {
+				delim := s[i+1]
+				if end := strings.IndexByte(s[i+2:], delim); end >= 0 {
+					i += end + 2
+				} else {
+					i = len(s)
+				}
+			}
+		default:
+			// \& \| \^ \, \/ \) \c \: \% and unknown escapes print nothing
+		}
+	}
+	return b.String()
+}
+
+// bracketed returns the name inside a leading "[name]" and the length consumed.
+func bracketed(s string) (string, int) {
+	end := strings.IndexByte(s, ']')
+	if end < 0 {
+		return "", len(s)
+	}
+	return s[1:end], end + 1
+}
+
+// mdocInline lists the mdoc(7) macros whose arguments are running text.
+var mdocInline = map[string]bool{
+	"Fl": true, "Ar": true, "Op": true, "Oo": true, "Oc": true, "Cm": true, "Pa": true,
+	"Xr": true, "Ql": true, "Dq": true, "Sq": true, "Qq": true, "Em": true, "Sy": true,
+	"Li": true, "Ev": true, "Va": true, "Ic": true, "Ns": true, "No": true, "Pq": true,
+	"Aq": true, "Bq": true, "Brq": true, "Dv": true, "Er": true, "Fa": true, "Fn": true,
+	"Ft": true, "Ad": true, "An": true, "Lk": true, "Mt": true, "Nm": true, "Tn": true,
+	"Ux": true, "Bx": true, "At": true, "Do": true, "Dc": true, "Po": true, "Pc": true,
+	"Qo": true, "Qc": true, "So": true, "Sc": true, "Xo": true, "Xc": true, "Ox": true,
+}
+
+// mdocText renders mdoc macro arguments: "Fl r Ar file" becomes "-r file".
+func mdocText(args []string) string {
+	var out []string
+	flag := false
+	for _, a := range args {
+		switch {
+		case a == "Fl" && flag && pendingFlag(out):
+			out[len(out)-1] += "-" // "Fl Fl stdout" is --stdout
+		case a == "Fl":
+			flag = true
+			out = append(out, "-")
+		case mdocInline[a]:
+			flag = false
+		case len(a) == 1 && strings.ContainsAny(a, ",.;:|()[]"):
+			flag = false
+			out = append(out, a)
+		case flag && pendingFlag(out):
+			out[len(out)-1] += unescape(a)
+		case flag:
+			out = append(out, "-"+unescape(a))
+		default:
+			out = append(out, unescape(a))
+		}
+	}
+	return strings.TrimSpace(strings.Join(out, " "))
+}
+
+// pendingFlag reports whether out ends with dashes waiting for a flag name.
+func pendingFlag(out []string) bool {
+	return len(out) > 0 && strings.Trim(out[len(out)-1], "-") == ""
+}
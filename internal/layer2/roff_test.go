@@ -0,0 +1,119 @@
+package layer2
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const lsRoff = `.\" DO NOT MODIFY THIS FILE!  It was generated by help2man 1.48.5.
+.TH LS "1" "September 2022" "GNU coreutils 9.1" "User Commands"
+.SH NAME
+ls, dir \- list directory contents
+.SH SYNOPSIS
+.B ls
+[\fI\,OPTION\/\fR]... [\fI\,FILE\/\fR]...
+.SH DESCRIPTION
+.\" Add any additional description here
+.PP
+List information about the FILEs (the current directory by default).
+Sort entries alphabetically if none of \fB\-cftuvSUX\fR nor \fB\-\-sort\fR is specified.
+.TP
+\fB\-a\fR, \fB\-\-all\fR
+do not ignore entries starting with .
+.TP
+.BR \-d ", " \-\-directory
+list directories themselves, not their contents
+.SH "SEE ALSO"
+\fBdircolors\fP(1)
+`
+
+const gzipMdoc = `.Dd $Mdocdate$
+.Dt GZIP 1
+.Os
+.Sh NAME
+.Nm gzip ,
+.Nm gunzip
+.Nd compression/decompression tool using Lempel\(enZiv coding (LZ77)
+.Sh DESCRIPTION
+The
+.Nm
+program compresses and decompresses files.
+.Sh OPTIONS
+.Bl -tag -width Ds
+.It Fl c , Fl Fl stdout
+write output to standard output
+.It Fl S Ar suffix
+use
+.Ar suffix
+as the compressed file suffix
+.El
+`
+
+func TestParseRoff(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want Page
+	}{
+		{"man", lsRoff, Page{
+			Names:       []string{"ls", "dir"},
+			Description: "list directory contents",
+			Body: "List information about the FILEs (the current directory by default). " +
+				"Sort entries alphabetically if none of -cftuvSUX nor --sort is specified.",
+			Options: "-a, --all do not ignore entries starting with . " +
+				"-d, --directory list directories themselves, not their contents",
+		}},
+		{"mdoc", gzipMdoc, Page{
+			Names:       []string{"gzip", "gunzip"},
+			Description: "compression/decompression tool using Lempel-Ziv coding (LZ77)",
+			Body:        "The gzip program compresses and decompresses files.",
+			Options:     "-c , --stdout write output to standard output -S suffix use suffix as the compressed file suffix",
+		}},
+	}
+	for _, tt := range tests {
+		got, err := ParseRoff(strings.NewReader(tt.src))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: ParseRoff =\n%+v\nwant\n%+v", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := ParseRoff(strings.NewReader(".so man1/gzip.1\n")); !errors.Is(err, ErrRedirect) {
+		t.Errorf(".so page: err = %v, want ErrRedirect", err)
+	}
+	if _, err := ParseRoff(strings.NewReader(".TH X 1\nno name section\n")); err == nil {
+		t.Error("page without NAME parsed")
+	}
+}
+
+func TestUnescape(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{`\fB\-\-all\fR`, "--all"},
+		{`\f[B]bold\f[]`, "bold"},
+		{`\f(CWcode\fP`, "code"},
+		{`a\(emb \[bu] c`, "a-b * c"},
+		{`\*(lqquoted\*(rq`, `"quoted"`},
+		{`\s-1SMALL\s0 and \s+2big\s[0]`, "SMALL and big"},
+		{`x\h'1n'y\&.`, "xy."},
+		{`text \" comment`, "text "},
+		{`back\e slash`, `back\ slash`},
+	}
+	for _, tt := range tests {
+		if got := unescape(tt.in); got != tt.want {
+			t.Errorf("unescape(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSplitArgs(t *testing.T) {
+	got := splitArgs(`BR "two words" x\ y "say ""hi""" \" comment`)
+	want := []string{"BR", "two words", `x\ y`, `say "hi"`}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("splitArgs = %q, want %q", got, want)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -18,15 +19,17 @@ type Module struct {
 	Description string
 	Command     string
 	Keywords    string
+	Score       float64 // relevance from SearchModules, 0 to 1
 }
 
 // ModuleMeta is module metadata without YAML content.
 type ModuleMeta struct {
-	ModuleID    string
-	Name        string
-	Description string
-	Version     string
-	Tags        string
+	ModuleID    string `json:"module_id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Version     string `json:"version"`
+	Tags        string `json:"tags"`
+	Origin      string `json:"origin"` // OriginRegistry or OriginLocal
 }
 
 var (
@@ -50,30 +53,72 @@ func GetDB() (*sql.DB, error) {
 
 		migrateLegacyDB(dbPath)
 
-		db, err := sql.Open("sqlite", dbPath)
+		db, err := Open(dbPath)
 		if err != nil {
 			dbErr = err
 			return
 		}
 
-		db.SetMaxOpenConns(1)
-		db.SetMaxIdleConns(1)
+		dbInstance = db
+	})
+	return dbInstance, dbErr
+}
 
-		if err := configureSQLite(db); err != nil {
-			db.Close()
-			dbErr = err
-			return
-		}
+// testingT is the part of testing.TB UseTestDB needs.
+type testingT interface {
+	Helper()
+	TempDir() string
+	Setenv(key, value string)
+	Fatalf(format string, args ...interface{})
+	Cleanup(func())
+}
 
-		if err := initSchema(db); err != nil {
-			db.Close()
-			dbErr = err
-			return
+// UseTestDB makes GetDB return a new database in a temporary directory, which
+// is also HOME, until the test t ends, so tests in any package start from an
+// empty ~/.clio and never touch the user's.
+func UseTestDB(t testingT) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	db, err := Open(filepath.Join(home, "clio.db"))
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	fresh := false
+	dbOnce.Do(func() { fresh = true })
+	prev, prevErr := dbInstance, dbErr
+	dbInstance, dbErr = db, nil
+	t.Cleanup(func() {
+		db.Close()
+		dbInstance, dbErr = prev, prevErr
+		if fresh {
+			dbOnce = sync.Once{} // GetDB never ran; let it open the real one
 		}
-
-		dbInstance = db
 	})
-	return dbInstance, dbErr
+}
+
+// Open opens the SQLite database at path, configures it for the current
+// profile and migrates its schema to the latest version. Most callers want
+// the shared GetDB instead.
+func Open(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+
+	if err := configureSQLite(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	if err := migrate(db, path); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
 }
 
 // migrateLegacyDB renames ~/.clio/modules.db to clio.db for older installs.
@@ -115,35 +160,6 @@ func configureSQLite(db *sql.DB) error {
 	return nil
 }
 
-func initSchema(db *sql.DB) error {
-	query := `
-	CREATE TABLE IF NOT EXISTS modules (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		module_id TEXT UNIQUE,
-		name TEXT NOT NULL,
-		description TEXT,
-		tags TEXT,
-		version TEXT,
-		content TEXT,
-		bash_script TEXT,
-		checksum TEXT,
-		synced_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);
-	
-	CREATE TABLE IF NOT EXISTS sync_metadata (
-		id INTEGER PRIMARY KEY CHECK (id = 1),
-		last_sync_timestamp TIMESTAMP
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_modules_search ON modules(name, tags);
-	`
-	_, err := db.Exec(query)
-	if err != nil {
-		return fmt.Errorf("init schema: %w", err)
-	}
-	return nil
-}
-
 // UpsertModule inserts or updates a module in the database (without checksum)
 func UpsertModule(modID, name, desc, tags, version, content, bashScript string) error {
 	db, err := GetDB()
@@ -162,8 +178,7 @@ func UpsertModule(modID, name, desc, tags, version, content, bashScript string)
         content=excluded.content,
         bash_script=excluded.bash_script;
     `
-	_, err = db.Exec(query, modID, name, desc, tags, version, content, bashScript)
-	return err
+	return upsertModule(db, OriginRegistry, query, modID, name, desc, tags, version, content, bashScript)
 }
 
 // UpsertModuleWithChecksum inserts or updates a module with checksum tracking
@@ -186,8 +201,7 @@ func UpsertModuleWithChecksum(modID, name, desc, tags, version, content, bashScr
         checksum=excluded.checksum,
         synced_at=CURRENT_TIMESTAMP;
     `
-	_, err = db.Exec(query, modID, name, desc, tags, version, content, bashScript, checksum)
-	return err
+	return upsertModule(db, OriginRegistry, query, modID, name, desc, tags, version, content, bashScript, checksum)
 }
 
 // ModuleExists reports whether a module ID is present (metadata only, no content load).
@@ -258,7 +272,7 @@ func ListModuleMeta() ([]ModuleMeta, error) {
 	if err != nil {
 		return nil, err
 	}
-	rows, err := db.Query(`SELECT module_id, name, description, version, tags FROM modules ORDER BY module_id`)
+	rows, err := db.Query(`SELECT module_id, name, description, version, tags, origin FROM modules ORDER BY module_id`)
 	if err != nil {
 		return nil, err
 	}
@@ -267,7 +281,7 @@ func ListModuleMeta() ([]ModuleMeta, error) {
 	var out []ModuleMeta
 	for rows.Next() {
 		var m ModuleMeta
-		if err := rows.Scan(&m.ModuleID, &m.Name, &m.Description, &m.Version, &m.Tags); err != nil {
+		if err := rows.Scan(&m.ModuleID, &m.Name, &m.Description, &m.Version, &m.Tags, &m.Origin); err != nil {
 			continue
 		}
 		out = append(out, m)
@@ -283,9 +297,9 @@ func FindModuleMeta(moduleID string) (*ModuleMeta, error) {
 	}
 	var m ModuleMeta
 	err = db.QueryRow(
-		`SELECT module_id, name, description, version, tags FROM modules WHERE module_id = ?`,
+		`SELECT module_id, name, description, version, tags, origin FROM modules WHERE module_id = ?`,
 		moduleID,
-	).Scan(&m.ModuleID, &m.Name, &m.Description, &m.Version, &m.Tags)
+	).Scan(&m.ModuleID, &m.Name, &m.Description, &m.Version, &m.Tags, &m.Origin)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -295,70 +309,68 @@ func FindModuleMeta(moduleID string) (*ModuleMeta, error) {
 	return &m, nil
 }
 
-// SearchModules searches the database for modules matching the given keywords.
-// Only metadata columns are read — never loads YAML content or bash_script.
-func SearchModules(keywords []string) ([]Module, error) {
-	if len(keywords) == 0 {
-		return nil, nil
-	}
-
+// GetModuleByID retrieves a module by its module_id and returns the full YAML content
+func GetModuleByID(moduleID string) (string, error) {
 	db, err := GetDB()
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
-	// Cap keywords on lite profile to reduce query size
-	if platform.IsLiteProfile() && len(keywords) > 2 {
-		keywords = keywords[:2]
+	query := "SELECT content FROM modules WHERE module_id = ?"
+	var content string
+	err = db.QueryRow(query, moduleID).Scan(&content)
+	if err != nil {
+		return "", fmt.Errorf("module not found: %w", err)
 	}
 
-	query := "SELECT id, name, description, module_id, tags FROM modules WHERE "
-	args := []interface{}{}
+	return content, nil
+}
 
-	for i, kw := range keywords {
-		if i > 0 {
-			query += " OR "
+// ModuleSource is a module's YAML as stored, with its checksum.
+type ModuleSource struct {
+	ModuleID string
+	Checksum string
+	Content  string
+}
+
+// ModuleSources returns the stored YAML of the given modules, or of every
+// module when ids is empty, ordered by module_id.
+func ModuleSources(ids []string) ([]ModuleSource, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+	query := "SELECT module_id, COALESCE(checksum, ''), COALESCE(content, '') FROM modules"
+	args := make([]interface{}, len(ids))
+	if len(ids) > 0 {
+		query += " WHERE module_id IN (?" + strings.Repeat(", ?", len(ids)-1) + ")"
+		for i, id := range ids {
+			args[i] = id
 		}
-		query += "name LIKE ? OR description LIKE ? OR tags LIKE ?"
-		term := "%" + kw + "%"
-		args = append(args, term, term, term)
 	}
-
-	query += " LIMIT 3"
-
-	rows, err := db.Query(query, args...)
+	rows, err := db.Query(query+" ORDER BY module_id", args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var modules []Module
+	var out []ModuleSource
+	found := make(map[string]bool)
 	for rows.Next() {
-		var m Module
-		var modID, tags string
-		if err := rows.Scan(&m.ID, &m.Name, &m.Description, &modID, &tags); err != nil {
-			continue
+		var m ModuleSource
+		if err := rows.Scan(&m.ModuleID, &m.Checksum, &m.Content); err != nil {
+			return nil, err
 		}
-		m.Command = "clio-run-module " + modID
-		m.Keywords = tags
-		modules = append(modules, m)
+		found[m.ModuleID] = true
+		out = append(out, m)
 	}
-	return modules, nil
-}
-
-// GetModuleByID retrieves a module by its module_id and returns the full YAML content
-func GetModuleByID(moduleID string) (string, error) {
-	db, err := GetDB()
-	if err != nil {
-		return "", err
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
-
-	query := "SELECT content FROM modules WHERE module_id = ?"
-	var content string
-	err = db.QueryRow(query, moduleID).Scan(&content)
-	if err != nil {
-		return "", fmt.Errorf("module not found: %w", err)
+	for _, id := range ids {
+		if !found[id] {
+			return nil, fmt.Errorf("module %q not found", id)
+		}
 	}
-
-	return content, nil
+	return out, nil
 }
@@ -0,0 +1,105 @@
+package layer3
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Outcomes recorded for a suggested command.
+const (
+	OutcomeRan       = "ran"       // run as suggested (placeholders filled in)
+	OutcomeEdited    = "edited"    // run after the user changed it
+	OutcomeCancelled = "cancelled" // declined or searched again
+)
+
+// HistoryEntry is one answered query and what the user did with it.
+type HistoryEntry struct {
+	ID           int64     `json:"id"`
+	Query        string    `json:"query"`
+	Keywords     string    `json:"-"`       // normalized wording used to match similar queries
+	Command      string    `json:"command"` // suggested command (catalog template if any)
+	FinalCommand string    `json:"final_command,omitempty"`
+	Description  string    `json:"description,omitempty"`
+	Source       string    `json:"source"`
+	Outcome      string    `json:"outcome"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// RecordHistory stores one query outcome.
+func RecordHistory(e HistoryEntry) error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`
+		INSERT INTO query_history (query, keywords, command, final_command, description, source, outcome)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		e.Query, e.Keywords, e.Command, e.FinalCommand, e.Description, e.Source, e.Outcome)
+	return err
+}
+
+// ListHistory returns the newest entries first. limit <= 0 returns all.
+func ListHistory(limit int) ([]HistoryEntry, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = -1 // SQLite: no limit
+	}
+	rows, err := db.Query(`
+		SELECT id, query, keywords, command, COALESCE(final_command, ''), COALESCE(description, ''),
+		       COALESCE(source, ''), outcome, created_at
+		FROM query_history ORDER BY id DESC LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []HistoryEntry
+	for rows.Next() {
+		var e HistoryEntry
+		if err := rows.Scan(&e.ID, &e.Query, &e.Keywords, &e.Command, &e.FinalCommand,
+			&e.Description, &e.Source, &e.Outcome, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+// GetHistory returns one entry by ID.
+func GetHistory(id int64) (*HistoryEntry, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+	var e HistoryEntry
+	err = db.QueryRow(`
+		SELECT id, query, keywords, command, COALESCE(final_command, ''), COALESCE(description, ''),
+		       COALESCE(source, ''), outcome, created_at
+		FROM query_history WHERE id = ?`, id,
+	).Scan(&e.ID, &e.Query, &e.Keywords, &e.Command, &e.FinalCommand,
+		&e.Description, &e.Source, &e.Outcome, &e.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("no history entry %d", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// ClearHistory deletes every entry and returns how many were removed.
+func ClearHistory() (int64, error) {
+	db, err := GetDB()
+	if err != nil {
+		return 0, err
+	}
+	res, err := db.Exec("DELETE FROM query_history")
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,301 @@
+package layer3
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// HelpSection is the section of pages harvested from --help output instead
+// of man page sources. Their Path is the executable.
+const HelpSection = "help"
+
+// ManPage is one man page source file and the text indexed from it.
+type ManPage struct {
+	Path        string
+	Name        string   // page name; "" for pages that only redirect (.so)
+	Section     string   // "1", "8", ... or HelpSection
+	Aliases     []string // other names from the NAME line, e.g. gunzip for gzip
+	Description string   // NAME line summary
+	Body        string   // DESCRIPTION text
+	Options     string   // flags and their descriptions
+	MTime       int64
+	Size        int64
+}
+
+// ManStamp identifies the version of an indexed source file.
+type ManStamp struct {
+	MTime int64
+	Size  int64
+}
+
+// ManMatch is a page found by SearchManIndex.
+type ManMatch struct {
+	Name        string
+	Section     string
+	Description string
+	Rank        float64 // BM25, lower is better
+	Hits        int     // terms found in the page's name or NAME line
+}
+
+// ManIndexStamps returns the stamp of every indexed man page source by path.
+func ManIndexStamps() (map[string]ManStamp, error) {
+	return indexStamps("section != ?")
+}
+
+// HelpIndexStamps returns the stamp of every harvested executable by path.
+func HelpIndexStamps() (map[string]ManStamp, error) {
+	return indexStamps("section = ?")
+}
+
+func indexStamps(where string) (map[string]ManStamp, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Query("SELECT path, mtime, size FROM man_pages WHERE "+where, HelpSection)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make(map[string]ManStamp)
+	for rows.Next() {
+		var path string
+		var s ManStamp
+		if err := rows.Scan(&path, &s.MTime, &s.Size); err != nil {
+			return nil, err
+		}
+		out[path] = s
+	}
+	return out, rows.Err()
+}
+
+// UpdateManIndex stores changed pages, drops removed paths and records the
+// refresh time, all in one transaction.
+func UpdateManIndex(changed []ManPage, removed []string) error {
+	return updateIndex(changed, removed, `
+		INSERT INTO man_index_meta (id, refreshed_at) VALUES (1, ?)
+		ON CONFLICT(id) DO UPDATE SET refreshed_at=excluded.refreshed_at`)
+}
+
+// UpdateHelpIndex is UpdateManIndex for pages harvested from --help output.
+func UpdateHelpIndex(changed []ManPage, removed []string) error {
+	return updateIndex(changed, removed, `
+		INSERT INTO help_index_meta (id, harvested_at) VALUES (1, ?)
+		ON CONFLICT(id) DO UPDATE SET harvested_at=excluded.harvested_at`)
+}
+
+func updateIndex(changed []ManPage, removed []string, stampQuery string) error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	drop := func(path string) error {
+		if _, err := tx.Exec("DELETE FROM man_fts WHERE rowid IN (SELECT id FROM man_pages WHERE path = ?)", path); err != nil {
+			return err
+		}
+		_, err := tx.Exec("DELETE FROM man_pages WHERE path = ?", path)
+		return err
+	}
+	for _, path := range removed {
+		if err := drop(path); err != nil {
+			return err
+		}
+	}
+	for _, p := range changed {
+		if err := drop(p.Path); err != nil {
+			return err
+		}
+		res, err := tx.Exec(`
+			INSERT INTO man_pages (path, name, section, description, mtime, size) VALUES (?, ?, ?, ?, ?, ?)`,
+			p.Path, p.Name, p.Section, p.Description, p.MTime, p.Size)
+		if err != nil {
+			return err
+		}
+		if p.Name == "" {
+			continue // remembered so it is not parsed again, but not searchable
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		names := strings.Join(append([]string{p.Name}, p.Aliases...), " ")
+		if _, err := tx.Exec(`INSERT INTO man_fts (rowid, name, description, body, options) VALUES (?, ?, ?, ?, ?)`,
+			id, names, p.Description, p.Body, p.Options); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(stampQuery, time.Now().UTC()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ManIndexRefreshedAt returns when the index was last refreshed; zero if never.
+func ManIndexRefreshedAt() (time.Time, error) {
+	db, err := GetDB()
+	if err != nil {
+		return time.Time{}, err
+	}
+	var t sql.NullTime
+	err = db.QueryRow("SELECT refreshed_at FROM man_index_meta WHERE id = 1").Scan(&t)
+	if err == sql.ErrNoRows || !t.Valid {
+		return time.Time{}, nil
+	}
+	return t.Time, err
+}
+
+// HelpIndexHarvestedAt returns when --help output was last harvested; zero if never.
+func HelpIndexHarvestedAt() (time.Time, error) {
+	db, err := GetDB()
+	if err != nil {
+		return time.Time{}, err
+	}
+	var t sql.NullTime
+	err = db.QueryRow("SELECT harvested_at FROM help_index_meta WHERE id = 1").Scan(&t)
+	if err == sql.ErrNoRows || !t.Valid {
+		return time.Time{}, nil
+	}
+	return t.Time, err
+}
+
+// ManIndexSize returns how many searchable man pages are indexed.
+func ManIndexSize() (int, error) {
+	return indexSize("section != ?")
+}
+
+// HelpIndexSize returns how many searchable --help pages are indexed.
+func HelpIndexSize() (int, error) {
+	return indexSize("section = ?")
+}
+
+func indexSize(where string) (int, error) {
+	db, err := GetDB()
+	if err != nil {
+		return 0, err
+	}
+	var n int
+	err = db.QueryRow("SELECT COUNT(*) FROM man_pages WHERE name != '' AND "+where, HelpSection).Scan(&n)
+	return n, err
+}
+
+// IndexedNames returns the names of searchable man pages, for skipping
+// commands that already have one when harvesting --help output.
+func IndexedNames() (map[string]bool, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Query("SELECT name FROM man_pages WHERE name != '' AND section != ?", HelpSection)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		out[name] = true
+	}
+	return out, rows.Err()
+}
+
+// SearchManIndex ranks indexed pages, man and --help alike, matching any of
+// terms with BM25: name matches weigh most, then the NAME line, options and
+// DESCRIPTION. Terms of three or more letters also match as prefixes, so the
+// stem "lin" finds "line". Each match counts the terms found in its name or
+// NAME line.
+func SearchManIndex(terms []string, limit int) ([]ManMatch, error) {
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	phrases := make([]string, len(terms))
+	for i, t := range terms {
+		phrases[i] = ftsPhrase(t)
+	}
+	rows, err := db.Query(`
+		SELECT f.rowid, p.name, p.section, p.description, bm25(man_fts, 10.0, 5.0, 1.0, 2.0) AS rank
+		FROM man_fts f JOIN man_pages p ON p.id = f.rowid
+		WHERE man_fts MATCH ?
+		ORDER BY rank LIMIT ?`, strings.Join(phrases, " OR "), limit)
+	if err != nil {
+		return nil, err
+	}
+	var out []ManMatch
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		var m ManMatch
+		if err := rows.Scan(&id, &m.Name, &m.Section, &m.Description, &m.Rank); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		out = append(out, m)
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil || len(out) == 0 {
+		return nil, err
+	}
+
+	hits, err := termHits(db, "man_fts", "{name description} : ", phrases, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range out {
+		out[i].Hits = hits[i]
+	}
+	return out, nil
+}
+
+// termHits counts, for each of the rows ids of an FTS5 table, how many of
+// phrases it matches. filter is an optional column filter such as
+// "{name description} : ".
+func termHits(db *sql.DB, table, filter string, phrases []string, ids []int64) ([]int, error) {
+	index := make(map[int64]int, len(ids))
+	args := make([]interface{}, 1, len(ids)+1)
+	for i, id := range ids {
+		index[id] = i
+		args = append(args, id)
+	}
+	in := "?" + strings.Repeat(", ?", len(ids)-1)
+	out := make([]int, len(ids))
+	for _, phrase := range phrases {
+		args[0] = filter + phrase
+		rows, err := db.Query("SELECT rowid FROM "+table+" WHERE "+table+" MATCH ? AND rowid IN ("+in+")", args...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err == nil {
+				out[index[id]]++
+			}
+		}
+		rows.Close()
+	}
+	return out, nil
+}
+
+// ftsPhrase quotes t as an FTS5 string, with a prefix match for longer terms.
+func ftsPhrase(t string) string {
+	q := `"` + strings.ReplaceAll(t, `"`, `""`) + `"`
+	if len(t) >= 3 {
+		q += "*"
+	}
+	return q
+}
@@ -0,0 +1,240 @@
+package layer3
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+)
+
+// A migration moves the schema from one version to the next. migrations[i]
+// takes PRAGMA user_version from i to i+1; it runs in a transaction with the
+// version bump, so a failed migration leaves the database as it was.
+//
+// Append new migrations; never edit or reorder ones that have shipped.
+type migration struct {
+	name string
+	up   func(tx *sql.Tx) error
+}
+
+var migrations = []migration{
+	{"baseline schema", func(tx *sql.Tx) error {
+		// IF NOT EXISTS adopts the tables of databases created before
+		// schema versions, which are at user_version 0.
+		_, err := tx.Exec(baselineSchema)
+		return err
+	}},
+	{"index modules for full-text search", func(tx *sql.Tx) error {
+		return indexMissingModules(tx)
+	}},
+	{"module version history", func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+	CREATE TABLE module_versions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		module_id TEXT NOT NULL,
+		version TEXT NOT NULL DEFAULT '',
+		checksum TEXT NOT NULL DEFAULT '',
+		content TEXT NOT NULL,
+		bash_script TEXT NOT NULL DEFAULT '',
+		synced_at TIMESTAMP NOT NULL
+	);
+
+	CREATE INDEX idx_module_versions ON module_versions(module_id, id);
+
+	ALTER TABLE modules ADD COLUMN pinned INTEGER NOT NULL DEFAULT 0;
+
+	INSERT INTO module_versions (module_id, version, checksum, content, bash_script, synced_at)
+	SELECT module_id, COALESCE(version, ''), COALESCE(checksum, ''), COALESCE(content, ''),
+		COALESCE(bash_script, ''), COALESCE(synced_at, CURRENT_TIMESTAMP)
+	FROM modules ORDER BY id;`)
+		return err
+	}},
+	{"module origin", func(tx *sql.Tx) error {
+		_, err := tx.Exec(`ALTER TABLE modules ADD COLUMN origin TEXT NOT NULL DEFAULT 'registry'`)
+		return err
+	}},
+	{"index tldr examples for full-text search", func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+	CREATE VIRTUAL TABLE tldr_fts USING fts5(
+		name, example, page,
+		tokenize = 'porter unicode61'
+	);
+
+	INSERT INTO tldr_fts (rowid, name, example, page)
+	SELECT e.id, p.name, e.description || ' ' || e.command, p.description
+	FROM tldr_examples e JOIN tldr_pages p ON p.name = e.name AND p.platform = e.platform;`)
+		return err
+	}},
+}
+
+// SchemaVersion is the user_version of a fully migrated database.
+func SchemaVersion() int {
+	return len(migrations)
+}
+
+// migrate brings db at path up to SchemaVersion. A database with tables is
+// first copied to path.v<version>.bak, so a bad migration can be undone by
+// hand; an empty one is created without a backup.
+func migrate(db *sql.DB, path string) error {
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("read schema version: %w", err)
+	}
+	if version > len(migrations) {
+		return fmt.Errorf("database schema version %d is newer than this clio supports (%d); upgrade clio", version, len(migrations))
+	}
+	if version == len(migrations) {
+		return nil
+	}
+
+	var tables int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master").Scan(&tables); err != nil {
+		return err
+	}
+	if tables > 0 {
+		if err := backup(db, fmt.Sprintf("%s.v%d.bak", path, version)); err != nil {
+			return fmt.Errorf("back up database before migrating: %w", err)
+		}
+	}
+
+	for v := version; v < len(migrations); v++ {
+		if err := runMigration(db, v); err != nil {
+			return fmt.Errorf("migration %d (%s): %w", v+1, migrations[v].name, err)
+		}
+	}
+	return nil
+}
+
+func runMigration(db *sql.DB, v int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := migrations[v].up(tx); err != nil {
+		return err
+	}
+	// PRAGMA takes no parameters; v is an int
+	if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", v+1)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// backup writes a consistent copy of db to dest, replacing an older one.
+func backup(db *sql.DB, dest string) error {
+	if err := os.Remove(dest); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	_, err := db.Exec("VACUUM INTO ?", dest)
+	return err
+}
+
+// baselineSchema is the schema as of the first versioned release.
+const baselineSchema = `
+		CREATE TABLE IF NOT EXISTS modules (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			module_id TEXT UNIQUE,
+			name TEXT NOT NULL,
+			description TEXT,
+			tags TEXT,
+			version TEXT,
+			content TEXT,
+			bash_script TEXT,
+			checksum TEXT,
+			synced_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE TABLE IF NOT EXISTS sync_metadata (
+			id INTEGER PRIMARY KEY CHECK (id = 1),
+			last_sync_timestamp TIMESTAMP
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_modules_search ON modules(name, tags);
+
+		CREATE VIRTUAL TABLE IF NOT EXISTS modules_fts USING fts5(
+			name, description, tags, steps,
+			tokenize = 'porter unicode61'
+		);
+
+		CREATE TABLE IF NOT EXISTS module_runs (
+			run_id INTEGER PRIMARY KEY AUTOINCREMENT,
+			module_id TEXT NOT NULL,
+			flow TEXT NOT NULL,
+			status TEXT NOT NULL,
+			completed_sections TEXT NOT NULL DEFAULT '[]',
+			variables TEXT NOT NULL DEFAULT '{}',
+			started_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_module_runs_lookup ON module_runs(module_id, flow, status);
+
+		CREATE TABLE IF NOT EXISTS query_history (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			query TEXT NOT NULL,
+			keywords TEXT NOT NULL,
+			command TEXT NOT NULL,
+			final_command TEXT,
+			description TEXT,
+			source TEXT,
+			outcome TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE TABLE IF NOT EXISTS tldr_pages (
+			name TEXT NOT NULL,
+			platform TEXT NOT NULL,
+			description TEXT NOT NULL DEFAULT '',
+			PRIMARY KEY (name, platform)
+		);
+
+		CREATE TABLE IF NOT EXISTS tldr_examples (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL,
+			platform TEXT NOT NULL,
+			description TEXT NOT NULL,
+			command TEXT NOT NULL
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_tldr_examples_page ON tldr_examples(name, platform);
+
+		CREATE TABLE IF NOT EXISTS man_pages (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			path TEXT UNIQUE NOT NULL,
+			name TEXT NOT NULL,
+			section TEXT NOT NULL,
+			description TEXT NOT NULL DEFAULT '',
+			mtime INTEGER NOT NULL,
+			size INTEGER NOT NULL
+		);
+
+		CREATE VIRTUAL TABLE IF NOT EXISTS man_fts USING fts5(
+			name, description, body, options,
+			tokenize = 'porter unicode61'
+		);
+
+		CREATE TABLE IF NOT EXISTS man_index_meta (
+			id INTEGER PRIMARY KEY CHECK (id = 1),
+			refreshed_at TIMESTAMP
+		);
+
+		CREATE TABLE IF NOT EXISTS help_index_meta (
+			id INTEGER PRIMARY KEY CHECK (id = 1),
+			harvested_at TIMESTAMP
+		);
+
+		CREATE TABLE IF NOT EXISTS tldr_meta (
+			id INTEGER PRIMARY KEY CHECK (id = 1),
+			source TEXT NOT NULL,
+			pages INTEGER NOT NULL,
+			imported_at TIMESTAMP
+		);
+
+
+		CREATE TABLE IF NOT EXISTS query_cache (
+			query_hash TEXT PRIMARY KEY,
+			command TEXT NOT NULL,
+			description TEXT,
+			cached_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+`
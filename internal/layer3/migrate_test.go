@@ -0,0 +1,189 @@
+package layer3
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func userVersion(t *testing.T, db *sql.DB) int {
+	t.Helper()
+	var v int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func hasTable(db *sql.DB, name string) bool {
+	var n int
+	db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name = ?", name).Scan(&n)
+	return n > 0
+}
+
+func TestMigrateFresh(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clio.db")
+	for i := 0; i < 2; i++ {
+		db, err := Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v := userVersion(t, db); v != SchemaVersion() {
+			t.Errorf("user_version = %d, want %d", v, SchemaVersion())
+		}
+		for _, table := range []string{"modules", "sync_metadata", "query_cache", "modules_fts", "man_pages"} {
+			if !hasTable(db, table) {
+				t.Errorf("table %s missing", table)
+			}
+		}
+		db.Close()
+	}
+	if backups, _ := filepath.Glob(path + ".*.bak"); len(backups) != 0 {
+		t.Errorf("backups of a new database: %v", backups)
+	}
+}
+
+func TestMigrateLegacy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clio.db")
+	old, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A database from before schema versions, without query_cache or the
+	// full-text index
+	_, err = old.Exec(`
+		CREATE TABLE modules (
+			id INTEGER PRIMARY KEY AUTOINCREMENT, module_id TEXT UNIQUE, name TEXT NOT NULL,
+			description TEXT, tags TEXT, version TEXT, content TEXT, bash_script TEXT,
+			checksum TEXT, synced_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP);
+		INSERT INTO modules (module_id, name, description, tags) VALUES ('backup', 'Backup', 'Back up your home directory', 'backup');`)
+	old.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if v := userVersion(t, db); v != SchemaVersion() {
+		t.Errorf("user_version = %d, want %d", v, SchemaVersion())
+	}
+	if !hasTable(db, "query_cache") {
+		t.Error("query_cache not created")
+	}
+	var n int
+	db.QueryRow("SELECT COUNT(*) FROM modules_fts WHERE modules_fts MATCH 'home'").Scan(&n)
+	if n != 1 {
+		t.Errorf("existing module not indexed: %d matches", n)
+	}
+	db.QueryRow("SELECT COUNT(*) FROM module_versions WHERE module_id = 'backup'").Scan(&n)
+	if n != 1 {
+		t.Errorf("existing module has %d stored versions, want 1", n)
+	}
+	var origin string
+	db.QueryRow("SELECT origin FROM modules WHERE module_id = 'backup'").Scan(&origin)
+	if origin != OriginRegistry {
+		t.Errorf("existing module has origin %q, want %q", origin, OriginRegistry)
+	}
+
+	bak, err := sql.Open("sqlite", path+".v0.bak")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bak.Close()
+	if err := bak.QueryRow("SELECT COUNT(*) FROM modules").Scan(&n); err != nil || n != 1 {
+		t.Errorf("backup has %d modules (%v), want 1", n, err)
+	}
+	if hasTable(bak, "query_cache") {
+		t.Error("backup was taken after migrating")
+	}
+}
+
+func TestMigrateIndexesTLDR(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clio.db")
+	saved := migrations
+	migrations = migrations[:4]
+	db, err := Open(path)
+	migrations = saved
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`
+		INSERT INTO tldr_pages (name, platform, description) VALUES ('shred', 'common', 'Overwrite files');
+		INSERT INTO tldr_examples (name, platform, description, command) VALUES ('shred', 'common', 'Delete a file securely', 'shred -u {{file}}');`)
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var n int
+	db.QueryRow("SELECT COUNT(*) FROM tldr_fts WHERE tldr_fts MATCH 'securely'").Scan(&n)
+	if n != 1 {
+		t.Errorf("existing tldr example not indexed: %d matches", n)
+	}
+}
+
+func TestMigrateNewerDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clio.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Exec("CREATE TABLE future (id INTEGER)")
+	db.Exec("PRAGMA user_version = 1000")
+	db.Close()
+
+	if db, err := Open(path); err == nil {
+		db.Close()
+		t.Fatal("Open of a newer database succeeded")
+	}
+	if _, err := os.Stat(path + ".v1000.bak"); err == nil {
+		t.Error("newer database was backed up")
+	}
+}
+
+func TestMigrationFailureRollsBack(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clio.db")
+	db, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	defer func(m []migration) { migrations = m }(migrations)
+	migrations = append(migrations[:len(migrations):len(migrations)], migration{"broken", func(tx *sql.Tx) error {
+		if _, err := tx.Exec("CREATE TABLE half_done (id INTEGER)"); err != nil {
+			return err
+		}
+		return errors.New("broken")
+	}})
+
+	if db, err := Open(path); err == nil {
+		db.Close()
+		t.Fatal("Open with a failing migration succeeded")
+	}
+	db, err = sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if v := userVersion(t, db); v != len(migrations)-1 {
+		t.Errorf("user_version = %d after a failed migration, want %d", v, len(migrations)-1)
+	}
+	if hasTable(db, "half_done") {
+		t.Error("failed migration was not rolled back")
+	}
+	if _, err := os.Stat(fmt.Sprintf("%s.v%d.bak", path, len(migrations)-1)); err != nil {
+		t.Errorf("no backup before the failed migration: %v", err)
+	}
+}
@@ -0,0 +1,233 @@
+package layer3
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxModuleResults is how many modules SearchModules returns.
+const maxModuleResults = 5
+
+// tagBoost is added to a module's score for each keyword naming one of its
+// tags; authors pick tags to say what a module is for.
+const tagBoost = 0.25
+
+// moduleExec is what indexing needs from a *sql.DB or *sql.Tx.
+type moduleExec interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// upsertModule runs an INSERT ... ON CONFLICT for the module whose ID is
+// args[0], records the new version in its history and reindexes it, all in
+// one transaction. A pinned module is left as it is, with ErrModulePinned,
+// and so is a local one when origin is not OriginLocal, with ErrModuleLocal.
+func upsertModule(db *sql.DB, origin, query string, args ...interface{}) error {
+	moduleID := args[0].(string)
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if pinned, err := modulePinned(tx, moduleID); err != nil {
+		return err
+	} else if pinned {
+		return fmt.Errorf("%s: %w", moduleID, ErrModulePinned)
+	}
+	if cur, err := moduleOrigin(tx, moduleID); err != nil {
+		return err
+	} else if cur == OriginLocal && origin != OriginLocal {
+		return fmt.Errorf("%s: %w", moduleID, ErrModuleLocal)
+	}
+	if _, err := tx.Exec(query, args...); err != nil {
+		return err
+	}
+	if err := recordVersion(tx, moduleID); err != nil {
+		return err
+	}
+	if err := indexModule(tx, moduleID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// indexModule replaces the full-text entry of one module.
+func indexModule(ex moduleExec, moduleID string) error {
+	var id int64
+	var name, desc, tags, content sql.NullString
+	err := ex.QueryRow("SELECT id, name, description, tags, content FROM modules WHERE module_id = ?", moduleID).
+		Scan(&id, &name, &desc, &tags, &content)
+	if err != nil {
+		return err
+	}
+	if _, err := ex.Exec("DELETE FROM modules_fts WHERE rowid = ?", id); err != nil {
+		return err
+	}
+	_, err = ex.Exec("INSERT INTO modules_fts (rowid, name, description, tags, steps) VALUES (?, ?, ?, ?, ?)",
+		id, name.String+" "+strings.ReplaceAll(moduleID, "_", " "), desc.String,
+		strings.ReplaceAll(tags.String, ",", " "), moduleStepText(content.String))
+	return err
+}
+
+// indexMissingModules indexes modules stored before the full-text index
+// existed.
+func indexMissingModules(ex moduleExec) error {
+	rows, err := ex.Query("SELECT module_id FROM modules WHERE id NOT IN (SELECT rowid FROM modules_fts)")
+	if err != nil {
+		return err
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := indexModule(ex, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// indexedFlow is the part of a module's YAML that says what its flows do.
+type indexedFlow struct {
+	Name        string        `yaml:"name"`
+	Description string        `yaml:"description"`
+	Steps       []indexedStep `yaml:"steps"`
+}
+
+type indexedStep struct {
+	Description string        `yaml:"description"`
+	Title       string        `yaml:"title"`
+	Prompt      string        `yaml:"prompt"`
+	Steps       []indexedStep `yaml:"steps"` // sections
+}
+
+// moduleStepText returns the flow and step descriptions of a module's YAML,
+// or "" when it does not parse.
+func moduleStepText(content string) string {
+	var mod struct {
+		Flows []indexedFlow `yaml:"flows"`
+	}
+	if yaml.Unmarshal([]byte(content), &mod) != nil {
+		return ""
+	}
+	var parts []string
+	var walk func([]indexedStep)
+	walk = func(steps []indexedStep) {
+		for _, s := range steps {
+			for _, t := range []string{s.Title, s.Description, s.Prompt} {
+				if t != "" {
+					parts = append(parts, t)
+				}
+			}
+			walk(s.Steps)
+		}
+	}
+	for _, f := range mod.Flows {
+		parts = append(parts, f.Name)
+		if f.Description != "" {
+			parts = append(parts, f.Description)
+		}
+		walk(f.Steps)
+	}
+	return strings.Join(parts, "\n")
+}
+
+// SearchModules ranks cached modules matching any of keywords with BM25
+// over their name, description, tags and step descriptions. Each result's
+// Score is the share of keywords it matched, plus tagBoost per keyword that
+// names one of its tags, capped at 1. Only the index and metadata columns
+// are read, never YAML content or bash_script.
+func SearchModules(keywords []string) ([]Module, error) {
+	if len(keywords) == 0 {
+		return nil, nil
+	}
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	phrases := make([]string, len(keywords))
+	for i, kw := range keywords {
+		phrases[i] = ftsPhrase(kw)
+	}
+	rows, err := db.Query(`
+		SELECT f.rowid, m.module_id, m.name, m.description, m.tags, bm25(modules_fts, 10.0, 4.0, 6.0, 1.0) AS rank
+		FROM modules_fts f JOIN modules m ON m.id = f.rowid
+		WHERE modules_fts MATCH ?
+		ORDER BY rank LIMIT ?`, strings.Join(phrases, " OR "), 4*maxModuleResults)
+	if err != nil {
+		return nil, err
+	}
+	var modules []Module
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		var m Module
+		var modID string
+		var desc, tags sql.NullString
+		if err := rows.Scan(&id, &modID, &m.Name, &desc, &tags, new(float64)); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		m.ID = int(id)
+		m.Description = desc.String
+		m.Command = "clio run " + modID
+		m.Keywords = tags.String
+		modules = append(modules, m)
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil || len(modules) == 0 {
+		return nil, err
+	}
+
+	hits, err := termHits(db, "modules_fts", "", phrases, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range modules {
+		m := &modules[i]
+		m.Score = float64(hits[i])/float64(len(keywords)) + tagBoost*float64(tagHits(m.Keywords, keywords))
+		if m.Score > 1 {
+			m.Score = 1
+		}
+	}
+	// Stable, so BM25 order breaks ties
+	sort.SliceStable(modules, func(i, j int) bool {
+		return modules[i].Score > modules[j].Score
+	})
+	if len(modules) > maxModuleResults {
+		modules = modules[:maxModuleResults]
+	}
+	return modules, nil
+}
+
+// tagHits counts the keywords that name one of the comma-separated tags,
+// exactly or, for stems of three or more letters, as a prefix.
+func tagHits(tags string, keywords []string) int {
+	n := 0
+	for _, kw := range keywords {
+		kw = strings.ToLower(kw)
+		for _, t := range strings.Split(tags, ",") {
+			t = strings.ToLower(strings.TrimSpace(t))
+			if t != "" && (t == kw || len(kw) >= 3 && strings.HasPrefix(t, kw)) {
+				n++
+				break
+			}
+		}
+	}
+	return n
+}
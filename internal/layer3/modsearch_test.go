@@ -0,0 +1,87 @@
+package layer3
+
+import "testing"
+
+const backupYAML = `name: Backup
+flows:
+  - name: run
+    description: Archive the home directory
+    steps:
+      - type: section
+        title: Cleanup
+        steps:
+          - type: command
+            command: restic forget --prune
+            description: Prune old snapshots
+`
+
+func TestSearchModules(t *testing.T) {
+	UseTestDB(t)
+	mods := []struct{ id, name, desc, tags, content string }{
+		{"file_organizer", "File Organizer", "Sort files into folders by type", "files,organize", ""},
+		{"backup", "Backup", "Back up your home directory", "backup,archive", backupYAML},
+		{"docker_install", "Docker", "Install the Docker engine", "docker,containers", "flows: [{name: setup, steps: [{description: Write the daemon file}]}]"},
+	}
+	for _, m := range mods {
+		if err := UpsertModuleWithChecksum(m.id, m.name, m.desc, m.tags, "1.0.0", m.content, "", "sum"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		keywords []string
+		first    string
+		score    float64
+	}{
+		{[]string{"docker", "install"}, "clio run docker_install", 1},
+		{[]string{"file"}, "clio run file_organizer", 1},                  // name and tag beat step text
+		{[]string{"snapshot"}, "clio run backup", 1},                      // step description only
+		{[]string{"organize", "photos"}, "clio run file_organizer", 0.75}, // half the words, one a tag
+		{[]string{"home", "kubernetes"}, "clio run backup", 0.5},
+	}
+	for _, tt := range tests {
+		got, err := SearchModules(tt.keywords)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) == 0 || got[0].Command != tt.first || got[0].Score != tt.score {
+			t.Errorf("SearchModules(%v) = %+v, want %s first with score %v", tt.keywords, got, tt.first, tt.score)
+		}
+	}
+
+	if got, _ := SearchModules([]string{"kubernetes"}); len(got) != 0 {
+		t.Errorf("SearchModules(kubernetes) = %+v, want none", got)
+	}
+
+	// Upserting replaces the indexed text
+	if err := UpsertModule("docker_install", "Podman", "Install Podman", "podman", "1.1.0", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := SearchModules([]string{"engine"}); len(got) != 0 {
+		t.Errorf("after update, SearchModules(engine) = %+v, want none", got)
+	}
+	if got, _ := SearchModules([]string{"podman"}); len(got) != 1 || got[0].Command != "clio run docker_install" {
+		t.Errorf("after update, SearchModules(podman) = %+v, want docker_install", got)
+	}
+}
+
+func TestIndexMissingModules(t *testing.T) {
+	UseTestDB(t)
+	db, err := GetDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// As stored by a clio without the full-text index
+	if _, err := db.Exec(`INSERT INTO modules (module_id, name, description, tags) VALUES ('nginx_setup', 'Nginx', 'Serve a website', 'web')`); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := SearchModules([]string{"website"}); len(got) != 0 {
+		t.Fatalf("unindexed module found: %+v", got)
+	}
+	if err := indexMissingModules(db); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := SearchModules([]string{"website"}); len(got) != 1 || got[0].Command != "clio run nginx_setup" {
+		t.Errorf("SearchModules(website) = %+v, want nginx_setup", got)
+	}
+}
@@ -0,0 +1,102 @@
+package layer3
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// Where a module came from, stored in modules.origin.
+const (
+	OriginRegistry = "registry" // downloaded by sync, from the registry or GitHub
+	OriginLocal    = "local"    // installed from a file with clio module add
+)
+
+// ErrModuleLocal is returned when a download would replace a module
+// installed from a local file.
+var ErrModuleLocal = errors.New("module is installed from a local file")
+
+// UpsertLocalModule stores a module read from a local file and marks it
+// local, so sync leaves it alone until it is removed. It may replace a
+// downloaded module of the same ID.
+func UpsertLocalModule(modID, name, desc, tags, version, content, bashScript, checksum string) error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	query := `
+    INSERT INTO modules (module_id, name, description, tags, version, content, bash_script, checksum, origin, synced_at)
+    VALUES (?, ?, ?, ?, ?, ?, ?, ?, 'local', CURRENT_TIMESTAMP)
+    ON CONFLICT(module_id) DO UPDATE SET
+        name=excluded.name,
+        description=excluded.description,
+        tags=excluded.tags,
+        version=excluded.version,
+        content=excluded.content,
+        bash_script=excluded.bash_script,
+        checksum=excluded.checksum,
+        origin=excluded.origin,
+        synced_at=CURRENT_TIMESTAMP;
+    `
+	return upsertModule(db, OriginLocal, query, modID, name, desc, tags, version, content, bashScript, checksum)
+}
+
+// ModuleOrigin returns OriginRegistry or OriginLocal; "" if the module is not
+// stored.
+func ModuleOrigin(moduleID string) (string, error) {
+	db, err := GetDB()
+	if err != nil {
+		return "", err
+	}
+	return moduleOrigin(db, moduleID)
+}
+
+// IsModuleLocal reports whether a module was installed from a local file.
+func IsModuleLocal(moduleID string) (bool, error) {
+	origin, err := ModuleOrigin(moduleID)
+	return origin == OriginLocal, err
+}
+
+func moduleOrigin(ex moduleExec, moduleID string) (string, error) {
+	var origin string
+	err := ex.QueryRow("SELECT origin FROM modules WHERE module_id = ?", moduleID).Scan(&origin)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return origin, err
+}
+
+// RemoveModule deletes a module with its stored versions and search entry.
+// Delta sync only fetches modules changed since the last sync, so a registry
+// module comes back when it next changes, on sync --full, or when it is run.
+func RemoveModule(moduleID string) error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var id int64
+	err = tx.QueryRow("SELECT id FROM modules WHERE module_id = ?", moduleID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("module %q not found", moduleID)
+	}
+	if err != nil {
+		return err
+	}
+	for _, stmt := range []string{
+		"DELETE FROM modules_fts WHERE rowid = ?",
+		"DELETE FROM module_versions WHERE module_id = (SELECT module_id FROM modules WHERE id = ?)",
+		"DELETE FROM modules WHERE id = ?",
+	} {
+		if _, err := tx.Exec(stmt, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
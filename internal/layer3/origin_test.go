@@ -0,0 +1,52 @@
+package layer3
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLocalModuleOrigin(t *testing.T) {
+	UseTestDB(t)
+	const id = "class_demo"
+	if err := UpsertModuleWithChecksum(id, "Demo", "From the registry", "demo", "1.0", moduleYAML("1.0", "From the registry"), "", "sum-1.0"); err != nil {
+		t.Fatal(err)
+	}
+	if origin, _ := ModuleOrigin(id); origin != OriginRegistry {
+		t.Errorf("origin of a downloaded module = %q, want %q", origin, OriginRegistry)
+	}
+
+	// A local file may replace a downloaded module, but not the other way round
+	if err := UpsertLocalModule(id, "Demo", "Teacher's copy", "demo", "1.1-dev", moduleYAML("1.1-dev", "Teacher's copy"), "", "sum-dev"); err != nil {
+		t.Fatal(err)
+	}
+	if local, _ := IsModuleLocal(id); !local {
+		t.Fatal("module is not local after UpsertLocalModule")
+	}
+	err := UpsertModuleWithChecksum(id, "Demo", "From the registry", "demo", "1.2", moduleYAML("1.2", "From the registry"), "", "sum-1.2")
+	if !errors.Is(err, ErrModuleLocal) {
+		t.Errorf("download over a local module: err = %v, want ErrModuleLocal", err)
+	}
+	if meta, _ := FindModuleMeta(id); meta == nil || meta.Version != "1.1-dev" || meta.Origin != OriginLocal {
+		t.Errorf("after refused download, meta = %+v", meta)
+	}
+
+	if err := RemoveModule(id); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := ModuleExists(id); ok {
+		t.Error("module still stored after RemoveModule")
+	}
+	if got, _ := SearchModules([]string{"teacher"}); len(got) != 0 {
+		t.Errorf("search finds a removed module: %+v", got)
+	}
+	if _, err := ModuleVersions(id); err == nil {
+		t.Error("versions of a removed module are still listed")
+	}
+	if err := RemoveModule(id); err == nil {
+		t.Error("second RemoveModule succeeded")
+	}
+	// Downloads work again once the local copy is gone
+	if err := UpsertModuleWithChecksum(id, "Demo", "From the registry", "demo", "1.2", moduleYAML("1.2", "From the registry"), "", "sum-1.2"); err != nil {
+		t.Errorf("download after removal: %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package layer3
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Module run statuses stored in module_runs.status.
+const (
+	RunRunning   = "running"
+	RunFailed    = "failed"
+	RunCompleted = "completed"
+)
+
+// ModuleRun is a checkpoint of one module flow execution.
+type ModuleRun struct {
+	ID        int64
+	ModuleID  string
+	Flow      string
+	Status    string
+	Completed []int             // section ordinals finished so far
+	Variables map[string]string // input collected so far
+	UpdatedAt time.Time
+}
+
+// StartModuleRun records a new run and returns it with its run ID. Completed
+// runs and older runs of the same flow are deleted: only the latest unfinished
+// run can be resumed, so nothing else is worth keeping.
+func StartModuleRun(moduleID, flow string) (*ModuleRun, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+	res, err := db.Exec(
+		"INSERT INTO module_runs (module_id, flow, status) VALUES (?, ?, ?)",
+		moduleID, flow, RunRunning)
+	if err != nil {
+		return nil, fmt.Errorf("start module run: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	_, err = db.Exec(
+		"DELETE FROM module_runs WHERE status = ? OR (module_id = ? AND flow = ? AND run_id < ?)",
+		RunCompleted, moduleID, flow, id)
+	if err != nil {
+		return nil, fmt.Errorf("prune module runs: %w", err)
+	}
+	return &ModuleRun{
+		ID:        id,
+		ModuleID:  moduleID,
+		Flow:      flow,
+		Status:    RunRunning,
+		Variables: map[string]string{},
+	}, nil
+}
+
+// SaveModuleRun writes the run's status, completed sections and variables.
+func SaveModuleRun(run *ModuleRun) error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+	completed, err := json.Marshal(run.Completed)
+	if err != nil {
+		return err
+	}
+	vars, err := json.Marshal(run.Variables)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`
+		UPDATE module_runs
+		SET status = ?, completed_sections = ?, variables = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE run_id = ?`,
+		run.Status, string(completed), string(vars), run.ID)
+	return err
+}
+
+// LatestUnfinishedRun returns the most recent run of a flow that did not
+// complete, or nil if there is none.
+func LatestUnfinishedRun(moduleID, flow string) (*ModuleRun, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+	run := &ModuleRun{ModuleID: moduleID, Flow: flow}
+	var completed, vars string
+	err = db.QueryRow(`
+		SELECT run_id, status, completed_sections, variables, updated_at
+		FROM module_runs
+		WHERE module_id = ? AND flow = ? AND status != ?
+		ORDER BY run_id DESC LIMIT 1`,
+		moduleID, flow, RunCompleted,
+	).Scan(&run.ID, &run.Status, &completed, &vars, &run.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(completed), &run.Completed); err != nil {
+		return nil, fmt.Errorf("run %d: completed sections: %w", run.ID, err)
+	}
+	if err := json.Unmarshal([]byte(vars), &run.Variables); err != nil {
+		return nil, fmt.Errorf("run %d: variables: %w", run.ID, err)
+	}
+	if run.Variables == nil {
+		run.Variables = map[string]string{}
+	}
+	return run, nil
+}
@@ -0,0 +1,50 @@
+package layer3
+
+import "testing"
+
+func TestStartModuleRunPrunes(t *testing.T) {
+	UseTestDB(t)
+	done, err := StartModuleRun("backup", "setup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done.Status = RunCompleted
+	if err := SaveModuleRun(done); err != nil {
+		t.Fatal(err)
+	}
+	failed, err := StartModuleRun("backup", "setup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	failed.Status = RunFailed
+	if err := SaveModuleRun(failed); err != nil {
+		t.Fatal(err)
+	}
+	other, err := StartModuleRun("backup", "restore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	latest, err := StartModuleRun("backup", "setup")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := GetDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := db.Query("SELECT run_id FROM module_runs ORDER BY run_id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		rows.Scan(&id)
+		ids = append(ids, id)
+	}
+	if len(ids) != 2 || ids[0] != other.ID || ids[1] != latest.ID {
+		t.Errorf("runs kept = %v, want the other flow's %d and the latest %d", ids, other.ID, latest.ID)
+	}
+}
@@ -0,0 +1,200 @@
+package layer3
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TLDRPage is one imported tldr-pages page: a command and its examples.
+type TLDRPage struct {
+	Name        string        `json:"name"`
+	Platform    string        `json:"platform"` // tldr directory: common, linux, osx, android, ...
+	Description string        `json:"description"`
+	Examples    []TLDRExample `json:"examples"`
+}
+
+// TLDRExample is one example of a page. Command keeps tldr's {{placeholders}}.
+type TLDRExample struct {
+	Description string `json:"description"`
+	Command     string `json:"command"`
+}
+
+// TLDRMatch is an example found by SearchTLDR, with its page.
+type TLDRMatch struct {
+	Name            string
+	Platform        string
+	PageDescription string
+	Description     string
+	Command         string
+}
+
+// TLDRStatus describes the last import.
+type TLDRStatus struct {
+	Source     string    `json:"source"`
+	Pages      int       `json:"pages"`
+	ImportedAt time.Time `json:"imported_at"`
+}
+
+// ReplaceTLDR replaces every imported page with pages in one transaction, so
+// a failed import leaves the previous one in place.
+func ReplaceTLDR(pages []TLDRPage, source string) error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, table := range []string{"tldr_fts", "tldr_examples", "tldr_pages"} {
+		if _, err := tx.Exec("DELETE FROM " + table); err != nil {
+			return err
+		}
+	}
+	pageStmt, err := tx.Prepare(`INSERT OR REPLACE INTO tldr_pages (name, platform, description) VALUES (?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer pageStmt.Close()
+	exampleStmt, err := tx.Prepare(`INSERT INTO tldr_examples (name, platform, description, command) VALUES (?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer exampleStmt.Close()
+	ftsStmt, err := tx.Prepare(`INSERT INTO tldr_fts (rowid, name, example, page) VALUES (?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer ftsStmt.Close()
+
+	for _, p := range pages {
+		if _, err := pageStmt.Exec(p.Name, p.Platform, p.Description); err != nil {
+			return fmt.Errorf("tldr page %s/%s: %w", p.Platform, p.Name, err)
+		}
+		for _, e := range p.Examples {
+			res, err := exampleStmt.Exec(p.Name, p.Platform, e.Description, e.Command)
+			if err != nil {
+				return fmt.Errorf("tldr page %s/%s: %w", p.Platform, p.Name, err)
+			}
+			id, err := res.LastInsertId()
+			if err != nil {
+				return err
+			}
+			if _, err := ftsStmt.Exec(id, p.Name, e.Description+" "+e.Command, p.Description); err != nil {
+				return fmt.Errorf("tldr page %s/%s: %w", p.Platform, p.Name, err)
+			}
+		}
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO tldr_meta (id, source, pages, imported_at) VALUES (1, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET source=excluded.source, pages=excluded.pages, imported_at=excluded.imported_at`,
+		source, len(pages), time.Now().UTC()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// TLDRPages returns every imported page that has examples, with them.
+func TLDRPages() ([]TLDRPage, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Query(`
+		SELECT p.name, p.platform, p.description, e.description, e.command
+		FROM tldr_pages p JOIN tldr_examples e ON e.name = p.name AND e.platform = p.platform
+		ORDER BY p.platform, p.name, e.id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []TLDRPage
+	for rows.Next() {
+		var p TLDRPage
+		var e TLDRExample
+		if err := rows.Scan(&p.Name, &p.Platform, &p.Description, &e.Description, &e.Command); err != nil {
+			return nil, err
+		}
+		if n := len(out); n > 0 && out[n-1].Name == p.Name && out[n-1].Platform == p.Platform {
+			out[n-1].Examples = append(out[n-1].Examples, e)
+			continue
+		}
+		p.Examples = []TLDRExample{e}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+// GetTLDRStatus returns the last import, or nil when nothing was imported.
+func GetTLDRStatus() (*TLDRStatus, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+	var s TLDRStatus
+	err = db.QueryRow("SELECT source, pages, imported_at FROM tldr_meta WHERE id = 1").
+		Scan(&s.Source, &s.Pages, &s.ImportedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// SearchTLDR returns up to limit examples from pages of the given platforms
+// that match any of terms, best first by BM25: page name matches weigh most,
+// then the example's description and command, then the page description.
+// Terms of three or more letters also match as prefixes. limit <= 0 returns
+// all.
+func SearchTLDR(terms, platforms []string, limit int) ([]TLDRMatch, error) {
+	if len(terms) == 0 || len(platforms) == 0 {
+		return nil, nil
+	}
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = -1 // SQLite: no limit
+	}
+
+	phrases := make([]string, len(terms))
+	for i, t := range terms {
+		phrases[i] = ftsPhrase(t)
+	}
+	args := make([]interface{}, 0, len(platforms)+2)
+	args = append(args, strings.Join(phrases, " OR "))
+	for _, p := range platforms {
+		args = append(args, p)
+	}
+	args = append(args, limit)
+
+	rows, err := db.Query(`
+		SELECT p.name, p.platform, p.description, e.description, e.command
+		FROM tldr_fts f
+		JOIN tldr_examples e ON e.id = f.rowid
+		JOIN tldr_pages p ON p.name = e.name AND p.platform = e.platform
+		WHERE tldr_fts MATCH ? AND p.platform IN (?`+strings.Repeat(", ?", len(platforms)-1)+`)
+		ORDER BY bm25(tldr_fts, 5.0, 3.0, 1.0) LIMIT ?`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []TLDRMatch
+	for rows.Next() {
+		var m TLDRMatch
+		if err := rows.Scan(&m.Name, &m.Platform, &m.PageDescription, &m.Description, &m.Command); err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,240 @@
+package layer3
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxModuleVersions is how many versions of each module are kept, the
+// current one included.
+const maxModuleVersions = 10
+
+// ErrModulePinned is returned when an update would replace a pinned module.
+var ErrModulePinned = errors.New("module is pinned")
+
+// ModuleVersion is one stored version of a module's YAML.
+type ModuleVersion struct {
+	ModuleID string    `json:"module_id"`
+	Version  string    `json:"version"`
+	Checksum string    `json:"checksum"`
+	SyncedAt time.Time `json:"synced_at"`
+	Current  bool      `json:"current"`
+	Pinned   bool      `json:"pinned"` // set on the current version of a pinned module
+}
+
+// Short returns the first characters of the checksum, enough to name a
+// version whose version string is ambiguous or empty.
+func (v ModuleVersion) Short() string {
+	if len(v.Checksum) > 8 {
+		return v.Checksum[:8]
+	}
+	return v.Checksum
+}
+
+// recordVersion adds the module's current content to its history unless it
+// is already the latest entry, then drops the oldest beyond maxModuleVersions.
+func recordVersion(ex moduleExec, moduleID string) error {
+	var latest sql.NullString
+	err := ex.QueryRow("SELECT content FROM module_versions WHERE module_id = ? ORDER BY id DESC LIMIT 1", moduleID).Scan(&latest)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	var version, checksum, content, script sql.NullString
+	err = ex.QueryRow("SELECT version, checksum, content, bash_script FROM modules WHERE module_id = ?", moduleID).
+		Scan(&version, &checksum, &content, &script)
+	if err != nil {
+		return err
+	}
+	if latest.Valid && latest.String == content.String {
+		return nil
+	}
+	sum := checksum.String
+	if sum == "" {
+		sum = fmt.Sprintf("%x", sha256.Sum256([]byte(content.String)))
+	}
+	if _, err := ex.Exec(`
+		INSERT INTO module_versions (module_id, version, checksum, content, bash_script, synced_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		moduleID, version.String, sum, content.String, script.String, time.Now().UTC()); err != nil {
+		return err
+	}
+	// The current content is never pruned, even after a rollback to an old one
+	_, err = ex.Exec(`
+		DELETE FROM module_versions WHERE module_id = ? AND id NOT IN (
+			SELECT id FROM module_versions WHERE module_id = ? ORDER BY id DESC LIMIT ?
+		) AND content != (SELECT COALESCE(content, '') FROM modules WHERE module_id = ?)`,
+		moduleID, moduleID, maxModuleVersions, moduleID)
+	return err
+}
+
+// ModuleVersions returns the stored versions of a module, newest first.
+func ModuleVersions(moduleID string) ([]ModuleVersion, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Query(`
+		SELECT v.version, v.checksum, v.synced_at, COALESCE(v.content = m.content, 0), m.pinned
+		FROM module_versions v JOIN modules m ON m.module_id = v.module_id
+		WHERE v.module_id = ? ORDER BY v.id DESC`, moduleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []ModuleVersion
+	seenCurrent := false
+	for rows.Next() {
+		v := ModuleVersion{ModuleID: moduleID}
+		var current, pinned bool
+		if err := rows.Scan(&v.Version, &v.Checksum, &v.SyncedAt, &current, &pinned); err != nil {
+			return nil, err
+		}
+		// Identical content stored twice is current only once, at its newest
+		v.Current = current && !seenCurrent
+		v.Pinned = v.Current && pinned
+		seenCurrent = seenCurrent || current
+		out = append(out, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		if ok, err := ModuleExists(moduleID); err != nil || !ok {
+			return nil, fmt.Errorf("module %q not found", moduleID)
+		}
+	}
+	return out, nil
+}
+
+// ModuleVersionContent returns the version of a module named by ref and its
+// YAML. ref is a version string, the newest match winning, or a checksum
+// prefix of at least four characters. An empty ref is the current version;
+// "previous" is the newest version stored before it.
+func ModuleVersionContent(moduleID, ref string) (ModuleVersion, string, error) {
+	v, content, _, err := findVersion(moduleID, ref)
+	return v, content, err
+}
+
+func findVersion(moduleID, ref string) (v ModuleVersion, content, script string, err error) {
+	versions, err := ModuleVersions(moduleID)
+	if err != nil {
+		return v, "", "", err
+	}
+	cur := -1
+	for i, v := range versions {
+		if v.Current {
+			cur = i
+		}
+	}
+	pick := -1
+	switch {
+	case ref == "":
+		pick = cur
+	case ref == "previous":
+		if cur < 0 || cur+1 == len(versions) {
+			return v, "", "", fmt.Errorf("%s has no version older than the current one", moduleID)
+		}
+		pick = cur + 1
+	default:
+		for i, v := range versions {
+			if v.Version == ref || len(ref) >= 4 && strings.HasPrefix(v.Checksum, ref) {
+				pick = i
+				break
+			}
+		}
+	}
+	if pick < 0 {
+		return v, "", "", fmt.Errorf("%s has no stored version %q", moduleID, ref)
+	}
+
+	db, err := GetDB()
+	if err != nil {
+		return v, "", "", err
+	}
+	// Versions are listed newest first, so the offset finds the same row
+	err = db.QueryRow("SELECT content, bash_script FROM module_versions WHERE module_id = ? ORDER BY id DESC LIMIT 1 OFFSET ?",
+		moduleID, pick).Scan(&content, &script)
+	return versions[pick], content, script, err
+}
+
+// RollbackModule makes a stored version, named as for ModuleVersionContent,
+// the module's current one. A pinned module stays pinned, to the new version.
+func RollbackModule(moduleID, ref string) (ModuleVersion, error) {
+	v, content, script, err := findVersion(moduleID, ref)
+	if err != nil {
+		return v, err
+	}
+	db, err := GetDB()
+	if err != nil {
+		return v, err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return v, err
+	}
+	defer tx.Rollback()
+
+	var meta struct {
+		Name        string   `yaml:"name"`
+		Description string   `yaml:"description"`
+		Tags        []string `yaml:"tags"`
+	}
+	if yaml.Unmarshal([]byte(content), &meta) == nil && meta.Name != "" {
+		if _, err := tx.Exec("UPDATE modules SET name = ?, description = ?, tags = ? WHERE module_id = ?",
+			meta.Name, meta.Description, strings.Join(meta.Tags, ","), moduleID); err != nil {
+			return v, err
+		}
+	}
+	if _, err := tx.Exec("UPDATE modules SET content = ?, bash_script = ?, version = ?, checksum = ? WHERE module_id = ?",
+		content, script, v.Version, v.Checksum, moduleID); err != nil {
+		return v, err
+	}
+	if err := indexModule(tx, moduleID); err != nil {
+		return v, err
+	}
+	if err := tx.Commit(); err != nil {
+		return v, err
+	}
+	v.Current = true
+	return v, nil
+}
+
+// PinModule pins or unpins a module. Sync leaves a pinned module as it is.
+func PinModule(moduleID string, pinned bool) error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+	res, err := db.Exec("UPDATE modules SET pinned = ? WHERE module_id = ?", pinned, moduleID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("module %q not found", moduleID)
+	}
+	return nil
+}
+
+// IsModulePinned reports whether a module is pinned; false if it is not stored.
+func IsModulePinned(moduleID string) (bool, error) {
+	db, err := GetDB()
+	if err != nil {
+		return false, err
+	}
+	return modulePinned(db, moduleID)
+}
+
+func modulePinned(ex moduleExec, moduleID string) (bool, error) {
+	var pinned bool
+	err := ex.QueryRow("SELECT pinned FROM modules WHERE module_id = ?", moduleID).Scan(&pinned)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return pinned, err
+}
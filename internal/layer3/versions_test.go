@@ -0,0 +1,96 @@
+package layer3
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func moduleYAML(version, desc string) string {
+	return fmt.Sprintf("name: Termux Setup\nversion: %s\ndescription: %s\ntags: [termux]\n", version, desc)
+}
+
+func TestModuleVersions(t *testing.T) {
+	UseTestDB(t)
+	const id = "termux_setup"
+	for _, v := range []struct{ version, desc string }{
+		{"1.0", "Set up Termux storage"},
+		{"1.1", "Set up Termux storage and packages"},
+		{"1.1", "Set up Termux storage and packages"}, // resynced, unchanged
+		{"2.0", "Broken release"},
+	} {
+		content := moduleYAML(v.version, v.desc)
+		if err := UpsertModuleWithChecksum(id, "Termux Setup", v.desc, "termux", v.version, content, "", "sum-"+v.version); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	versions, err := ModuleVersions(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 3 || versions[0].Version != "2.0" || !versions[0].Current || versions[2].Version != "1.0" {
+		t.Fatalf("ModuleVersions = %+v, want 2.0 (current), 1.1, 1.0", versions)
+	}
+
+	v, err := RollbackModule(id, "previous")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Version != "1.1" {
+		t.Errorf("rolled back to %s, want 1.1", v.Version)
+	}
+	meta, _ := FindModuleMeta(id)
+	if meta == nil || meta.Version != "1.1" || meta.Description != "Set up Termux storage and packages" {
+		t.Errorf("after rollback, meta = %+v", meta)
+	}
+	if got, _ := SearchModules([]string{"broken"}); len(got) != 0 {
+		t.Errorf("search still finds the rolled-back text: %+v", got)
+	}
+	if _, content, _ := ModuleVersionContent(id, ""); content != moduleYAML("1.1", "Set up Termux storage and packages") {
+		t.Errorf("current content = %q", content)
+	}
+
+	if err := PinModule(id, true); err != nil {
+		t.Fatal(err)
+	}
+	err = UpsertModuleWithChecksum(id, "Termux Setup", "Broken again", "termux", "2.1", moduleYAML("2.1", "Broken again"), "", "sum-2.1")
+	if !errors.Is(err, ErrModulePinned) {
+		t.Errorf("update of a pinned module: err = %v, want ErrModulePinned", err)
+	}
+	versions, _ = ModuleVersions(id)
+	if len(versions) != 3 || !versions[1].Current || !versions[1].Pinned {
+		t.Errorf("after pinned update, versions = %+v, want 1.1 current and pinned", versions)
+	}
+
+	if err := PinModule(id, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ModuleVersionContent(id, "9.9"); err == nil {
+		t.Error("unknown version found")
+	}
+	if v, _, err := ModuleVersionContent(id, "sum-1.0"); err != nil || v.Version != "1.0" {
+		t.Errorf("by checksum prefix = %+v, %v", v, err)
+	}
+	if err := PinModule("no_such_module", true); err == nil {
+		t.Error("pinned a module that does not exist")
+	}
+}
+
+func TestModuleVersionsPruned(t *testing.T) {
+	UseTestDB(t)
+	const id = "pruned"
+	for i := 0; i < maxModuleVersions+5; i++ {
+		v := fmt.Sprint(i)
+		if err := UpsertModuleWithChecksum(id, "Pruned", "d", "", v, moduleYAML(v, "d"), "", "sum-"+v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	versions, err := ModuleVersions(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != maxModuleVersions || versions[0].Version != fmt.Sprint(maxModuleVersions+4) {
+		t.Errorf("kept %d versions, newest %s; want %d ending at %d", len(versions), versions[0].Version, maxModuleVersions, maxModuleVersions+4)
+	}
+}
@@ -18,18 +18,6 @@ func hashQuery(query string) string {
 	return hex.EncodeToString(sum[:])
 }
 
-func ensureCacheSchema(db *sql.DB) error {
-	_, err := db.Exec(`
-		CREATE TABLE IF NOT EXISTS query_cache (
-			query_hash TEXT PRIMARY KEY,
-			command TEXT NOT NULL,
-			description TEXT,
-			cached_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		);
-	`)
-	return err
-}
-
 // GetCached returns a previously cached remote result if still fresh.
 func GetCached(query string, ttl time.Duration) (CommandResult, bool) {
 	if ttl <= 0 {
@@ -39,9 +27,6 @@ func GetCached(query string, ttl time.Duration) (CommandResult, bool) {
 	if err != nil {
 		return CommandResult{}, false
 	}
-	if err := ensureCacheSchema(db); err != nil {
-		return CommandResult{}, false
-	}
 
 	hash := hashQuery(query)
 	var cmd, desc string
@@ -66,9 +51,6 @@ func PutCached(query string, result CommandResult) error {
 	if err != nil {
 		return err
 	}
-	if err := ensureCacheSchema(db); err != nil {
-		return err
-	}
 
 	hash := hashQuery(query)
 	_, err = db.Exec(`
@@ -83,6 +65,12 @@ func PutCached(query string, result CommandResult) error {
 		return err
 	}
 
+	trimCache(db)
+	return nil
+}
+
+// trimCache drops the oldest entries beyond maxCacheEntries.
+func trimCache(db *sql.DB) {
 	// LRU trim — keep cache tiny on 2 GB phones
 	var count int
 	_ = db.QueryRow(`SELECT COUNT(*) FROM query_cache`).Scan(&count)
@@ -95,7 +83,6 @@ func PutCached(query string, result CommandResult) error {
 			)
 		`, count-maxCacheEntries)
 	}
-	return nil
 }
 
 // CacheStats returns entry count for diagnostics.
@@ -104,11 +91,84 @@ func CacheStats() (int, error) {
 	if err != nil {
 		return 0, err
 	}
-	if err := ensureCacheSchema(db); err != nil {
-		return 0, err
-	}
 	var n int
 	err = db.QueryRow(`SELECT COUNT(*) FROM query_cache`).Scan(&n)
 	return n, err
 }
 
+// ClearCache deletes every cached remote result and returns how many were removed.
+func ClearCache() (int, error) {
+	db, err := layer3.GetDB()
+	if err != nil {
+		return 0, err
+	}
+	res, err := db.Exec(`DELETE FROM query_cache`)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
+
+// CacheEntry is one cached remote result, keyed by the hash of its query.
+type CacheEntry struct {
+	QueryHash   string    `json:"query_hash"`
+	Command     string    `json:"command"`
+	Description string    `json:"description"`
+	CachedAt    time.Time `json:"cached_at"`
+}
+
+// CacheEntries returns every cached remote result, newest first.
+func CacheEntries() ([]CacheEntry, error) {
+	db, err := layer3.GetDB()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Query(`SELECT query_hash, command, COALESCE(description,''), cached_at FROM query_cache ORDER BY cached_at DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []CacheEntry
+	for rows.Next() {
+		var e CacheEntry
+		if err := rows.Scan(&e.QueryHash, &e.Command, &e.Description, &e.CachedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+// MergeCache adds entries from another device's cache, keeping the newer of
+// two results for the same query, then trims the cache as PutCached does.
+// It returns how many entries were added or replaced.
+func MergeCache(entries []CacheEntry) (int, error) {
+	db, err := layer3.GetDB()
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, e := range entries {
+		if len(e.QueryHash) != sha256.Size*2 || e.Command == "" {
+			continue
+		}
+		res, err := db.Exec(`
+			INSERT INTO query_cache (query_hash, command, description, cached_at)
+			VALUES (?, ?, ?, ?)
+			ON CONFLICT(query_hash) DO UPDATE SET
+				command=excluded.command,
+				description=excluded.description,
+				cached_at=excluded.cached_at
+			WHERE excluded.cached_at > query_cache.cached_at
+		`, e.QueryHash, e.Command, e.Description, e.CachedAt.UTC().Format("2006-01-02 15:04:05")) // as CURRENT_TIMESTAMP
+		if err != nil {
+			return n, err
+		}
+		if k, _ := res.RowsAffected(); k > 0 {
+			n++
+		}
+	}
+	trimCache(db)
+	return n, nil
+}
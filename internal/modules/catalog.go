@@ -98,7 +98,7 @@ func RunCommand(moduleID, flow string) string {
 	if flow == "" {
 		flow = "setup"
 	}
-	return "clio-run-module " + moduleID + " " + flow
+	return runPrefix + moduleID + " " + flow
 }
 
 // ShowFullCatalog prints setup wizards and automation modules in two distinct sections.
@@ -161,7 +161,7 @@ func showAutomationEntries(cached map[string]bool) {
 	fmt.Printf("%d automation module(s)\n", len(entries))
 	fmt.Println("  get one:  download <module_id>")
 	fmt.Println("  get all:  sync")
-	fmt.Println("  run:      clio-run-module <module_id> setup")
+	fmt.Println("  run:      clio run <module_id> setup")
 	fmt.Println()
 }
 
@@ -233,7 +233,9 @@ func printCatalogEntry(m CatalogEntry, cached bool) {
 
 func printMetaEntry(m layer3.ModuleMeta, cached bool) {
 	status := "○ not downloaded"
-	if cached {
+	if m.Origin == layer3.OriginLocal {
+		status = "✓ local"
+	} else if cached {
 		status = "✓ downloaded"
 	}
 	desc := strings.TrimSpace(m.Description)
@@ -262,6 +264,12 @@ func ShowModuleDetail(moduleID string) error {
 	fmt.Println("[AUTOMATION MODULE]")
 	fmt.Println()
 
+	// A local module differs from the registry's, if the registry has one
+	if meta, err := layer3.FindModuleMeta(moduleID); err == nil && meta != nil && meta.Origin == layer3.OriginLocal {
+		printMetaEntry(*meta, true)
+		return nil
+	}
+
 	registryURL := strings.TrimRight(config.GetRegistryURL(), "/")
 	url := fmt.Sprintf("%s/api/v1/modules/%s", registryURL, moduleID)
 	resp, err := syncHTTP.Get(url)
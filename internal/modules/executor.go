@@ -2,6 +2,7 @@ package modules
 
 import (
 	"bufio"
+	"clio/internal/risk"
 	"clio/internal/safeexec"
 	"clio/internal/setup"
 	"errors"
@@ -55,6 +56,7 @@ type Step struct {
 	Operation       string `yaml:"operation"`
 	Variable        string `yaml:"variable"`
 	Required        bool   `yaml:"required"`
+	Sensitive       bool   `yaml:"sensitive"` // input never saved in checkpoints
 	Condition       string `yaml:"condition"`
 }
 
@@ -62,7 +64,17 @@ type Step struct {
 type ExecutionContext struct {
 	Variables map[string]string
 	Scanner   *bufio.Scanner
-	Labels    map[string]int // Map label names to step indices
+
+	// Completed holds the ordinals of sections already finished; they are
+	// skipped. Resumed keeps saved Variables instead of prompting again.
+	Completed map[int]bool
+	Resumed   bool
+	// OnSection is called after each section completes, for checkpointing.
+	OnSection func(ctx *ExecutionContext)
+
+	sections      map[*Step]int // section step → ordinal in the flow
+	totalSections int
+	sensitive     map[string]bool // variables of sensitive input steps
 }
 
 // LoadModule loads and parses a module from YAML content
@@ -76,15 +88,17 @@ func LoadModule(yamlContent string) (*FullModuleYAML, error) {
 
 // ExecuteModule runs a module's flow
 func ExecuteModule(module *FullModuleYAML, flowName string, scanner *bufio.Scanner) error {
-	// Find the flow
-	var flow *Flow
-	for i := range module.Flows {
-		if module.Flows[i].Name == flowName {
-			flow = &module.Flows[i]
-			break
-		}
+	ctx := &ExecutionContext{
+		Variables: make(map[string]string),
+		Scanner:   scanner,
 	}
+	return ExecuteModuleWith(module, flowName, ctx)
+}
 
+// ExecuteModuleWith runs a flow using a caller-supplied context, so a run can
+// start from saved variables and completed sections.
+func ExecuteModuleWith(module *FullModuleYAML, flowName string, ctx *ExecutionContext) error {
+	flow := findFlow(module, flowName)
 	if flow == nil {
 		return fmt.Errorf("flow '%s' not found in module", flowName)
 	}
@@ -94,58 +108,105 @@ func ExecuteModule(module *FullModuleYAML, flowName string, scanner *bufio.Scann
 		return fmt.Errorf("this module requires Termux")
 	}
 
-	ctx := &ExecutionContext{
-		Variables: make(map[string]string),
-		Scanner:   scanner,
-		Labels:    buildLabelMap(flow.Steps),
+	if ctx.Variables == nil {
+		ctx.Variables = make(map[string]string)
+	}
+	if ctx.Completed == nil {
+		ctx.Completed = make(map[int]bool)
 	}
+	ctx.sections = make(map[*Step]int)
+	ctx.totalSections = numberSections(flow.Steps, ctx.sections, 0)
+	ctx.sensitive = make(map[string]bool)
+	findSensitive(flow.Steps, ctx.sensitive)
+
+	err := executeSteps(flow.Steps, ctx)
+	if label, ok := gotoLabel(err); ok {
+		return fmt.Errorf("label not found: %s", label)
+	}
+	return err
+}
 
-	return executeSteps(flow.Steps, ctx, 0, 0)
+// findFlow returns the named flow, or nil if the module has none by that name
+func findFlow(module *FullModuleYAML, flowName string) *Flow {
+	for i := range module.Flows {
+		if module.Flows[i].Name == flowName {
+			return &module.Flows[i]
+		}
+	}
+	return nil
 }
 
-// buildLabelMap creates a map of label names to step indices (depth-first)
-func buildLabelMap(steps []Step) map[string]int {
-	labels := make(map[string]int)
-	buildLabelMapRecursive(steps, labels, 0)
-	return labels
+// numberSections assigns each section step (including nested ones) its
+// position in the flow, which stays stable across runs for checkpointing.
+func numberSections(steps []Step, ordinals map[*Step]int, next int) int {
+	for i := range steps {
+		if steps[i].Type != "section" {
+			continue
+		}
+		ordinals[&steps[i]] = next
+		next = numberSections(steps[i].Steps, ordinals, next+1)
+	}
+	return next
 }
 
-func buildLabelMapRecursive(steps []Step, labels map[string]int, baseIndex int) int {
-	currentIndex := baseIndex
+// findSensitive records the variables of sensitive input steps, including
+// those in nested sections.
+func findSensitive(steps []Step, vars map[string]bool) {
 	for _, step := range steps {
-		if step.Type == "label" {
-			labels[step.Name] = currentIndex
+		if step.Type == "input" && step.Sensitive && step.Variable != "" {
+			vars[step.Variable] = true
 		}
-		currentIndex++
+		findSensitive(step.Steps, vars)
+	}
+}
+
+// SavedVariables returns the variables a checkpoint may store: all of them
+// except the answers to sensitive input steps, which a resumed run asks for
+// again.
+func (ctx *ExecutionContext) SavedVariables() map[string]string {
+	saved := make(map[string]string, len(ctx.Variables))
+	for k, v := range ctx.Variables {
+		if !ctx.sensitive[k] {
+			saved[k] = v
+		}
+	}
+	return saved
+}
 
-		// Recurse into section steps
-		if step.Type == "section" && len(step.Steps) > 0 {
-			currentIndex = buildLabelMapRecursive(step.Steps, labels, currentIndex)
+// findLabel returns the index of a label step within steps (not nested sections)
+func findLabel(steps []Step, name string) (int, bool) {
+	for i, step := range steps {
+		if step.Type == "label" && step.Name == name {
+			return i, true
 		}
 	}
-	return currentIndex
+	return 0, false
+}
+
+// gotoLabel reports whether err is a pending goto and returns its label
+func gotoLabel(err error) (string, bool) {
+	if err == nil || !strings.HasPrefix(err.Error(), "goto:") {
+		return "", false
+	}
+	return strings.TrimPrefix(err.Error(), "goto:"), true
 }
 
 // executeSteps executes a list of steps
-func executeSteps(steps []Step, ctx *ExecutionContext, sectionNum, totalSections int) error {
+func executeSteps(steps []Step, ctx *ExecutionContext) error {
 	for i := 0; i < len(steps); i++ {
-		step := steps[i]
-
-		if err := executeStep(&step, ctx, sectionNum, totalSections); err != nil {
+		if err := executeStep(&steps[i], ctx); err != nil {
 			if err.Error() == "abort" {
 				return fmt.Errorf("setup cancelled")
 			}
 			if err.Error() == "skip" {
 				continue
 			}
-			if strings.HasPrefix(err.Error(), "goto:") {
-				// Handle goto
-				label := strings.TrimPrefix(err.Error(), "goto:")
-				if idx, ok := ctx.Labels[label]; ok {
+			if label, ok := gotoLabel(err); ok {
+				// Jump within this list, or hand the goto to the enclosing one
+				if idx, ok := findLabel(steps, label); ok {
 					i = idx - 1 // -1 because loop will increment
 					continue
 				}
-				return fmt.Errorf("label not found: %s", label)
 			}
 			return err
 		}
@@ -154,7 +215,7 @@ func executeSteps(steps []Step, ctx *ExecutionContext, sectionNum, totalSections
 }
 
 // executeStep executes a single step
-func executeStep(step *Step, ctx *ExecutionContext, sectionNum, totalSections int) error {
+func executeStep(step *Step, ctx *ExecutionContext) error {
 	switch step.Type {
 	case "message":
 		content := expandTemplate(step.Content, ctx.Variables)
@@ -199,6 +260,10 @@ func executeStep(step *Step, ctx *ExecutionContext, sectionNum, totalSections in
 		}
 
 	case "input":
+		if saved, ok := ctx.Variables[step.Variable]; ok && ctx.Resumed && step.Variable != "" {
+			fmt.Printf("%s: %s (saved)\n", step.Prompt, saved)
+			return nil
+		}
 		fmt.Print(step.Prompt + ": ")
 		if !ctx.Scanner.Scan() {
 			return fmt.Errorf("input error")
@@ -207,7 +272,7 @@ func executeStep(step *Step, ctx *ExecutionContext, sectionNum, totalSections in
 		value := strings.TrimSpace(ctx.Scanner.Text())
 		if value == "" && step.Required {
 			fmt.Println("This field is required.")
-			return executeStep(step, ctx, sectionNum, totalSections) // Retry
+			return executeStep(step, ctx) // Retry
 		}
 
 		if step.Variable != "" {
@@ -228,6 +293,9 @@ func executeStep(step *Step, ctx *ExecutionContext, sectionNum, totalSections in
 		}
 
 		cmdStr := expandTemplate(step.Command, ctx.Variables)
+		if !risk.Confirm(risk.Classify(cmdStr), ctx.Scanner) {
+			return errors.New("abort")
+		}
 
 		// Always use safeexec.Command to avoid SIGSYS on Termux/Android
 		cmd := safeexec.Command("sh", "-c", cmdStr)
@@ -250,10 +318,18 @@ func executeStep(step *Step, ctx *ExecutionContext, sectionNum, totalSections in
 		}
 
 	case "section":
-		fmt.Printf("\n[%d/%d] %s\n", sectionNum+1, totalSections, step.Title)
+		num := ctx.sections[step]
+		if ctx.Completed[num] {
+			fmt.Printf("\n[%d/%d] %s — already done, skipping\n", num+1, ctx.totalSections, step.Title)
+			return nil
+		}
+		fmt.Printf("\n[%d/%d] %s\n", num+1, ctx.totalSections, step.Title)
 		fmt.Println(strings.Repeat("─", 60))
 
-		if err := executeSteps(step.Steps, ctx, sectionNum, totalSections); err != nil {
+		if err := executeSteps(step.Steps, ctx); err != nil {
+			if _, ok := gotoLabel(err); ok {
+				return err
+			}
 			if step.ContinueOnError {
 				fmt.Printf("⚠️  Warning: %s failed: %v\n", step.Title, err)
 				fmt.Print("Continue anyway? [Y/n]: ")
@@ -269,6 +345,10 @@ func executeStep(step *Step, ctx *ExecutionContext, sectionNum, totalSections in
 		}
 
 		fmt.Printf("✅ %s complete\n", step.Title)
+		ctx.Completed[num] = true
+		if ctx.OnSection != nil {
+			ctx.OnSection(ctx)
+		}
 
 	case "check_command":
 		if _, err := safeexec.LookPath(step.Command); err != nil {
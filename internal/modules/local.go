@@ -0,0 +1,257 @@
+package modules
+
+import (
+	"clio/internal/layer3"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// LocalModule is a module installed from a file by AddLocal or WatchLocal.
+type LocalModule struct {
+	ID       string `json:"id"`
+	Path     string `json:"path"`
+	Name     string `json:"name"`
+	Version  string `json:"version"`
+	Checksum string `json:"checksum"`
+	Changed  bool   `json:"changed"` // false when the stored copy was already this file
+}
+
+// localIDRe is what a file name may hold to be a module ID, as in the registry.
+var localIDRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// AddLocal validates and installs the module in a YAML file, or each *.yaml
+// and *.yml file in a directory, named by its file name without the
+// extension. Installed modules are marked local, so sync does not replace
+// them. A file that fails leaves the rest installed; the error lists every
+// failure.
+func AddLocal(path string) ([]LocalModule, error) {
+	files, err := localFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	var added []LocalModule
+	var errs []error
+	for _, f := range files {
+		m, err := installLocal(f)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		added = append(added, m)
+	}
+	return added, errors.Join(errs...)
+}
+
+// WatchLocal installs path as AddLocal does, then polls it every interval
+// and reinstalls each file that changes, until ctx is done. New files in a
+// watched directory are picked up; deleted ones stay installed. report is
+// called for every install attempt. A file that fails validation keeps its
+// last good version installed.
+func WatchLocal(ctx context.Context, path string, interval time.Duration, report func(LocalModule, error)) error {
+	type stamp struct {
+		mod  time.Time
+		size int64
+	}
+	seen := make(map[string]stamp)
+	for first := true; ; first = false {
+		files, err := localFiles(path)
+		if err != nil && first {
+			return err
+		}
+		for _, f := range files {
+			info, err := os.Stat(f)
+			if err != nil {
+				continue
+			}
+			s := stamp{info.ModTime(), info.Size()}
+			if old, ok := seen[f]; ok && old == s {
+				continue
+			}
+			seen[f] = s
+			report(installLocal(f))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// localFiles returns path itself, or the module files in it when it is a
+// directory, sorted.
+func localFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, e := range entries {
+		ext := filepath.Ext(e.Name())
+		if !e.IsDir() && (ext == ".yaml" || ext == ".yml") {
+			files = append(files, filepath.Join(path, e.Name()))
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("%s has no .yaml or .yml files", path)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// installLocal validates one file and stores it as a local module unless the
+// stored copy is already identical.
+func installLocal(path string) (LocalModule, error) {
+	m := LocalModule{Path: path}
+	base := filepath.Base(path)
+	m.ID = strings.TrimSuffix(base, filepath.Ext(base))
+	if !localIDRe.MatchString(m.ID) {
+		return m, fmt.Errorf("%s: %q is not a valid module ID; rename the file", path, m.ID)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return m, err
+	}
+	body, err := io.ReadAll(io.LimitReader(f, maxModuleBytes+1))
+	f.Close()
+	if err != nil {
+		return m, err
+	}
+	if len(body) > maxModuleBytes {
+		return m, fmt.Errorf("%s: module exceeds %d byte limit", path, maxModuleBytes)
+	}
+	mod, err := ValidateModule(body)
+	if err != nil {
+		return m, fmt.Errorf("%s: %w", path, err)
+	}
+	m.Name, m.Version = mod.Name, mod.Version
+	m.Checksum = fmt.Sprintf("%x", sha256.Sum256(body))
+
+	origin, err := layer3.ModuleOrigin(m.ID)
+	if err != nil {
+		return m, err
+	}
+	if sum, err := layer3.GetModuleChecksum(m.ID); err == nil && sum == m.Checksum && origin == layer3.OriginLocal {
+		return m, nil
+	}
+	if err := storeModule(m.ID, body, layer3.OriginLocal); err != nil {
+		return m, fmt.Errorf("%s: %w", path, err)
+	}
+	m.Changed = true
+	return m, nil
+}
+
+// ValidateModule parses a module's YAML and checks what a run relies on:
+// a name, named flows with steps, known step types with the field each acts
+// on, and a label in scope for every jump. Every problem found is in the
+// returned error, one per line.
+func ValidateModule(body []byte) (*FullModuleYAML, error) {
+	mod, err := LoadModule(string(body))
+	if err != nil {
+		return nil, err
+	}
+	var errs []error
+	if strings.TrimSpace(mod.Name) == "" {
+		errs = append(errs, errors.New("missing name"))
+	}
+	if len(mod.Flows) == 0 {
+		errs = append(errs, errors.New("no flows"))
+	}
+	flows := make(map[string]bool)
+	for i, f := range mod.Flows {
+		where := fmt.Sprintf("flow %q", f.Name)
+		switch {
+		case f.Name == "":
+			where = fmt.Sprintf("flow %d", i+1)
+			errs = append(errs, fmt.Errorf("%s has no name", where))
+		case flows[f.Name]:
+			errs = append(errs, fmt.Errorf("%s is defined twice", where))
+		}
+		flows[f.Name] = true
+		if len(f.Steps) == 0 {
+			errs = append(errs, fmt.Errorf("%s has no steps", where))
+		}
+		errs = validateSteps(f.Steps, where, nil, errs)
+	}
+	return mod, errors.Join(errs...)
+}
+
+// validateSteps checks one list of steps. Like executeSteps, a jump may
+// target a label in the same list or in an enclosing one, given in outer.
+func validateSteps(steps []Step, where string, outer map[string]bool, errs []error) []error {
+	labels := make(map[string]bool, len(outer))
+	for l := range outer {
+		labels[l] = true
+	}
+	for _, s := range steps {
+		if s.Type == "label" && s.Name != "" {
+			labels[s.Name] = true
+		}
+	}
+
+	for i := range steps {
+		s := &steps[i]
+		at := fmt.Sprintf("%s step %d", where, i+1)
+		need := func(field, value string) {
+			if strings.TrimSpace(value) == "" {
+				errs = append(errs, fmt.Errorf("%s (%s) needs %s", at, s.Type, field))
+			}
+		}
+		jump := func(field, target string) {
+			if target != "" && target != "abort" && target != "skip" && !labels[target] {
+				errs = append(errs, fmt.Errorf("%s: %s %q is not a label in scope", at, field, target))
+			}
+		}
+
+		switch s.Type {
+		case "message", "input":
+		case "confirm":
+			jump("on_yes", s.OnYes)
+			jump("on_no", s.OnNo)
+		case "command":
+			need("command", s.Command)
+		case "section":
+			errs = validateSteps(s.Steps, fmt.Sprintf("%s section %q", where, s.Title), labels, errs)
+		case "check_command":
+			need("command", s.Command)
+			jump("on_missing", s.OnMissing)
+			jump("on_exists", s.OnExists)
+		case "check_path":
+			need("path", s.Path)
+			jump("on_missing", s.OnMissing)
+			jump("on_exists", s.OnExists)
+		case "label":
+			need("name", s.Name)
+		case "goto":
+			need("label", s.Label)
+			jump("label", s.Label)
+		case "file_operation":
+			if fileOperationPath(s.Operation) == "" {
+				errs = append(errs, fmt.Errorf("%s: unknown file operation %q", at, s.Operation))
+			}
+		case "":
+			errs = append(errs, fmt.Errorf("%s has no type", at))
+		default:
+			errs = append(errs, fmt.Errorf("%s: unknown step type %q", at, s.Type))
+		}
+	}
+	return errs
+}
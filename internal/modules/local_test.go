@@ -0,0 +1,202 @@
+package modules
+
+import (
+	"clio/internal/layer3"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const localYAML = `name: Hello
+version: "0.1"
+description: Greet the class
+tags: [demo]
+flows:
+  - name: run
+    steps:
+      - type: check_command
+        command: figlet
+        on_missing: plain
+      - type: command
+        command: figlet hello
+      - type: goto
+        label: done
+      - type: label
+        name: plain
+      - type: section
+        title: Plain greeting
+        steps:
+          - type: command
+            command: echo hello
+          - type: goto
+            label: done
+      - type: label
+        name: done
+`
+
+func TestValidateModule(t *testing.T) {
+	tests := []struct {
+		name, yaml string
+		wantErr    []string // substrings of the error; none means valid
+	}{
+		{"valid", localYAML, nil},
+		{"skip and abort", `name: X
+flows:
+  - name: run
+    steps:
+      - {type: check_path, path: ~/.vimrc, on_exists: skip}
+      - {type: confirm, prompt: "Go on?", on_no: abort}
+      - {type: file_operation, operation: create_vimrc}
+`, nil},
+		{"not yaml", "name: [", []string{"yaml parse error"}},
+		{"empty", "description: nothing\n", []string{"missing name", "no flows"}},
+		{"flows", `name: X
+flows:
+  - name: run
+    steps: [{type: message, content: hi}]
+  - name: run
+  - steps: [{type: message}]
+`, []string{`flow "run" is defined twice`, `flow "run" has no steps`, "flow 3 has no name"}},
+		{"steps", `name: X
+flows:
+  - name: run
+    steps:
+      - {type: command}
+      - {type: shell, command: ls}
+      - {command: ls}
+      - {type: file_operation, operation: format_disk}
+`, []string{
+			`flow "run" step 1 (command) needs command`,
+			`step 2: unknown step type "shell"`,
+			"step 3 has no type",
+			`unknown file operation "format_disk"`,
+		}},
+		{"labels out of scope", `name: X
+flows:
+  - name: run
+    steps:
+      - {type: goto, label: inner}
+      - type: section
+        title: S
+        steps:
+          - {type: label, name: inner}
+      - {type: confirm, prompt: "ok?", on_yes: nowhere}
+`, []string{`step 1: label "inner" is not a label in scope`, `step 3: on_yes "nowhere"`}},
+	}
+	for _, tt := range tests {
+		_, err := ValidateModule([]byte(tt.yaml))
+		if len(tt.wantErr) == 0 {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("%s: no error, want %q", tt.name, tt.wantErr)
+			continue
+		}
+		for _, want := range tt.wantErr {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("%s: error %q does not mention %q", tt.name, err, want)
+			}
+		}
+	}
+}
+
+func TestAddLocal(t *testing.T) {
+	layer3.UseTestDB(t)
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "hello.yaml"), []byte(localYAML), 0o644)
+	os.WriteFile(filepath.Join(dir, "broken.yml"), []byte("name: Broken\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a module"), 0o644)
+
+	added, err := AddLocal(dir)
+	if err == nil || !strings.Contains(err.Error(), "broken.yml: no flows") {
+		t.Errorf("AddLocal error = %v, want broken.yml rejected", err)
+	}
+	if len(added) != 1 || added[0].ID != "hello" || !added[0].Changed || added[0].Version != "0.1" {
+		t.Fatalf("AddLocal = %+v, want hello installed", added)
+	}
+	if local, _ := layer3.IsModuleLocal("hello"); !local {
+		t.Error("hello is not marked local")
+	}
+	if ok, _ := layer3.ModuleExists("broken"); ok {
+		t.Error("invalid module was installed")
+	}
+
+	added, err = AddLocal(filepath.Join(dir, "hello.yaml"))
+	if err != nil || len(added) != 1 || added[0].Changed {
+		t.Errorf("second AddLocal = %+v, %v, want unchanged", added, err)
+	}
+
+	// Sync and bundles leave a local module alone
+	err = StoreModule("hello", []byte(strings.Replace(localYAML, "Hello", "Registry Hello", 1)))
+	if !errors.Is(err, layer3.ErrModuleLocal) {
+		t.Errorf("StoreModule over a local module: err = %v, want ErrModuleLocal", err)
+	}
+	if meta, _ := layer3.FindModuleMeta("hello"); meta == nil || meta.Name != "Hello" {
+		t.Errorf("local module was replaced: %+v", meta)
+	}
+
+	if _, err := AddLocal(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Error("AddLocal of a missing file succeeded")
+	}
+}
+
+func TestWatchLocal(t *testing.T) {
+	layer3.UseTestDB(t)
+	path := filepath.Join(t.TempDir(), "watched.yaml")
+	if err := os.WriteFile(path, []byte(localYAML), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	type result struct {
+		m   LocalModule
+		err error
+	}
+	results := make(chan result, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- WatchLocal(ctx, path, 10*time.Millisecond, func(m LocalModule, err error) {
+			results <- result{m, err}
+		})
+	}()
+	next := func() result {
+		t.Helper()
+		select {
+		case r := <-results:
+			return r
+		case <-time.After(5 * time.Second):
+			t.Fatal("no reload reported")
+		}
+		return result{}
+	}
+
+	if r := next(); r.err != nil || r.m.Version != "0.1" {
+		t.Fatalf("first install = %+v, %v", r.m, r.err)
+	}
+	// A broken edit is reported and the last good version stays installed
+	os.WriteFile(path, []byte("name: [\n"), 0o644)
+	if r := next(); r.err == nil {
+		t.Error("broken edit was not reported")
+	}
+	if meta, _ := layer3.FindModuleMeta("watched"); meta == nil || meta.Version != "0.1" {
+		t.Errorf("after a broken edit the module is %+v", meta)
+	}
+	os.WriteFile(path, []byte(strings.Replace(localYAML, `"0.1"`, `"0.2"`, 1)), 0o644)
+	if r := next(); r.err != nil || r.m.Version != "0.2" || !r.m.Changed {
+		t.Errorf("reload = %+v, %v, want 0.2", r.m, r.err)
+	}
+	if meta, _ := layer3.FindModuleMeta("watched"); meta == nil || meta.Version != "0.2" {
+		t.Errorf("after reload the module is %+v", meta)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("WatchLocal = %v", err)
+	}
+}
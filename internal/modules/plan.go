@@ -0,0 +1,361 @@
+package modules
+
+import (
+	"clio/internal/risk"
+	"clio/internal/safeexec"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// maxPlanSteps stops a dry run that keeps jumping between labels.
+const maxPlanSteps = 1000
+
+// PlanSummary lists what a flow would touch if it ran.
+type PlanSummary struct {
+	Commands []string
+	Packages []string
+	Files    []string
+}
+
+// planner walks a flow like executeSteps does, but prints instead of acting.
+type planner struct {
+	w        io.Writer
+	vars     map[string]string
+	sections map[*Step]int
+	total    int
+	visited  int
+	summary  *PlanSummary
+	packages map[string]bool
+	files    map[string]bool
+}
+
+// Plan loads a module and prints what its flow would do. See PlanModule.
+func Plan(moduleID, flow string, vars map[string]string, w io.Writer) (*PlanSummary, error) {
+	module, err := loadModuleByID(moduleID)
+	if err != nil {
+		return nil, err
+	}
+	return PlanModule(module, flow, vars, w)
+}
+
+// PlanModule walks a flow without running any command. Input steps take values
+// from vars or a <variable> placeholder, confirms take their default answer, and
+// check_command/check_path are evaluated against this machine so the printed
+// branch is the one a real run would take.
+func PlanModule(module *FullModuleYAML, flowName string, vars map[string]string, w io.Writer) (*PlanSummary, error) {
+	flow := findFlow(module, flowName)
+	if flow == nil {
+		return nil, fmt.Errorf("flow '%s' not found in module", flowName)
+	}
+
+	p := &planner{
+		w:        w,
+		vars:     make(map[string]string, len(vars)),
+		sections: make(map[*Step]int),
+		summary:  &PlanSummary{},
+		packages: make(map[string]bool),
+		files:    make(map[string]bool),
+	}
+	for k, v := range vars {
+		p.vars[k] = v
+	}
+	p.total = numberSections(flow.Steps, p.sections, 0)
+
+	fmt.Fprintf(w, "📝 Plan: %s (flow %s) — nothing will be executed\n", module.Name, flowName)
+	if module.RequiresTermux {
+		fmt.Fprintln(w, "   requires Termux")
+	}
+	err := p.steps(flow.Steps)
+	if label, ok := gotoLabel(err); ok {
+		return nil, fmt.Errorf("label not found: %s", label)
+	}
+	if err != nil && err != errPlanAbort {
+		return nil, err
+	}
+
+	p.summary.Packages = sortedKeys(p.packages)
+	p.summary.Files = sortedKeys(p.files)
+	p.printSummary()
+	return p.summary, nil
+}
+
+// errPlanAbort ends a plan where a confirm would abort on its default answer.
+var errPlanAbort = errors.New("abort")
+
+func (p *planner) steps(steps []Step) error {
+	for i := 0; i < len(steps); i++ {
+		p.visited++
+		if p.visited > maxPlanSteps {
+			return fmt.Errorf("plan stopped after %d steps (goto loop?)", maxPlanSteps)
+		}
+		target, err := p.step(&steps[i])
+		if label, ok := gotoLabel(err); ok {
+			target = label // unresolved goto from a nested section
+		} else if err != nil {
+			return err
+		}
+		if target == "" {
+			continue
+		}
+		// Same scoping as executeSteps: this list first, then the enclosing one
+		idx, ok := findLabel(steps, target)
+		if !ok {
+			return errors.New("goto:" + target)
+		}
+		i = idx - 1
+	}
+	return nil
+}
+
+// step prints one step and returns the label it jumps to, if any.
+func (p *planner) step(step *Step) (string, error) {
+	switch step.Type {
+	case "message":
+		// Messages are shown as-is during a real run; keep the plan compact.
+
+	case "confirm":
+		yes := step.Default == "yes" || step.Default == "y"
+		answer := "no"
+		if yes {
+			answer = "yes"
+		}
+		fmt.Fprintf(p.w, "  ? %s → default %s (yes: %s, no: %s)\n",
+			step.Prompt, answer, branch(step.OnYes, "continue"), branch(step.OnNo, "continue"))
+		if !yes && step.OnNo != "" {
+			if step.OnNo == "abort" {
+				fmt.Fprintln(p.w, "  ✖ aborts here on the default answer")
+				return "", errPlanAbort
+			}
+			return step.OnNo, nil
+		}
+		if yes && step.OnYes != "" {
+			return step.OnYes, nil
+		}
+
+	case "input":
+		value, ok := p.vars[step.Variable]
+		if !ok {
+			value = "<" + step.Variable + ">"
+			if step.Variable != "" {
+				p.vars[step.Variable] = value
+			}
+		}
+		fmt.Fprintf(p.w, "  ✎ input %s: %s = %s\n", step.Prompt, step.Variable, value)
+
+	case "command":
+		if step.Condition != "" {
+			condition := expandTemplate(step.Condition, p.vars)
+			if condition == "" || condition == "false" {
+				fmt.Fprintf(p.w, "  ⏭  skip (condition %q is false)\n", step.Condition)
+				return "", nil
+			}
+		}
+		cmdStr := expandTemplate(step.Command, p.vars)
+		p.summary.Commands = append(p.summary.Commands, cmdStr)
+		for _, pkg := range commandPackages(cmdStr) {
+			p.packages[pkg] = true
+		}
+		for _, f := range commandFiles(cmdStr) {
+			p.files[f] = true
+		}
+		note := ""
+		if a := risk.Classify(cmdStr); a.Level >= risk.Privileged {
+			note = "  [" + a.Level.String() + "]"
+		}
+		if step.ContinueOnError {
+			note += "  (continues on error)"
+		}
+		fmt.Fprintf(p.w, "  $ %s%s\n", cmdStr, note)
+
+	case "section":
+		fmt.Fprintf(p.w, "\n[%d/%d] %s\n", p.sections[step]+1, p.total, step.Title)
+		return "", p.steps(step.Steps)
+
+	case "check_command":
+		_, err := safeexec.LookPath(step.Command)
+		found := err == nil
+		fmt.Fprintf(p.w, "  ⎇ %s installed? %s\n", step.Command, yesNo(found))
+		if !found && step.OnMissing != "" {
+			if step.OnMissing == "skip" {
+				return "", nil
+			}
+			return p.jump(step.OnMissing), nil
+		}
+		if found && step.OnExists != "" {
+			return p.jump(step.OnExists), nil
+		}
+
+	case "check_path":
+		_, err := os.Stat(expandPath(step.Path))
+		found := err == nil
+		fmt.Fprintf(p.w, "  ⎇ %s exists? %s\n", step.Path, yesNo(found))
+		if found && step.OnExists != "" {
+			if step.OnExists == "skip" {
+				return "", nil
+			}
+			return p.jump(step.OnExists), nil
+		}
+		if !found && step.OnMissing != "" {
+			return p.jump(step.OnMissing), nil
+		}
+
+	case "label":
+		fmt.Fprintf(p.w, "  ◆ %s:\n", step.Name)
+
+	case "goto":
+		return p.jump(step.Label), nil
+
+	case "file_operation":
+		path := fileOperationPath(step.Operation)
+		if path == "" {
+			return "", fmt.Errorf("unknown file operation: %s", step.Operation)
+		}
+		p.files[path] = true
+		fmt.Fprintf(p.w, "  ✎ %s → %s\n", step.Operation, path)
+
+	default:
+		return "", fmt.Errorf("unknown step type: %s", step.Type)
+	}
+	return "", nil
+}
+
+func (p *planner) jump(label string) string {
+	fmt.Fprintf(p.w, "  ↪ goto %s\n", label)
+	return label
+}
+
+func (p *planner) printSummary() {
+	fmt.Fprintln(p.w)
+	fmt.Fprintf(p.w, "Summary: %d command(s)\n", len(p.summary.Commands))
+	if len(p.summary.Packages) > 0 {
+		fmt.Fprintf(p.w, "  packages: %s\n", strings.Join(p.summary.Packages, ", "))
+	}
+	if len(p.summary.Files) > 0 {
+		fmt.Fprintf(p.w, "  files:    %s\n", strings.Join(p.summary.Files, ", "))
+	}
+}
+
+// installVerbs maps a package manager to the argument that starts its package list.
+var installVerbs = map[string]string{
+	"pkg":     "install",
+	"apt":     "install",
+	"apt-get": "install",
+	"dnf":     "install",
+	"yum":     "install",
+	"apk":     "add",
+	"pacman":  "-S",
+	"brew":    "install",
+	"pip":     "install",
+	"pip3":    "install",
+	"npm":     "install",
+	"gem":     "install",
+	"go":      "install",
+}
+
+// commandPackages returns the packages a shell command would install.
+func commandPackages(cmd string) []string {
+	var out []string
+	for _, part := range splitCommands(cmd) {
+		fields := strings.Fields(part)
+		if len(fields) > 0 && fields[0] == "sudo" {
+			fields = fields[1:]
+		}
+		if len(fields) < 3 {
+			continue
+		}
+		verb, ok := installVerbs[fields[0]]
+		if !ok || fields[1] != verb {
+			continue
+		}
+		for _, f := range fields[2:] {
+			if strings.ContainsAny(f, "<>") {
+				break // redirection; the package list is over
+			}
+			if !strings.HasPrefix(f, "-") {
+				out = append(out, f)
+			}
+		}
+	}
+	return out
+}
+
+// commandFiles returns files a shell command writes via redirection, touch or mkdir.
+func commandFiles(cmd string) []string {
+	var out []string
+	add := func(f string) {
+		if f != "" && !strings.HasPrefix(f, "/dev/") && !strings.HasPrefix(f, "&") {
+			out = append(out, f)
+		}
+	}
+	for _, part := range splitCommands(cmd) {
+		fields := strings.Fields(part)
+		for i, f := range fields {
+			switch {
+			case (f == ">" || f == ">>") && i+1 < len(fields):
+				add(fields[i+1])
+			case strings.HasPrefix(f, ">>"):
+				add(f[2:])
+			case strings.HasPrefix(f, ">"):
+				add(f[1:])
+			}
+		}
+		if len(fields) > 1 && (fields[0] == "touch" || fields[0] == "mkdir") {
+			for _, f := range fields[1:] {
+				if !strings.HasPrefix(f, "-") {
+					add(f)
+				}
+			}
+		}
+	}
+	return out
+}
+
+// splitCommands breaks a command line on &&, ||, |, ; and newlines.
+func splitCommands(cmd string) []string {
+	r := strings.NewReplacer("&&", "\n", "||", "\n", "|", "\n", ";", "\n")
+	return strings.Split(r.Replace(cmd), "\n")
+}
+
+// fileOperationPath is the file each built-in file_operation writes.
+func fileOperationPath(op string) string {
+	switch op {
+	case "create_vimrc":
+		return filepath.Join("~", ".vimrc")
+	case "configure_zshrc":
+		return filepath.Join("~", ".zshrc")
+	case "mark_complete":
+		return filepath.Join("~", ".clio", "termux_setup_complete")
+	}
+	return ""
+}
+
+func branch(target, fallback string) string {
+	if target == "" {
+		return fallback
+	}
+	if target == "abort" {
+		return "abort"
+	}
+	return "goto " + target
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+func sortedKeys(m map[string]bool) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,94 @@
+package modules
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const planYAML = `
+name: Dev Tools
+id: dev_tools
+flows:
+  - name: setup
+    steps:
+      - type: input
+        prompt: Git user name
+        variable: user
+      - type: section
+        title: Packages
+        steps:
+          - type: check_command
+            command: definitely-not-installed-clio
+            on_missing: install
+          - type: goto
+            label: done
+          - type: label
+            name: install
+          - type: command
+            command: "pkg install -y git vim > /dev/null"
+          - type: command
+            command: "git config --global user.name {{.user}}"
+      - type: label
+        name: done
+      - type: file_operation
+        operation: create_vimrc
+      - type: confirm
+        prompt: Install extras?
+        default: "no"
+        on_no: abort
+      - type: command
+        command: "pkg install nodejs"
+`
+
+func TestPlanModule(t *testing.T) {
+	module, err := LoadModule(planYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	summary, err := PlanModule(module, "setup", map[string]string{"user": "ada"}, &out)
+	if err != nil {
+		t.Fatalf("PlanModule: %v", err)
+	}
+
+	wantCmds := []string{"pkg install -y git vim > /dev/null", "git config --global user.name ada"}
+	if !reflect.DeepEqual(summary.Commands, wantCmds) {
+		t.Errorf("Commands = %q, want %q", summary.Commands, wantCmds)
+	}
+	if want := []string{"git", "vim"}; !reflect.DeepEqual(summary.Packages, want) {
+		t.Errorf("Packages = %q, want %q", summary.Packages, want)
+	}
+	if want := []string{"~/.vimrc"}; !reflect.DeepEqual(summary.Files, want) {
+		t.Errorf("Files = %q, want %q", summary.Files, want)
+	}
+	for _, want := range []string{"[1/1] Packages", "definitely-not-installed-clio installed? no", "goto install", "aborts here"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("plan output missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestPlanModulePlaceholders(t *testing.T) {
+	module, err := LoadModule(planYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	summary, err := PlanModule(module, "setup", nil, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := summary.Commands[1]; got != "git config --global user.name <user>" {
+		t.Errorf("command = %q, want placeholder", got)
+	}
+}
+
+func TestCommandFiles(t *testing.T) {
+	got := commandFiles("mkdir -p ~/bin && echo hi >> ~/.bashrc 2>/dev/null; touch a.txt")
+	want := []string{"~/bin", "~/.bashrc", "a.txt"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("commandFiles = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,122 @@
+package modules
+
+import (
+	"bufio"
+	"clio/internal/layer3"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// runPrefix starts every command that runs a module in-process.
+const runPrefix = "clio run "
+
+// ParseRunCommand splits "clio run <id> [flow]" (or the legacy
+// "clio-run-module <id> [flow]") into its module ID and flow.
+func ParseRunCommand(cmd string) (moduleID, flow string, ok bool) {
+	fields := strings.Fields(cmd)
+	switch {
+	case len(fields) >= 3 && fields[0] == "clio" && fields[1] == "run":
+		fields = fields[2:]
+	case len(fields) >= 2 && fields[0] == "clio-run-module":
+		fields = fields[1:]
+	default:
+		return "", "", false
+	}
+	flow = "setup"
+	if len(fields) > 1 {
+		flow = fields[1]
+	}
+	return fields[0], flow, true
+}
+
+// loadModuleByID downloads the module if needed and parses its YAML.
+func loadModuleByID(moduleID string) (*FullModuleYAML, error) {
+	if err := EnsureModule(moduleID); err != nil {
+		return nil, err
+	}
+	content, err := layer3.GetModuleByID(moduleID)
+	if err != nil {
+		return nil, err
+	}
+	module, err := LoadModule(content)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", moduleID, err)
+	}
+	return module, nil
+}
+
+// RunModule downloads the module if needed, loads its YAML from the local
+// database and executes the flow in-process. Commands still go through
+// safeexec, so this is safe on Termux without the sqlite3 CLI.
+//
+// Progress is checkpointed after every section. With resume, the latest
+// unfinished run of the flow continues: finished sections are skipped and
+// saved input is reused. Sensitive input is never saved, so it is asked again.
+func RunModule(moduleID, flow string, resume bool, scanner *bufio.Scanner) error {
+	if flow == "" {
+		flow = "setup"
+	}
+	module, err := loadModuleByID(moduleID)
+	if err != nil {
+		return err
+	}
+
+	var run *layer3.ModuleRun
+	if resume {
+		if run, err = layer3.LatestUnfinishedRun(moduleID, flow); err != nil {
+			return err
+		}
+		if run == nil {
+			fmt.Printf("No unfinished run of %s %s — starting from the beginning.\n", moduleID, flow)
+		}
+	}
+	ctx := &ExecutionContext{Scanner: scanner}
+	if run != nil {
+		ctx.Resumed = true
+		ctx.Variables = run.Variables
+		ctx.Completed = make(map[int]bool, len(run.Completed))
+		for _, n := range run.Completed {
+			ctx.Completed[n] = true
+		}
+		fmt.Printf("↻ Resuming run #%d (%d section(s) done)\n", run.ID, len(run.Completed))
+	} else if run, err = layer3.StartModuleRun(moduleID, flow); err != nil {
+		return err
+	}
+	ctx.OnSection = func(ctx *ExecutionContext) {
+		checkpoint(run, ctx, layer3.RunRunning)
+	}
+
+	fmt.Printf("📋 %s\n", module.Name)
+	if module.Description != "" {
+		fmt.Printf("   %s\n", module.Description)
+	}
+	if module.EstimatedTime != "" {
+		fmt.Printf("   ⏱️  Estimated time: %s\n", module.EstimatedTime)
+	}
+	fmt.Println()
+
+	if err := ExecuteModuleWith(module, flow, ctx); err != nil {
+		checkpoint(run, ctx, layer3.RunFailed)
+		fmt.Printf("\n💾 Progress saved. Continue with: clio run --resume %s %s\n", moduleID, flow)
+		return err
+	}
+	checkpoint(run, ctx, layer3.RunCompleted)
+	fmt.Printf("\n✅ %s finished\n", module.Name)
+	return nil
+}
+
+// checkpoint stores the context's progress on run. A failed write only warns:
+// losing a checkpoint must not stop the module itself.
+func checkpoint(run *layer3.ModuleRun, ctx *ExecutionContext, status string) {
+	run.Status = status
+	run.Variables = ctx.SavedVariables()
+	run.Completed = run.Completed[:0]
+	for n := range ctx.Completed {
+		run.Completed = append(run.Completed, n)
+	}
+	sort.Ints(run.Completed)
+	if err := layer3.SaveModuleRun(run); err != nil {
+		fmt.Printf("⚠️  Could not save progress: %v\n", err)
+	}
+}
@@ -0,0 +1,249 @@
+package modules
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseRunCommand(t *testing.T) {
+	cases := []struct {
+		cmd, id, flow string
+		ok            bool
+	}{
+		{"clio run copy_file backup", "copy_file", "backup", true},
+		{"clio run copy_file", "copy_file", "setup", true},
+		{"clio-run-module vim_setup setup", "vim_setup", "setup", true},
+		{"clio run", "", "", false},
+		{"ls -la", "", "", false},
+	}
+	for _, c := range cases {
+		id, flow, ok := ParseRunCommand(c.cmd)
+		if id != c.id || flow != c.flow || ok != c.ok {
+			t.Errorf("ParseRunCommand(%q) = %q, %q, %v", c.cmd, id, flow, ok)
+		}
+	}
+}
+
+func TestExecuteModuleStepTypes(t *testing.T) {
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "marker")
+	yamlContent := `
+name: Test
+id: test
+flows:
+  - name: setup
+    steps:
+      - type: input
+        prompt: Name
+        variable: who
+      - type: check_path
+        path: ` + dir + `
+        on_exists: write
+      - type: command
+        command: "false"
+      - type: label
+        name: write
+      - type: command
+        command: "echo {{.who}} > ` + marker + `"
+`
+	module, err := LoadModule(yamlContent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scanner := bufio.NewScanner(strings.NewReader("ada\n"))
+	if err := ExecuteModule(module, "setup", scanner); err != nil {
+		t.Fatalf("ExecuteModule: %v", err)
+	}
+	got, err := os.ReadFile(marker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(string(got)) != "ada" {
+		t.Fatalf("marker = %q, want ada", got)
+	}
+	if err := ExecuteModule(module, "missing", scanner); err == nil {
+		t.Fatal("expected error for unknown flow")
+	}
+}
+
+func TestExecuteModuleGotoInsideSection(t *testing.T) {
+	dir := t.TempDir()
+	yamlContent := `
+name: Test
+flows:
+  - name: setup
+    steps:
+      - type: section
+        title: One
+        steps:
+          - type: goto
+            label: write
+          - type: command
+            command: "touch ` + filepath.Join(dir, "skipped") + `"
+          - type: label
+            name: write
+          - type: command
+            command: "touch ` + filepath.Join(dir, "written") + `"
+`
+	module, err := LoadModule(yamlContent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ExecuteModule(module, "setup", bufio.NewScanner(strings.NewReader(""))); err != nil {
+		t.Fatalf("ExecuteModule: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "skipped")); err == nil {
+		t.Error("goto did not skip the command before the label")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "written")); err != nil {
+		t.Error("command after the label did not run")
+	}
+}
+
+func TestExecuteModuleGotoOuterLabel(t *testing.T) {
+	dir := t.TempDir()
+	yamlContent := `
+name: Test
+flows:
+  - name: setup
+    steps:
+      - type: section
+        title: One
+        steps:
+          - type: goto
+            label: done
+          - type: command
+            command: "touch ` + filepath.Join(dir, "section") + `"
+      - type: command
+        command: "touch ` + filepath.Join(dir, "between") + `"
+      - type: label
+        name: done
+      - type: command
+        command: "touch ` + filepath.Join(dir, "done") + `"
+  - name: missing
+    steps:
+      - type: section
+        title: One
+        steps:
+          - type: goto
+            label: nowhere
+`
+	module, err := LoadModule(yamlContent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ExecuteModule(module, "setup", bufio.NewScanner(strings.NewReader(""))); err != nil {
+		t.Fatalf("ExecuteModule: %v", err)
+	}
+	for _, name := range []string{"section", "between"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			t.Errorf("goto to an outer label ran the %s command it should skip", name)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "done")); err != nil {
+		t.Error("command after the outer label did not run")
+	}
+
+	err = ExecuteModule(module, "missing", bufio.NewScanner(strings.NewReader("")))
+	if err == nil || !strings.Contains(err.Error(), "label not found: nowhere") {
+		t.Errorf("goto to an unknown label = %v, want label not found", err)
+	}
+}
+
+func TestExecuteModuleWithResume(t *testing.T) {
+	dir := t.TempDir()
+	yamlContent := `
+name: Test
+flows:
+  - name: setup
+    steps:
+      - type: section
+        title: One
+        steps:
+          - type: command
+            command: "touch ` + filepath.Join(dir, "one") + `"
+      - type: section
+        title: Two
+        steps:
+          - type: input
+            prompt: Name
+            variable: who
+          - type: command
+            command: "echo {{.who}} > ` + filepath.Join(dir, "two") + `"
+`
+	module, err := LoadModule(yamlContent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var checkpoints [][]int
+	ctx := &ExecutionContext{
+		// No input available: a prompt would fail the run
+		Scanner:   bufio.NewScanner(strings.NewReader("")),
+		Variables: map[string]string{"who": "ada"},
+		Completed: map[int]bool{0: true},
+		Resumed:   true,
+		OnSection: func(c *ExecutionContext) {
+			var done []int
+			for n := range c.Completed {
+				done = append(done, n)
+			}
+			checkpoints = append(checkpoints, done)
+		},
+	}
+	if err := ExecuteModuleWith(module, "setup", ctx); err != nil {
+		t.Fatalf("ExecuteModuleWith: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "one")); err == nil {
+		t.Error("completed section ran again")
+	}
+	got, err := os.ReadFile(filepath.Join(dir, "two"))
+	if err != nil || strings.TrimSpace(string(got)) != "ada" {
+		t.Errorf("section two output = %q, %v", got, err)
+	}
+	if len(checkpoints) != 1 || len(checkpoints[0]) != 2 {
+		t.Errorf("checkpoints = %v, want one checkpoint with both sections", checkpoints)
+	}
+}
+
+func TestSensitiveInputIsNotSaved(t *testing.T) {
+	yamlContent := `
+name: Test
+flows:
+  - name: setup
+    steps:
+      - type: section
+        title: One
+        steps:
+          - type: input
+            prompt: User
+            variable: user
+          - type: input
+            prompt: Token
+            variable: token
+            sensitive: true
+`
+	module, err := LoadModule(yamlContent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved map[string]string
+	ctx := &ExecutionContext{
+		Scanner:   bufio.NewScanner(strings.NewReader("ada\ns3cret\n")),
+		OnSection: func(c *ExecutionContext) { saved = c.SavedVariables() },
+	}
+	if err := ExecuteModuleWith(module, "setup", ctx); err != nil {
+		t.Fatalf("ExecuteModuleWith: %v", err)
+	}
+	if ctx.Variables["token"] != "s3cret" {
+		t.Errorf("token = %q, want it available to the run", ctx.Variables["token"])
+	}
+	if saved["user"] != "ada" {
+		t.Errorf("saved user = %q, want ada", saved["user"])
+	}
+	if _, ok := saved["token"]; ok {
+		t.Errorf("sensitive token was saved: %v", saved)
+	}
+}
@@ -2,7 +2,9 @@ package modules
 
 import (
 	"clio/internal/config"
+	"clio/internal/examples"
 	"clio/internal/layer3"
+	"clio/internal/tldr"
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
@@ -75,11 +77,46 @@ func SyncWithOptions(full bool) error {
 	if err := SyncFromRegistry(lite); err != nil {
 		fmt.Printf("⚠️  Registry sync failed: %v\n", err)
 		fmt.Println("📦 Falling back to GitHub...")
-		return SyncFromGitHub(lite)
+		err = SyncFromGitHub(lite)
+		syncTLDR(full)
+		return err
 	}
+	syncExamples()
+	syncTLDR(full)
 	return nil
 }
 
+// syncExamples refreshes the examples database from the registry. Modules
+// are already synced, so a failure here is only a warning.
+func syncExamples() {
+	n, err := examples.Sync(config.GetRegistryURL())
+	if err != nil {
+		fmt.Printf("⚠️  Examples not updated: %v\n", err)
+		return
+	}
+	fmt.Printf("✅ Examples updated (%d).\n", n)
+}
+
+// syncTLDR imports the configured tldr-pages archive once per sync_interval,
+// or on every full sync. Like syncExamples, a failure is only a warning.
+func syncTLDR(full bool) {
+	url := config.GetTLDRURL()
+	if url == "" {
+		return
+	}
+	if !full {
+		if st, err := layer3.GetTLDRStatus(); err == nil && st != nil && time.Since(st.ImportedAt) < config.GetSyncInterval() {
+			return
+		}
+	}
+	n, err := tldr.Sync(url)
+	if err != nil {
+		fmt.Printf("⚠️  tldr pages not updated: %v\n", err)
+		return
+	}
+	fmt.Printf("✅ tldr pages updated (%d).\n", n)
+}
+
 // isEssentialModule returns true for modules needed on constrained Termux devices.
 func isEssentialModule(moduleID string) bool {
 	switch moduleID {
@@ -128,6 +165,15 @@ func SyncFromRegistry(lite bool) error {
 			continue
 		}
 
+		if pinned, _ := layer3.IsModulePinned(mod.ID); pinned {
+			fmt.Printf("  📌 %s is pinned; not updated\n", mod.ID)
+			continue
+		}
+		if local, _ := layer3.IsModuleLocal(mod.ID); local {
+			fmt.Printf("  🛠  %s is a local module; not updated\n", mod.ID)
+			continue
+		}
+
 		// Check if we need to download (checksum differs)
 		localChecksum, err := layer3.GetModuleChecksum(mod.ID)
 		if err == nil && localChecksum == mod.ChecksumSHA256 {
@@ -181,6 +227,18 @@ func downloadAndSaveModule(registryURL, moduleID string) error {
 		return fmt.Errorf("module exceeds %d byte limit", maxModuleBytes)
 	}
 
+	return StoreModule(moduleID, body)
+}
+
+// StoreModule saves a module's YAML under moduleID with its metadata,
+// generated bash script and SHA-256 checksum, as sync does for a download.
+// A module installed from a local file is left as it is, with
+// layer3.ErrModuleLocal.
+func StoreModule(moduleID string, body []byte) error {
+	return storeModule(moduleID, body, layer3.OriginRegistry)
+}
+
+func storeModule(moduleID string, body []byte, origin string) error {
 	hash := sha256.Sum256(body)
 	checksum := fmt.Sprintf("%x", hash)
 
@@ -203,7 +261,10 @@ func downloadAndSaveModule(registryURL, moduleID string) error {
 		bashScript = "" // Store empty on error
 	}
 
-	// Registry name is the DB key (what clio-run-module uses), not necessarily yaml id.
+	if origin == layer3.OriginLocal {
+		return layer3.UpsertLocalModule(moduleID, mod.Name, mod.Description, tags, mod.Version, string(body), bashScript, checksum)
+	}
+	// Registry name is the DB key (what clio run uses), not necessarily yaml id.
 	return layer3.UpsertModuleWithChecksum(moduleID, mod.Name, mod.Description, tags, mod.Version, string(body), bashScript, checksum)
 }
 
@@ -241,6 +302,15 @@ func SyncFromGitHub(lite bool) error {
 			continue
 		}
 
+		if pinned, _ := layer3.IsModulePinned(moduleID); pinned {
+			fmt.Printf("  📌 %s is pinned; not updated\n", moduleID)
+			continue
+		}
+		if local, _ := layer3.IsModuleLocal(moduleID); local {
+			fmt.Printf("  🛠  %s is a local module; not updated\n", moduleID)
+			continue
+		}
+
 		fmt.Printf("  Processing %s...\n", item.Name)
 		if err := processModuleByID(moduleID, item.DownloadURL); err != nil {
 			fmt.Printf("  ❌ Failed %s: %v\n", item.Name, err)
@@ -274,27 +344,7 @@ func processModuleByID(moduleID, url string) error {
 		return fmt.Errorf("module exceeds %d byte limit", maxModuleBytes)
 	}
 
-	var mod ModuleYAML
-	if err := yaml.Unmarshal(body, &mod); err != nil {
-		return fmt.Errorf("yaml parse error: %w", err)
-	}
-
-	if mod.Name == "" {
-		return fmt.Errorf("missing name in %s", moduleID)
-	}
-
-	tags := strings.Join(mod.Tags, ",")
-
-	bashScript, err := convertYAMLToBashScript(string(body))
-	if err != nil {
-		fmt.Printf("  Warning: failed to generate bash script: %v\n", err)
-		bashScript = ""
-	}
-
-	hash := sha256.Sum256(body)
-	checksum := fmt.Sprintf("%x", hash)
-
-	return layer3.UpsertModuleWithChecksum(moduleID, mod.Name, mod.Description, tags, mod.Version, string(body), bashScript, checksum)
+	return StoreModule(moduleID, body)
 }
 
 // convertYAMLToBashScript converts module YAML to bash-friendly format
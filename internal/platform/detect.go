@@ -0,0 +1,178 @@
+package platform
+
+import (
+	"clio/internal/safeexec"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// Info describes the machine clio suggests commands for.
+type Info struct {
+	OS             string   // runtime.GOOS: linux, darwin, freebsd…
+	Distro         string   // os-release ID (debian, fedora, arch, alpine…), "termux" or "macos"
+	Like           []string // os-release ID_LIKE, e.g. [debian] on Ubuntu
+	PackageManager string   // pkg, apt, dnf, yum, pacman, apk, zypper or brew; "" if none found
+	Termux         bool
+	Root           bool // already root, so package commands need no sudo
+}
+
+// Keys lists the names a command variant can be keyed by, most specific first:
+// package manager, distro, ID_LIKE, OS, then userland (gnu or bsd).
+func (i Info) Keys() []string {
+	var keys []string
+	add := func(k string) {
+		if k == "" {
+			return
+		}
+		for _, seen := range keys {
+			if seen == k {
+				return
+			}
+		}
+		keys = append(keys, k)
+	}
+	add(i.PackageManager)
+	if i.Termux {
+		add("termux")
+	}
+	add(i.Distro)
+	for _, l := range i.Like {
+		add(l)
+	}
+	add(i.OS)
+	add(i.Userland())
+	return keys
+}
+
+// Userland is "bsd" for macOS and the BSDs, whose ls/sed/stat/date take
+// different flags, and "gnu" otherwise (Termux ships GNU coreutils).
+func (i Info) Userland() string {
+	switch i.OS {
+	case "darwin", "freebsd", "openbsd", "netbsd", "dragonfly":
+		return "bsd"
+	}
+	return "gnu"
+}
+
+// distroManagers is the native package manager for each os-release ID.
+var distroManagers = map[string]string{
+	"debian": "apt", "ubuntu": "apt", "linuxmint": "apt", "pop": "apt", "raspbian": "apt",
+	"fedora": "dnf", "rhel": "dnf", "centos": "dnf", "rocky": "dnf", "almalinux": "dnf",
+	"arch": "pacman", "manjaro": "pacman", "endeavouros": "pacman",
+	"alpine":   "apk",
+	"opensuse": "zypper", "opensuse-leap": "zypper", "opensuse-tumbleweed": "zypper", "suse": "zypper",
+	"termux": "pkg", "macos": "brew", "freebsd": "pkg",
+}
+
+// managerProbeOrder is tried on PATH when the distro's manager is unknown or missing.
+var managerProbeOrder = []string{"apt", "dnf", "yum", "pacman", "apk", "zypper", "brew", "pkg"}
+
+// detectEnv is everything Detect reads from the machine, so tests can simulate one.
+type detectEnv struct {
+	goos      string
+	osRelease string // contents of /etc/os-release
+	termux    bool
+	root      bool
+	onPath    func(name string) bool
+}
+
+var (
+	currentOnce sync.Once
+	current     Info
+)
+
+var override *Info
+
+// Current returns the detected platform, computed once per process.
+func Current() Info {
+	if override != nil {
+		return *override
+	}
+	currentOnce.Do(func() { current = Detect() })
+	return current
+}
+
+// Override makes Current return info instead of detecting it, for commands
+// such as clio eval that answer as if on another machine. Pass nil to go back
+// to detection.
+func Override(info *Info) {
+	override = info
+}
+
+// Detect reads os-release, checks for Termux and looks for a package manager on PATH.
+func Detect() Info {
+	env := detectEnv{
+		goos:   runtime.GOOS,
+		termux: IsTermux(),
+		root:   os.Geteuid() == 0,
+		onPath: func(name string) bool {
+			_, err := safeexec.LookPath(name)
+			return err == nil
+		},
+	}
+	for _, path := range []string{"/etc/os-release", "/usr/lib/os-release"} {
+		if data, err := os.ReadFile(path); err == nil {
+			env.osRelease = string(data)
+			break
+		}
+	}
+	return detect(env)
+}
+
+func detect(env detectEnv) Info {
+	info := Info{OS: env.goos, Termux: env.termux, Root: env.root}
+	switch {
+	case env.termux:
+		info.Distro = "termux"
+		info.Root = false // Termux runs as an app user; pkg never needs sudo
+	case env.goos == "darwin":
+		info.Distro = "macos"
+	default:
+		fields := parseOSRelease(env.osRelease)
+		info.Distro = fields["ID"]
+		if like := fields["ID_LIKE"]; like != "" {
+			info.Like = strings.Fields(like)
+		}
+	}
+
+	// Prefer the distro's own manager, then anything ID_LIKE suggests, then whatever is on PATH
+	var preferred []string
+	for _, id := range append([]string{info.Distro}, info.Like...) {
+		if m, ok := distroManagers[id]; ok {
+			preferred = append(preferred, m)
+			if m == "dnf" {
+				preferred = append(preferred, "yum") // older RHEL/CentOS
+			}
+		}
+	}
+	for _, m := range append(preferred, managerProbeOrder...) {
+		if env.onPath != nil && env.onPath(m) {
+			info.PackageManager = m
+			break
+		}
+	}
+	if info.PackageManager == "" && len(preferred) > 0 {
+		// Nothing on PATH (minimal containers, tests): trust os-release
+		info.PackageManager = preferred[0]
+	}
+	return info
+}
+
+// parseOSRelease reads KEY=value lines, unquoting values.
+func parseOSRelease(data string) map[string]string {
+	out := make(map[string]string)
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		out[key] = strings.ToLower(strings.Trim(value, `"'`))
+	}
+	return out
+}
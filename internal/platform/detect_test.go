@@ -0,0 +1,86 @@
+package platform
+
+import (
+	"reflect"
+	"testing"
+)
+
+func onPath(names ...string) func(string) bool {
+	return func(name string) bool {
+		for _, n := range names {
+			if n == name {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func TestDetect(t *testing.T) {
+	cases := []struct {
+		name string
+		env  detectEnv
+		want Info
+	}{
+		{
+			"termux",
+			detectEnv{goos: "linux", termux: true, root: true, onPath: onPath("pkg", "apt")},
+			Info{OS: "linux", Distro: "termux", PackageManager: "pkg", Termux: true},
+		},
+		{
+			"ubuntu",
+			detectEnv{goos: "linux", osRelease: "NAME=\"Ubuntu\"\nID=ubuntu\nID_LIKE=debian\n", onPath: onPath("apt", "snap")},
+			Info{OS: "linux", Distro: "ubuntu", Like: []string{"debian"}, PackageManager: "apt"},
+		},
+		{
+			"fedora",
+			detectEnv{goos: "linux", osRelease: "ID=fedora\n", onPath: onPath("dnf", "yum")},
+			Info{OS: "linux", Distro: "fedora", PackageManager: "dnf"},
+		},
+		{
+			"centos7 has only yum",
+			detectEnv{goos: "linux", osRelease: "ID=\"centos\"\nID_LIKE=\"rhel fedora\"\n", onPath: onPath("yum")},
+			Info{OS: "linux", Distro: "centos", Like: []string{"rhel", "fedora"}, PackageManager: "yum"},
+		},
+		{
+			"arch",
+			detectEnv{goos: "linux", osRelease: "ID=arch\n", onPath: onPath("pacman")},
+			Info{OS: "linux", Distro: "arch", PackageManager: "pacman"},
+		},
+		{
+			"alpine container without PATH hits",
+			detectEnv{goos: "linux", osRelease: "ID=alpine\n", root: true, onPath: onPath()},
+			Info{OS: "linux", Distro: "alpine", PackageManager: "apk", Root: true},
+		},
+		{
+			"macos",
+			detectEnv{goos: "darwin", onPath: onPath("brew")},
+			Info{OS: "darwin", Distro: "macos", PackageManager: "brew"},
+		},
+		{
+			"unknown distro falls back to PATH",
+			detectEnv{goos: "linux", osRelease: "ID=nixos\n", onPath: onPath("apk")},
+			Info{OS: "linux", Distro: "nixos", PackageManager: "apk"},
+		},
+	}
+	for _, c := range cases {
+		got := detect(c.env)
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: detect = %+v, want %+v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestInfoKeys(t *testing.T) {
+	ubuntu := Info{OS: "linux", Distro: "ubuntu", Like: []string{"debian"}, PackageManager: "apt"}
+	if got, want := ubuntu.Keys(), []string{"apt", "ubuntu", "debian", "linux", "gnu"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ubuntu keys = %v, want %v", got, want)
+	}
+	termux := Info{OS: "linux", Distro: "termux", PackageManager: "pkg", Termux: true}
+	if got, want := termux.Keys(), []string{"pkg", "termux", "linux", "gnu"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("termux keys = %v, want %v", got, want)
+	}
+	if got := (Info{OS: "darwin"}).Userland(); got != "bsd" {
+		t.Errorf("darwin userland = %q, want bsd", got)
+	}
+}
@@ -0,0 +1,224 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Context describes the project clio was started in.
+type Context struct {
+	Root    string   // directory holding the nearest project marker
+	Kinds   []string // go, node, python, php, rust, make — nearest first
+	Tools   []string // tools implied by lock files: yarn, pnpm, poetry…
+	Markers []string // marker files found, e.g. go.mod, Makefile
+	Git     bool     // inside a git work tree
+}
+
+// markers maps a file in a project directory to the kind of project it marks.
+// Order matters when one directory holds several: languages before make.
+var markers = []struct{ file, kind string }{
+	{"go.mod", "go"},
+	{"package.json", "node"},
+	{"pyproject.toml", "python"},
+	{"requirements.txt", "python"},
+	{"setup.py", "python"},
+	{"composer.json", "php"},
+	{"Cargo.toml", "rust"},
+	{"Makefile", "make"},
+	{"makefile", "make"},
+	{"GNUmakefile", "make"},
+}
+
+// lockTools names the tool a lock file implies over the ecosystem default.
+var lockTools = []struct{ file, tool string }{
+	{"yarn.lock", "yarn"},
+	{"pnpm-lock.yaml", "pnpm"},
+	{"bun.lockb", "bun"},
+	{"poetry.lock", "poetry"},
+	{"uv.lock", "uv"},
+}
+
+// toolKinds is the kind of project each lock-file tool belongs to.
+var toolKinds = map[string]string{
+	"yarn": "node", "pnpm": "node", "bun": "node", "poetry": "python", "uv": "python",
+}
+
+// names is how each kind and tool is shown to the user.
+var names = map[string]string{
+	"go": "Go", "node": "Node.js", "python": "Python", "php": "PHP", "rust": "Rust", "make": "Make",
+	"yarn": "Yarn", "pnpm": "pnpm", "bun": "Bun", "poetry": "Poetry", "uv": "uv",
+}
+
+// Name is the display name of a kind or tool ("node" → "Node.js").
+func Name(key string) string {
+	if n, ok := names[key]; ok {
+		return n
+	}
+	return key
+}
+
+// Empty reports whether no project was found.
+func (c Context) Empty() bool {
+	return len(c.Kinds) == 0 && !c.Git
+}
+
+// Has reports whether the project is of the given kind.
+func (c Context) Has(kind string) bool {
+	for _, k := range c.Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Keys lists the names a project command can be keyed by, most specific
+// first: for each kind its lock-file tool, then the kind itself. Make comes
+// last so a language's own tooling wins over a Makefile next to it.
+func (c Context) Keys() []string {
+	var keys []string
+	for _, kind := range c.Kinds {
+		if kind == "make" {
+			continue
+		}
+		for _, tool := range c.Tools {
+			if toolKinds[tool] == kind {
+				keys = append(keys, tool)
+			}
+		}
+		keys = append(keys, kind)
+	}
+	if c.Has("make") {
+		keys = append(keys, "make")
+	}
+	return keys
+}
+
+// Label is a short description for result headers, e.g. "Go + Make, git (~/src/app)".
+func (c Context) Label() string {
+	if c.Empty() {
+		return ""
+	}
+	var kinds []string
+	for _, k := range c.Kinds {
+		kinds = append(kinds, Name(k))
+	}
+	label := strings.Join(kinds, " + ")
+	if c.Git {
+		if label == "" {
+			label = "git repository"
+		} else {
+			label += ", git"
+		}
+	}
+	if c.Root != "" {
+		label += " (" + shortPath(c.Root) + ")"
+	}
+	return label
+}
+
+var (
+	currentOnce sync.Once
+	current     Context
+)
+
+var override *Context
+
+// Current returns the project around the working directory, computed once per process.
+func Current() Context {
+	if override != nil {
+		return *override
+	}
+	currentOnce.Do(func() {
+		if wd, err := os.Getwd(); err == nil {
+			current = Detect(wd)
+		}
+	})
+	return current
+}
+
+// Override makes Current return ctx instead of detecting it, for commands
+// such as clio eval that answer as if on another machine. Pass nil to go back
+// to detection.
+func Override(ctx *Context) {
+	override = ctx
+}
+
+// Detect looks for project markers in dir and its parents. It stops at the
+// top of the git work tree, the home directory or the filesystem root, so a
+// stray package.json in $HOME does not make every folder a Node project.
+func Detect(dir string) Context {
+	var ctx Context
+	home, _ := os.UserHomeDir()
+	dir = filepath.Clean(dir)
+	for {
+		if home != "" && dir == home {
+			break
+		}
+		found := false
+		for _, m := range markers {
+			if !exists(filepath.Join(dir, m.file)) {
+				continue
+			}
+			found = true
+			ctx.Markers = append(ctx.Markers, m.file)
+			if !ctx.Has(m.kind) {
+				ctx.Kinds = append(ctx.Kinds, m.kind)
+			}
+		}
+		if found {
+			if ctx.Root == "" {
+				ctx.Root = dir
+			}
+			for _, l := range lockTools {
+				if exists(filepath.Join(dir, l.file)) && ctx.Has(toolKinds[l.tool]) && !contains(ctx.Tools, l.tool) {
+					ctx.Tools = append(ctx.Tools, l.tool)
+				}
+			}
+		}
+		if exists(filepath.Join(dir, ".git")) {
+			ctx.Git = true
+			if ctx.Root == "" {
+				ctx.Root = dir
+			}
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	return ctx
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// shortPath abbreviates the home directory to ~.
+func shortPath(path string) string {
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" || home == "/" {
+		return path
+	}
+	if path == home {
+		return "~"
+	}
+	if rest, ok := strings.CutPrefix(path, home+string(filepath.Separator)); ok {
+		return "~/" + rest
+	}
+	return path
+}
@@ -0,0 +1,98 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// tree creates files (and their parent directories) under a temp dir.
+func tree(t *testing.T, files ...string) string {
+	t.Helper()
+	root := t.TempDir()
+	for _, f := range files {
+		path := filepath.Join(root, f)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestDetect(t *testing.T) {
+	cases := []struct {
+		name  string
+		files []string
+		dir   string // relative to the tree root
+		kinds []string
+		tools []string
+		git   bool
+		root  string // relative to the tree root
+	}{
+		{"go with makefile", []string{".git/HEAD", "go.mod", "Makefile"}, ".", []string{"go", "make"}, nil, true, "."},
+		{"subdirectory", []string{".git/HEAD", "go.mod", "internal/x/x.go"}, "internal/x", []string{"go"}, nil, true, "."},
+		{"yarn", []string{"package.json", "yarn.lock"}, ".", []string{"node"}, []string{"yarn"}, false, "."},
+		{"poetry", []string{"pyproject.toml", "poetry.lock"}, ".", []string{"python"}, []string{"poetry"}, false, "."},
+		{"nearest first", []string{".git/HEAD", "Makefile", "web/package.json"}, "web", []string{"node", "make"}, nil, true, "web"},
+		{"stops at git root", []string{"package.json", "repo/.git/HEAD", "repo/Cargo.toml"}, "repo", []string{"rust"}, nil, true, "repo"},
+		{"git only", []string{".git/HEAD", "docs/readme"}, "docs", nil, nil, true, "."},
+		{"composer", []string{"composer.json"}, ".", []string{"php"}, nil, false, "."},
+		{"lock file without its ecosystem", []string{"go.mod", "yarn.lock"}, ".", []string{"go"}, nil, false, "."},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			root := tree(t, c.files...)
+			got := Detect(filepath.Join(root, c.dir))
+			if !reflect.DeepEqual(got.Kinds, c.kinds) {
+				t.Errorf("Kinds = %v, want %v", got.Kinds, c.kinds)
+			}
+			if !reflect.DeepEqual(got.Tools, c.tools) {
+				t.Errorf("Tools = %v, want %v", got.Tools, c.tools)
+			}
+			if got.Git != c.git {
+				t.Errorf("Git = %v, want %v", got.Git, c.git)
+			}
+			if want := filepath.Join(root, c.root); got.Root != want {
+				t.Errorf("Root = %q, want %q", got.Root, want)
+			}
+		})
+	}
+}
+
+func TestKeys(t *testing.T) {
+	cases := []struct {
+		ctx  Context
+		want []string
+	}{
+		{Context{Kinds: []string{"go"}}, []string{"go"}},
+		{Context{Kinds: []string{"make", "go"}}, []string{"go", "make"}},
+		{Context{Kinds: []string{"node", "python"}, Tools: []string{"poetry", "pnpm"}}, []string{"pnpm", "node", "poetry", "python"}},
+		{Context{Git: true}, nil},
+	}
+	for _, c := range cases {
+		if got := c.ctx.Keys(); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%+v.Keys() = %v, want %v", c.ctx, got, c.want)
+		}
+	}
+}
+
+func TestLabel(t *testing.T) {
+	cases := []struct {
+		ctx  Context
+		want string
+	}{
+		{Context{}, ""},
+		{Context{Root: "/src/app", Kinds: []string{"go", "make"}, Git: true}, "Go + Make, git (/src/app)"},
+		{Context{Root: "/src/notes", Git: true}, "git repository (/src/notes)"},
+		{Context{Root: "/src/web", Kinds: []string{"node"}}, "Node.js (/src/web)"},
+	}
+	for _, c := range cases {
+		if got := c.ctx.Label(); got != c.want {
+			t.Errorf("%+v.Label() = %q, want %q", c.ctx, got, c.want)
+		}
+	}
+}
@@ -3,17 +3,28 @@ package repl
 import (
 	"bufio"
 	"clio/internal/config"
+	"clio/internal/examples"
+	"clio/internal/explain"
 	"clio/internal/intent"
+	"clio/internal/layer1"
+	"clio/internal/layer3"
 	"clio/internal/modules"
-	"clio/internal/safeexec"
+	"clio/internal/platform"
+	"clio/internal/project"
+	"clio/internal/risk"
 	"clio/internal/setup"
+	"clio/internal/shell"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 )
 
 const maxInputBytes = 4096
 
+// maxCandidates caps how many ranked matches are fetched per query.
+const maxCandidates = 5
+
 // Run starts the REPL loop
 func Run() {
 	scanner := bufio.NewScanner(os.Stdin)
@@ -42,6 +53,24 @@ func Run() {
 			printHelp()
 			continue
 		}
+		if line, ok := explain.ParseQuery(input); ok {
+			x, err := explain.Explain(line)
+			if err != nil {
+				fmt.Printf("Cannot explain: %v\n", err)
+				continue
+			}
+			fmt.Println()
+			x.Write(os.Stdout)
+			continue
+		}
+		if query, ok := examples.ParseQuery(input); ok {
+			showExampleSearch(query)
+			continue
+		}
+		if arg, ok := historyCommandArg(input); ok {
+			handleHistory(arg, scanner)
+			continue
+		}
 		if input == "catalog" || input == "browse" {
 			modules.ShowFullCatalog()
 			continue
@@ -104,12 +133,18 @@ func Run() {
 			continue
 		}
 
-		result, err := intent.Detect(input)
+		cands, err := intent.DetectAll(input, maxCandidates)
 		if err != nil {
 			fmt.Printf("⚠ No matching command found for '%s'. Try rephrasing.\n", input)
 			fmt.Println("   Browse: 'catalog' (both kinds) · 'setup' (wizards) · 'modules' (tasks)")
 			continue
 		}
+		result := cands[0]
+		if intent.IsAmbiguous(cands) {
+			if result = chooseCandidate(cands, scanner); result == nil {
+				continue
+			}
+		}
 
 		if result.Source == "setup" || result.Source == "setup-menu" {
 			switch result.Source {
@@ -128,14 +163,14 @@ func Run() {
 			continue
 		}
 
-		handleResult(result, scanner)
+		handleResult(input, result, scanner)
 	}
 }
 
 func printWelcome() {
 	fmt.Println("CLIPilot Client (Offline First) - Type 'catalog', 'setup', or ask anything")
 	if config.IsLiteProfile() {
-		fmt.Println("Profile: lite (offline layers 1+3 — 'sync full' for all modules)")
+		fmt.Println("Profile: lite (offline layers 1-3 — 'sync full' for all modules)")
 	}
 	fmt.Println("-----------------------------------------------------")
 	if setup.IsTermux() {
@@ -164,6 +199,9 @@ func printHelp() {
 	fmt.Println("  module <id>    Details for one automation module")
 	fmt.Println("  sync           Download changed modules from registry")
 	fmt.Println("  sync full      Download full module catalog")
+	fmt.Println("  explain <cmd>  Describe a command line flag by flag")
+	fmt.Println("  example of …   Worked examples, e.g. 'example of rsync with delete'")
+	fmt.Println("  history        Past queries · history <id> re-runs · history clear")
 	fmt.Println("  clear / help / exit")
 	fmt.Println()
 	fmt.Println("── Setup wizards [SETUP WIZARD] ── ask or type setup <name> ──")
@@ -181,12 +219,29 @@ func printHelp() {
 	fmt.Println()
 }
 
-func handleResult(res *intent.DetectionResult, scanner *bufio.Scanner) {
+func handleResult(input string, res *intent.DetectionResult, scanner *bufio.Scanner) {
 	printResultHeader(res)
 	for {
 		fmt.Printf("\n✓ Use: %s\n", res.Command)
 		fmt.Println("────────────────────────")
-		fmt.Printf("Purpose : %s\n\n", res.Description)
+		fmt.Printf("Purpose : %s\n", res.Description)
+		if len(res.Stages) > 0 {
+			fmt.Println("Pipeline:")
+			for i, st := range res.Stages {
+				fmt.Printf("  %d. %-40s %s\n", i+1, st, st.Desc)
+			}
+		}
+		if len(res.Reasons) > 0 {
+			fmt.Printf("Why     : %s (%.0f%%)\n", strings.Join(res.Reasons, "; "), res.Confidence*100)
+		}
+		if res.NotInstalled != "" {
+			fmt.Printf("Missing : %s is not installed", res.NotInstalled)
+			if res.Install != "" {
+				fmt.Printf(" — install with: %s", res.Install)
+			}
+			fmt.Println()
+		}
+		fmt.Println()
 		fmt.Println("What would you like to do?")
 		fmt.Println("  1) Show examples and usage")
 		fmt.Println("  2) Run the command")
@@ -207,11 +262,14 @@ func handleResult(res *intent.DetectionResult, scanner *bufio.Scanner) {
 			fmt.Println("\nPress Enter to return to menu...")
 			scanner.Scan()
 		case "2":
-			runCommand(res, scanner)
+			outcome, finalCmd := runCommand(res, scanner)
+			record(input, res, outcome, finalCmd)
 			return // Exit after running (usually what you want)
 		case "3":
+			record(input, res, layer3.OutcomeCancelled, "")
 			return // Returns to main loop (new search)
 		case "0":
+			record(input, res, layer3.OutcomeCancelled, "")
 			return // Returns to main loop
 		default:
 			fmt.Println("Invalid choice.")
@@ -219,6 +277,39 @@ func handleResult(res *intent.DetectionResult, scanner *bufio.Scanner) {
 	}
 }
 
+// chooseCandidate offers "did you mean" choices when the top matches score too close to call.
+// Returns nil when the user cancels.
+func chooseCandidate(cands []*intent.DetectionResult, scanner *bufio.Scanner) *intent.DetectionResult {
+	var tied []*intent.DetectionResult
+	for _, c := range cands {
+		if cands[0].Confidence-c.Confidence > intent.AmbiguityMargin {
+			break
+		}
+		tied = append(tied, c)
+	}
+
+	fmt.Println()
+	fmt.Println("🤔 Did you mean:")
+	for i, c := range tied {
+		fmt.Printf("  %d) %-28s %s (%.0f%%)\n", i+1, c.Command, c.Description, c.Confidence*100)
+	}
+	fmt.Println("  0) Cancel")
+	fmt.Printf("Choice [1-%d, Enter=1]: ", len(tied))
+
+	if !scanner.Scan() {
+		return nil
+	}
+	choice := strings.TrimSpace(scanner.Text())
+	if choice == "" {
+		return tied[0]
+	}
+	n, err := strconv.Atoi(choice)
+	if err != nil || n < 1 || n > len(tied) {
+		return nil
+	}
+	return tied[n-1]
+}
+
 func setupWizardID(moduleID string) string {
 	for _, w := range setup.AllWizards() {
 		if w.ModuleID == moduleID {
@@ -240,221 +331,141 @@ func printResultHeader(res *intent.DetectionResult) {
 		fmt.Println("⌨️  [SHELL COMMAND] — run directly in your terminal")
 	}
 	fmt.Println("   Matched from your natural-language question.")
+	if ctx := project.Current(); !ctx.Empty() {
+		fmt.Printf("📁 Project: %s", ctx.Label())
+		if res.Project != "" {
+			fmt.Print(" — command chosen for this project")
+		}
+		fmt.Println()
+	}
 	fmt.Println()
 }
 
+// maxExamples caps the examples shown for one suggestion.
+const maxExamples = 4
+
 func showExamples(res *intent.DetectionResult) {
 	fmt.Println("\n--- Examples / Usage ---")
 	fmt.Printf("Command: %s\n", res.Command)
 	fmt.Printf("Details: %s\n", res.Description)
 
-	// Extract base command (first word)
-	baseCmd := strings.Fields(res.Command)[0]
-
-	// Provide concise, practical examples (max 3-4 per command)
-	switch baseCmd {
-	case "ls":
-		fmt.Println("\nCommon usage:")
-		fmt.Println("  ls -la       - All files with details (most common)")
-		fmt.Println("  ls -lh       - Human-readable file sizes")
-		fmt.Println("  ls -lt       - Sort by time (newest first)")
-
-	case "find":
-		fmt.Println("\nCommon usage:")
-		fmt.Println("  find . -name \"*.txt\"        - Find all .txt files")
-		fmt.Println("  find . -type f -size +100M  - Files larger than 100MB")
-		fmt.Println("  find . -mtime -7            - Modified in last 7 days")
-
-	case "grep":
-		fmt.Println("\nCommon usage:")
-		fmt.Println("  grep -r \"pattern\" .         - Recursive search")
-		fmt.Println("  grep -i \"pattern\" file      - Case-insensitive")
-		fmt.Println("  grep -n \"pattern\" file      - Show line numbers")
-
-	case "tar":
-		fmt.Println("\nCommon usage:")
-		fmt.Println("  tar -xzvf file.tar.gz       - Extract .tar.gz")
-		fmt.Println("  tar -czvf archive.tar.gz dir/ - Create archive")
-		fmt.Println("\nFlags: -x=extract -c=create -z=gzip -v=verbose -f=file")
-
-	case "chmod":
-		fmt.Println("\nCommon usage:")
-		fmt.Println("  chmod +x script.sh          - Make executable")
-		fmt.Println("  chmod 755 file              - rwxr-xr-x")
-		fmt.Println("  chmod 644 file              - rw-r--r--")
-		fmt.Println("\nTip: 7=rwx, 6=rw, 5=rx, 4=r (Owner-Group-Other)")
-
-	case "chown":
-		fmt.Println("\nCommon usage:")
-		fmt.Println("  chown user:group file       - Change owner and group")
-		fmt.Println("  chown -R user:group dir/    - Recursive")
-
-	case "cp":
-		fmt.Println("\nCommon usage:")
-		fmt.Println("  cp file1 file2              - Copy file")
-		fmt.Println("  cp -r dir1/ dir2/           - Copy directory recursively")
-		fmt.Println("  cp -i file dest             - Prompt before overwrite")
-
-	case "mv":
-		fmt.Println("\nCommon usage:")
-		fmt.Println("  mv old new                  - Rename file")
-		fmt.Println("  mv file dir/                - Move to directory")
-		fmt.Println("  mv -i file dest             - Prompt before overwrite")
-
-	case "rm":
-		fmt.Println("\nCommon usage:")
-		fmt.Println("  rm file                     - Delete file")
-		fmt.Println("  rm -rf directory/           - Delete directory and contents")
-		fmt.Println("\n⚠️  WARNING: rm -rf is permanent!")
-
-	case "df":
-		fmt.Println("\nCommon usage:")
-		fmt.Println("  df -h                       - Human-readable sizes")
-		fmt.Println("  df -h /                     - Space for root filesystem")
-
-	case "free":
-		fmt.Println("\nCommon usage:")
-		fmt.Println("  free -h                     - Human-readable memory usage")
-		fmt.Println("  free -s 5                   - Update every 5 seconds")
-
-	case "ps":
-		fmt.Println("\nCommon usage:")
-		fmt.Println("  ps aux                      - All processes with details")
-		fmt.Println("  ps aux | grep name          - Find specific process")
-
-	case "top", "htop":
-		fmt.Println("\nInteractive keys:")
-		fmt.Println("  q = quit, k = kill process")
-		fmt.Println("  M = sort by memory, P = sort by CPU")
-
-	case "cat":
-		fmt.Println("\nCommon usage:")
-		fmt.Println("  cat file                    - Display contents")
-		fmt.Println("  cat file1 file2             - Concatenate files")
-
-	case "tail":
-		fmt.Println("\nCommon usage:")
-		fmt.Println("  tail -n 20 file             - Last 20 lines")
-		fmt.Println("  tail -f logfile             - Follow file (watch updates)")
-
-	case "head":
-		fmt.Println("\nCommon usage:")
-		fmt.Println("  head -n 20 file             - First 20 lines")
-
-	case "wc":
-		fmt.Println("\nCommon usage:")
-		fmt.Println("  wc -l file                  - Count lines")
-		fmt.Println("  wc -w file                  - Count words")
-
-	case "mkdir":
-		fmt.Println("\nCommon usage:")
-		fmt.Println("  mkdir -p path/to/dir        - Create nested directories")
-
-	case "wget":
-		fmt.Println("\nCommon usage:")
-		fmt.Println("  wget URL                    - Download file")
-		fmt.Println("  wget -c URL                 - Resume download")
-
-	case "curl":
-		fmt.Println("\nCommon usage:")
-		fmt.Println("  curl -O URL                 - Download file")
-		fmt.Println("  curl ifconfig.me            - Check public IP")
-
-	case "sed":
-		fmt.Println("\nCommon usage:")
-		fmt.Println("  sed 's/old/new/g' file      - Replace all occurrences")
-		fmt.Println("  sed -i 's/old/new/g' file   - Edit file in-place")
-
-	case "awk":
-		fmt.Println("\nCommon usage:")
-		fmt.Println("  awk '{print $1}' file       - Print first column")
-		fmt.Println("  awk -F: '{print $1}' file   - Use : as delimiter")
-
-	case "ssh":
-		fmt.Println("\nCommon usage:")
-		fmt.Println("  ssh user@host               - Connect to remote")
-		fmt.Println("  ssh -p 2222 user@host       - Use specific port")
-
-	case "scp":
-		fmt.Println("\nCommon usage:")
-		fmt.Println("  scp file user@host:/path    - Copy to remote")
-		fmt.Println("  scp user@host:/path/file .  - Copy from remote")
-
-	case "rsync":
-		fmt.Println("\nCommon usage:")
-		fmt.Println("  rsync -avz src/ dest/       - Sync with compression")
-		fmt.Println("  rsync -av --delete src/ dest/ - Delete extra files")
-
-	case "git":
-		fmt.Println("\nCommon usage:")
-		fmt.Println("  git status                  - Check status")
-		fmt.Println("  git add file                - Stage changes")
-		fmt.Println("  git commit -m \"msg\"         - Commit changes")
-
-	case "unzip":
-		fmt.Println("\nCommon usage:")
-		fmt.Println("  unzip file.zip              - Extract zip")
-		fmt.Println("  unzip -l file.zip           - List contents")
-
-	case "zip":
-		fmt.Println("\nCommon usage:")
-		fmt.Println("  zip -r archive.zip dir/     - Compress directory")
-
-	case "du":
-		fmt.Println("\nCommon usage:")
-		fmt.Println("  du -sh dir/                 - Directory size summary")
-		fmt.Println("  du -h --max-depth=1         - Subdirectories size")
-
-	case "ln":
-		fmt.Println("\nCommon usage:")
-		fmt.Println("  ln -s target linkname       - Create symbolic link")
-
-	case "which":
-		fmt.Println("\nCommon usage:")
-		fmt.Println("  which command               - Show path to command")
-
-	case "ping":
-		fmt.Println("\nCommon usage:")
-		fmt.Println("  ping -c 4 host              - Send 4 packets")
-		fmt.Println("  ping google.com             - Test connectivity")
-
-	case "kill":
-		fmt.Println("\nCommon usage:")
-		fmt.Println("  kill PID                    - Terminate process")
-		fmt.Println("  kill -9 PID                 - Force kill")
-
-	default:
-		// For commands without specific examples, show generic help
-		fmt.Println("\nTip: Use 'man " + baseCmd + "' for detailed documentation")
+	exs := examples.For(res.Command, platform.Current())
+	if len(exs) == 0 {
+		fmt.Println("\nTip: Use 'man " + examples.Program(res.Command) + "' for detailed documentation")
+		return
 	}
+	if len(exs) > maxExamples {
+		exs = exs[:maxExamples]
+	}
+	fmt.Println("\nCommon usage:")
+	examples.Write(os.Stdout, exs)
 }
 
-func runCommand(res *intent.DetectionResult, scanner *bufio.Scanner) {
-	fmt.Printf("\nRun: %s [y/N/edit]: ", res.Command)
-	if !scanner.Scan() {
+// showExampleSearch answers "example of rsync with delete".
+func showExampleSearch(query string) {
+	exs := examples.Search(query, platform.Current())
+	if len(exs) == 0 {
+		fmt.Printf("No examples for '%s'. Try a program name, e.g. 'examples for tar'.\n", query)
 		return
 	}
-	ans := strings.ToLower(strings.TrimSpace(scanner.Text()))
+	if len(exs) > maxExamples*2 {
+		exs = exs[:maxExamples*2]
+	}
+	fmt.Println()
+	examples.Write(os.Stdout, exs)
+}
 
+// runCommand confirms and runs a suggestion. It returns the history outcome
+// (empty when there is nothing to learn from, e.g. only a plan was shown) and
+// the command line that actually ran.
+func runCommand(res *intent.DetectionResult, scanner *bufio.Scanner) (string, string) {
 	finalCmd := res.Command
+	if len(res.Missing) > 0 {
+		filled, ok := promptSlots(finalCmd, res.Missing, scanner)
+		if !ok {
+			fmt.Println("Aborted.")
+			return layer3.OutcomeCancelled, ""
+		}
+		finalCmd = filled
+	}
+
+	moduleID, flow, isModule := modules.ParseRunCommand(finalCmd)
+	outcome := layer3.OutcomeRan
+	options := "y/N/edit"
+	if isModule {
+		options = "y/N/plan"
+	} else if a := risk.Classify(finalCmd); a.Level > risk.ReadOnly {
+		fmt.Printf("\nRisk    : %s\n", a.Summary())
+	}
+	fmt.Printf("\nRun: %s [%s]: ", finalCmd, options)
+	if !scanner.Scan() {
+		return "", ""
+	}
+	ans := strings.ToLower(strings.TrimSpace(scanner.Text()))
 
+	if isModule && (ans == "plan" || ans == "p") {
+		if _, err := modules.Plan(moduleID, flow, nil, os.Stdout); err != nil {
+			fmt.Printf("Plan error: %v\n", err)
+		}
+		return "", ""
+	}
 	if ans == "edit" || ans == "e" {
 		fmt.Print("Edit command: ")
 		if scanner.Scan() {
-			finalCmd = strings.TrimSpace(scanner.Text())
+			if edited := strings.TrimSpace(scanner.Text()); edited != "" && edited != finalCmd {
+				finalCmd = edited
+				outcome = layer3.OutcomeEdited
+			}
 		}
 	} else if ans != "y" && ans != "yes" {
 		fmt.Println("Aborted.")
-		return
+		return layer3.OutcomeCancelled, ""
 	}
 
-	// Execute safely
-	parts := strings.Fields(finalCmd)
-	if len(parts) == 0 {
-		return
+	// Modules run in-process; no need to spawn a second clio
+	if moduleID, flow, ok := modules.ParseRunCommand(finalCmd); ok {
+		resume := false
+		if run, _ := layer3.LatestUnfinishedRun(moduleID, flow); run != nil {
+			fmt.Printf("A previous run stopped after %d section(s). Resume it? [Y/n]: ", len(run.Completed))
+			if scanner.Scan() {
+				ans := strings.ToLower(strings.TrimSpace(scanner.Text()))
+				resume = ans == "" || ans == "y" || ans == "yes"
+			}
+		}
+		if err := modules.RunModule(moduleID, flow, resume, scanner); err != nil {
+			fmt.Printf("Module error: %v\n", err)
+		}
+		return outcome, finalCmd
+	}
+
+	// Classify again: the command may have been edited
+	if !risk.Confirm(risk.Classify(finalCmd), scanner) {
+		fmt.Println("Aborted.")
+		return layer3.OutcomeCancelled, ""
+	}
+
+	// Only a confirmed command is worth installing packages for
+	if bin := intent.MissingBinary(finalCmd); bin != "" {
+		cmd, ok := resolveMissing(bin, finalCmd, scanner)
+		if !ok {
+			return layer3.OutcomeCancelled, ""
+		}
+		if cmd != finalCmd {
+			if !risk.Confirm(risk.Classify(cmd), scanner) {
+				fmt.Println("Aborted.")
+				return layer3.OutcomeCancelled, ""
+			}
+			finalCmd = cmd
+			outcome = layer3.OutcomeEdited
+		}
 	}
 
-	cmd := safeexec.Command(parts[0], parts[1:]...)
+	// Plain argv runs directly; pipes, quotes-with-globs, ~ and $VARS go via sh -c
+	cmd, err := shell.Command(finalCmd)
+	if err != nil {
+		fmt.Printf("Cannot run command: %v\n", err)
+		return "", ""
+	}
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -462,4 +473,184 @@ func runCommand(res *intent.DetectionResult, scanner *bufio.Scanner) {
 	if err := cmd.Run(); err != nil {
 		fmt.Printf("Error executing command: %v\n", err)
 	}
+	return outcome, finalCmd
+}
+
+// resolveMissing offers to install the missing binary first or to switch to an
+// installed alternative. It returns the command to continue with, or false if
+// the user cancels or the install fails.
+func resolveMissing(bin, cmdline string, scanner *bufio.Scanner) (string, bool) {
+	install := intent.InstallCommand(bin)
+	alt := intent.Alternative(cmdline, bin)
+	fmt.Printf("\n⚠️  %s is not installed.\n", bin)
+	if install == "" && alt == "" {
+		return cmdline, true
+	}
+	if install != "" {
+		fmt.Printf("  1) Install it first: %s\n", install)
+	}
+	if alt != "" {
+		fmt.Printf("  2) Use instead: %s\n", alt)
+	}
+	fmt.Println("  3) Run it anyway")
+	fmt.Println("  0) Cancel")
+	fmt.Print("Choice: ")
+	if !scanner.Scan() {
+		return "", false
+	}
+
+	switch strings.TrimSpace(scanner.Text()) {
+	case "1":
+		if install == "" {
+			break
+		}
+		if !risk.Confirm(risk.Classify(install), scanner) {
+			return "", false
+		}
+		cmd, err := shell.Command(install)
+		if err != nil {
+			fmt.Printf("Cannot run command: %v\n", err)
+			return "", false
+		}
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+		if err := cmd.Run(); err != nil {
+			fmt.Printf("Install failed: %v\n", err)
+			return "", false
+		}
+		return cmdline, true
+	case "2":
+		if alt != "" {
+			return alt, true
+		}
+	case "3":
+		return cmdline, true
+	}
+	fmt.Println("Aborted.")
+	return "", false
+}
+
+// record stores the outcome for learning. History is best-effort: a failed
+// write must not interrupt the session.
+func record(input string, res *intent.DetectionResult, outcome, finalCmd string) {
+	if outcome == "" {
+		return
+	}
+	_ = intent.Record(input, res, outcome, finalCmd)
+}
+
+// historyListSize is how many entries the bare history command shows.
+const historyListSize = 20
+
+// historyCommandArg recognizes the history command: "history", "history
+// clear" or "history <id>". Anything else, such as "history of git
+// commands", is a query.
+func historyCommandArg(input string) (string, bool) {
+	fields := strings.Fields(input)
+	if len(fields) == 0 || fields[0] != "history" || len(fields) > 2 {
+		return "", false
+	}
+	if len(fields) == 1 {
+		return "", true
+	}
+	if fields[1] == "clear" {
+		return "clear", true
+	}
+	if _, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+		return fields[1], true
+	}
+	return "", false
+}
+
+// handleHistory lists past queries, re-runs one by ID, or wipes them all.
+func handleHistory(arg string, scanner *bufio.Scanner) {
+	switch arg {
+	case "":
+		entries, err := layer3.ListHistory(historyListSize)
+		if err != nil {
+			fmt.Printf("History error: %v\n", err)
+			return
+		}
+		if len(entries) == 0 {
+			fmt.Println("No history yet.")
+			return
+		}
+		fmt.Println()
+		for i := len(entries) - 1; i >= 0; i-- {
+			e := entries[i]
+			fmt.Printf("  %4d  %-9s %-32q → %s\n", e.ID, e.Outcome, e.Query, historyCommand(e))
+		}
+		fmt.Println("\nRe-run one with: history <id>  ·  wipe with: history clear")
+
+	case "clear":
+		fmt.Print("Delete all query history and learned preferences? [y/N]: ")
+		if !scanner.Scan() {
+			return
+		}
+		if ans := strings.ToLower(strings.TrimSpace(scanner.Text())); ans != "y" && ans != "yes" {
+			fmt.Println("Aborted.")
+			return
+		}
+		n, err := layer3.ClearHistory()
+		if err != nil {
+			fmt.Printf("History error: %v\n", err)
+			return
+		}
+		fmt.Printf("🗑️  Removed %d entr%s.\n", n, pluralY(n))
+
+	default:
+		id, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			fmt.Println("Usage: history [<id> | clear]")
+			return
+		}
+		e, err := layer3.GetHistory(id)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		cmd := historyCommand(*e)
+		res := &intent.DetectionResult{
+			Command:     cmd,
+			Description: e.Description,
+			Source:      e.Source,
+			Template:    e.Command,
+			Missing:     layer1.ParseSlots(cmd),
+		}
+		fmt.Printf("\n↻ %q\n", e.Query)
+		outcome, finalCmd := runCommand(res, scanner)
+		record(e.Query, res, outcome, finalCmd)
+	}
+}
+
+// historyCommand is what an entry ran, or what was suggested if it never ran.
+func historyCommand(e layer3.HistoryEntry) string {
+	if e.FinalCommand != "" {
+		return e.FinalCommand
+	}
+	return e.Command
+}
+
+func pluralY(n int64) string {
+	if n == 1 {
+		return "y"
+	}
+	return "ies"
+}
+
+// promptSlots asks for each placeholder the query did not fill.
+// Returns false if the user leaves one empty, so a half-formed command never runs.
+func promptSlots(cmd string, missing []layer1.Slot, scanner *bufio.Scanner) (string, bool) {
+	fmt.Printf("\nThis command needs more details: %s\n", cmd)
+	for _, slot := range missing {
+		fmt.Printf("  %s (%s): ", slot.Type, slot.Hint())
+		if !scanner.Scan() {
+			return "", false
+		}
+		value := strings.TrimSpace(scanner.Text())
+		if value == "" {
+			return "", false
+		}
+		cmd = layer1.FillSlot(cmd, slot, value)
+	}
+	return cmd, true
 }
@@ -0,0 +1,39 @@
+package risk
+
+import (
+	"bufio"
+	"clio/internal/config"
+	"fmt"
+	"strings"
+)
+
+// Confirm gates a command on its assessment and reports whether it may run.
+// Destructive commands are refused when block_destructive is set, and
+// otherwise need the full word "yes" typed after the affected paths are shown.
+// Lower levels pass; privileged ones print a warning first.
+func Confirm(a Assessment, scanner *bufio.Scanner) bool {
+	if a.Level < Destructive {
+		if a.Privileged {
+			fmt.Printf("⚠️  %s\n", a.Summary())
+		}
+		return true
+	}
+	if config.BlockDestructive() {
+		fmt.Printf("⛔ Blocked: %s\n", a.Summary())
+		fmt.Println("   (block_destructive is on — clio config set block_destructive false)")
+		return false
+	}
+
+	fmt.Printf("⚠️  DESTRUCTIVE — %s\n", strings.Join(a.Reasons, "; "))
+	if len(a.Paths) > 0 {
+		fmt.Println("   Affects:")
+		for _, p := range a.Paths {
+			fmt.Printf("     %s\n", p)
+		}
+	}
+	fmt.Print("Type 'yes' to run it, anything else cancels: ")
+	if !scanner.Scan() {
+		return false
+	}
+	return strings.TrimSpace(scanner.Text()) == "yes"
+}
@@ -0,0 +1,411 @@
+// Package risk classifies shell command lines before clio runs them, so
+// destructive or privileged commands can be gated behind stronger confirmation.
+package risk
+
+import (
+	"clio/internal/shell"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Level is how much damage a command line can do, lowest first.
+type Level int
+
+const (
+	ReadOnly Level = iota
+	Modifying
+	Privileged
+	Destructive
+)
+
+func (l Level) String() string {
+	switch l {
+	case ReadOnly:
+		return "read-only"
+	case Modifying:
+		return "modifying"
+	case Privileged:
+		return "privileged"
+	case Destructive:
+		return "destructive"
+	}
+	return "unknown"
+}
+
+// maxPaths caps how many expanded glob matches an Assessment lists.
+const maxPaths = 20
+
+// Assessment is the result of classifying one command line.
+type Assessment struct {
+	Level      Level
+	Privileged bool     // runs through sudo/su/doas or touches system state
+	Reasons    []string // why the level was raised
+	Paths      []string // files a destructive command would affect
+}
+
+func (a *Assessment) raise(l Level, reason string) {
+	if l > a.Level {
+		a.Level = l
+	}
+	if reason != "" {
+		a.Reasons = append(a.Reasons, reason)
+	}
+}
+
+// merge adds the assessment of a script run by a wrapper such as sh -c.
+func (a *Assessment) merge(b Assessment) {
+	a.raise(b.Level, "")
+	a.Privileged = a.Privileged || b.Privileged
+	a.Reasons = append(a.Reasons, b.Reasons...)
+	a.Paths = append(a.Paths, b.Paths...)
+}
+
+// Summary is a one-line description such as "destructive: rm -r (recursive delete)".
+func (a Assessment) Summary() string {
+	if len(a.Reasons) == 0 {
+		return a.Level.String()
+	}
+	return a.Level.String() + ": " + strings.Join(a.Reasons, "; ")
+}
+
+var privilegeWrappers = map[string]bool{
+	"sudo": true, "doas": true, "su": true, "pkexec": true, "tsu": true,
+}
+
+var readOnlyBins = map[string]bool{
+	"ls": true, "cat": true, "less": true, "more": true, "head": true, "tail": true,
+	"grep": true, "egrep": true, "fgrep": true, "rg": true, "df": true, "du": true,
+	"free": true, "ps": true, "top": true, "htop": true, "uname": true, "whoami": true,
+	"id": true, "pwd": true, "echo": true, "printf": true, "wc": true, "stat": true,
+	"file": true, "which": true, "type": true, "man": true, "ping": true, "date": true,
+	"uptime": true, "history": true, "printenv": true, "hostname": true,
+	"ifconfig": true, "ip": true, "ss": true, "netstat": true, "lsof": true, "pgrep": true,
+	"tree": true, "sort": true, "uniq": true, "cut": true, "diff": true, "md5sum": true,
+	"sha256sum": true, "nslookup": true, "dig": true, "traceroute": true, "lsblk": true,
+	"whereis": true, "apropos": true, "cal": true, "jobs": true, "nproc": true,
+	"lscpu": true, "vmstat": true, "iostat": true, "termux-info": true,
+}
+
+// deleteBins remove files or data outright.
+var deleteBins = map[string]bool{
+	"rm": true, "shred": true, "unlink": true, "srm": true, "wipe": true,
+}
+
+// diskBins rewrite disks or partitions.
+var diskBins = map[string]bool{
+	"dd": true, "fdisk": true, "parted": true, "wipefs": true, "sfdisk": true, "gdisk": true,
+}
+
+// systemBins change machine-wide state and usually need root.
+var systemBins = map[string]bool{
+	"shutdown": true, "reboot": true, "poweroff": true, "halt": true, "mount": true,
+	"umount": true, "systemctl": true, "service": true, "useradd": true, "userdel": true,
+	"usermod": true, "groupadd": true, "groupdel": true, "passwd": true, "chroot": true,
+	"modprobe": true, "insmod": true, "rmmod": true, "iptables": true, "ufw": true,
+	"visudo": true, "crontab": true, "swapon": true, "swapoff": true, "sysctl": true,
+}
+
+var killBins = map[string]bool{"kill": true, "pkill": true, "killall": true}
+
+// Classify inspects a command line: the binary, risky flags, redirections,
+// globs against home or root, and privilege wrappers.
+func Classify(cmdline string) Assessment {
+	var a Assessment
+	segs, err := shell.Split(cmdline)
+	if err != nil {
+		a.raise(Modifying, "could not parse: "+err.Error())
+		return a
+	}
+	for _, seg := range segs {
+		classifySegment(seg, &a)
+	}
+	if a.Privileged {
+		a.raise(Privileged, "")
+	}
+	return a
+}
+
+func classifySegment(toks []shell.Token, a *Assessment) {
+	// Judge the command that runs, not the sudo, env, xargs or sh -c around it
+	u := shell.Unwrap(checkRedirects(toks, a))
+	for _, w := range u.Wrappers {
+		if privilegeWrappers[w] {
+			a.Reasons = append(a.Reasons, "runs as root via "+w)
+		}
+	}
+	a.Privileged = a.Privileged || u.Privileged
+	if u.Script != "" {
+		a.merge(Classify(u.Script))
+		return
+	}
+	fields := u.Args
+	if len(fields) == 0 {
+		return
+	}
+
+	bin := filepath.Base(fields[0])
+	args := fields[1:]
+	flags := shortFlags(args)
+	operands := operandsOf(args)
+
+	switch {
+	case deleteBins[bin]:
+		if flags['i'] && !flags['f'] {
+			a.raise(Modifying, bin+" -i (asks before each delete)")
+			return
+		}
+		reason := bin + " (permanent delete)"
+		if flags['r'] || flags['R'] || contains(args, "--recursive") {
+			reason = bin + " -r (recursive delete)"
+		}
+		a.raise(Destructive, reason)
+		a.addPaths(operands)
+		if targets := homeOrRoot(operands); len(targets) > 0 {
+			a.Reasons = append(a.Reasons, "targets home or root ("+strings.Join(targets, " ")+")")
+		}
+
+	case bin == "rmdir":
+		a.raise(Modifying, "rmdir (removes empty directories)")
+
+	case diskBins[bin] || strings.HasPrefix(bin, "mkfs"):
+		a.raise(Destructive, bin+" rewrites disks or partitions")
+		a.Privileged = true
+
+	case killBins[bin]:
+		if flags['9'] || contains(args, "-KILL") || contains(args, "-SIGKILL") {
+			a.raise(Destructive, bin+" -9 (process cannot clean up)")
+		} else {
+			a.raise(Modifying, bin+" stops processes")
+		}
+
+	case bin == "truncate":
+		a.raise(Destructive, "truncate discards file contents")
+		a.addPaths(operands)
+
+	case bin == "find":
+		switch {
+		case contains(args, "-delete"):
+			a.raise(Destructive, "find -delete")
+		case execRuns(args, deleteBins):
+			a.raise(Destructive, "find -exec rm")
+		case contains(args, "-exec") || contains(args, "-execdir"):
+			a.raise(Modifying, "find -exec runs a command per file")
+		}
+
+	case bin == "chmod" || bin == "chown" || bin == "chgrp":
+		if flags['R'] && touchesHomeOrRoot(operands) {
+			a.raise(Destructive, bin+" -R on home or root")
+			a.addPaths(operands)
+		} else {
+			a.raise(Modifying, bin+" changes permissions")
+		}
+
+	case bin == "mv" || bin == "cp":
+		if flags['i'] || flags['n'] {
+			a.raise(Modifying, "")
+		} else {
+			a.raise(Modifying, bin+" may overwrite the destination")
+		}
+
+	case bin == "sed" || bin == "perl":
+		if flags['i'] || hasLongPrefix(args, "--in-place") {
+			a.raise(Modifying, bin+" -i edits files in place")
+		}
+
+	case bin == "git":
+		classifyGit(args, a)
+
+	case bin == "curl":
+		if flags['o'] || flags['O'] || contains(args, "--output") {
+			a.raise(Modifying, "curl writes a file")
+		}
+
+	case systemBins[bin]:
+		a.Privileged = true
+		a.raise(Modifying, bin+" changes system state")
+
+	case readOnlyBins[bin]:
+		// Nothing to raise
+
+	default:
+		a.raise(Modifying, "")
+	}
+}
+
+func classifyGit(args []string, a *Assessment) {
+	if len(args) == 0 {
+		return
+	}
+	switch args[0] {
+	case "status", "log", "diff", "show", "branch", "remote", "fetch", "blame", "grep":
+		return
+	case "clean":
+		if shortFlags(args[1:])['f'] {
+			a.raise(Destructive, "git clean -f deletes untracked files")
+			return
+		}
+	case "reset":
+		if contains(args, "--hard") {
+			a.raise(Destructive, "git reset --hard discards changes")
+			return
+		}
+	case "push":
+		if contains(args, "--force") || contains(args, "-f") {
+			a.raise(Destructive, "git push --force rewrites remote history")
+			return
+		}
+	case "checkout":
+		if contains(args, "--") || contains(args, ".") {
+			a.raise(Destructive, "git checkout discards local changes")
+			return
+		}
+	}
+	a.raise(Modifying, "")
+}
+
+// checkRedirects records output redirections and returns the remaining words.
+func checkRedirects(toks []shell.Token, a *Assessment) []string {
+	var out []string
+	for i := 0; i < len(toks); i++ {
+		t := toks[i]
+		if !t.IsRedirect() {
+			out = append(out, t.Text)
+			continue
+		}
+		op := t.Redirect()
+		if i+1 >= len(toks) || toks[i+1].Op {
+			continue
+		}
+		i++
+		target := toks[i].Text
+		// Input redirections and fd duplication (2>&1) write nothing
+		if !strings.Contains(op, ">") || strings.HasSuffix(op, "&") || strings.HasPrefix(target, "/dev/") {
+			continue
+		}
+		if op == ">>" {
+			a.raise(Modifying, "appends to "+target)
+			continue
+		}
+		if _, err := os.Stat(expandHome(target)); err == nil {
+			a.raise(Destructive, "overwrites "+target)
+			a.Paths = append(a.Paths, target)
+		} else {
+			a.raise(Modifying, "writes "+target)
+		}
+	}
+	return out
+}
+
+// addPaths records the files a destructive command would affect, expanding globs.
+func (a *Assessment) addPaths(operands []string) {
+	for _, op := range operands {
+		if len(a.Paths) >= maxPaths {
+			a.Paths = append(a.Paths, "…")
+			return
+		}
+		if !strings.ContainsAny(op, "*?[") {
+			a.Paths = append(a.Paths, op)
+			continue
+		}
+		matches, err := filepath.Glob(expandHome(op))
+		if err != nil || len(matches) == 0 {
+			a.Paths = append(a.Paths, op)
+			continue
+		}
+		for i, m := range matches {
+			if len(a.Paths) >= maxPaths {
+				a.Paths = append(a.Paths, fmt.Sprintf("… and %d more", len(matches)-i))
+				return
+			}
+			a.Paths = append(a.Paths, m)
+		}
+	}
+}
+
+// shortFlags collects single-letter flags, so "-rf" and "-r -f" look alike.
+func shortFlags(args []string) map[rune]bool {
+	flags := make(map[rune]bool)
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if !strings.HasPrefix(arg, "-") || strings.HasPrefix(arg, "--") {
+			continue
+		}
+		for _, r := range arg[1:] {
+			flags[r] = true
+		}
+	}
+	return flags
+}
+
+func operandsOf(args []string) []string {
+	var out []string
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			out = append(out, arg)
+		}
+	}
+	return out
+}
+
+func hasLongPrefix(args []string, prefix string) bool {
+	for _, arg := range args {
+		if strings.HasPrefix(arg, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(args []string, s string) bool {
+	for _, arg := range args {
+		if arg == s {
+			return true
+		}
+	}
+	return false
+}
+
+func execRuns(args []string, bins map[string]bool) bool {
+	for i, arg := range args {
+		if (arg == "-exec" || arg == "-execdir") && i+1 < len(args) && bins[filepath.Base(args[i+1])] {
+			return true
+		}
+	}
+	return false
+}
+
+// homeOrRoot returns operands that are, or glob the whole of, $HOME or /.
+func homeOrRoot(operands []string) []string {
+	var out []string
+	for _, op := range operands {
+		trimmed := strings.TrimRight(op, "/*")
+		switch {
+		case trimmed == "" && strings.HasPrefix(op, "/"):
+			out = append(out, op)
+		case trimmed == "~" || trimmed == "$HOME" || trimmed == "${HOME}":
+			out = append(out, op)
+		default:
+			if home, err := os.UserHomeDir(); err == nil && trimmed == strings.TrimRight(home, "/") {
+				out = append(out, op)
+			}
+		}
+	}
+	return out
+}
+
+func touchesHomeOrRoot(operands []string) bool {
+	return len(homeOrRoot(operands)) > 0
+}
+
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return home + path[1:]
+		}
+	}
+	return path
+}
@@ -0,0 +1,124 @@
+package risk
+
+import (
+	"bufio"
+	"clio/internal/config"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	existing := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(existing, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		cmd  string
+		want Level
+	}{
+		{"ls -la", ReadOnly},
+		{"df -h", ReadOnly},
+		{"ps aux | grep node", ReadOnly},
+		{"mkdir -p projects", Modifying},
+		{"kill %1", Modifying},
+		{"kill -9 4312", Destructive},
+		{"rm notes.txt", Destructive},
+		{"rm -rf *", Destructive},
+		{"rm -i notes.txt", Modifying},
+		{"find . -name '*.tmp' -delete", Destructive},
+		{"find . -type f -exec rm {} +", Destructive},
+		{"chmod +x run.sh", Modifying},
+		{"chmod -R 777 /", Destructive},
+		{"sudo apt install git", Privileged},
+		{"sudo rm -rf /var/cache", Destructive},
+		{"echo hi >> " + existing, Modifying},
+		{"echo hi > " + existing, Destructive},
+		{"ls > /dev/null", ReadOnly},
+		{"git status", ReadOnly},
+		{"git reset --hard HEAD", Destructive},
+		{"dd if=/dev/zero of=/dev/sda", Destructive},
+		{"reboot", Privileged},
+		{"env rm -rf /", Destructive},
+		{"env -i PATH=/bin rm -rf build", Destructive},
+		{"env -u HOME printenv", ReadOnly},
+		{"env", ReadOnly},
+		{"awk 'BEGIN{system(\"rm -rf ~\")}'", Modifying},
+		{"find . | xargs rm -rf", Destructive},
+		{"find . -name '*.log' | xargs -0 -n 10 rm", Destructive},
+		{"ls | xargs", ReadOnly},
+		{"nice -n 10 rm -rf build", Destructive},
+		{"nice -5 tar czf a.tgz .", Modifying},
+		{"nohup rm -rf build &", Destructive},
+		{"timeout 10 rm -rf build", Destructive},
+		{"timeout -s KILL 5s ping example.com", ReadOnly},
+		{"sh -c 'rm -rf ~'", Destructive},
+		{"bash -lc \"ls -la\"", ReadOnly},
+		{"sh script.sh", Modifying},
+		{"sudo -u postgres dropdb app", Privileged},
+		{"sudo -u postgres rm -rf /var/lib/pgsql", Destructive},
+		{"su -c 'rm -rf /'", Destructive},
+		{"FOO=1 rm notes.txt", Destructive},
+	}
+	for _, c := range cases {
+		if got := Classify(c.cmd); got.Level != c.want {
+			t.Errorf("Classify(%q) = %s (%v), want %s", c.cmd, got.Level, got.Reasons, c.want)
+		}
+	}
+}
+
+func TestClassifyPrivilegedFlag(t *testing.T) {
+	a := Classify("sudo rm -rf ~")
+	if !a.Privileged {
+		t.Error("sudo not flagged as privileged")
+	}
+	if !strings.Contains(a.Summary(), "targets home or root") {
+		t.Errorf("summary = %q, want home/root warning", a.Summary())
+	}
+}
+
+func TestClassifyExpandsGlobPaths(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.log", "b.log", "keep.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a := Classify("rm " + filepath.Join(dir, "*.log"))
+	want := []string{filepath.Join(dir, "a.log"), filepath.Join(dir, "b.log")}
+	if strings.Join(a.Paths, ",") != strings.Join(want, ",") {
+		t.Errorf("Paths = %v, want %v", a.Paths, want)
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	config.ResetCache()
+	defer config.ResetCache()
+
+	destructive := Classify("rm -rf build")
+	cases := []struct {
+		input string
+		want  bool
+	}{
+		{"yes\n", true},
+		{"y\n", false},
+		{"\n", false},
+	}
+	for _, c := range cases {
+		if got := Confirm(destructive, bufio.NewScanner(strings.NewReader(c.input))); got != c.want {
+			t.Errorf("Confirm with %q = %v, want %v", c.input, got, c.want)
+		}
+	}
+	if !Confirm(Classify("ls"), bufio.NewScanner(strings.NewReader(""))) {
+		t.Error("read-only command should not need confirmation")
+	}
+
+	if err := config.Set("block_destructive", "true"); err != nil {
+		t.Fatal(err)
+	}
+	if Confirm(destructive, bufio.NewScanner(strings.NewReader("yes\n"))) {
+		t.Error("block_destructive should refuse even after typing yes")
+	}
+}
@@ -43,14 +43,14 @@ func TestRunCommandFor(t *testing.T) {
 	if w == nil {
 		t.Fatal("vim wizard not found")
 	}
-	want := "clio-run-module vim_setup setup"
+	want := "clio run vim_setup setup"
 	if got := RunCommandFor(*w); got != want {
 		t.Fatalf("RunCommandFor(vim) = %q, want %q", got, want)
 	}
 }
 
 func TestWizardFromCommand(t *testing.T) {
-	w := WizardFromCommand("clio-run-module git_setup setup")
+	w := WizardFromCommand("clio run git_setup setup")
 	if w == nil || w.ID != "git" {
 		t.Fatalf("WizardFromCommand git = %v", w)
 	}
@@ -121,9 +121,9 @@ func WizardByID(id string) *Wizard {
 	return nil
 }
 
-// RunCommandFor returns the clio-run-module command for a wizard.
+// RunCommandFor returns the clio run command for a wizard.
 func RunCommandFor(w Wizard) string {
-	return "clio-run-module " + w.ModuleID + " " + w.Flow
+	return "clio run " + w.ModuleID + " " + w.Flow
 }
 
 // IsSetupModule reports whether a registry module ID is a first-class setup wizard.
@@ -137,7 +137,7 @@ func IsSetupModule(moduleID string) bool {
 	return false
 }
 
-// WizardFromCommand finds a wizard from a clio-run-module command string.
+// WizardFromCommand finds a wizard from a clio run command string.
 func WizardFromCommand(cmd string) *Wizard {
 	cmd = strings.ToLower(cmd)
 	for _, w := range AllWizards() {
@@ -0,0 +1,330 @@
+// Package shell tokenizes POSIX-ish command lines and decides whether a
+// command can be exec'd directly or has to go through sh -c.
+package shell
+
+import (
+	"clio/internal/safeexec"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Token is a word or an operator from a command line. Quotes and escapes are
+// already removed from Text.
+type Token struct {
+	Text string
+	Op   bool // control or redirection operator: | && || ; & ( ) < > >> 2> …
+	// Expand is set when the word has unquoted $, `, globs or a leading ~,
+	// which only a shell can expand.
+	Expand bool
+}
+
+// controlOps separate simple commands; everything else in opsByLength redirects.
+var controlOps = map[string]bool{
+	"|": true, "||": true, "&": true, "&&": true, ";": true, "(": true, ")": true, ";;": true,
+}
+
+// opsByLength lists operators longest first so ">>" wins over ">".
+var opsByLength = []string{
+	"&&", "||", ";;", ">>", "<<", ">&", "<&", ">|", "&>", "<>",
+	"|", "&", ";", "(", ")", "<", ">",
+}
+
+// builtins only exist inside a shell; exec'ing them directly fails.
+var builtins = map[string]bool{
+	"cd": true, "export": true, "alias": true, "unalias": true, "source": true, ".": true,
+	"history": true, "jobs": true, "fg": true, "bg": true, "ulimit": true, "umask": true,
+	"set": true, "unset": true, "exec": true, "eval": true, "wait": true, "type": true,
+	"hash": true, "shift": true, "trap": true, "read": true, "exit": true,
+}
+
+// sessionBuiltins act on the state of the user's interactive shell. The
+// fresh sh that runs a command has no history and no background jobs, so
+// they would print nothing or fail.
+var sessionBuiltins = map[string]bool{
+	"history": true, "jobs": true, "fg": true, "bg": true, "disown": true,
+}
+
+// Tokenize splits a command line into words and operators, honouring single
+// quotes, double quotes and backslash escapes. Comments (# at the start of a
+// word) end the line.
+func Tokenize(line string) ([]Token, error) {
+	var toks []Token
+	var word strings.Builder
+	inWord, expand := false, false
+
+	flush := func() {
+		if inWord {
+			toks = append(toks, Token{Text: word.String(), Expand: expand})
+		}
+		word.Reset()
+		inWord, expand = false, false
+	}
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == ' ' || c == '\t':
+			flush()
+
+		case c == '\n':
+			flush()
+			toks = append(toks, Token{Text: ";", Op: true})
+
+		case c == '#' && !inWord:
+			return toks, nil
+
+		case c == '\'':
+			end := strings.IndexByte(line[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated single quote")
+			}
+			word.WriteString(line[i+1 : i+1+end])
+			inWord = true
+			i += end + 1
+
+		case c == '"':
+			j := i + 1
+			for ; j < len(line) && line[j] != '"'; j++ {
+				switch line[j] {
+				case '\\':
+					if j+1 < len(line) && strings.IndexByte("\\\"$`\n", line[j+1]) >= 0 {
+						j++
+					}
+				case '$', '`':
+					expand = true
+				}
+				word.WriteByte(line[j])
+			}
+			if j >= len(line) {
+				return nil, fmt.Errorf("unterminated double quote")
+			}
+			inWord = true
+			i = j
+
+		case c == '\\':
+			if i+1 < len(line) {
+				i++
+				if line[i] != '\n' {
+					word.WriteByte(line[i])
+					inWord = true
+				}
+			}
+
+		case c == '$' || c == '`':
+			n, err := substitutionLen(line[i:])
+			if err != nil {
+				return nil, err
+			}
+			word.WriteString(line[i : i+n])
+			inWord, expand = true, true
+			i += n - 1
+
+		case isOpStart(c):
+			// An all-digit word directly before a redirection is its fd ("2>")
+			if (c == '>' || c == '<') && inWord && !expand && isDigits(word.String()) {
+				fd := word.String()
+				word.Reset()
+				inWord = false
+				op := matchOp(line[i:])
+				toks = append(toks, Token{Text: fd + op, Op: true})
+				i += len(op) - 1
+				continue
+			}
+			flush()
+			op := matchOp(line[i:])
+			toks = append(toks, Token{Text: op, Op: true})
+			i += len(op) - 1
+
+		default:
+			switch {
+			case c == '*' || c == '?' || c == '[':
+				expand = true
+			case c == '~' && !inWord:
+				expand = true
+			}
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	flush()
+	return toks, nil
+}
+
+// substitutionLen returns the length of a $var, ${…}, $(…) or `…` at the start of s.
+func substitutionLen(s string) (int, error) {
+	if s[0] == '`' {
+		end := strings.IndexByte(s[1:], '`')
+		if end < 0 {
+			return 0, fmt.Errorf("unterminated backquote")
+		}
+		return end + 2, nil
+	}
+	if len(s) == 1 {
+		return 1, nil
+	}
+	switch s[1] {
+	case '{', '(':
+		open, close := s[1], byte('}')
+		if open == '(' {
+			close = ')'
+		}
+		depth := 0
+		for i := 1; i < len(s); i++ {
+			switch s[i] {
+			case open:
+				depth++
+			case close:
+				depth--
+				if depth == 0 {
+					return i + 1, nil
+				}
+			}
+		}
+		return 0, fmt.Errorf("unterminated $%c", open)
+	}
+	if strings.IndexByte("?$!#@*-0123456789", s[1]) >= 0 {
+		return 2, nil // special parameters are one character
+	}
+	n := 1
+	for n < len(s) && isNameChar(s[n]) {
+		n++
+	}
+	return n, nil
+}
+
+// Segments groups tokens into simple commands, splitting on control
+// operators. Redirection operators stay in place.
+func Segments(toks []Token) [][]Token {
+	var out [][]Token
+	var cur []Token
+	for _, t := range toks {
+		if t.Op && controlOps[t.Text] {
+			if len(cur) > 0 {
+				out = append(out, cur)
+			}
+			cur = nil
+			continue
+		}
+		cur = append(cur, t)
+	}
+	if len(cur) > 0 {
+		out = append(out, cur)
+	}
+	return out
+}
+
+// Split tokenizes line and returns its simple commands. See Segments.
+func Split(line string) ([][]Token, error) {
+	toks, err := Tokenize(line)
+	if err != nil {
+		return nil, err
+	}
+	return Segments(toks), nil
+}
+
+// IsRedirect reports whether t is a redirection operator such as > or 2>>.
+func (t Token) IsRedirect() bool {
+	return t.Op && !controlOps[t.Text]
+}
+
+// Redirect returns the operator of a redirection without its fd ("2>>" → ">>").
+func (t Token) Redirect() string {
+	return strings.TrimLeft(t.Text, "0123456789")
+}
+
+// IsBuiltin reports whether name is a shell builtin rather than a binary on PATH.
+func IsBuiltin(name string) bool {
+	return builtins[name]
+}
+
+// NeedsShell reports whether the tokens use operators, expansions or
+// builtins that a direct exec cannot handle.
+func NeedsShell(toks []Token) bool {
+	for i, t := range toks {
+		if t.Op || t.Expand {
+			return true
+		}
+		if i == 0 && (builtins[t.Text] || strings.Contains(t.Text, "=")) {
+			return true // builtin or VAR=value prefix
+		}
+	}
+	return false
+}
+
+// SessionOnly reports why toks can only run in the user's own shell: a
+// session builtin such as history or jobs, or a job spec such as kill %1.
+// It returns "" when a new shell can run them.
+func SessionOnly(toks []Token) string {
+	for _, seg := range Segments(toks) {
+		if len(seg) == 0 || seg[0].Op {
+			continue
+		}
+		name := seg[0].Text
+		if sessionBuiltins[name] {
+			return name + " only knows about your own shell session"
+		}
+		if name != "kill" && name != "wait" {
+			continue
+		}
+		for _, t := range seg[1:] {
+			if !t.Op && strings.HasPrefix(t.Text, "%") {
+				return "job " + t.Text + " only exists in your own shell session"
+			}
+		}
+	}
+	return ""
+}
+
+// Command builds an exec.Cmd for line: a direct exec when the line is a plain
+// argv, otherwise sh -c. Both go through safeexec for the Termux protections.
+// Lines that need the user's shell session (see SessionOnly) are refused.
+func Command(line string) (*exec.Cmd, error) {
+	toks, err := Tokenize(line)
+	if err != nil {
+		return nil, err
+	}
+	if len(toks) == 0 {
+		return nil, fmt.Errorf("empty command")
+	}
+	if why := SessionOnly(toks); why != "" {
+		return nil, fmt.Errorf("%s; run it in your shell instead", why)
+	}
+	if NeedsShell(toks) {
+		return safeexec.Command("sh", "-c", line), nil
+	}
+	argv := make([]string, len(toks))
+	for i, t := range toks {
+		argv[i] = t.Text
+	}
+	return safeexec.Command(argv[0], argv[1:]...), nil
+}
+
+func isOpStart(c byte) bool {
+	return strings.IndexByte("|&;()<>", c) >= 0
+}
+
+func matchOp(s string) string {
+	for _, op := range opsByLength {
+		if strings.HasPrefix(s, op) {
+			return op
+		}
+	}
+	return s[:1]
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
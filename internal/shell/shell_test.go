@@ -0,0 +1,208 @@
+package shell
+
+import (
+	"clio/internal/layer1"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func words(toks []Token) []string {
+	out := make([]string, len(toks))
+	for i, t := range toks {
+		out[i] = t.Text
+	}
+	return out
+}
+
+func TestTokenize(t *testing.T) {
+	cases := []struct {
+		line string
+		want []string
+	}{
+		{`find . -name "*.pdf"`, []string{"find", ".", "-name", "*.pdf"}},
+		{`grep -r 'two words' .`, []string{"grep", "-r", "two words", "."}},
+		{`echo a\ b`, []string{"echo", "a b"}},
+		{`history | grep git`, []string{"history", "|", "grep", "git"}},
+		{`make&&make install`, []string{"make", "&&", "make", "install"}},
+		{`ls >out.txt 2>&1`, []string{"ls", ">", "out.txt", "2>&", "1"}},
+		{`echo "$HOME/x"`, []string{"echo", "$HOME/x"}},
+		{`echo $(date +%s) done`, []string{"echo", "$(date +%s)", "done"}},
+		{`ls # a comment`, []string{"ls"}},
+		{`git commit -m "fix \"quotes\""`, []string{"git", "commit", "-m", `fix "quotes"`}},
+	}
+	for _, c := range cases {
+		toks, err := Tokenize(c.line)
+		if err != nil {
+			t.Errorf("Tokenize(%q): %v", c.line, err)
+			continue
+		}
+		if got := words(toks); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("Tokenize(%q) = %q, want %q", c.line, got, c.want)
+		}
+	}
+}
+
+func TestTokenizeErrors(t *testing.T) {
+	for _, line := range []string{`echo "open`, `echo 'open`, "echo `date", "echo $(date"} {
+		if _, err := Tokenize(line); err == nil {
+			t.Errorf("Tokenize(%q) succeeded, want error", line)
+		}
+	}
+}
+
+func TestNeedsShell(t *testing.T) {
+	cases := []struct {
+		line string
+		want bool
+	}{
+		{`ls -la`, false},
+		{`find . -name "*.pdf"`, false}, // quoted glob is passed through literally
+		{`find . -type f -exec rm {} +`, false},
+		{`tar -xzvf "my backup.tar.gz"`, false},
+		{`history | grep git`, true},
+		{`cd ~/projects && ls`, true},
+		{`du -sh * | sort -h`, true},
+		{`ls ~`, true},
+		{`echo $HOME`, true},
+		{`echo hi > out.txt`, true},
+		{`cd projects`, true},
+		{`FOO=1 env`, true},
+	}
+	for _, c := range cases {
+		toks, err := Tokenize(c.line)
+		if err != nil {
+			t.Fatalf("Tokenize(%q): %v", c.line, err)
+		}
+		if got := NeedsShell(toks); got != c.want {
+			t.Errorf("NeedsShell(%q) = %v, want %v", c.line, got, c.want)
+		}
+	}
+}
+
+func TestCommand(t *testing.T) {
+	cmd, err := Command(`printf '%s|' "a b" c`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cmd.Args[1:]; !reflect.DeepEqual(got, []string{"%s|", "a b", "c"}) {
+		t.Errorf("direct argv = %q", got)
+	}
+	out, err := cmd.Output()
+	if err != nil || string(out) != "a b|c|" {
+		t.Errorf("output = %q, %v", out, err)
+	}
+
+	cmd, err = Command(`printf x | tr x y`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmd.Args[0] != "sh" && !strings.HasSuffix(cmd.Path, "/sh") {
+		t.Errorf("pipeline not run via sh: %q", cmd.Args)
+	}
+	if out, err := cmd.Output(); err != nil || string(out) != "y" {
+		t.Errorf("pipeline output = %q, %v", out, err)
+	}
+}
+
+// Every built-in catalog command must tokenize, and the ones that broke under
+// strings.Fields (pipes, quoted patterns) must get the right strategy.
+func TestCatalogCommandsTokenize(t *testing.T) {
+	for _, e := range layer1.CatalogCommands() {
+		filled, _ := layer1.FillSlots(e.Cmd, layer1.Args{})
+		if _, err := Tokenize(filled); err != nil {
+			t.Errorf("Tokenize(%q): %v", filled, err)
+		}
+	}
+
+	fixed := []struct {
+		cmd   string
+		shell bool
+		words []string
+	}{
+		{`find . -name "*assignment*"`, false, []string{"find", ".", "-name", "*assignment*"}},
+		{`git commit -m "update"`, false, []string{"git", "commit", "-m", "update"}},
+		{`grep "ssh" ~/.bash_history`, true, nil},
+		{`find Documents -name "notes.pdf"`, false, []string{"find", "Documents", "-name", "notes.pdf"}},
+		{`cat 'my notes.txt'`, false, []string{"cat", "my notes.txt"}},
+	}
+	for _, c := range fixed {
+		toks, err := Tokenize(c.cmd)
+		if err != nil {
+			t.Fatalf("Tokenize(%q): %v", c.cmd, err)
+		}
+		if got := NeedsShell(toks); got != c.shell {
+			t.Errorf("NeedsShell(%q) = %v, want %v", c.cmd, got, c.shell)
+		}
+		if c.words != nil && !reflect.DeepEqual(words(toks), c.words) {
+			t.Errorf("Tokenize(%q) = %q, want %q", c.cmd, words(toks), c.words)
+		}
+	}
+}
+
+// history and job specs need the user's own shell; a new sh has neither.
+func TestSessionOnly(t *testing.T) {
+	cases := []struct {
+		cmd  string
+		want bool
+	}{
+		{`history | grep "ssh"`, true},
+		{"jobs", true},
+		{"fg %2", true},
+		{"kill %1", true},
+		{"sleep 1 && wait %1", true},
+		{"kill -9 4312", false},
+		{"printf '%s\\n' hi", false},
+		{"date +%s", false},
+		{`grep "ssh" ~/.bash_history`, false},
+	}
+	for _, c := range cases {
+		toks, err := Tokenize(c.cmd)
+		if err != nil {
+			t.Fatalf("Tokenize(%q): %v", c.cmd, err)
+		}
+		if got := SessionOnly(toks) != ""; got != c.want {
+			t.Errorf("SessionOnly(%q) = %q, want refused = %v", c.cmd, SessionOnly(toks), c.want)
+		}
+		if _, err := Command(c.cmd); (err != nil) != c.want {
+			t.Errorf("Command(%q) error = %v, want refused = %v", c.cmd, err, c.want)
+		}
+	}
+}
+
+func TestUnwrap(t *testing.T) {
+	cases := []struct {
+		line       string
+		args       string
+		script     string
+		privileged bool
+	}{
+		{"rm -rf build", "rm -rf build", "", false},
+		{"sudo -u postgres psql", "psql", "", true},
+		{"sudo -E -u postgres -- psql -c 'select 1'", "psql -c select 1", "", true},
+		{"sudo --user=postgres psql", "psql", "", true},
+		{"env -i -u HOME LANG=C sort file", "sort file", "", false},
+		{"env -S 'rm -rf' build", "", "rm -rf build", false},
+		{"FOO=1 BAR=2 make", "make", "", false},
+		{"xargs -0 -n 10 -I{} rm {}", "rm {}", "", false},
+		{"xargs", "echo", "", false},
+		{"nice -n 10 nohup timeout -s KILL 5s ./run.sh", "./run.sh", "", false},
+		{"nice -5 make", "make", "", false},
+		{"sh -c 'rm -rf ~' sh", "", "rm -rf ~", false},
+		{"bash -lc 'ls'", "", "ls", false},
+		{"bash script.sh", "bash script.sh", "", false},
+		{"su -c 'apt update'", "", "apt update", true},
+		{"su root", "", "", true},
+	}
+	for _, c := range cases {
+		toks, err := Tokenize(c.line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		u := Unwrap(words(toks))
+		if got := strings.Join(u.Args, " "); got != c.args || u.Script != c.script || u.Privileged != c.privileged {
+			t.Errorf("Unwrap(%q) = args %q, script %q, privileged %v; want %q, %q, %v",
+				c.line, got, u.Script, u.Privileged, c.args, c.script, c.privileged)
+		}
+	}
+}
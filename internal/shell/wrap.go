@@ -0,0 +1,169 @@
+package shell
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// wrapper describes a command that runs the command in its arguments.
+type wrapper struct {
+	// valueFlags are the short options that take a value: the rest of their
+	// word or the next word. valueLong are the long ones, when not written
+	// --opt=value.
+	valueFlags string
+	valueLong  []string
+	privileged bool
+	// operands is how many words after the options come before the command,
+	// such as timeout's duration.
+	operands int
+	// scriptFlag and scriptLong are the options whose value is a shell
+	// script (su -c, env -S). With scriptOperand, scriptFlag takes no value
+	// and the script is the first operand instead (sh -c).
+	scriptFlag    byte
+	scriptLong    string
+	scriptOperand bool
+}
+
+var shellWrapper = wrapper{valueFlags: "oO", scriptFlag: 'c', scriptOperand: true}
+
+var wrappers = map[string]wrapper{
+	"sudo": {valueFlags: "ugCDhpRrTtU", privileged: true, valueLong: []string{
+		"--user", "--group", "--chdir", "--host", "--prompt", "--role", "--type", "--other-user", "--close-from", "--command-timeout"}},
+	"doas":    {valueFlags: "uC", privileged: true},
+	"su":      {valueFlags: "cgGsw", valueLong: []string{"--command", "--group", "--supp-group", "--shell", "--whitelist-environment"}, privileged: true, scriptFlag: 'c', scriptLong: "--command"},
+	"pkexec":  {valueLong: []string{"--user"}, privileged: true},
+	"tsu":     {valueFlags: "se", privileged: true},
+	"env":     {valueFlags: "uCS", valueLong: []string{"--unset", "--chdir", "--split-string"}, scriptFlag: 'S', scriptLong: "--split-string"},
+	"nice":    {valueFlags: "n", valueLong: []string{"--adjustment"}},
+	"nohup":   {},
+	"time":    {valueFlags: "fo", valueLong: []string{"--format", "--output"}},
+	"timeout": {valueFlags: "ks", valueLong: []string{"--kill-after", "--signal"}, operands: 1},
+	"xargs": {valueFlags: "adEeIiLlnPs", valueLong: []string{
+		"--arg-file", "--delimiter", "--eof", "--replace", "--max-lines", "--max-args", "--max-procs", "--max-chars", "--process-slot-var"}},
+	"sh": shellWrapper, "bash": shellWrapper, "dash": shellWrapper, "zsh": shellWrapper,
+	"ksh": shellWrapper, "mksh": shellWrapper, "ash": shellWrapper,
+}
+
+// Unwrapped is a simple command with the wrappers in front of it removed.
+type Unwrapped struct {
+	Args       []string // the command that finally runs and its arguments; empty for a script or a login shell
+	Wrappers   []string // wrappers skipped, outermost first
+	Privileged bool     // one of them runs the command as another user
+	Script     string   // a script a wrapper runs: sh -c, su -c, env -S
+}
+
+// Unwrap looks through VAR=value prefixes and wrappers that run another
+// command (sudo, doas, su, env, nice, nohup, time, timeout, xargs, sh -c),
+// with their options, to the command that actually runs. A shell without -c
+// runs a script file, so it is the command itself; xargs without a command
+// runs echo.
+func Unwrap(fields []string) Unwrapped {
+	var u Unwrapped
+	for len(fields) > 0 {
+		if isAssignment(fields[0]) {
+			fields = fields[1:]
+			continue
+		}
+		name := filepath.Base(fields[0])
+		w, ok := wrappers[name]
+		if !ok {
+			break
+		}
+		rest, script, hasScript := w.skip(fields[1:])
+		if w.scriptOperand && !hasScript {
+			break
+		}
+		u.Wrappers = append(u.Wrappers, name)
+		u.Privileged = u.Privileged || w.privileged
+		if hasScript {
+			u.Script = script
+			if name == "env" && len(rest) > 0 {
+				u.Script += " " + strings.Join(rest, " ")
+			}
+			return u
+		}
+		if name == "su" {
+			return u // a login shell as the other user
+		}
+		for i := 0; i < w.operands && len(rest) > 0; i++ {
+			rest = rest[1:]
+		}
+		for name == "env" && len(rest) > 0 && isAssignment(rest[0]) {
+			rest = rest[1:]
+		}
+		if name == "xargs" && len(rest) == 0 {
+			rest = []string{"echo"}
+		}
+		fields = rest
+	}
+	u.Args = fields
+	return u
+}
+
+// skip drops w's options from args and returns what follows, and the script
+// given to the wrapper if any.
+func (w wrapper) skip(args []string) (rest []string, script string, hasScript bool) {
+	shellC := false
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") && args[0] != "-" {
+		arg := args[0]
+		args = args[1:]
+		if arg == "--" {
+			break
+		}
+		if strings.HasPrefix(arg, "--") {
+			name, value, inline := strings.Cut(arg, "=")
+			if !inline && contains(w.valueLong, name) && len(args) > 0 {
+				value, args = args[0], args[1:]
+			}
+			if w.scriptLong != "" && name == w.scriptLong {
+				script, hasScript = value, true
+			}
+			continue
+		}
+		for i := 1; i < len(arg); i++ {
+			c := arg[i]
+			if w.scriptOperand && c == w.scriptFlag {
+				shellC = true
+				continue
+			}
+			if strings.IndexByte(w.valueFlags, c) < 0 {
+				continue
+			}
+			value := arg[i+1:]
+			if value == "" && len(args) > 0 {
+				value, args = args[0], args[1:]
+			}
+			if c == w.scriptFlag {
+				script, hasScript = value, true
+			}
+			break
+		}
+	}
+	if shellC && len(args) > 0 {
+		return args[1:], args[0], true
+	}
+	return args, script, hasScript
+}
+
+// isAssignment reports whether word is a NAME=value prefix.
+func isAssignment(word string) bool {
+	name, _, ok := strings.Cut(word, "=")
+	if !ok || name == "" || name[0] >= '0' && name[0] <= '9' {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		if !isNameChar(name[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
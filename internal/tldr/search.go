@@ -0,0 +1,229 @@
+package tldr
+
+import (
+	"clio/internal/config"
+	"clio/internal/layer1"
+	"clio/internal/layer3"
+	"clio/internal/platform"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Result is the best example of one page for a query.
+type Result struct {
+	Name            string // page, e.g. "tar" or "git commit"
+	Platform        string
+	PageDescription string
+	Description     string // what the example does
+	Command         string // example command line with {{placeholders}}
+	Score           int
+	Hits            int // query words matched
+	Terms           int // query words searched for
+}
+
+// maxResults is how many pages Search returns, like layer2.Search.
+const maxResults = 5
+
+// rowLimit caps the examples read per search, best BM25 match first;
+// liteRowLimit is the cap on the lite profile.
+const (
+	rowLimit     = 500
+	liteRowLimit = 100
+)
+
+// placeholderRe matches {{path/to/file}}, whose words say nothing about what
+// the example does.
+var placeholderRe = regexp.MustCompile(`\{\{.*?\}\}`)
+
+// queryStopWords are question words Tokenize keeps for the catalog but which
+// would match half of tldr's descriptions.
+var queryStopWords = map[string]bool{
+	"how": true, "what": true, "where": true, "when": true, "why": true, "do": true,
+	"my": true, "there": true, "here": true, "out": true, "all": true, "some": true,
+}
+
+// Platforms lists the tldr page directories that apply to p, most specific
+// first: a linux page of a command is preferred over its common page.
+func Platforms(p platform.Info) []string {
+	var out []string
+	if p.Termux {
+		out = append(out, "android")
+	}
+	switch p.OS {
+	case "linux", "android":
+		out = append(out, "linux")
+	case "darwin":
+		out = append(out, "osx")
+	case "freebsd", "openbsd", "netbsd":
+		out = append(out, p.OS)
+	case "solaris", "illumos":
+		out = append(out, "sunos")
+	}
+	return append(out, "common")
+}
+
+// Search finds imported examples for a natural-language query. Each query
+// word, or one of its catalog aliases, scores five points when it names the
+// page, three when it appears in the example's description or command line and
+// one when only the page description has it. The best example of each page is
+// returned, highest score first.
+func Search(query string, p platform.Info) ([]Result, error) {
+	terms := queryTerms(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	var flat []string
+	seen := make(map[string]bool)
+	for _, alts := range terms {
+		for _, a := range alts {
+			if !seen[a] {
+				seen[a] = true
+				flat = append(flat, a)
+			}
+		}
+	}
+	limit := rowLimit
+	if config.IsLiteProfile() {
+		limit = liteRowLimit
+	}
+	platforms := Platforms(p)
+	matches, err := layer3.SearchTLDR(flat, platforms, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	rank := make(map[string]int, len(platforms))
+	for i, pl := range platforms {
+		rank[pl] = i
+	}
+	best := make(map[string]*Result) // by page name
+	var order []string
+	for _, m := range matches {
+		r := score(m, terms)
+		if r.Hits == 0 {
+			continue
+		}
+		cur, ok := best[m.Name]
+		switch {
+		case !ok:
+			order = append(order, m.Name)
+		case rank[m.Platform] > rank[cur.Platform]:
+			continue
+		case rank[m.Platform] == rank[cur.Platform] && r.Score <= cur.Score:
+			continue
+		}
+		best[m.Name] = &r
+	}
+
+	out := make([]Result, 0, len(order))
+	for _, name := range order {
+		out = append(out, *best[name])
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Score != out[j].Score {
+			return out[i].Score > out[j].Score
+		}
+		return out[i].Hits > out[j].Hits
+	})
+	if len(out) > maxResults {
+		out = out[:maxResults]
+	}
+	return out, nil
+}
+
+func score(m layer3.TLDRMatch, terms [][]string) Result {
+	r := Result{
+		Name:            m.Name,
+		Platform:        m.Platform,
+		PageDescription: m.PageDescription,
+		Description:     m.Description,
+		Command:         m.Command,
+		Terms:           len(terms),
+	}
+	name := stems(m.Name)
+	for _, w := range words(m.Name) {
+		name[w] = true
+	}
+	example := stems(m.Description + " " + placeholderRe.ReplaceAllString(m.Command, " "))
+	page := stems(m.PageDescription)
+	for _, alts := range terms {
+		switch {
+		case anyIn(name, alts):
+			r.Score += 5
+		case anyIn(example, alts):
+			r.Score += 3
+		case anyIn(page, alts):
+			r.Score++
+		default:
+			continue
+		}
+		r.Hits++
+	}
+	return r
+}
+
+// queryTerms returns, for each meaningful query word, its stem and the stems
+// of its catalog aliases. Arguments such as file names and numbers are not
+// search words.
+func queryTerms(query string) [][]string {
+	var terms [][]string
+	for _, w := range strings.Fields(strings.ToLower(query)) {
+		w = strings.Trim(w, "?!.,;:\"'()")
+		if w == "" || queryStopWords[w] || strings.ContainsAny(w, "./~@*") || isNumber(w) ||
+			len(layer1.Tokenize(w)) == 0 {
+			continue
+		}
+		s := layer1.Stem(w)
+		alts := []string{s}
+		for _, alias := range []string{layer1.VerbAliases[s], layer1.NounAliases[s]} {
+			if alias != "" && layer1.Stem(alias) != s {
+				alts = append(alts, layer1.Stem(alias))
+			}
+		}
+		terms = append(terms, alts)
+	}
+	return terms
+}
+
+func isNumber(w string) bool {
+	for _, r := range w {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// words splits s into lowercase words at anything but letters, digits and
+// inner "-" or "_": "--recursive" gives "recursive".
+func words(s string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_'
+	})
+	out := fields[:0]
+	for _, f := range fields {
+		if f = strings.Trim(f, "-_"); f != "" {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+func stems(s string) map[string]bool {
+	out := make(map[string]bool)
+	for _, w := range words(s) {
+		out[layer1.Stem(w)] = true
+	}
+	return out
+}
+
+func anyIn(set map[string]bool, alts []string) bool {
+	for _, a := range alts {
+		if set[a] {
+			return true
+		}
+	}
+	return false
+}
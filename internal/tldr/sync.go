@@ -0,0 +1,43 @@
+package tldr
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+const maxArchiveBytes = 64 << 20 // the English pages zip is a few MiB
+
+var syncHTTP = &http.Client{Timeout: 2 * time.Minute}
+
+// Sync downloads a tldr-pages zip archive and imports it. The previous import
+// is kept when the download or import fails.
+func Sync(url string) (int, error) {
+	resp, err := syncHTTP.Get(url)
+	if err != nil {
+		return 0, fmt.Errorf("failed to download tldr pages: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("tldr pages download returned status %d", resp.StatusCode)
+	}
+
+	tmp, err := os.CreateTemp("", "clio-tldr-*.zip")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+	n, err := io.Copy(tmp, io.LimitReader(resp.Body, maxArchiveBytes+1))
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return 0, err
+	}
+	if n > maxArchiveBytes {
+		return 0, fmt.Errorf("tldr archive exceeds %d byte limit", maxArchiveBytes)
+	}
+	return importFrom(tmp.Name(), url)
+}
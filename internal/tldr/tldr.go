@@ -0,0 +1,156 @@
+// Package tldr imports tldr-pages (https://tldr.sh) into clio's database and
+// searches them. Pages are short markdown files of described example command
+// lines, which makes them useful where man pages are missing, as on Termux.
+package tldr
+
+import (
+	"archive/zip"
+	"bufio"
+	"clio/internal/layer3"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// mnemonicRe matches tldr's option mnemonics: "[r]ecursively" is "recursively".
+var mnemonicRe = regexp.MustCompile(`\[([A-Za-z])\]`)
+
+// ParsePage reads one page in the tldr markdown format:
+//
+//	# tar
+//
+//	> Archiving utility.
+//	> More information: <https://www.gnu.org/software/tar>.
+//
+//	- [c]reate an archive from files:
+//
+//	`tar cf {{path/to/target.tar}} {{path/to/file1 path/to/file2 ...}}`
+//
+// Examples keep their {{placeholders}}; layer1.ParseSlots understands them.
+func ParsePage(r io.Reader, platform string) (layer3.TLDRPage, error) {
+	page := layer3.TLDRPage{Platform: platform}
+	var desc []string
+	pending := "" // description waiting for its command line
+
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		switch {
+		case strings.HasPrefix(line, "# ") && page.Name == "":
+			page.Name = strings.TrimSpace(line[2:])
+		case strings.HasPrefix(line, ">"):
+			d := strings.TrimSpace(line[1:])
+			if d != "" && !strings.HasPrefix(d, "More information:") {
+				desc = append(desc, d)
+			}
+		case strings.HasPrefix(line, "- "):
+			pending = mnemonicRe.ReplaceAllString(strings.TrimSuffix(strings.TrimSpace(line[2:]), ":"), "$1")
+		case len(line) > 2 && line[0] == '`' && line[len(line)-1] == '`' && pending != "":
+			page.Examples = append(page.Examples, layer3.TLDRExample{
+				Description: capitalize(pending),
+				Command:     line[1 : len(line)-1],
+			})
+			pending = ""
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return page, err
+	}
+	if page.Name == "" {
+		return page, fmt.Errorf("no \"# name\" heading")
+	}
+	page.Description = strings.Join(desc, " ")
+	return page, nil
+}
+
+// Import reads the English pages from a tldr-pages zip archive or a checkout
+// of the repository and replaces the imported pages with them. It returns the
+// number of pages imported.
+func Import(src string) (int, error) {
+	return importFrom(src, src)
+}
+
+func importFrom(src, source string) (int, error) {
+	var pages []layer3.TLDRPage
+	add := func(name string, open func() (io.ReadCloser, error)) error {
+		platform, ok := pagePlatform(name)
+		if !ok {
+			return nil
+		}
+		rc, err := open()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		page, err := ParsePage(rc, platform)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		pages = append(pages, page)
+		return nil
+	}
+
+	info, err := os.Stat(src)
+	if err != nil {
+		return 0, err
+	}
+	if info.IsDir() {
+		err = filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			return add(filepath.ToSlash(p), func() (io.ReadCloser, error) { return os.Open(p) })
+		})
+	} else {
+		var zr *zip.ReadCloser
+		if zr, err = zip.OpenReader(src); err != nil {
+			return 0, fmt.Errorf("%s: not a tldr-pages zip archive or directory: %w", src, err)
+		}
+		defer zr.Close()
+		for _, f := range zr.File {
+			if err = add(f.Name, f.Open); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+	if len(pages) == 0 {
+		return 0, fmt.Errorf("%s: no tldr pages found (expected pages/<platform>/<command>.md)", src)
+	}
+	if err := layer3.ReplaceTLDR(pages, source); err != nil {
+		return 0, err
+	}
+	return len(pages), nil
+}
+
+// pagePlatform returns the platform of an English page path such as
+// "tldr-main/pages/linux/apt.md". Translations live in pages.<lang>/ and are
+// skipped, as are Windows pages, which clio never runs.
+func pagePlatform(name string) (string, bool) {
+	if path.Ext(name) != ".md" {
+		return "", false
+	}
+	parts := strings.Split(name, "/")
+	if len(parts) < 3 || parts[len(parts)-3] != "pages" {
+		return "", false
+	}
+	platform := parts[len(parts)-2]
+	if platform == "windows" {
+		return "", false
+	}
+	return platform, true
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
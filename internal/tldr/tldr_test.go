@@ -0,0 +1,231 @@
+package tldr
+
+import (
+	"archive/zip"
+	"clio/internal/layer3"
+	"clio/internal/platform"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var (
+	termux = platform.Info{OS: "linux", Distro: "termux", PackageManager: "pkg", Termux: true}
+	macos  = platform.Info{OS: "darwin", Distro: "macos", PackageManager: "brew"}
+)
+
+const tarPage = `# tar
+
+> Archiving utility.
+> Often combined with a compression method, such as gzip or bzip2.
+> More information: <https://www.gnu.org/software/tar>.
+
+- [c]reate an archive and write it to a file:
+
+` + "`tar cf {{path/to/target.tar}} {{path/to/file1 path/to/file2 ...}}`" + `
+
+- E[x]tract a (compressed) archive file into the current directory [v]erbosely:
+
+` + "`tar xvf {{path/to/source.tar[.gz|.bz2|.xz]}}`" + `
+`
+
+// pages is a small tldr-pages tree: English pages, a translation and a
+// Windows page that must be skipped.
+var pages = map[string]string{
+	"tldr/pages/common/tar.md":  tarPage,
+	"tldr/pages/common/free.md": "# free\n\n> Display memory usage.\n\n- Display system memory:\n\n`free`\n",
+	"tldr/pages/linux/free.md": "# free\n\n> Display amount of free and used memory in the system.\n\n" +
+		"- Display memory in human-readable units:\n\n`free {{[-h|--human]}}`\n",
+	"tldr/pages/osx/vm_stat.md": "# vm_stat\n\n> Show virtual memory statistics.\n\n" +
+		"- Display memory statistics:\n\n`vm_stat`\n",
+	"tldr/pages/android/pm.md": "# pm\n\n> Display information about apps on an Android device.\n\n" +
+		"- List all installed apps:\n\n`pm list packages`\n",
+	"tldr/pages.fr/common/tar.md": "# tar\n\n> Archiveur.\n\n- Extraire une archive:\n\n`tar xf {{archive.tar}}`\n",
+	"tldr/pages/windows/dir.md":   "# dir\n\n> List directory contents.\n\n- List files:\n\n`dir`\n",
+	"tldr/README.md":              "# tldr\n",
+}
+
+func writeTree(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, body := range pages {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func writeZip(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "tldr.zip")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for name, body := range pages {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(body))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	return path
+}
+
+func TestParsePage(t *testing.T) {
+	page, err := ParsePage(strings.NewReader(tarPage), "common")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := layer3.TLDRPage{
+		Name:        "tar",
+		Platform:    "common",
+		Description: "Archiving utility. Often combined with a compression method, such as gzip or bzip2.",
+		Examples: []layer3.TLDRExample{
+			{Description: "Create an archive and write it to a file", Command: "tar cf {{path/to/target.tar}} {{path/to/file1 path/to/file2 ...}}"},
+			{Description: "Extract a (compressed) archive file into the current directory verbosely", Command: "tar xvf {{path/to/source.tar[.gz|.bz2|.xz]}}"},
+		},
+	}
+	if !reflect.DeepEqual(page, want) {
+		t.Fatalf("ParsePage = %+v\nwant %+v", page, want)
+	}
+	if _, err := ParsePage(strings.NewReader("- no heading:\n\n`x`\n"), "common"); err == nil {
+		t.Error("page without heading parsed")
+	}
+}
+
+func TestPagePlatform(t *testing.T) {
+	tests := []struct {
+		name     string
+		platform string
+		ok       bool
+	}{
+		{"pages/linux/apt.md", "linux", true},
+		{"tldr-main/pages/common/git-commit.md", "common", true},
+		{"pages.de/common/tar.md", "", false},
+		{"pages/windows/dir.md", "", false},
+		{"pages/common/tar.txt", "", false},
+		{"README.md", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := pagePlatform(tt.name)
+		if got != tt.platform || ok != tt.ok {
+			t.Errorf("pagePlatform(%q) = %q, %v; want %q, %v", tt.name, got, ok, tt.platform, tt.ok)
+		}
+	}
+}
+
+func TestImportAndSearch(t *testing.T) {
+	layer3.UseTestDB(t)
+	for _, src := range []string{writeTree(t), writeZip(t)} {
+		n, err := Import(src)
+		if err != nil || n != 5 {
+			t.Fatalf("Import(%s) = %d, %v; want 5 pages", src, n, err)
+		}
+		status, err := layer3.GetTLDRStatus()
+		if err != nil || status == nil || status.Pages != 5 || status.Source != src {
+			t.Fatalf("GetTLDRStatus = %+v, %v", status, err)
+		}
+	}
+
+	tests := []struct {
+		query   string
+		p       platform.Info
+		page    string
+		command string
+	}{
+		{"extract archive backup.tar.gz", termux, "tar", "tar xvf {{path/to/source.tar[.gz|.bz2|.xz]}}"},
+		{"create an archive", termux, "tar", "tar cf {{path/to/target.tar}} {{path/to/file1 path/to/file2 ...}}"},
+		{"memory usage", termux, "free", "free {{[-h|--human]}}"}, // linux page wins over common
+		{"memory statistics", macos, "vm_stat", "vm_stat"},
+		{"list installed apps", termux, "pm", "pm list packages"},
+	}
+	for _, tt := range tests {
+		got, err := Search(tt.query, tt.p)
+		if err != nil || len(got) == 0 || got[0].Name != tt.page || got[0].Command != tt.command {
+			t.Errorf("Search(%q) = %+v, %v; want %s: %s", tt.query, got, err, tt.page, tt.command)
+		}
+	}
+	if got, _ := Search("list installed apps", macos); len(got) > 0 && got[0].Name == "pm" {
+		t.Error("android page offered on macOS")
+	}
+	if got, _ := Search("xyzzy", termux); len(got) != 0 {
+		t.Errorf("Search(nonsense) = %+v", got)
+	}
+
+	// A failed import keeps the previous pages
+	if _, err := Import(t.TempDir()); err == nil {
+		t.Error("Import of an empty directory succeeded")
+	}
+	if got, _ := Search("create an archive", termux); len(got) == 0 {
+		t.Error("failed import dropped the previous pages")
+	}
+}
+
+// Rows are ranked before the row limit applies, so a page imported after
+// hundreds of weaker matches is still found.
+func TestSearchRanksBeforeLimit(t *testing.T) {
+	layer3.UseTestDB(t)
+	var imported []layer3.TLDRPage
+	for i := 0; i < 3*liteRowLimit; i++ {
+		imported = append(imported, layer3.TLDRPage{
+			Name: fmt.Sprintf("tool%d", i), Platform: "common", Description: "Work with files.",
+			Examples: []layer3.TLDRExample{{Description: "Show a file", Command: "tool {{file}}"}},
+		})
+	}
+	imported = append(imported, layer3.TLDRPage{
+		Name: "shred", Platform: "common", Description: "Overwrite files to hide their contents.",
+		Examples: []layer3.TLDRExample{{Description: "Delete a file securely", Command: "shred -u {{file}}"}},
+	})
+	if err := layer3.ReplaceTLDR(imported, "test"); err != nil {
+		t.Fatal(err)
+	}
+	matches, err := layer3.SearchTLDR([]string{"delet", "file", "secur"}, []string{"common"}, liteRowLimit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) == 0 || matches[0].Name != "shred" {
+		t.Errorf("SearchTLDR = %d matches, first %+v; want shred first", len(matches), matches[:min(1, len(matches))])
+	}
+}
+
+func TestSync(t *testing.T) {
+	layer3.UseTestDB(t)
+	archive, err := os.ReadFile(writeZip(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/tldr.zip" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(archive)
+	}))
+	defer srv.Close()
+
+	if n, err := Sync(srv.URL + "/tldr.zip"); err != nil || n != 5 {
+		t.Fatalf("Sync = %d, %v", n, err)
+	}
+	if status, _ := layer3.GetTLDRStatus(); status == nil || status.Source != srv.URL+"/tldr.zip" {
+		t.Errorf("GetTLDRStatus after sync = %+v", status)
+	}
+	if _, err := Sync(srv.URL + "/missing.zip"); err == nil {
+		t.Error("Sync from a missing URL succeeded")
+	}
+}